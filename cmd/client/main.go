@@ -1,14 +1,26 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"os/user"
+	"slices"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/hersh/gotris/internal/netclient"
+	"github.com/hersh/gotris/internal/export"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/logging"
+	"github.com/hersh/gotris/internal/overlay"
+	"github.com/hersh/gotris/internal/prefs"
 	"github.com/hersh/gotris/internal/tui"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
 )
 
 // DefaultServer is the default server address.
@@ -19,10 +31,99 @@ var DefaultServer = "http://localhost:8080"
 
 func main() {
 	serverAddr := flag.String("server", DefaultServer, "Server HTTP address")
-	playerName := flag.String("name", "", "Player name (defaults to OS username)")
+	playerName := flag.String("name", "", "Player name (defaults to saved name, then OS username)")
+	reducedMotion := flag.Bool("reduced-motion", false, "Skip decorative animations (saved for next time)")
+	sound := flag.Bool("sound", false, "Ring the terminal bell on game events (saved for next time)")
+	sdf := flag.Int("sdf", 0, fmt.Sprintf("Soft drop factor: how many times faster than gravity a held soft drop falls, 1-%d (default %d, saved for next time)", tui.MaxSoftDropFactor, tui.DefaultSoftDropFactor))
+	keymap := flag.String("keymap", "", "Gameplay keys: "+strings.Join(tui.KeymapNames(), ", ")+" (default guideline, saved for next time)")
+	mono := flag.Bool("mono", false, "Draw without color, telling pieces apart by fill pattern (default on with NO_COLOR or under 256 colors, saved for next time)")
+	lang := flag.String("lang", "", "UI language: "+strings.Join(i18n.Langs(), ", ")+" (saved for next time)")
+	profileKey := flag.String("profile-key", "", "Use this profile key, copied from profile_key in another machine's prefs.json, to share its profile (saved for next time)")
+	overlayAddr := flag.String("overlay-addr", "", "Serve live game state as JSON for stream overlays, e.g. localhost:7070")
+	overlayFile := flag.String("overlay-file", "", "Write live game state as JSON to this file for stream overlays")
+	proxy := flag.String("proxy", "", "Proxy for all server traffic, http://host:port or socks5://host:port (default: HTTP_PROXY/HTTPS_PROXY)")
+	simLatency := flag.Duration("sim-latency", 0, "Development: delay every message sent and received by this much")
+	simJitter := flag.Duration("sim-jitter", 0, "Development: add up to this much random delay on top of --sim-latency")
+	simDrop := flag.Float64("sim-drop", 0, "Development: drop this fraction (0-1) of messages sent and received")
+	debugLog := flag.String("debug-log", "", "Write logs to this file (rotated at 1 MiB) instead of discarding them")
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn or error")
+	caCert := flag.String("ca-cert", "", "PEM file of extra CA certificates to trust, for servers with self-signed certificates")
+	exportStats := flag.String("export-stats", "", "Export your game log and match history on the server to this file (.json for JSON, else CSV), then exit")
 	flag.Parse()
 
+	// Saved preferences fill in anything not given on the command line.
+	settings := prefs.Load()
+
+	addr := *serverAddr
+	serverSet := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "server":
+			serverSet = true
+			return
+		case "reduced-motion":
+			settings.ReducedMotion = *reducedMotion
+		case "sound":
+			settings.Sound = *sound
+		case "lang":
+			settings.Lang = *lang
+		case "sdf":
+			settings.SoftDropFactor = *sdf
+		case "keymap":
+			settings.Keymap = *keymap
+		case "mono":
+			settings.Monochrome = mono
+			return
+		default:
+			return
+		}
+		settings.ProfileChanged = true
+	})
+
+	// A new profile key fetches its profile rather than overwriting it.
+	if key := strings.TrimSpace(*profileKey); key != "" && key != settings.ProfileKey {
+		if len(key) < prefs.MinProfileKey {
+			fmt.Fprintf(os.Stderr, "Profile key must be at least %d characters\n", prefs.MinProfileKey)
+			os.Exit(1)
+		}
+		settings.ProfileKey = key
+		settings.ProfileChanged = false
+	}
+
+	if settings.SoftDropFactor < 0 || settings.SoftDropFactor > tui.MaxSoftDropFactor {
+		fmt.Fprintf(os.Stderr, "Soft drop factor must be between 1 and %d, or 0 for the default (%d)\n", tui.MaxSoftDropFactor, tui.DefaultSoftDropFactor)
+		os.Exit(1)
+	}
+
+	if settings.Keymap != "" && !slices.Contains(tui.KeymapNames(), settings.Keymap) {
+		fmt.Fprintf(os.Stderr, "Unknown keymap %q, available: %s\n", settings.Keymap, strings.Join(tui.KeymapNames(), ", "))
+		settings.Keymap = ""
+	}
+
+	if !i18n.SetLang(settings.Lang) {
+		if settings.Lang != "" {
+			fmt.Fprintf(os.Stderr, "Unknown language %q, available: %s\n", settings.Lang, strings.Join(i18n.Langs(), ", "))
+			settings.Lang = ""
+		}
+		i18n.SetLang(i18n.Detect())
+	}
+
+	monochrome := tui.MonochromeByDefault()
+	if settings.Monochrome != nil {
+		monochrome = *settings.Monochrome
+	}
+	tui.SetMonochrome(monochrome)
+
+	if !serverSet && settings.LastServer != "" {
+		addr = settings.LastServer
+	}
+	addr = client.NormalizeServer(addr)
+	settings.AddRecentServer(addr)
+
 	name := *playerName
+	if name == "" {
+		name = settings.PlayerName
+	}
 	if name == "" {
 		if u, err := user.Current(); err == nil && u.Username != "" {
 			name = u.Username
@@ -30,23 +131,94 @@ func main() {
 			name = "Player"
 		}
 	}
+	settings.Save()
+
+	// Logs must stay off the terminal, which the TUI owns. They go to the
+	// --debug-log file, if any, and to the F12 overlay.
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Log level: %v\n", err)
+		os.Exit(1)
+	}
+	logw, err := logging.Open(*debugLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Debug log: %v\n", err)
+		os.Exit(1)
+	}
+	defer logw.Close()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logw, &slog.HandlerOptions{Level: level})))
 
 	// Create the client (HTTP only at startup, no WS connection yet)
-	client := netclient.New(*serverAddr)
-	defer client.Close()
+	var opts []client.Option
+	if *caCert != "" {
+		cfg, err := tlsConfig(*caCert)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "CA certificate: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, client.WithTLSConfig(cfg))
+	}
+	if *proxy != "" {
+		u, err := url.Parse(*proxy)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "socks5") || u.Host == "" {
+			fmt.Fprintf(os.Stderr, "Proxy: want http://host:port or socks5://host:port, got %q\n", *proxy)
+			os.Exit(1)
+		}
+		opts = append(opts, client.WithProxy(u))
+	}
+	if *simLatency > 0 || *simJitter > 0 || *simDrop > 0 {
+		opts = append(opts, client.WithNetSim(client.NetSim{
+			Latency: *simLatency,
+			Jitter:  *simJitter,
+			Drop:    *simDrop,
+		}))
+	}
+	// Save the room session so "rejoin" can resume it after a crash
+	opts = append(opts, client.WithSessionHook(func(s *client.Session) {
+		if s == nil {
+			prefs.SaveSession(nil)
+			return
+		}
+		prefs.SaveSession(&prefs.Session{Server: s.Server, RoomID: s.RoomID, Token: s.Token})
+	}))
+	c := client.New(addr, opts...)
+	defer c.Close()
+
+	if *exportStats != "" {
+		n, err := writeStats(c, settings, *exportStats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Exporting stats: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %d games to %s\n", n, *exportStats)
+		return
+	}
 
 	// Create the bubbletea model
-	model := tui.NewModel(name, client)
+	model := tui.NewModel(name, c, settings).WithDebugLog(logw)
+
+	// Optional stream overlay output
+	if *overlayAddr != "" || *overlayFile != "" {
+		ov := overlay.New(*overlayFile)
+		if *overlayAddr != "" {
+			if err := ov.Listen(*overlayAddr); err != nil {
+				fmt.Fprintf(os.Stderr, "Overlay: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		model = model.WithOverlay(ov)
+	}
 
 	// Create the program
 	p := tea.NewProgram(
 		model,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
+		tea.WithReportFocus(),
 	)
 
-	// Wire the program into the client so WS readPump can send tea.Msgs
-	client.SetProgram(p)
+	// Forward the client's network events to the program
+	go tui.Forward(c, p)
 
 	// Run the TUI (blocking) — no server connection needed to start
 	if _, err := p.Run(); err != nil {
@@ -54,3 +226,51 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// writeStats writes the game log and the server's history of the player
+// IDs in settings to path, returning how many games it wrote. A server
+// that can't be reached is reported, and the game log exported anyway.
+func writeStats(c *client.Client, settings *prefs.Prefs, path string) (int, error) {
+	games, err := prefs.Games()
+	if err != nil {
+		return 0, err
+	}
+	var matches []protocol.MatchRecord
+	for _, id := range settings.RecentPlayerIDs {
+		recs, err := c.MatchHistory(id, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Match history from %s: %v (exporting local games only)\n", c.Server(), err)
+			matches = nil
+			break
+		}
+		matches = append(matches, recs...)
+	}
+	rows := export.Rows(games, matches, settings.RecentPlayerIDs)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := export.Write(f, export.FormatFor(path), rows); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return len(rows), f.Close()
+}
+
+// tlsConfig trusts the certificates in the PEM file at path on top of the
+// system ones.
+func tlsConfig(path string) (*tls.Config, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
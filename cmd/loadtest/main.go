@@ -0,0 +1,90 @@
+// Command loadtest plays a gotris server with simulated players, to see
+// how it holds up under load and where its capacity limits bite.
+//
+// Each player is a pkg/client connection, like the TUI's. Players are put
+// in rooms of --room-size: the first creates the room and the rest join
+// it. Once a room is full, everyone readies up and plays real games on
+// the match seed, placing pieces the way server bots do at --pps. They
+// send board snapshots as often as the TUI does, send the clears they
+// make as attacks, take the garbage sent to them, and ready up again when
+// a match ends. After --duration it prints latency percentiles and
+// counts.
+//
+// The server rations room creation per address, so for more than a few
+// rooms, run it with a CONFIG_FILE that sets "rooms_per_ip": 0.
+//
+//	go run ./cmd/loadtest --server localhost:8080 --players 64 --duration 2m
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/pkg/client"
+)
+
+func main() {
+	serverAddr := flag.String("server", "http://localhost:8080", "Server HTTP address")
+	players := flag.Int("players", 16, "Number of simulated players")
+	roomSize := flag.Int("room-size", 4, "Players per room")
+	duration := flag.Duration("duration", time.Minute, "How long to run")
+	ramp := flag.Duration("ramp", 10*time.Second, "Spread the players' arrival over this long")
+	pps := flag.Float64("pps", 2, "Pieces each player places per second")
+	snapshot := flag.Duration("snapshot", 100*time.Millisecond, "How often each player sends a board snapshot")
+	flag.Parse()
+
+	if *players < 1 || *roomSize < 2 || *pps <= 0 || *snapshot <= 0 {
+		fmt.Fprintln(os.Stderr, "Need at least 1 player, rooms of 2 or more, and positive --pps and --snapshot")
+		os.Exit(1)
+	}
+
+	lt := &loadTest{
+		server:   client.NormalizeServer(*serverAddr),
+		piece:    time.Duration(float64(time.Second) / *pps),
+		snapshot: *snapshot,
+		stats:    newStats(),
+	}
+	if err := client.New(lt.server).CheckServer(lt.server); err != nil {
+		fmt.Fprintf(os.Stderr, "Server %s: %v\n", lt.server, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	rooms := (*players + *roomSize - 1) / *roomSize
+	gap := *ramp / time.Duration(*players)
+	fmt.Printf("%d players in %d rooms against %s for %s\n", *players, rooms, lt.server, *duration)
+
+	var wg sync.WaitGroup
+	for room := range rooms {
+		size := min(*roomSize, *players-room**roomSize)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Stagger arrivals across the whole run, not room by room.
+			lt.runRoom(ctx, room, size, time.Duration(room**roomSize)*gap, gap)
+		}()
+	}
+
+	progress := time.NewTicker(10 * time.Second)
+	defer progress.Stop()
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	start := time.Now()
+	for running := true; running; {
+		select {
+		case <-progress.C:
+			fmt.Printf("%s  %s\n", time.Since(start).Round(time.Second), lt.stats.progress())
+		case <-done:
+			running = false
+		}
+	}
+
+	fmt.Println()
+	lt.stats.report(os.Stdout)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// gravityStep is how often a player's game is moved on between pieces.
+const gravityStep = 50 * time.Millisecond
+
+// loadTest is the run's settings and what it has measured.
+type loadTest struct {
+	server   string
+	piece    time.Duration // time taken over each piece
+	snapshot time.Duration // time between board snapshots
+	stats    *stats
+}
+
+// runRoom fills room number n with size players, arriving gap apart after
+// delay. The first creates the room, and the others join it once it has.
+// A match in progress can't be joined, so no one readies up until the
+// room is full.
+func (lt *loadTest) runRoom(ctx context.Context, n, size int, delay, gap time.Duration) {
+	if !sleep(ctx, delay) {
+		return
+	}
+	host := client.New(lt.server)
+	defer host.Close()
+	start := time.Now()
+	roomID, err := host.Create(fmt.Sprintf("load%d-0", n))
+	if err != nil {
+		lt.stats.fail("create", err)
+		return
+	}
+	lt.stats.observe("create", time.Since(start))
+
+	var wg sync.WaitGroup
+	for i := 1; i < size; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !sleep(ctx, time.Duration(i)*gap) {
+				return
+			}
+			c := client.New(lt.server)
+			defer c.Close()
+			start := time.Now()
+			if err := c.Join(roomID, fmt.Sprintf("load%d-%d", n, i)); err != nil {
+				lt.stats.fail("join", err)
+				return
+			}
+			lt.stats.observe("join", time.Since(start))
+			lt.play(ctx, c, size)
+		}()
+	}
+	lt.play(ctx, host, size)
+	wg.Wait()
+}
+
+// player is one simulated player's state in their room.
+type player struct {
+	c    *client.Client
+	id   string
+	size int        // players the room is waiting for
+	loop *game.Loop // the match being played, nil between matches
+}
+
+// play plays matches in c's room, once it has size players in it, until
+// ctx is done.
+func (lt *loadTest) play(ctx context.Context, c *client.Client, size int) {
+	p := &player{c: c, size: size}
+	think := time.NewTicker(lt.piece)
+	defer think.Stop()
+	gravity := time.NewTicker(gravityStep)
+	defer gravity.Stop()
+	snapshot := time.NewTicker(lt.snapshot)
+	defer snapshot.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-c.Events():
+			lt.handle(p, ev)
+		case <-think.C:
+			if p.loop != nil && p.loop.State().Phase == game.PhaseFalling {
+				p.loop.AutoPlace()
+				lt.advance(p)
+			}
+		case <-gravity.C:
+			if p.loop != nil {
+				lt.advance(p)
+			}
+		case <-snapshot.C:
+			if p.loop != nil {
+				lt.sendSnapshot(p)
+			}
+		}
+	}
+}
+
+// handle deals with something the client reported.
+func (lt *loadTest) handle(p *player, ev client.Event) {
+	switch ev := ev.(type) {
+	case client.ConnectedMsg:
+		p.id = ev.PlayerID
+	case client.ConnStatusMsg:
+		if ev.RTT > 0 {
+			lt.stats.observe("rtt", ev.RTT)
+		}
+	case client.DisconnectedMsg:
+		lt.stats.count("disconnects")
+	case client.ReconnectedMsg:
+		lt.stats.count("reconnects")
+	case client.ServerMsg:
+		if !ev.Sent.IsZero() {
+			lt.stats.observe("delivery", time.Since(ev.Sent))
+		}
+		lt.handleServerMsg(p, ev)
+	}
+}
+
+func (lt *loadTest) handleServerMsg(p *player, msg client.ServerMsg) {
+	switch msg.Type {
+	case protocol.MsgLobbyUpdate:
+		// Readiness is cleared when the room goes back to the lobby.
+		payload, err := protocol.DecodePayload[protocol.LobbyUpdatePayload](msg.Type, msg.Raw)
+		if err != nil || len(payload.Players) < p.size {
+			return
+		}
+		for _, lp := range payload.Players {
+			if lp.PlayerID == p.id && !lp.Ready {
+				p.c.SetReady(true)
+			}
+		}
+	case protocol.MsgGameStart:
+		payload, err := protocol.DecodePayload[protocol.GameStartPayload](msg.Type, msg.Raw)
+		if err != nil {
+			return
+		}
+		s := payload.Settings
+		p.loop = game.NewLoop(game.NewSeededGameStateWithRules(p.id, p.id, payload.Seed, game.Rules{
+			AttackTable:    s.AttackTable,
+			Randomizer:     s.Randomizer,
+			EntryDelay:     time.Duration(s.EntryDelayMs) * time.Millisecond,
+			LineClearDelay: time.Duration(s.LineClearDelayMs) * time.Millisecond,
+			AllSpin:        s.AllSpin,
+			GarbageStyle:   s.GarbageStyle,
+			Items:          s.Items,
+			Hold:           s.HoldMode,
+		}))
+		lt.stats.count("games started")
+	case protocol.MsgReceiveGarbage:
+		payload, err := protocol.DecodePayload[protocol.ReceiveGarbagePayload](msg.Type, msg.Raw)
+		if err != nil || p.loop == nil {
+			return
+		}
+		p.loop.ReceiveGarbage(payload.Lines)
+		lt.stats.count("garbage received")
+	case protocol.MsgMatchOver:
+		p.loop = nil
+		lt.stats.count("games finished")
+	case protocol.MsgRoomError:
+		lt.stats.count("room errors")
+	}
+}
+
+// advance moves p's game on to now, sending their clears as attacks and
+// reporting them out if they top out.
+func (lt *loadTest) advance(p *player) {
+	for _, ev := range p.loop.Advance(time.Now()) {
+		switch ev.Kind {
+		case game.EventLock:
+			if ev.Attack == 0 {
+				continue
+			}
+			a := ev.Clear
+			p.c.SendClear(protocol.LinesClearedPayload{
+				Count:       a.Lines,
+				AttackPower: ev.Attack,
+				ClearType:   protocol.ClearTypeFor(a.Lines, a.TSpin),
+				Combo:       a.Combo,
+				B2B:         a.B2B,
+				Spin:        a.Spin,
+			})
+			lt.stats.count("attacks sent")
+		case game.EventTopOut:
+			lt.sendSnapshot(p)
+			p.c.SendDead()
+			p.loop = nil
+			lt.stats.count("top outs")
+			return
+		}
+	}
+}
+
+func (lt *loadTest) sendSnapshot(p *player) {
+	gs := p.loop.State()
+	p.c.SendBoard(protocol.BoardSnapshotPayload{
+		Score: gs.Score,
+		Level: gs.Level,
+		Lines: gs.Lines,
+		Alive: !gs.IsGameOver,
+		Board: gs.Board.ToFlat(),
+	})
+}
+
+// sleep waits for d, reporting false if ctx was done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hersh/gotris/pkg/client"
+)
+
+// The latencies measured, in the order they're reported.
+var latencies = []struct{ name, about string }{
+	{"create", "POST /create-room"},
+	{"join", "POST /join-room"},
+	{"rtt", "WebSocket heartbeat round trip"},
+	{"delivery", "server send to client receipt, by the two clocks"},
+}
+
+// stats collects what the players measured and counted.
+type stats struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+	counts  map[string]int
+	errs    map[string]int // by what failed and why
+}
+
+func newStats() *stats {
+	return &stats{
+		samples: make(map[string][]time.Duration),
+		counts:  make(map[string]int),
+		errs:    make(map[string]int),
+	}
+}
+
+func (s *stats) observe(name string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples[name] = append(s.samples[name], d)
+}
+
+func (s *stats) count(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[name]++
+}
+
+// fail counts a failed request, by the server's error code if it gave one.
+func (s *stats) fail(name string, err error) {
+	reason := string(client.ErrorCode(err))
+	switch {
+	case reason != "":
+	case errors.Is(err, client.ErrUnreachable):
+		reason = "unreachable"
+	case errors.Is(err, client.ErrServer):
+		reason = "server error"
+	default:
+		reason = err.Error()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs[name+": "+reason]++
+}
+
+// progress sums the run up so far in a line.
+func (s *stats) progress() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rtt := "-"
+	if d := s.samples["rtt"]; len(d) > 0 {
+		rtt = percentile(d, 0.99).Round(100 * time.Microsecond).String()
+	}
+	failed := 0
+	for _, n := range s.errs {
+		failed += n
+	}
+	return fmt.Sprintf("games %d, attacks %d, rtt p99 %s, failures %d",
+		s.counts["games started"], s.counts["attacks sent"], rtt, failed)
+}
+
+// report writes the latency percentiles, counts and failures to w.
+func (s *stats) report(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "latency\tn\tp50\tp90\tp99\tmax\t")
+	for _, l := range latencies {
+		d := s.samples[l.name]
+		if len(d) == 0 {
+			fmt.Fprintf(tw, "%s\t0\t-\t-\t-\t-\t\n", l.name)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t\n", l.name, len(d),
+			round(percentile(d, 0.5)), round(percentile(d, 0.9)), round(percentile(d, 0.99)), round(percentile(d, 1)))
+	}
+	tw.Flush()
+	for _, l := range latencies {
+		fmt.Fprintf(w, "  %s: %s\n", l.name, l.about)
+	}
+
+	fmt.Fprintln(w)
+	for _, name := range slices.Sorted(maps.Keys(s.counts)) {
+		fmt.Fprintf(w, "%-18s %d\n", name, s.counts[name])
+	}
+	if len(s.errs) > 0 {
+		fmt.Fprintln(w, "\nfailures:")
+		for _, reason := range slices.Sorted(maps.Keys(s.errs)) {
+			fmt.Fprintf(w, "  %s (%d)\n", strings.TrimSpace(reason), s.errs[reason])
+		}
+	}
+}
+
+// percentile returns the q quantile of d, sorting it in place.
+func percentile(d []time.Duration, q float64) time.Duration {
+	slices.Sort(d)
+	return d[min(int(q*float64(len(d))), len(d)-1)]
+}
+
+func round(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(100 * time.Microsecond)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/internal/tui"
+)
+
+// The GIF draws the board the way the game screen does, in blocks of
+// cellSize pixels, framed, with the next and held pieces beside it. There
+// is no text: the frame would need a font.
+const (
+	cellSize = 10
+	// gifHold is how long the last frame stays up, in hundredths of a
+	// second.
+	gifHold = 300
+
+	boardLeft = 1 // cells, inside the frame
+	sideLeft  = boardLeft + game.BoardWidth + 2
+	gifWidth  = (sideLeft + 5) * cellSize
+	gifHeight = (game.BoardHeight + 2) * cellSize
+)
+
+// Colors the board is drawn in besides the pieces', as xterm-256 codes.
+const (
+	frameColor = "15"
+	clearColor = "15"  // lines about to collapse
+	ghostColor = "244" // where the piece will land
+)
+
+// palette is the GIF's colors, and the index of each xterm code in it.
+type palette struct {
+	colors color.Palette
+	index  map[string]uint8
+	cells  []string // the code for each cell color
+}
+
+func newPalette() *palette {
+	p := &palette{index: map[string]uint8{}, cells: tui.CellColors()}
+	for _, code := range p.cells {
+		p.add(code)
+	}
+	p.add(frameColor)
+	p.add(clearColor)
+	p.add(ghostColor)
+	return p
+}
+
+func (p *palette) add(code string) {
+	if _, ok := p.index[code]; ok {
+		return
+	}
+	p.index[code] = uint8(len(p.colors))
+	p.colors = append(p.colors, xterm(code))
+}
+
+// writeGIF writes the replay as an animated GIF.
+func (v video) writeGIF(w io.Writer) error {
+	pal := newPalette()
+	anim := &gif.GIF{}
+	var shown time.Duration // when the last frame added is shown
+	err := v.frames(func(at time.Duration, gs *game.GameState) error {
+		img := image.NewPaletted(image.Rect(0, 0, gifWidth, gifHeight), pal.colors)
+		pal.drawGame(img, gs)
+		if n := len(anim.Image); n > 0 {
+			if bytes.Equal(anim.Image[n-1].Pix, img.Pix) {
+				return nil
+			}
+			anim.Delay[n-1] = hundredths(at) - hundredths(shown)
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, 0)
+		shown = at
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	anim.Delay[len(anim.Delay)-1] = gifHold
+	return gif.EncodeAll(w, anim)
+}
+
+func hundredths(d time.Duration) int {
+	return int(d / (10 * time.Millisecond))
+}
+
+// drawGame draws gs into img as RenderBoard draws it in the terminal.
+func (p *palette) drawGame(img *image.Paletted, gs *game.GameState) {
+	frame := image.Rect(0, 0, (game.BoardWidth+2)*cellSize, gifHeight)
+	draw.Draw(img, frame, image.NewUniform(p.colors[p.index[frameColor]]), image.Point{}, draw.Src)
+	inside := frame.Inset(cellSize)
+	draw.Draw(img, inside, image.NewUniform(p.colors[p.index[p.cells[0]]]), image.Point{}, draw.Src)
+
+	for y, row := range gs.Board.Cells {
+		clearing := slices.Contains(gs.ClearingRows, y)
+		for x, cell := range row {
+			switch {
+			case clearing && cell.Filled:
+				p.block(img, boardLeft+x, 1+y, clearColor)
+			case cell.Filled:
+				p.block(img, boardLeft+x, 1+y, p.cells[cell.Color])
+			}
+		}
+	}
+	// During a delay the piece is already part of the board.
+	if gs.Phase == game.PhaseFalling && gs.CurrentPiece != nil {
+		piece := gs.CurrentPiece
+		ghostY := gs.GetGhostY()
+		p.piece(img, piece, boardLeft+piece.X, 1+ghostY, ghostColor, true)
+		p.piece(img, piece, boardLeft+piece.X, 1+piece.Y, p.cells[piece.Color], false)
+	}
+
+	if gs.NextPiece != nil {
+		p.piece(img, gs.NextPiece, sideLeft, 1, p.cells[gs.NextPiece.Color], false)
+	}
+	if gs.HoldPiece != nil {
+		p.piece(img, gs.HoldPiece, sideLeft, 6, p.cells[gs.HoldPiece.Color], false)
+	}
+}
+
+// piece draws piece with its top left at cell (x, y); a hollow piece is
+// drawn in outline, as for the ghost.
+func (p *palette) piece(img *image.Paletted, piece *game.Piece, x, y int, code string, hollow bool) {
+	for py, row := range piece.Shape {
+		for px, filled := range row {
+			if !filled || y+py < 1 {
+				continue
+			}
+			if hollow {
+				p.outline(img, x+px, y+py, code)
+			} else {
+				p.block(img, x+px, y+py, code)
+			}
+		}
+	}
+}
+
+// block fills cell (x, y), leaving a pixel's gap around it so blocks
+// stand apart.
+func (p *palette) block(img *image.Paletted, x, y int, code string) {
+	r := image.Rect(x*cellSize, y*cellSize, (x+1)*cellSize, (y+1)*cellSize).Inset(1)
+	draw.Draw(img, r, image.NewUniform(p.colors[p.index[code]]), image.Point{}, draw.Src)
+}
+
+// outline draws the edge of cell (x, y).
+func (p *palette) outline(img *image.Paletted, x, y int, code string) {
+	r := image.Rect(x*cellSize, y*cellSize, (x+1)*cellSize, (y+1)*cellSize).Inset(1)
+	i := p.index[code]
+	for px := r.Min.X; px < r.Max.X; px++ {
+		img.SetColorIndex(px, r.Min.Y, i)
+		img.SetColorIndex(px, r.Max.Y-1, i)
+	}
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		img.SetColorIndex(r.Min.X, py, i)
+		img.SetColorIndex(r.Max.X-1, py, i)
+	}
+}
+
+// ansi16 is the usual RGB for the 16 basic xterm colors.
+var ansi16 = [16]color.RGBA{
+	{0, 0, 0, 255}, {128, 0, 0, 255}, {0, 128, 0, 255}, {128, 128, 0, 255},
+	{0, 0, 128, 255}, {128, 0, 128, 255}, {0, 128, 128, 255}, {192, 192, 192, 255},
+	{128, 128, 128, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+	{0, 0, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+// xterm converts an xterm-256 color code to RGB: the 16 basic colors, a
+// 6×6×6 color cube, then 24 shades of gray.
+func xterm(code string) color.RGBA {
+	n, _ := strconv.Atoi(code)
+	switch {
+	case n < 16:
+		return ansi16[max(n, 0)]
+	case n < 232:
+		levels := [6]uint8{0, 95, 135, 175, 215, 255}
+		n -= 16
+		return color.RGBA{levels[n/36], levels[n/6%6], levels[n%6], 255}
+	default:
+		g := uint8(8 + 10*(min(n, 255)-232))
+		return color.RGBA{g, g, g, 255}
+	}
+}
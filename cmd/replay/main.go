@@ -0,0 +1,137 @@
+// Command replay renders a recorded game, so it can be shared outside the
+// terminal: as an asciinema cast, an animated GIF, or a plain-text dump of
+// its frames.
+//
+// The client records every game and saves it when the game ends, keeping
+// the newest in the replays directory next to its preferences (e.g.
+// ~/.config/gotris/replays on Linux). A replay holds the game's seeds and
+// each step the engine made, so it is played back exactly and drawn the
+// way the client draws the game screen.
+//
+//	go run ./cmd/replay                                # the newest replay, as a cast
+//	go run ./cmd/replay --format gif --speed 2 game.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/internal/prefs"
+)
+
+// formats maps each output format to the extension of the file it writes.
+var formats = map[string]string{
+	"cast": ".cast",
+	"gif":  ".gif",
+	"text": ".txt",
+}
+
+func main() {
+	format := flag.String("format", "cast", "Output format: cast, gif or text")
+	out := flag.String("out", "", "File to write, - for stdout (default: the replay's name with the format's extension)")
+	fps := flag.Float64("fps", 10, "Frames per second")
+	speed := flag.Float64("speed", 1, "Playback speed, e.g. 2 for twice as fast")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [replay.json]\n\nWith no replay given, renders the newest one saved.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	ext, ok := formats[*format]
+	if !ok || *fps <= 0 || *speed <= 0 || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	if path == "" {
+		latest, err := prefs.LatestReplay()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Finding the newest replay: %v\n", err)
+			os.Exit(1)
+		}
+		path = latest
+	}
+	r, err := readReplay(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		*out = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ext
+	}
+	v := video{
+		replay: r,
+		step:   time.Duration(float64(time.Second) * *speed / *fps),
+		speed:  *speed,
+	}
+	if err := write(*out, func(w io.Writer) error {
+		switch *format {
+		case "gif":
+			return v.writeGIF(w)
+		case "text":
+			return v.writeText(w)
+		}
+		return v.writeCast(w)
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Writing %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+	if *out != "-" {
+		fmt.Printf("Wrote %s (%s of play by %s)\n", *out, r.Length().Round(time.Second), r.PlayerName)
+	}
+}
+
+func readReplay(path string) (*game.Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return game.ReadReplay(f)
+}
+
+// write calls fn to write the file at path, or stdout for "-".
+func write(path string, fn func(io.Writer) error) error {
+	if path == "-" {
+		return fn(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// video is a replay being turned into frames.
+type video struct {
+	replay *game.Replay
+	step   time.Duration // game time between frames
+	speed  float64
+}
+
+// frames plays the replay back, calling fn with the game a step apart
+// through to the end, and when in the output each frame is shown.
+func (v video) frames(fn func(at time.Duration, gs *game.GameState) error) error {
+	p := v.replay.Play()
+	for at := time.Duration(0); ; at += v.step {
+		p.AdvanceTo(at)
+		if err := fn(time.Duration(float64(at)/v.speed), p.State()); err != nil {
+			return err
+		}
+		if p.Done() {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/internal/tui"
+	"github.com/muesli/termenv"
+)
+
+// castHeader is the first line of an asciinema v2 cast.
+type castHeader struct {
+	Version   int    `json:"version"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp"`
+	Title     string `json:"title"`
+}
+
+// textFrame is a frame as the game screen draws it, and when it's shown.
+type textFrame struct {
+	at   time.Duration
+	text string
+}
+
+// textFrames renders the frames that differ from the one before, in the
+// given color profile.
+func (v video) textFrames(profile termenv.Profile) ([]textFrame, error) {
+	lipgloss.SetColorProfile(profile)
+	var frames []textFrame
+	err := v.frames(func(at time.Duration, gs *game.GameState) error {
+		text := tui.RenderReplayFrame(gs)
+		if len(frames) == 0 || frames[len(frames)-1].text != text {
+			frames = append(frames, textFrame{at, text})
+		}
+		return nil
+	})
+	return frames, err
+}
+
+// writeCast writes the replay as an asciinema v2 cast, in 256 colors.
+func (v video) writeCast(w io.Writer) error {
+	frames, err := v.textFrames(termenv.ANSI256)
+	if err != nil {
+		return err
+	}
+	h := castHeader{
+		Version:   2,
+		Timestamp: v.replay.Started.Unix(),
+		Title:     fmt.Sprintf("gotris: %s, %s", v.replay.PlayerName, v.replay.Started.Format("2006-01-02 15:04")),
+	}
+	for _, f := range frames {
+		h.Width = max(h.Width, lipgloss.Width(f.text))
+		h.Height = max(h.Height, lipgloss.Height(f.text))
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(h); err != nil {
+		return err
+	}
+	for _, f := range frames {
+		out := "\x1b[2J\x1b[H" + strings.ReplaceAll(f.text, "\n", "\r\n")
+		if err := enc.Encode([]any{f.at.Seconds(), "o", out}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeText writes the replay's frames one after another as plain text,
+// each headed by when it's shown.
+func (v video) writeText(w io.Writer) error {
+	frames, err := v.textFrames(termenv.Ascii)
+	if err != nil {
+		return err
+	}
+	for _, f := range frames {
+		if _, err := fmt.Fprintf(w, "--- %s ---\n%s\n\n", f.at.Round(100*time.Millisecond), f.text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package clipboard copies to and pastes from the system clipboard.
+//
+// Copying always writes an OSC 52 escape sequence to the terminal, which
+// sets the clipboard of the machine the terminal runs on, over SSH too,
+// in terminals that allow it (most do, tmux and screen with passthrough).
+// It also hands the text to the first clipboard tool found locally:
+// pbcopy, wl-copy, xclip, xsel or clip.exe. Terminals rarely let programs
+// read the clipboard, so pasting needs one of those tools' counterparts.
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// ErrUnavailable is returned by Paste when no clipboard tool is installed.
+var ErrUnavailable = errors.New("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+
+// tool is a clipboard command line: the program and its arguments.
+type tool []string
+
+var (
+	copyTools = []tool{
+		{"pbcopy"},
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		{"clip.exe"},
+	}
+	pasteTools = []tool{
+		{"pbpaste"},
+		{"wl-paste", "--no-newline"},
+		{"xclip", "-selection", "clipboard", "-o"},
+		{"xsel", "--clipboard", "--output"},
+		{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+	}
+)
+
+// Copy puts text on the clipboard, writing the OSC 52 sequence to term.
+// It reports an error only if the sequence couldn't be written; a local
+// tool that fails is passed over, as OSC 52 may have worked.
+func Copy(term io.Writer, text string) error {
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(term)
+
+	for _, t := range installed(copyTools) {
+		cmd := exec.Command(t[0], t[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if cmd.Run() == nil {
+			break
+		}
+	}
+	return err
+}
+
+// Paste returns the text on the clipboard, from the first clipboard tool
+// that works: wl-paste, say, is no use outside a Wayland session.
+func Paste() (string, error) {
+	tools := installed(pasteTools)
+	if len(tools) == 0 {
+		return "", ErrUnavailable
+	}
+	var err error
+	for _, t := range tools {
+		var out bytes.Buffer
+		cmd := exec.Command(t[0], t[1:]...)
+		cmd.Stdout = &out
+		if err = cmd.Run(); err == nil {
+			return out.String(), nil
+		}
+	}
+	return "", err
+}
+
+// installed returns the tools whose programs are installed.
+func installed(tools []tool) []tool {
+	var found []tool
+	for _, t := range tools {
+		if _, err := exec.LookPath(t[0]); err == nil {
+			found = append(found, t)
+		}
+	}
+	return found
+}
@@ -0,0 +1,200 @@
+// Package export writes a player's statistics as CSV or JSON for
+// spreadsheets: the games in this client's game log, and the matches the
+// server has in its history for the player IDs the client has had. Each
+// game is one row, newest first.
+package export
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hersh/gotris/internal/prefs"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// Formats.
+const (
+	CSV  = "csv"
+	JSON = "json"
+)
+
+// Where a row came from.
+const (
+	SourceLocal  = "local"  // the client's game log
+	SourceServer = "server" // the server's match history
+)
+
+// Row is one game. The server's history doesn't say everything the game
+// log does: its rows leave the piece counts, PPS, clears and garbage
+// received at 0, and time only the player's survival.
+type Row struct {
+	Source    string    `json:"source"`
+	PlayedAt  time.Time `json:"played_at"`
+	Mode      string    `json:"mode"`
+	Room      string    `json:"room,omitempty"`
+	Seed      string    `json:"seed,omitempty"`
+	Players   int       `json:"players,omitempty"`
+	Place     int       `json:"place,omitempty"`
+	Score     int       `json:"score"`
+	Lines     int       `json:"lines"`
+	Level     int       `json:"level,omitempty"`
+	KOs       int       `json:"kos"`
+	DurationS float64   `json:"duration_s"`
+	Pieces    int       `json:"pieces"`
+	PPS       float64   `json:"pps"`
+	APM       float64   `json:"apm"`
+	Sent      int       `json:"sent"`
+	Received  int       `json:"received"`
+	Tetrises  int       `json:"tetrises"`
+	TSpins    int       `json:"tspins"`
+	MaxCombo  int       `json:"max_combo"`
+}
+
+// sameMatch is how far apart the client's and the server's clocks may put
+// the start of one match: a server match in a room the game log has a
+// game in, starting within it, is that game.
+const sameMatch = 30 * time.Second
+
+// Rows makes a row of each local game and each match in which one of
+// ownIDs played, newest first. A match the game log has too is only
+// counted once, from the log, which says more about it.
+func Rows(games []prefs.GameRecord, matches []protocol.MatchRecord, ownIDs []string) []Row {
+	var rows []Row
+	for _, g := range games {
+		rows = append(rows, Row{
+			Source:    SourceLocal,
+			PlayedAt:  g.PlayedAt,
+			Mode:      g.Mode,
+			Room:      g.RoomID,
+			Seed:      g.Seed,
+			Players:   g.Players,
+			Place:     g.Place,
+			Score:     g.Score,
+			Lines:     g.Lines,
+			Level:     g.Level,
+			KOs:       g.KOs,
+			DurationS: float64(g.DurationMs) / 1000,
+			Pieces:    g.Pieces,
+			PPS:       g.PPS,
+			APM:       g.APM,
+			Sent:      g.Sent,
+			Received:  g.Received,
+			Tetrises:  g.Tetrises,
+			TSpins:    g.TSpins,
+			MaxCombo:  g.MaxCombo,
+		})
+	}
+	for _, rec := range matches {
+		if logged(games, rec) {
+			continue
+		}
+		for _, st := range rec.Standings {
+			if !slices.Contains(ownIDs, st.PlayerID) {
+				continue
+			}
+			room := rec.RoomID
+			if rec.RoomTitle != "" {
+				room = rec.RoomTitle
+			}
+			row := Row{
+				Source:    SourceServer,
+				PlayedAt:  time.UnixMilli(rec.StartedAt),
+				Mode:      "multi",
+				Room:      room,
+				Seed:      rec.Settings.Seed,
+				Players:   len(rec.Standings),
+				Place:     st.Rank,
+				Score:     st.Score,
+				Lines:     st.Lines,
+				KOs:       st.KOs,
+				DurationS: float64(st.SurvivalMs) / 1000,
+				Sent:      st.Sent,
+			}
+			if st.SurvivalMs > 0 {
+				row.APM = float64(st.Sent) / (float64(st.SurvivalMs) / 60000)
+			}
+			rows = append(rows, row)
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b Row) int {
+		return cmp.Compare(b.PlayedAt.UnixMilli(), a.PlayedAt.UnixMilli())
+	})
+	return rows
+}
+
+// logged reports whether the game log has the match rec.
+func logged(games []prefs.GameRecord, rec protocol.MatchRecord) bool {
+	started := time.UnixMilli(rec.StartedAt)
+	return slices.ContainsFunc(games, func(g prefs.GameRecord) bool {
+		return g.Mode == "multi" && g.RoomID == rec.RoomID &&
+			g.PlayedAt.Sub(started).Abs() < sameMatch
+	})
+}
+
+// FormatFor picks the format for a file by its extension: JSON for
+// .json, CSV for anything else.
+func FormatFor(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return JSON
+	}
+	return CSV
+}
+
+// Write writes rows to w in format.
+func Write(w io.Writer, format string, rows []Row) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if rows == nil {
+			rows = []Row{}
+		}
+		return enc.Encode(rows)
+	case CSV:
+		return writeCSV(w, rows)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// csvHeader names the CSV columns, which are Row's fields in order.
+var csvHeader = []string{
+	"source", "played_at", "mode", "room", "seed", "players", "place",
+	"score", "lines", "level", "kos", "duration_s", "pieces", "pps", "apm",
+	"sent", "received", "tetrises", "tspins", "max_combo",
+}
+
+func writeCSV(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	itoa := strconv.Itoa
+	ftoa := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+	for _, r := range rows {
+		cw.Write([]string{
+			r.Source, r.PlayedAt.Format(time.RFC3339), r.Mode, csvText(r.Room), csvText(r.Seed),
+			itoa(r.Players), itoa(r.Place), itoa(r.Score), itoa(r.Lines),
+			itoa(r.Level), itoa(r.KOs), ftoa(r.DurationS), itoa(r.Pieces),
+			ftoa(r.PPS), ftoa(r.APM), itoa(r.Sent), itoa(r.Received),
+			itoa(r.Tetrises), itoa(r.TSpins), itoa(r.MaxCombo),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText makes text someone else chose, like a room title or seed, safe
+// to open in a spreadsheet: a cell starting with =, +, - or @ is read as
+// a formula, so it's written with a ' in front.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
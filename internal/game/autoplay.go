@@ -0,0 +1,79 @@
+package game
+
+// AutoPlace queues the inputs that drop the current piece where it leaves
+// the best board: it tries every rotation and column, then steers the
+// piece there and hard drops it. Server bots play this way.
+func (l *Loop) AutoPlace() {
+	gs := l.gs
+	bestRot, bestX, bestScore := 0, gs.CurrentPiece.X, 0.0
+	found := false
+	for rot := range 4 {
+		piece := gs.CurrentPiece.Clone()
+		for range rot {
+			piece.Rotate()
+		}
+		for x := -len(piece.Shape[0]); x < gs.Board.Width; x++ {
+			piece.X = x
+			piece.Y = gs.CurrentPiece.Y
+			if !gs.Board.IsValidPosition(piece, 0, 0) {
+				continue
+			}
+			for gs.Board.IsValidPosition(piece, 0, 1) {
+				piece.Y++
+			}
+			b := gs.Board.Clone()
+			b.LockPiece(piece)
+			lines := b.ClearLines()
+			if score := evaluateBoard(b, lines); !found || score > bestScore {
+				bestRot, bestX, bestScore, found = rot, x, score, true
+			}
+		}
+	}
+
+	// Rotating can kick the piece sideways, so see where it ends up first.
+	sim := gs.Clone()
+	for range bestRot {
+		sim.Rotate()
+		l.Queue(InputRotate)
+	}
+	for x := sim.CurrentPiece.X; x > bestX; x-- {
+		l.Queue(InputLeft)
+	}
+	for x := sim.CurrentPiece.X; x < bestX; x++ {
+		l.Queue(InputRight)
+	}
+	l.Queue(InputHardDrop)
+}
+
+// evaluateBoard scores a board left by a placement that cleared lines:
+// low, flat stacks without holes score best.
+func evaluateBoard(b *Board, lines int) float64 {
+	heights := make([]int, b.Width)
+	holes := 0
+	for x := range b.Width {
+		for y := range b.Height {
+			if b.Cells[y][x].Filled {
+				if heights[x] == 0 {
+					heights[x] = b.Height - y
+				}
+			} else if heights[x] > 0 {
+				holes++
+			}
+		}
+	}
+	aggregate, bumpiness := 0, 0
+	for x, h := range heights {
+		aggregate += h
+		if x > 0 {
+			bumpiness += abs(h - heights[x-1])
+		}
+	}
+	return -0.51*float64(aggregate) + 0.76*float64(lines) - 0.36*float64(holes) - 0.18*float64(bumpiness)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,108 @@
+package game
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// Clone returns a deep copy of the game, random number generators
+// included, so the copy can be played forward (to search placements or
+// preview a move) without touching the original: both deal the same
+// pieces and garbage holes from here on.
+func (gs *GameState) Clone() *GameState {
+	c := *gs
+	c.Board = gs.Board.Clone()
+	c.CurrentPiece = gs.CurrentPiece.Clone()
+	c.NextPiece = gs.NextPiece.Clone()
+	c.HoldPiece = gs.HoldPiece.Clone()
+	c.PieceGen = gs.PieceGen.Clone()
+	c.ClearingRows = slices.Clone(gs.ClearingRows)
+	c.Stats.Samples = slices.Clone(gs.Stats.Samples)
+	c.incoming = slices.Clone(gs.incoming)
+	c.holeRNG = gs.holeRNG.clone()
+	c.chance = gs.chance.clone()
+	return &c
+}
+
+// Clone returns a deep copy of the board.
+func (b *Board) Clone() *Board {
+	c := *b
+	c.Cells = make([][]Cell, len(b.Cells))
+	for y, row := range b.Cells {
+		c.Cells[y] = slices.Clone(row)
+	}
+	return &c
+}
+
+// Clone returns a copy of the piece, or nil for a nil piece.
+func (p *Piece) Clone() *Piece {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Shape = make([][]bool, len(p.Shape))
+	for y, row := range p.Shape {
+		c.Shape[y] = slices.Clone(row)
+	}
+	return &c
+}
+
+// Clone returns a generator that deals the same pieces as pg from here
+// on, or nil for a nil generator.
+func (pg *PieceGenerator) Clone() *PieceGenerator {
+	if pg == nil {
+		return nil
+	}
+	c := *pg
+	c.rng = pg.rng.clone()
+	c.bag = slices.Clone(pg.bag)
+	return &c
+}
+
+// replayRand is a seeded *rand.Rand whose state can be copied, which
+// math/rand doesn't allow directly: it counts the values drawn, and a
+// copy replays that many from the same seed.
+type replayRand struct {
+	*rand.Rand
+	src *replaySource
+}
+
+func newReplayRand(seed int64) *replayRand {
+	src := &replaySource{seed: seed, Source64: rand.NewSource(seed).(rand.Source64)}
+	return &replayRand{Rand: rand.New(src), src: src}
+}
+
+func (r *replayRand) clone() *replayRand {
+	if r == nil {
+		return nil
+	}
+	c := newReplayRand(r.src.seed)
+	for range r.src.draws {
+		c.src.Source64.Uint64()
+	}
+	c.src.draws = r.src.draws
+	return c
+}
+
+// replaySource counts draws from a seeded source. Int63 and Uint64 each
+// advance the underlying source by one step.
+type replaySource struct {
+	rand.Source64
+	seed  int64
+	draws uint64
+}
+
+func (s *replaySource) Int63() int64 {
+	s.draws++
+	return s.Source64.Int63()
+}
+
+func (s *replaySource) Uint64() uint64 {
+	s.draws++
+	return s.Source64.Uint64()
+}
+
+func (s *replaySource) Seed(seed int64) {
+	s.seed, s.draws = seed, 0
+	s.Source64.Seed(seed)
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// Items for item mode (Rules.Items). Clearing lines sometimes earns one,
+// which the player fires when they like: ItemClear on their own board,
+// the others on their target's.
+const (
+	ItemClear    = "clear"    // remove your bottom ItemClearRows rows
+	ItemScramble = "scramble" // shuffle the cells in each of the target's rows
+	ItemSpeedUp  = "speed"    // the target's pieces fall much faster for a while
+)
+
+// Items lists the valid item names.
+var Items = []string{ItemClear, ItemScramble, ItemSpeedUp}
+
+const (
+	// ItemChance is the chance per line cleared of earning an item.
+	ItemChance = 0.15
+	// ItemClearRows is how many rows ItemClear removes.
+	ItemClearRows = 4
+	// SpeedUpDuration is how long ItemSpeedUp lasts, and SpeedUpFactor how
+	// many times faster pieces fall meanwhile.
+	SpeedUpDuration = 10 * time.Second
+	SpeedUpFactor   = 4
+)
+
+// IsTargeted reports whether an item is used on an opponent rather than
+// on the player's own board.
+func IsTargeted(item string) bool {
+	return item == ItemScramble || item == ItemSpeedUp
+}
+
+// maybeAwardItem gives the player an item, if they have none, with
+// ItemChance per line just cleared.
+func (gs *GameState) maybeAwardItem(lines int) {
+	if !gs.Rules.Items || gs.Rules.GivenItems || gs.Item != "" {
+		return
+	}
+	for range lines {
+		rng := gs.chanceRand()
+		if rng.Float64() < ItemChance {
+			gs.Item = Items[rng.Intn(len(Items))]
+			return
+		}
+	}
+}
+
+// GiveItem gives the player item, under Rules.GivenItems, in place of
+// any they hold. Unknown items are ignored.
+func (gs *GameState) GiveItem(item string) {
+	if gs.Rules.Items && slices.Contains(Items, item) {
+		gs.Item = item
+	}
+}
+
+// TakeItem returns the item the player is holding, if any, and empties
+// their item slot.
+func (gs *GameState) TakeItem() string {
+	item := gs.Item
+	gs.Item = ""
+	return item
+}
+
+// ApplyItem applies an item's effect to this game at now. Unknown items
+// are ignored.
+func (gs *GameState) ApplyItem(item string, now time.Time) {
+	if gs.IsGameOver {
+		return
+	}
+	switch item {
+	case ItemClear:
+		gs.Board.ClearBottom(ItemClearRows)
+	case ItemScramble:
+		gs.Board.Scramble(gs.chanceRand().Rand)
+		gs.unstick()
+	case ItemSpeedUp:
+		gs.speedUntil = now.Add(SpeedUpDuration)
+	}
+}
+
+// SpeedUpLeft is how long a speed-up item has left to run at now.
+func (gs *GameState) SpeedUpLeft(now time.Time) time.Duration {
+	return max(0, gs.speedUntil.Sub(now))
+}
+
+// unstick moves the falling piece up out of any cells that were just put
+// where it is.
+func (gs *GameState) unstick() {
+	p := gs.CurrentPiece
+	for p.Y > -len(p.Shape) && !gs.Board.IsValidPosition(p, 0, 0) {
+		p.Y--
+	}
+}
+
+// ClearBottom removes the bottom n rows, moving everything above down.
+func (b *Board) ClearBottom(n int) {
+	n = min(n, b.Height)
+	for range n {
+		b.Cells = b.Cells[:b.Height-1]
+		b.Cells = append([][]Cell{make([]Cell, b.Width)}, b.Cells...)
+	}
+}
+
+// Scramble shuffles the cells within each row, so every row keeps its
+// number of blocks but loses its shape, drawing on rng.
+func (b *Board) Scramble(rng *rand.Rand) {
+	for _, row := range b.Cells {
+		rng.Shuffle(len(row), func(i, j int) {
+			row[i], row[j] = row[j], row[i]
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package game
+
+import "time"
+
+// Loop runs a GameState: the player's inputs, gravity, and the entry and
+// line clear delays, reporting what happened as Events. Gravity moves in
+// fixed steps of the drop speed, counted from when the piece came in, so
+// a caller that is late to Advance catches up on the steps it missed
+// rather than slowing the game down. The terminal client advances its
+// loop on bubbletea ticks and key presses, in single player and in
+// matches alike, and server bots on timers of their own.
+//
+// Everything that changes the game goes through the loop, so a loop can
+// record its game as a Replay; see Record.
+type Loop struct {
+	gs        *GameState
+	inputs    []Input
+	nextDrop  time.Time // when gravity next moves the piece
+	toppedOut bool
+	rec       *Replay // the recording, if Record was called
+}
+
+// Input is a player's command to the game.
+type Input int
+
+const (
+	InputLeft Input = iota
+	InputRight
+	InputRotate
+	InputSoftDrop
+	InputHardDrop
+	InputHold
+	// InputRotateBack rotates counterclockwise. It comes last so the
+	// inputs in replays recorded before it keep their numbers.
+	InputRotateBack
+)
+
+// EventKind says what an Event is.
+type EventKind int
+
+const (
+	// EventLock is a piece locking, with whatever it cleared.
+	EventLock EventKind = iota
+	// EventTopOut is the game ending.
+	EventTopOut
+)
+
+// Event is something that happened while the loop advanced.
+type Event struct {
+	Kind EventKind
+	// For EventLock: the lines cleared, the clear (if any), and the
+	// garbage it sends.
+	Lines  int
+	Clear  Clear
+	Attack int
+}
+
+// maxCatchUp is the most gravity steps one Advance makes up for; a caller
+// further behind than that, say after the machine slept, resumes from
+// now instead.
+const maxCatchUp = 5
+
+// NewLoop starts a loop running gs, with the first gravity step a drop
+// interval from now.
+func NewLoop(gs *GameState) *Loop {
+	return &Loop{gs: gs, nextDrop: time.Now().Add(gs.GetDropSpeed())}
+}
+
+// State is the game the loop runs.
+func (l *Loop) State() *GameState {
+	return l.gs
+}
+
+// Queue adds an input for the next Advance to apply.
+func (l *Loop) Queue(in Input) {
+	l.inputs = append(l.inputs, in)
+}
+
+// Advance runs the game up to now: the inputs queued, in order, then any
+// delay that has ended and each gravity step due. It returns what
+// happened, in order.
+func (l *Loop) Advance(now time.Time) []Event {
+	gs := l.gs
+	var events []Event
+
+	inputs := l.inputs
+	l.inputs = nil
+	for _, in := range inputs {
+		if gs.IsGameOver {
+			break
+		}
+		events = l.apply(Step{Op: OpInput, Input: in}, now, events)
+	}
+
+	for steps := 0; !gs.IsGameOver; steps++ {
+		if gs.Phase != PhaseFalling {
+			if now.Before(gs.phaseEnds) {
+				break
+			}
+			events = l.apply(Step{Op: OpDelayEnd}, now, events)
+			l.nextDrop = now.Add(gs.GetDropSpeed()) // a new piece
+			continue
+		}
+		if now.Before(l.nextDrop) {
+			break
+		}
+		if steps == maxCatchUp {
+			l.nextDrop = now.Add(gs.GetDropSpeed())
+			break
+		}
+		l.nextDrop = l.nextDrop.Add(gs.GetDropSpeed())
+		events = l.apply(Step{Op: OpGravity}, now, events)
+	}
+
+	return l.toppedOutEvent(events)
+}
+
+// ReceiveGarbage queues lines of garbage sent by an opponent, to rise
+// when the next piece locks.
+func (l *Loop) ReceiveGarbage(lines int) {
+	l.apply(Step{Op: OpGarbage, N: lines}, time.Now(), nil)
+}
+
+// ApplyItem applies an item's effect to the game at now; see
+// GameState.ApplyItem.
+func (l *Loop) ApplyItem(item string, now time.Time) {
+	l.apply(Step{Op: OpItem, Item: item}, now, nil)
+}
+
+// GiveItem gives the player an item at now; see GameState.GiveItem.
+func (l *Loop) GiveItem(item string, now time.Time) {
+	l.apply(Step{Op: OpGiveItem, Item: item}, now, nil)
+}
+
+// SetLevel sets the game's level at now, as a speed race's room does;
+// see GameState.SetLevel.
+func (l *Loop) SetLevel(level int, now time.Time) {
+	l.apply(Step{Op: OpLevel, N: level}, now, nil)
+}
+
+// TakeItem returns the item the player is holding, if any, and empties
+// their item slot.
+func (l *Loop) TakeItem() string {
+	item := l.gs.Item
+	l.apply(Step{Op: OpTakeItem}, time.Now(), nil)
+	return item
+}
+
+// apply makes one step of the game at now, recording it if the loop is
+// recording, and adds what happened to events. Playing a Replay back
+// makes the same steps in the same order.
+func (l *Loop) apply(s Step, now time.Time, events []Event) []Event {
+	if l.rec != nil {
+		s.At = now.Sub(l.rec.Started)
+		l.rec.Steps = append(l.rec.Steps, s)
+	}
+	gs := l.gs
+	switch s.Op {
+	case OpInput:
+		switch s.Input {
+		case InputLeft:
+			gs.MoveLeft()
+		case InputRight:
+			gs.MoveRight()
+		case InputRotate:
+			gs.Rotate()
+		case InputRotateBack:
+			gs.RotateBack()
+		case InputSoftDrop:
+			gs.SoftDrop()
+		case InputHold:
+			gs.Hold()
+		case InputHardDrop:
+			if gs.Phase == PhaseFalling {
+				gs.HardDrop()
+				events = l.locked(events, now)
+			}
+		}
+	case OpGravity:
+		if !gs.MoveDown() {
+			gs.LockPiece()
+			events = l.locked(events, now)
+		}
+	case OpDelayEnd:
+		gs.endDelay()
+	case OpGarbage:
+		gs.ReceiveGarbage(s.N)
+	case OpItem:
+		gs.ApplyItem(s.Item, now)
+	case OpTakeItem:
+		gs.TakeItem()
+	case OpGiveItem:
+		gs.GiveItem(s.Item)
+	case OpLevel:
+		gs.SetLevel(s.N)
+	}
+	return events
+}
+
+// toppedOutEvent adds EventTopOut to events the first time the game is
+// seen to be over.
+func (l *Loop) toppedOutEvent(events []Event) []Event {
+	if l.gs.IsGameOver && !l.toppedOut {
+		l.toppedOut = true
+		events = append(events, Event{Kind: EventTopOut})
+	}
+	return events
+}
+
+// locked records the lock just made as an event. The garbage it sends is
+// handed over in the event, so AttackPower is left at 0. A piece that
+// locks with no entry delay brings in the next, which gravity starts on
+// afresh.
+func (l *Loop) locked(events []Event, now time.Time) []Event {
+	gs := l.gs
+	ev := Event{Kind: EventLock, Lines: gs.LastClear, Attack: gs.AttackPower}
+	if ev.Lines > 0 {
+		ev.Clear = gs.Attack
+	}
+	gs.AttackPower = 0
+	l.nextDrop = now.Add(gs.GetDropSpeed())
+	return append(events, ev)
+}
+
+// Wait is how long after now the loop next has something to do: a
+// gravity step, or the end of a delay.
+func (l *Loop) Wait(now time.Time) time.Duration {
+	if l.gs.Phase != PhaseFalling {
+		return l.gs.DelayLeft(now)
+	}
+	return max(l.nextDrop.Sub(now), 0)
+}
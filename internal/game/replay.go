@@ -0,0 +1,135 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReplayVersion is the version of the replay format Replay.Write writes
+// and ReadReplay reads.
+const ReplayVersion = 1
+
+// Replay is a recorded game: the seeds and rules it started from and
+// every step the loop made, in order. A game is deterministic given
+// those, so playing the steps back rebuilds it exactly, board by board.
+type Replay struct {
+	Version    int       `json:"version"`
+	PlayerID   string    `json:"player_id"`
+	PlayerName string    `json:"player_name"`
+	Started    time.Time `json:"started"`
+	Seed       int64     `json:"seed"`
+	ChanceSeed int64     `json:"chance_seed"`
+	Rules      Rules     `json:"rules"`
+	Steps      []Step    `json:"steps"`
+}
+
+// Op says what a Step is.
+type Op string
+
+const (
+	OpInput    Op = "input"   // a player input
+	OpGravity  Op = "gravity" // gravity moving the piece down a row, or locking it
+	OpDelayEnd Op = "delay"   // an entry or line clear delay ending
+	OpGarbage  Op = "garbage" // garbage arriving from an opponent
+	OpItem     Op = "item"    // an item used on this game
+	OpTakeItem Op = "take"    // the player firing the item they held
+	OpGiveItem Op = "give"    // the server giving the player an item
+	OpLevel    Op = "level"   // the room setting the level, in a speed race
+)
+
+// Step is one thing the loop did to the game, At a time into it.
+type Step struct {
+	At    time.Duration `json:"t"`
+	Op    Op            `json:"op"`
+	Input Input         `json:"in,omitempty"`   // for OpInput
+	N     int           `json:"n,omitempty"`    // lines, for OpGarbage; the level, for OpLevel
+	Item  string        `json:"item,omitempty"` // for OpItem and OpGiveItem
+}
+
+// Record starts recording the loop's game from now; Recording returns the
+// recording so far. Call it before the game has moved, on a game dealt
+// from a seed, as from NewSeededGameStateWithRules: a game of legacy
+// random pieces can't be played back.
+func (l *Loop) Record(now time.Time) {
+	gs := l.gs
+	l.rec = &Replay{
+		Version:    ReplayVersion,
+		PlayerID:   gs.PlayerID,
+		PlayerName: gs.PlayerName,
+		Started:    now,
+		Seed:       gs.seed,
+		ChanceSeed: gs.chanceRand().src.seed,
+		Rules:      gs.Rules,
+	}
+}
+
+// Recording is the loop's game as recorded since Record, or nil if it
+// isn't recording.
+func (l *Loop) Recording() *Replay {
+	return l.rec
+}
+
+// Length is how long the recorded game ran, to its last step.
+func (r *Replay) Length() time.Duration {
+	if len(r.Steps) == 0 {
+		return 0
+	}
+	return r.Steps[len(r.Steps)-1].At
+}
+
+// Write writes the replay to w as JSON.
+func (r *Replay) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// ReadReplay reads a replay written by Replay.Write.
+func ReadReplay(rd io.Reader) (*Replay, error) {
+	var r Replay
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return nil, fmt.Errorf("reading replay: %w", err)
+	}
+	if r.Version != ReplayVersion {
+		return nil, fmt.Errorf("replay version %d not supported (want %d)", r.Version, ReplayVersion)
+	}
+	return &r, nil
+}
+
+// Playback plays a Replay back, rebuilding the game step by step.
+type Playback struct {
+	replay *Replay
+	loop   *Loop
+	next   int // the next step to make
+}
+
+// Play starts playing the replay back from the start of the game.
+func (r *Replay) Play() *Playback {
+	gs := NewSeededGameStateWithRules(r.PlayerID, r.PlayerName, r.Seed, r.Rules)
+	gs.chance = newReplayRand(r.ChanceSeed)
+	return &Playback{replay: r, loop: &Loop{gs: gs}}
+}
+
+// State is the game as played back so far.
+func (p *Playback) State() *GameState {
+	return p.loop.gs
+}
+
+// AdvanceTo makes every step recorded up to at into the game and returns
+// what happened, as Loop.Advance would have.
+func (p *Playback) AdvanceTo(at time.Duration) []Event {
+	var events []Event
+	for ; p.next < len(p.replay.Steps); p.next++ {
+		s := p.replay.Steps[p.next]
+		if s.At > at {
+			break
+		}
+		events = p.loop.apply(s, p.replay.Started.Add(s.At), events)
+	}
+	return p.loop.toppedOutEvent(events)
+}
+
+// Done reports whether every step has been played back.
+func (p *Playback) Done() bool {
+	return p.next == len(p.replay.Steps)
+}
@@ -0,0 +1,164 @@
+package game
+
+import "time"
+
+// StatsInterval is how often Stats takes a sample for the PPS/APM graphs.
+const StatsInterval = 5 * time.Second
+
+// SprintLines is how many lines Stats.Sprint times.
+const SprintLines = 40
+
+// Stats tracks what happened during one game, for the post-game breakdown.
+type Stats struct {
+	Start, End time.Time // End is zero while the game is running
+
+	Pieces   int
+	Clears   [5]int // locks by lines cleared at once, index 1-4
+	TSpins   int    // line clears made by a T-spin
+	MaxCombo int    // most consecutive clears after the first
+	Sent     int    // garbage lines sent
+	Received int    // garbage lines received
+	// Sprint is how long the game took to clear SprintLines lines, 0
+	// until it has.
+	Sprint time.Duration
+
+	// Samples holds the running totals at the end of each StatsInterval.
+	Samples []StatSample
+
+	run int // consecutive locks that cleared lines
+}
+
+// StatSample is a snapshot of the running totals.
+type StatSample struct {
+	Pieces int
+	Sent   int
+}
+
+func newStats() Stats {
+	return Stats{Start: time.Now()}
+}
+
+// record counts a locked piece. It samples first, so any intervals that
+// ended before this lock see the totals without it.
+func (s *Stats) record(lines, attack int, tspin bool) {
+	now := time.Now()
+	s.sample(now)
+
+	s.Pieces++
+	s.Clears[lines]++
+	if s.Sprint == 0 && !s.Start.IsZero() && s.linesCleared() >= SprintLines {
+		s.Sprint = now.Sub(s.Start)
+	}
+	s.Sent += attack
+	if lines == 0 {
+		s.run = 0
+		return
+	}
+	if tspin {
+		s.TSpins++
+	}
+	s.run++
+	s.MaxCombo = max(s.MaxCombo, s.run-1)
+}
+
+// linesCleared is the lines cleared so far.
+func (s *Stats) linesCleared() int {
+	n := 0
+	for lines, count := range s.Clears {
+		n += lines * count
+	}
+	return n
+}
+
+// sample appends a sample for every interval that has ended by now.
+func (s *Stats) sample(now time.Time) {
+	if s.Start.IsZero() {
+		return
+	}
+	for now.Sub(s.Start) >= time.Duration(len(s.Samples)+1)*StatsInterval {
+		s.Samples = append(s.Samples, StatSample{Pieces: s.Pieces, Sent: s.Sent})
+	}
+}
+
+// Finish stops the clock. Later calls keep the first end time.
+func (s *Stats) Finish() {
+	if !s.End.IsZero() {
+		return
+	}
+	s.End = time.Now()
+	s.sample(s.End)
+}
+
+// Duration is how long the game ran, or has run so far.
+func (s *Stats) Duration() time.Duration {
+	if s.Start.IsZero() {
+		return 0
+	}
+	if s.End.IsZero() {
+		return time.Since(s.Start)
+	}
+	return s.End.Sub(s.Start)
+}
+
+// PPS is pieces placed per second over the whole game.
+func (s *Stats) PPS() float64 {
+	secs := s.Duration().Seconds()
+	if secs == 0 {
+		return 0
+	}
+	return float64(s.Pieces) / secs
+}
+
+// APM is garbage lines sent per minute over the whole game.
+func (s *Stats) APM() float64 {
+	mins := s.Duration().Minutes()
+	if mins == 0 {
+		return 0
+	}
+	return float64(s.Sent) / mins
+}
+
+// Rates returns pieces per second and attack per minute for each sampled
+// interval, for graphing.
+func (s *Stats) Rates() (pps, apm []float64) {
+	var prev StatSample
+	for _, cur := range s.Samples {
+		pps = append(pps, float64(cur.Pieces-prev.Pieces)/StatsInterval.Seconds())
+		apm = append(apm, float64(cur.Sent-prev.Sent)/StatsInterval.Minutes())
+		prev = cur
+	}
+	return pps, apm
+}
+
+// isTSpin reports whether the current piece is a T that got into place by
+// rotating and has at least three of the corners around its center
+// blocked (the usual three-corner rule). Walls and floor count as blocked.
+func (gs *GameState) isTSpin() bool {
+	p := gs.CurrentPiece
+	if p.Type != PieceT || !gs.lastRotated {
+		return false
+	}
+	blocked := 0
+	for _, c := range [][2]int{{0, 0}, {2, 0}, {0, 2}, {2, 2}} {
+		x, y := p.X+c[0], p.Y+c[1]
+		if x < 0 || x >= BoardWidth || y >= BoardHeight || (y >= 0 && gs.Board.Cells[y][x].Filled) {
+			blocked++
+		}
+	}
+	return blocked >= 3
+}
+
+// isImmobileSpin reports whether the current piece got into place by
+// rotating and can't move left, right or up from there: the all-spin
+// rule's test for a spin by any piece.
+func (gs *GameState) isImmobileSpin() bool {
+	if !gs.lastRotated {
+		return false
+	}
+	for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}} {
+		if gs.Board.IsValidPosition(gs.CurrentPiece, d[0], d[1]) {
+			return false
+		}
+	}
+	return true
+}
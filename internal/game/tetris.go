@@ -1,7 +1,10 @@
 package game
 
 import (
+	"hash/fnv"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,6 +25,16 @@ const (
 	PieceL
 )
 
+var pieceNames = [...]string{"I", "O", "T", "S", "Z", "J", "L"}
+
+// String returns the piece's letter, e.g. "T".
+func (t PieceType) String() string {
+	if t < 0 || int(t) >= len(pieceNames) {
+		return "?"
+	}
+	return pieceNames[t]
+}
+
 type Piece struct {
 	Type  PieceType
 	Shape [][]bool
@@ -92,21 +105,158 @@ func NewPiece(t PieceType) *Piece {
 	}
 }
 
+// Randomizer names for Rules.Randomizer.
+const (
+	RandomizerBag    = "7bag"   // each piece once per bag of 7 (default)
+	RandomizerRandom = "random" // every piece drawn independently
+)
+
+// Randomizers lists the valid Rules.Randomizer values.
+var Randomizers = []string{RandomizerBag, RandomizerRandom}
+
+// Garbage styles for Rules.GarbageStyle: where the holes go in the garbage
+// a player receives.
+const (
+	GarbageClean  = "clean"  // one hole per attack (default)
+	GarbageCheese = "cheese" // a new hole every line
+	GarbageSeeded = "seeded" // one hole per attack, the same sequence for everyone
+)
+
+// GarbageStyles lists the valid Rules.GarbageStyle values.
+var GarbageStyles = []string{GarbageClean, GarbageCheese, GarbageSeeded}
+
+// Hold modes for Rules.Hold.
+const (
+	HoldNormal   = "normal"   // one hold per piece (default)
+	HoldOff      = "off"      // no hold, classic style
+	HoldInfinite = "infinite" // swap as often as you like, for practice
+)
+
+// HoldModes lists the valid Rules.Hold values.
+var HoldModes = []string{HoldNormal, HoldOff, HoldInfinite}
+
+// AttackTables are the garbage presets for Rules.AttackTable: the number of
+// lines sent for clearing 1, 2, 3 and 4 lines at once.
+var AttackTables = map[string][4]int{
+	"standard":   {0, 1, 2, 4},
+	"aggressive": {1, 2, 3, 5},
+	"casual":     {0, 0, 1, 2},
+}
+
+// AttackTableNames lists the AttackTables presets in display order.
+var AttackTableNames = []string{"standard", "aggressive", "casual"}
+
+// Rules are the per-match rule options a room can configure.
+// The zero value is the standard ruleset.
+type Rules struct {
+	AttackTable string // key into AttackTables, "" = standard
+	Randomizer  string // RandomizerBag or RandomizerRandom, "" = 7-bag
+	// GarbageStyle is one of GarbageStyles, "" = clean.
+	GarbageStyle string
+	// Items turns on item mode: clearing lines sometimes earns an item.
+	// With GivenItems, clears earn nothing and items come from GiveItem
+	// instead, for multiplayer, where the server hands them out.
+	Items      bool
+	GivenItems bool
+	// Hold is one of HoldModes, "" = normal.
+	Hold string
+
+	// EntryDelay (ARE) is how long after a piece locks the next one
+	// appears, and LineClearDelay how long cleared lines stay on the
+	// board before collapsing. Zero means no delay.
+	EntryDelay     time.Duration
+	LineClearDelay time.Duration
+
+	// AllSpin rewards spins by any piece, not just the T: a clear made by
+	// rotating a piece into a spot it can't slide out of sends one extra
+	// line of garbage per line cleared.
+	AllSpin bool
+
+	// LinesPerLevel is how many lines it takes to go up a level, 0 = 10.
+	// LevelCap is the highest level reachable, 0 = no cap.
+	LinesPerLevel int
+	LevelCap      int
+	// SharedLevel leaves the level to SetLevel rather than the lines
+	// cleared, for speed races where a room levels everyone up together.
+	SharedLevel bool
+	// Gravity is how long a piece takes to fall one row at each level,
+	// starting from level 1; levels past the end use the last entry, so a
+	// single entry gives fixed gravity. Nil means StandardGravity.
+	Gravity []time.Duration
+}
+
+// StandardGravity is the default gravity curve, levels 1 to 20.
+var StandardGravity = []time.Duration{
+	800 * time.Millisecond,
+	720 * time.Millisecond,
+	630 * time.Millisecond,
+	550 * time.Millisecond,
+	470 * time.Millisecond,
+	380 * time.Millisecond,
+	300 * time.Millisecond,
+	220 * time.Millisecond,
+	130 * time.Millisecond,
+	100 * time.Millisecond,
+	80 * time.Millisecond,
+	80 * time.Millisecond,
+	80 * time.Millisecond,
+	70 * time.Millisecond,
+	70 * time.Millisecond,
+	70 * time.Millisecond,
+	50 * time.Millisecond,
+	50 * time.Millisecond,
+	50 * time.Millisecond,
+	30 * time.Millisecond,
+}
+
+// Phase is where a game is in the cycle of dropping a piece, clearing
+// lines and bringing in the next piece. Without entry or line clear
+// delays a game is always PhaseFalling.
+type Phase int
+
+const (
+	PhaseFalling   Phase = iota // a piece is in play
+	PhaseLineClear              // cleared lines are shown before they collapse
+	PhaseEntry                  // waiting for the next piece (ARE)
+)
+
+// SeedFromText turns a seed a player typed into a game seed: a number is
+// used as is, anything else is a phrase, hashed, so the same text always
+// gives the same pieces.
+func SeedFromText(text string) int64 {
+	text = strings.TrimSpace(text)
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n
+	}
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return int64(h.Sum64())
+}
+
 // PieceGenerator produces pieces using the 7-bag randomizer system.
 // When created with the same seed, two generators produce identical sequences.
 type PieceGenerator struct {
-	rng *rand.Rand
-	bag []PieceType
+	rng    *replayRand
+	bag    []PieceType
+	noBags bool // draw every piece independently instead of from a bag
 }
 
 // NewPieceGenerator creates a seeded 7-bag piece generator.
 func NewPieceGenerator(seed int64) *PieceGenerator {
 	pg := &PieceGenerator{
-		rng: rand.New(rand.NewSource(seed)),
+		rng: newReplayRand(seed),
 	}
 	return pg
 }
 
+// NewPieceGeneratorWith creates a seeded generator using the named randomizer.
+// Unknown names fall back to the 7-bag.
+func NewPieceGeneratorWith(seed int64, randomizer string) *PieceGenerator {
+	pg := NewPieceGenerator(seed)
+	pg.noBags = randomizer == RandomizerRandom
+	return pg
+}
+
 // Next returns the next piece from the 7-bag.
 func (pg *PieceGenerator) Next() *Piece {
 	if len(pg.bag) == 0 {
@@ -126,6 +276,10 @@ func (pg *PieceGenerator) Peek() PieceType {
 }
 
 func (pg *PieceGenerator) refillBag() {
+	if pg.noBags {
+		pg.bag = []PieceType{PieceType(pg.rng.Intn(7))}
+		return
+	}
 	pg.bag = []PieceType{PieceI, PieceO, PieceT, PieceS, PieceZ, PieceJ, PieceL}
 	// Fisher-Yates shuffle
 	for i := len(pg.bag) - 1; i > 0; i-- {
@@ -154,6 +308,21 @@ func (p *Piece) Rotate() {
 	p.Shape = rotated
 }
 
+// RotateBack turns the piece counterclockwise, undoing Rotate.
+func (p *Piece) RotateBack() {
+	n := len(p.Shape)
+	rotated := make([][]bool, n)
+	for i := range rotated {
+		rotated[i] = make([]bool, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			rotated[n-1-j][i] = p.Shape[i][j]
+		}
+	}
+	p.Shape = rotated
+}
+
 type Cell struct {
 	Filled bool
 	Color  int
@@ -213,6 +382,25 @@ func (b *Board) LockPiece(p *Piece) {
 	}
 }
 
+// FullRows returns the indexes of the rows that are completely filled,
+// top to bottom.
+func (b *Board) FullRows() []int {
+	var rows []int
+	for y := 0; y < b.Height; y++ {
+		full := true
+		for x := 0; x < b.Width; x++ {
+			if !b.Cells[y][x].Filled {
+				full = false
+				break
+			}
+		}
+		if full {
+			rows = append(rows, y)
+		}
+	}
+	return rows
+}
+
 func (b *Board) ClearLines() int {
 	linesCleared := 0
 	newCells := make([][]Cell, 0, b.Height)
@@ -306,7 +494,41 @@ type GameState struct {
 	PlayerID     string
 	PlayerName   string
 	AttackPower  int
+	LastClear    int    // lines cleared by the most recent lock; consumers reset it
+	Attack       Clear  // what the most recent clearing lock was, sent with its attack
+	Item         string // item held in item mode, "" = none
 	PieceGen     *PieceGenerator
+	Rules        Rules
+	Stats        Stats
+
+	// Phase is PhaseFalling unless a delay is running; ClearingRows are
+	// the full rows waiting to collapse during PhaseLineClear.
+	Phase        Phase
+	ClearingRows []int
+
+	lastRotated bool        // the current piece's last successful move was a rotation
+	phaseEnds   time.Time   // when the running delay is over
+	combo       int         // consecutive clearing locks so far
+	b2b         bool        // the last clear was a Tetris or T-spin
+	incoming    []int       // lines in each attack making up GarbageQueue
+	seed        int64       // the seed pieces are dealt from
+	holeRNG     *replayRand // hole positions for GarbageSeeded
+	chance      *replayRand // everything else left to chance; see chanceRand
+	speedUntil  time.Time   // when a speed-up item wears off
+}
+
+// Clear describes the lines cleared by one lock.
+type Clear struct {
+	Lines int
+	TSpin bool
+	Combo int  // clearing locks in a row before this one
+	B2B   bool // a Tetris or T-spin following another with no easier clear between
+	Spin  bool // a spin by any piece, under all-spin rules
+}
+
+// Difficult reports whether the clear keeps a back-to-back chain going.
+func (c Clear) Difficult() bool {
+	return c.Lines == 4 || (c.TSpin && c.Lines > 0)
 }
 
 // NewGameState creates a game state with legacy random piece generation.
@@ -326,12 +548,19 @@ func NewGameState(playerID, playerName string) *GameState {
 		PlayerID:     playerID,
 		PlayerName:   playerName,
 		AttackPower:  0,
+		Stats:        newStats(),
 	}
 }
 
 // NewSeededGameState creates a game state with a deterministic 7-bag generator.
 func NewSeededGameState(playerID, playerName string, seed int64) *GameState {
-	gen := NewPieceGenerator(seed)
+	return NewSeededGameStateWithRules(playerID, playerName, seed, Rules{})
+}
+
+// NewSeededGameStateWithRules creates a deterministic game state that plays
+// by the given room rules.
+func NewSeededGameStateWithRules(playerID, playerName string, seed int64, rules Rules) *GameState {
+	gen := NewPieceGeneratorWith(seed, rules.Randomizer)
 	return &GameState{
 		Board:        NewBoard(),
 		CurrentPiece: gen.Next(),
@@ -348,26 +577,41 @@ func NewSeededGameState(playerID, playerName string, seed int64) *GameState {
 		PlayerName:   playerName,
 		AttackPower:  0,
 		PieceGen:     gen,
+		Rules:        rules,
+		Stats:        newStats(),
+		seed:         seed,
+		holeRNG:      newReplayRand(seed),
 	}
 }
 
 func (gs *GameState) MoveLeft() bool {
+	if gs.Phase != PhaseFalling {
+		return false
+	}
 	if gs.Board.IsValidPosition(gs.CurrentPiece, -1, 0) {
 		gs.CurrentPiece.X--
+		gs.lastRotated = false
 		return true
 	}
 	return false
 }
 
 func (gs *GameState) MoveRight() bool {
+	if gs.Phase != PhaseFalling {
+		return false
+	}
 	if gs.Board.IsValidPosition(gs.CurrentPiece, 1, 0) {
 		gs.CurrentPiece.X++
+		gs.lastRotated = false
 		return true
 	}
 	return false
 }
 
 func (gs *GameState) MoveDown() bool {
+	if gs.Phase != PhaseFalling {
+		return false
+	}
 	if gs.Board.IsValidPosition(gs.CurrentPiece, 0, 1) {
 		gs.CurrentPiece.Y++
 		return true
@@ -375,6 +619,17 @@ func (gs *GameState) MoveDown() bool {
 	return false
 }
 
+// SoftDrop moves the current piece down one row on the player's command,
+// scoring a point for the row as in standard scoring. Gravity uses
+// MoveDown, which scores nothing.
+func (gs *GameState) SoftDrop() bool {
+	if !gs.MoveDown() {
+		return false
+	}
+	gs.Score++
+	return true
+}
+
 func (gs *GameState) GetGhostY() int {
 	ghostY := gs.CurrentPiece.Y
 	for gs.Board.IsValidPosition(gs.CurrentPiece, 0, ghostY-gs.CurrentPiece.Y+1) {
@@ -384,6 +639,9 @@ func (gs *GameState) GetGhostY() int {
 }
 
 func (gs *GameState) HardDrop() {
+	if gs.Phase != PhaseFalling {
+		return
+	}
 	for gs.MoveDown() {
 		gs.Score += 2
 	}
@@ -391,8 +649,26 @@ func (gs *GameState) HardDrop() {
 }
 
 func (gs *GameState) Rotate() bool {
+	return gs.turn((*Piece).Rotate)
+}
+
+// RotateBack rotates the falling piece counterclockwise, with the same
+// kicks as Rotate.
+func (gs *GameState) RotateBack() bool {
+	return gs.turn((*Piece).RotateBack)
+}
+
+func (gs *GameState) turn(rotate func(*Piece)) bool {
+	if gs.Phase != PhaseFalling || !gs.rotate(rotate) {
+		return false
+	}
+	gs.lastRotated = true
+	return true
+}
+
+func (gs *GameState) rotate(turn func(*Piece)) bool {
 	original := gs.CurrentPiece.Shape
-	gs.CurrentPiece.Rotate()
+	turn(gs.CurrentPiece)
 
 	if !gs.Board.IsValidPosition(gs.CurrentPiece, 0, 0) {
 		if gs.Board.IsValidPosition(gs.CurrentPiece, -1, 0) {
@@ -418,11 +694,12 @@ func (gs *GameState) Rotate() bool {
 }
 
 func (gs *GameState) Hold() bool {
-	if !gs.CanHold {
+	if !gs.CanHold || gs.Phase != PhaseFalling || gs.Rules.Hold == HoldOff {
 		return false
 	}
 
-	gs.CanHold = false
+	gs.CanHold = gs.Rules.Hold == HoldInfinite
+	gs.lastRotated = false
 
 	if gs.HoldPiece == nil {
 		gs.HoldPiece = NewPiece(gs.CurrentPiece.Type)
@@ -448,34 +725,146 @@ func (gs *GameState) nextPiece() *Piece {
 }
 
 func (gs *GameState) LockPiece() int {
+	tspin := gs.isTSpin()
+	spin := gs.Rules.AllSpin && (tspin || gs.isImmobileSpin())
 	gs.Board.LockPiece(gs.CurrentPiece)
-	linesCleared := gs.Board.ClearLines()
+	full := gs.Board.FullRows()
+	linesCleared := len(full)
 
 	gs.Lines += linesCleared
+	gs.LastClear = linesCleared
 	gs.Score += gs.calculateScore(linesCleared)
-	gs.Level = gs.Lines/10 + 1
+	if !gs.Rules.SharedLevel {
+		gs.Level = gs.Rules.level(gs.Lines)
+	}
 
 	if linesCleared > 0 {
-		gs.AttackPower = gs.calculateAttack(linesCleared)
+		gs.AttackPower = gs.Rules.Attack(linesCleared, spin)
+		gs.recordClear(linesCleared, tspin, spin)
+		gs.maybeAwardItem(linesCleared)
 	} else {
 		gs.AttackPower = 0
+		gs.combo = 0
 	}
 
-	gs.CurrentPiece = gs.NextPiece
-	gs.NextPiece = gs.nextPiece()
+	gs.Stats.record(linesCleared, gs.AttackPower, tspin)
+
 	gs.CanHold = true
+	gs.lastRotated = false
+
+	if linesCleared > 0 && gs.Rules.LineClearDelay > 0 {
+		gs.ClearingRows = full
+		gs.startPhase(PhaseLineClear, time.Now(), gs.Rules.LineClearDelay)
+		return linesCleared
+	}
+	gs.Board.ClearLines()
+	gs.afterClear(time.Now())
+
+	return linesCleared
+}
+
+// recordClear fills in Attack for a lock that cleared lines, and moves
+// the combo and back-to-back chains on.
+func (gs *GameState) recordClear(lines int, tspin, spin bool) {
+	c := Clear{Lines: lines, TSpin: tspin, Combo: gs.combo, Spin: spin}
+	c.B2B = c.Difficult() && gs.b2b
+	gs.Attack = c
+	gs.combo++
+	gs.b2b = c.Difficult()
+}
 
-	if gs.GarbageQueue > 0 {
-		holeX := rand.Intn(BoardWidth)
-		gs.Board.AddGarbageLines(gs.GarbageQueue, holeX)
-		gs.GarbageQueue = 0
+// afterClear raises any garbage waiting, then brings in the next piece,
+// after the entry delay if there is one.
+func (gs *GameState) afterClear(now time.Time) {
+	gs.raiseGarbage()
+	if gs.Rules.EntryDelay > 0 {
+		gs.startPhase(PhaseEntry, now, gs.Rules.EntryDelay)
+		return
 	}
+	gs.spawn()
+}
+
+// raiseGarbage pushes the queued garbage up from the bottom of the board,
+// holed according to the garbage style.
+func (gs *GameState) raiseGarbage() {
+	hole := gs.chanceRand().Intn
+	if gs.Rules.GarbageStyle == GarbageSeeded && gs.holeRNG != nil {
+		hole = gs.holeRNG.Intn
+	}
+	for _, lines := range gs.incoming {
+		if gs.Rules.GarbageStyle == GarbageCheese {
+			for range lines {
+				gs.Board.AddGarbageLines(1, hole(BoardWidth))
+			}
+			continue
+		}
+		gs.Board.AddGarbageLines(lines, hole(BoardWidth))
+	}
+	gs.incoming = nil
+	gs.GarbageQueue = 0
+}
+
+// spawn brings in the next piece, ending the game if there's no room.
+func (gs *GameState) spawn() {
+	gs.Phase = PhaseFalling
+	gs.CurrentPiece = gs.NextPiece
+	gs.NextPiece = gs.nextPiece()
 
 	if gs.Board.IsGameOver(gs.CurrentPiece) {
 		gs.IsGameOver = true
+		gs.Stats.Finish()
 	}
+}
 
-	return linesCleared
+func (gs *GameState) startPhase(phase Phase, from time.Time, d time.Duration) {
+	gs.Phase = phase
+	gs.phaseEnds = from.Add(d)
+}
+
+// DelayLeft is how long the running entry or line clear delay has left
+// at now, or 0 if none is running.
+func (gs *GameState) DelayLeft(now time.Time) time.Duration {
+	if gs.Phase == PhaseFalling {
+		return 0
+	}
+	return max(gs.phaseEnds.Sub(now), 0)
+}
+
+// Update ends any entry or line clear delay that is over by now: cleared
+// lines collapse and the next piece comes in. It reports whether anything
+// changed. Callers with delays in their rules should call it when
+// DelayLeft runs out.
+func (gs *GameState) Update(now time.Time) bool {
+	changed := false
+	for gs.Phase != PhaseFalling && !now.Before(gs.phaseEnds) {
+		gs.endDelay()
+		changed = true
+	}
+	return changed
+}
+
+// endDelay ends the running delay, whenever it was due to: the cleared
+// lines collapse, or the next piece comes in.
+func (gs *GameState) endDelay() {
+	switch gs.Phase {
+	case PhaseLineClear:
+		gs.Board.ClearLines()
+		gs.ClearingRows = nil
+		gs.afterClear(gs.phaseEnds)
+	case PhaseEntry:
+		gs.spawn()
+	}
+}
+
+// chanceRand is the game's generator for what isn't dealt from the seed:
+// items earned, and garbage holes outside GarbageSeeded. It is seeded
+// afresh for each game, but seeded all the same, so a recorded game
+// replays exactly.
+func (gs *GameState) chanceRand() *replayRand {
+	if gs.chance == nil {
+		gs.chance = newReplayRand(rand.Int63())
+	}
+	return gs.chance
 }
 
 func (gs *GameState) calculateScore(lines int) int {
@@ -491,25 +880,38 @@ func (gs *GameState) calculateScore(lines int) int {
 	return 0
 }
 
-func (gs *GameState) calculateAttack(lines int) int {
-	attackTable := map[int]int{
-		1: 0,
-		2: 1,
-		3: 2,
-		4: 4,
+// baseAttack is the garbage the attack table sends for clearing lines.
+func (r Rules) baseAttack(lines int) int {
+	table, ok := AttackTables[r.AttackTable]
+	if !ok {
+		table = AttackTables["standard"]
 	}
-	if attack, ok := attackTable[lines]; ok {
-		return attack
+	if lines >= 1 && lines <= len(table) {
+		return table[lines-1]
 	}
 	return 0
 }
 
+// Attack is the garbage a clear of lines sends under these rules; spin
+// is whether it was a spin, which earns a line of garbage per line
+// cleared in all-spin rooms. The server works attacks out with it from
+// the clears clients report.
+func (r Rules) Attack(lines int, spin bool) int {
+	attack := r.baseAttack(lines)
+	if r.AllSpin && spin {
+		attack += lines
+	}
+	return attack
+}
+
 func (gs *GameState) ReceiveGarbage(lines int) {
 	gs.GarbageQueue += lines
+	gs.incoming = append(gs.incoming, lines)
+	gs.Stats.Received += lines
 }
 
 func (gs *GameState) Tick() bool {
-	if gs.IsGameOver {
+	if gs.IsGameOver || gs.Phase != PhaseFalling {
 		return false
 	}
 
@@ -521,31 +923,33 @@ func (gs *GameState) Tick() bool {
 }
 
 func (gs *GameState) GetDropSpeed() time.Duration {
-	speeds := []time.Duration{
-		800 * time.Millisecond,
-		720 * time.Millisecond,
-		630 * time.Millisecond,
-		550 * time.Millisecond,
-		470 * time.Millisecond,
-		380 * time.Millisecond,
-		300 * time.Millisecond,
-		220 * time.Millisecond,
-		130 * time.Millisecond,
-		100 * time.Millisecond,
-		80 * time.Millisecond,
-		80 * time.Millisecond,
-		80 * time.Millisecond,
-		70 * time.Millisecond,
-		70 * time.Millisecond,
-		70 * time.Millisecond,
-		50 * time.Millisecond,
-		50 * time.Millisecond,
-		50 * time.Millisecond,
-		30 * time.Millisecond,
-	}
-
-	if gs.Level > len(speeds) {
-		return speeds[len(speeds)-1]
-	}
-	return speeds[gs.Level-1]
+	gravity := gs.Rules.Gravity
+	if len(gravity) == 0 {
+		gravity = StandardGravity
+	}
+	speed := gravity[min(max(gs.Level, 1), len(gravity))-1]
+	if gs.SpeedUpLeft(time.Now()) > 0 {
+		speed /= SpeedUpFactor
+	}
+	return speed
+}
+
+// SetLevel sets the level, which with Rules.SharedLevel comes from the
+// room rather than the lines cleared. It takes effect from the next
+// gravity step.
+func (gs *GameState) SetLevel(level int) {
+	gs.Level = max(level, 1)
+}
+
+// level is the level reached after clearing lines.
+func (r Rules) level(lines int) int {
+	perLevel := r.LinesPerLevel
+	if perLevel <= 0 {
+		perLevel = 10
+	}
+	level := lines/perLevel + 1
+	if r.LevelCap > 0 {
+		level = min(level, r.LevelCap)
+	}
+	return level
 }
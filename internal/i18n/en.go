@@ -0,0 +1,385 @@
+package i18n
+
+// english is the source catalog. Every key used by the TUI must be here.
+var english = Catalog{
+	// Status screens
+	"status.connecting":   "Connecting...",
+	"status.disconnected": "Disconnected from server.\nPress Ctrl+C to exit.",
+	"status.loading":      "Loading...",
+
+	// Main menu
+	"menu.subtitle":     "Multiplayer Tetris TUI",
+	"menu.player":       "Player: %s",
+	"menu.single":       "Single Player (Practice)",
+	"menu.create":       "Create Room",
+	"menu.join":         "Join Room (by code)",
+	"menu.browse":       "Browse Rooms",
+	"menu.name":         "Edit Name",
+	"menu.settings":     "Settings",
+	"menu.server":       "Server",
+	"menu.server_addr":  "Server: %s",
+	"menu.server_stats": "%d online (peak %d today) · %d matches · %d lines",
+	"menu.tutorial":     "Tutorial",
+	"menu.rejoin":       "Rejoin Last Room (%s)",
+	"menu.history":      "Match History",
+
+	// Shared hints
+	"hint.quit":    "Press Q to quit",
+	"hint.cancel":  "Press ESC to cancel",
+	"hint.select":  "Select",
+	"hint.toggle":  "Toggle",
+	"hint.back":    "Go back",
+	"hint.confirm": "Press ENTER to confirm",
+
+	// Edit name / join room
+	"name.title":      "=== Edit Name ===",
+	"name.prompt":     "Type your name: %s_",
+	"server.title":    "=== Server ===",
+	"server.prompt":   "Server address: %s_",
+	"server.recent":   "Recent servers:",
+	"server.checking": "Checking server...",
+	"server.pick":     "Pick a recent server, or host a LAN game",
+	"server.host_lan": "Host a LAN game",
+	"server.connect":  "Check and use this server",
+
+	"server.unreachable":  "Can't reach the server. Check the address and your connection.",
+	"server.failed":       "The server ran into a problem. Try again in a moment.",
+	"server.bad_response": "The server sent a reply gotris doesn't understand. Is it a gotris server?",
+
+	// Server error codes
+	"error.bad_request":        "The server didn't understand the request. Is the client up to date?",
+	"error.room_not_found":     "No room with that code. Check it and try again.",
+	"error.room_full":          "That room is full.",
+	"error.game_in_progress":   "A game is in progress in that room. Try again when it's over.",
+	"error.invalid_token":      "Your invitation to the room has expired. Join again.",
+	"error.token_mismatch":     "That invitation is for a different room.",
+	"error.not_host":           "Only the host can change the room settings.",
+	"error.not_in_lobby":       "Settings can only be changed between matches.",
+	"error.invalid_settings":   "The server doesn't support that setting.",
+	"error.too_many_players":   "There are already more players in the room than that.",
+	"error.server_full":        "The server is full right now. Try again in a little while.",
+	"error.server_full_retry":  "The server is full right now. Try again in %d seconds.",
+	"error.rate_limited":       "You've created too many rooms lately. Try again in a little while.",
+	"error.rate_limited_retry": "You've created too many rooms lately. Try again in %d seconds.",
+	"error.banned":             "You're banned from this server.",
+	"error.ranked_room":        "Ranked rooms play with fixed settings.",
+
+	"join.title":      "=== Join Room ===",
+	"join.prompt":     "Enter room code: %s_",
+	"join.confirm":    "Press ENTER to join",
+	"join.paste_hint": "CTRL+V to paste a code",
+
+	"create.title":   "=== Create Room ===",
+	"create.prompt":  "Room title (optional): %s_",
+	"create.confirm": "Press ENTER to create",
+
+	// Match history
+	"history.title":         "=== Match History ===",
+	"history.empty":         "No matches yet on this server. Go play some!",
+	"history.col_when":      "When",
+	"history.col_room":      "Room",
+	"history.select":        "Select match",
+	"history.details":       "Show details",
+	"history.match":         "=== Match in %s ===",
+	"history.played":        "Played %s, lasted %s",
+	"history.sent":          "You sent %d garbage lines",
+	"history.export":        "Export your stats to CSV (shift: JSON)",
+	"history.exported":      "Exported %d games to %s",
+	"history.export_failed": "Export failed: %v",
+	"history.achievements":  "Show your achievements",
+
+	// Room browser
+	"rooms.title":        "=== Browse Rooms ===",
+	"rooms.empty":        "No rooms available. Create one!",
+	"rooms.col_room":     "Room",
+	"rooms.col_title":    "Title",
+	"rooms.col_type":     "Type",
+	"rooms.col_players":  "Players",
+	"rooms.col_status":   "Status",
+	"rooms.lobby":        "Lobby",
+	"rooms.playing":      "Playing",
+	"rooms.live_players": "Playing: %s",
+	"rooms.starting":     "Starting",
+	"rooms.finished":     "Finished",
+	"rooms.page":         "Page %d / %d",
+	"rooms.select":       "Select room",
+	"rooms.change_page":  "Change page",
+	"rooms.join":         "Join selected room",
+	"rooms.refresh":      "Refresh",
+	"rooms.search":       "Search: %s",
+	"rooms.search_hint":  "Search by code or title",
+	"rooms.no_match":     "No rooms match your search.",
+	"rooms.filters":      "Open seats only: %s   Sort: %s",
+	"rooms.open_hint":    "Toggle open seats only",
+	"rooms.sort_hint":    "Change sort order",
+	"rooms.sort.code":    "code",
+	"rooms.sort.players": "most players",
+	"rooms.sort.newest":  "newest",
+	"rooms.in_progress":  "Cannot join: game already in progress",
+	"rooms.type.casual":  "Casual",
+	"rooms.type.ranked":  "Ranked",
+	"rooms.quick_hint":   "Quick play (ranked or casual)",
+
+	"quick.title":  "=== Quick Play ===",
+	"quick.ranked": "Ranked: fixed settings, attacks checked",
+	"quick.casual": "Casual: any settings the host picks",
+
+	// Lobby
+	"attract.hint":            "DEMO: press any key",
+	"lobby.title":             "=== LOBBY ===",
+	"lobby.code":              "Room Code: %s",
+	"lobby.share":             "Share this code with friends!",
+	"lobby.copy_hint":         "Press Y to copy it",
+	"lobby.qr_hint":           "Press C for a QR code to join by",
+	"lobby.invite_hint":       "Press F to invite a friend",
+	"qr.scan":                 "Scan to join room %s in a browser",
+	"qr.copy_hint":            "Press Y to copy the link",
+	"qr.back_hint":            "Press C to go back to the lobby",
+	"lan.address":             "LAN game: friends on your network use server %s",
+	"lan.failed":              "Couldn't start a LAN server: %v",
+	"clipboard.copied":        "Copied %s to the clipboard",
+	"clipboard.copy_failed":   "Couldn't copy: %v",
+	"clipboard.paste_failed":  "Couldn't paste: %v",
+	"lobby.players":           "Players in lobby:",
+	"lobby.ready_hint":        "Press SPACE to toggle ready",
+	"lobby.leave_hint":        "Press ESC to leave room",
+	"lobby.host":              "(host)",
+	"lobby.ranked":            "Ranked room: standard settings, can't be changed",
+	"lobby.muted":             "(muted)",
+	"lobby.bot":               "(CPU)",
+	"lobby.mute_hint":         "Press 1-9 to mute or unmute a player's emotes",
+	"lobby.auto_start":        "Match starts in %ds",
+	"lobby.auto_start_paused": "Auto-start paused at %ds: waiting for new players",
+
+	// Room settings
+	"room.settings":          "Room settings:",
+	"room.max_players":       "Max players",
+	"room.targeting":         "Targeting",
+	"room.attack_table":      "Attack table",
+	"room.randomizer":        "Randomizer",
+	"room.targeting.free":    "Free choice",
+	"room.targeting.random":  "Random only",
+	"room.attack.standard":   "Standard",
+	"room.attack.aggressive": "Aggressive",
+	"room.attack.casual":     "Casual",
+	"room.randomizer.7bag":   "7-bag",
+	"room.randomizer.random": "Pure random",
+	"room.on_join":           "New joiner",
+	"room.on_join.reset":     "Resets timer",
+	"room.on_join.pause":     "Pauses timer",
+	"room.entry_delay":       "Entry delay",
+	"room.line_clear_delay":  "Line clear delay",
+	"room.delay_ms":          "%dms",
+	"room.spins":             "Spin bonus",
+	"room.spins.t":           "Standard",
+	"room.spins.all":         "All spins",
+	"room.garbage":           "Garbage",
+	"room.garbage.clean":     "Clean",
+	"room.garbage.cheese":    "Cheese",
+	"room.garbage.seeded":    "Seeded",
+	"room.items":             "Items",
+	"room.hold":              "Hold",
+	"room.hold.normal":       "Once per piece",
+	"room.hold.off":          "Off",
+	"room.hold.infinite":     "Unlimited",
+	"room.host_hint":         "You're the host: press a key to change",
+	"room.bots":              "CPU opponents",
+	"room.points":            "Points play",
+	"room.points.rule":       "First to %d (%s, +%d per KO)",
+	"room.speed_race":        "Speed race",
+	"room.speed_race.rule":   "Level up every %ds",
+	"room.seed":              "Seed",
+	"room.seed.random":       "Random each match",
+	"room.seed_prompt":       "Seed: %s",
+	"room.seed_hint":         "Type a number or phrase, ENTER to set (empty = random), ESC to cancel",
+
+	// Connection widget
+	"conn.connected":    "connected",
+	"conn.reconnecting": "reconnecting",
+	"conn.disconnected": "disconnected",
+	"conn.rtt":          "%dms",
+	"conn.attempt":      "reconnecting %d/%d",
+	"conn.rejoined":     "Connection restored. The match went on without you; you are back in the lobby.",
+	"conn.resumed_lost": "Reconnected to your room, but the game in progress was lost.",
+	"conn.missed":       "Reconnected, but too much of the match went by while you were away to carry on.",
+
+	// In-game HUD
+	"info.player":            "Player: %s",
+	"info.score":             "Score: %d",
+	"info.level":             "Level: %d",
+	"info.lines":             "Lines: %d",
+	"info.next":              "NEXT",
+	"info.hold":              "HOLD",
+	"info.empty":             "Empty",
+	"info.attack_from":       "+%d from %s",
+	"info.attack":            "+%d incoming",
+	"info.incoming":          "INCOMING: %d",
+	"info.target":            "TARGET: %s",
+	"info.target_hint":       "[Tab/1-8] change target",
+	"info.random_hint":       "[0] random target",
+	"info.target_locked":     "Targets are random in this room",
+	"feed.tetris":            "%s sent a Tetris!",
+	"feed.ko":                "%s was KO'd by %s",
+	"feed.out":               "%s topped out",
+	"feed.suspect":           "⚠ %s's board looks tampered with",
+	"info.item":              "Item: %s [E]",
+	"info.speed_up":          "Speed-up! %ds",
+	"item.clear":             "Clear",
+	"item.scramble":          "Scramble",
+	"item.speed":             "Speed-up",
+	"feed.item":              "%s used %s on %s",
+	"feed.item_self":         "%s used %s",
+	"feed.attack":            "%s: %s",
+	"feed.b2b":               "B2B",
+	"feed.combo":             "%d combo",
+	"clear.single":           "Single",
+	"clear.double":           "Double",
+	"clear.triple":           "Triple",
+	"clear.tetris":           "Tetris",
+	"clear.tspin_single":     "T-Spin Single",
+	"clear.tspin_double":     "T-Spin Double",
+	"clear.tspin_triple":     "T-Spin Triple",
+	"emote.glhf":             "GL HF!",
+	"emote.gg":               "GG!",
+	"emote.nice":             "Nice!",
+	"emote.oops":             "Oops!",
+	"emote.bring_it":         "Bring it!",
+	"emote.hint":             "[F1-F5] emotes",
+	"practice.title":         "PRACTICE",
+	"practice.auto":          "Auto: %s",
+	"practice.off":           "off",
+	"practice.every":         "every %gs",
+	"practice.add_hint":      "[g/G] add 1/4 lines",
+	"practice.interval_hint": "[i] change interval",
+	"target.random":          "Random",
+	"opponent.page":          "◀ [ Page %d / %d ] ▶",
+	"opponent.attacks":       "↓%d ↑%d",
+	"opponent.remaining":     "%d players left",
+	"opponent.out":           "OUT",
+	"opponent.stats":         "S:%d L:%d",
+
+	// Results
+	"result.winner":        "WINNER!",
+	"result.game_over":     "GAME OVER",
+	"result.score":         "Score: %d",
+	"result.seed":          "Seed: %s (use it in a room to play the same pieces)",
+	"result.replay":        "Replay saved to %s",
+	"result.champion":      "%s wins the series!",
+	"result.points_target": "First to %d points wins the series",
+	"result.rank":          "Rank: #%d",
+	"result.play_again":    "Play Again",
+	"result.main_menu":     "Main Menu",
+	"result.back_to_lobby": "Back to Lobby",
+	"result.leave_room":    "Leave Room",
+	"result.stats":         "Statistics",
+
+	"standings.title":  "=== STANDINGS ===",
+	"standings.rank":   "Rank",
+	"standings.player": "Player",
+	"standings.score":  "Score",
+	"standings.lines":  "Lines",
+	"standings.kos":    "KOs",
+	"standings.points": "Points",
+	"standings.time":   "Time",
+
+	"awards.title":           "=== AWARDS ===",
+	"award.most_sent":        "Most garbage sent: %s (%d lines)",
+	"award.most_kos":         "Most KOs: %s (%d)",
+	"award.fastest_tetris":   "Fastest Tetris: %s (%s)",
+	"award.longest_survival": "Longest survival: %s (%s)",
+
+	// Settings
+	"settings.title":          "=== Settings ===",
+	"settings.on":             "on",
+	"settings.off":            "off",
+	"settings.sound":          "Sound cues (terminal bell)",
+	"settings.reduced_motion": "Reduced motion",
+	"settings.input_display":  "Input display",
+	"settings.keymap":         "Keys",
+	"keymap.guideline":        "Guideline (Z/X/C, Space)",
+	"keymap.classic":          "Classic (arrows only)",
+	"keymap.vim":              "Vim (hjkl)",
+	"settings.notify":         "Notify when a match starts (in the background)",
+	"notify.match_starting":   "Match starting in room %s",
+
+	// Controls help
+	"controls.title":       "Controls:",
+	"controls.move":        "Move left/right",
+	"controls.soft_drop":   "Soft drop",
+	"controls.hard_drop":   "Hard drop",
+	"controls.rotate":      "Rotate",
+	"controls.rotate_back": "Rotate back",
+	"controls.hold":        "Hold piece",
+	"controls.quit":        "Quit",
+
+	// Tutorial
+	"tutorial.step":          "LESSON %d / %d",
+	"tutorial.next":          "Press ENTER for the next lesson",
+	"tutorial.finished":      "Tutorial complete! Press ENTER to return to the menu",
+	"tutorial.retry":         "Not quite. The board has been reset, try again!",
+	"tutorial.quit":          "leave the tutorial",
+	"tutorial.move.title":    "Moving",
+	"tutorial.move.prompt":   "Move the piece left and right with %s and %s. %s nudges it down.",
+	"tutorial.move.done":     "Nice moves!",
+	"tutorial.rotate.title":  "Rotating",
+	"tutorial.rotate.prompt": "Rotate the piece with %s. Spin it all the way round.",
+	"tutorial.rotate.done":   "Full turn!",
+	"tutorial.ghost.title":   "Ghost piece",
+	"tutorial.ghost.prompt":  "The outline below the piece is its ghost: where it will land. Line the ghost up with the gap on the right.",
+	"tutorial.ghost.done":    "Right on target.",
+	"tutorial.drop.title":    "Hard drop",
+	"tutorial.drop.prompt":   "%s drops the piece straight to its ghost. Fill the gap to clear both lines.",
+	"tutorial.drop.done":     "Two lines cleared!",
+	"tutorial.hold.title":    "Hold",
+	"tutorial.hold.prompt":   "Don't want this piece? Press %s to put it in HOLD and take the next one. You can swap once per piece.",
+	"tutorial.hold.done":     "Saved for later.",
+	"tutorial.attack.title":  "Attacking",
+	"tutorial.attack.prompt": "Clearing 2 or more lines at once sends garbage to your opponents, and 4 at once (a Tetris) hits hardest. Stand the I piece up and drop it into the well.",
+	"tutorial.attack.done":   "Tetris! That sends 4 lines of garbage.",
+
+	// Post-game statistics
+	"stats.title":      "GAME STATISTICS",
+	"stats.time":       "Time      %d:%02d",
+	"stats.pieces":     "Pieces    %d (%.2f PPS)",
+	"stats.attack":     "Garbage   %d sent, %d received (%.1f APM)",
+	"stats.clears":     "Singles %d  Doubles %d  Triples %d  Tetrises %d",
+	"stats.tspins":     "T-spins %d",
+	"stats.combo":      "Max combo %d",
+	"stats.interval":   "one bar per %ds",
+	"stats.no_samples": "Game too short for graphs",
+
+	// Input display
+	"inputs.title": "INPUT",
+
+	// Debug overlay
+	"debug.title": "DEBUG LOG",
+	"debug.hide":  "F12 to hide",
+
+	// Invites
+	"invite.prompt":      "Friend code: %s",
+	"invite.hint":        "ENTER to send (no code = a one-use link), ESC to cancel",
+	"invite.your_code":   "Your friend code: %s",
+	"invite.sent":        "Invite sent to %s",
+	"invite.link":        "One-use invite link: %s",
+	"invite.from":        "%s invites you to room %s",
+	"invite.answer_hint": "Press A to accept, D to decline",
+	"invite.more":        "(+%d more)",
+
+	// Achievements
+	"achievements.title":            "=== Achievements ===",
+	"achievements.count":            "%d of %d unlocked",
+	"achievements.unlocked":         "unlocked %s",
+	"achievements.new":              "Achievement unlocked: %s",
+	"achievement.first_tetris":      "Tetris!",
+	"achievement.first_tetris.desc": "Clear four lines at once",
+	"achievement.first_tspin":       "Spin Doctor",
+	"achievement.first_tspin.desc":  "Clear lines with a T-spin",
+	"achievement.sprint":            "Sprinter",
+	"achievement.sprint.desc":       "Clear 40 lines in a game's first 2 minutes",
+	"achievement.first_win":         "First Blood",
+	"achievement.first_win.desc":    "Win a multiplayer match",
+	"achievement.ten_wins":          "Veteran",
+	"achievement.ten_wins.desc":     "Win 10 multiplayer matches",
+	"achievement.close_call":        "Close Call",
+	"achievement.close_call.desc":   "Win a match with 3 or more lines of garbage waiting",
+}
@@ -0,0 +1,385 @@
+package i18n
+
+// spanish is the Spanish translation, and a template for new languages.
+var spanish = Catalog{
+	// Status screens
+	"status.connecting":   "Conectando...",
+	"status.disconnected": "Desconectado del servidor.\nPulsa Ctrl+C para salir.",
+	"status.loading":      "Cargando...",
+
+	// Main menu
+	"menu.subtitle":     "Tetris multijugador (TUI)",
+	"menu.player":       "Jugador: %s",
+	"menu.single":       "Un jugador (práctica)",
+	"menu.create":       "Crear sala",
+	"menu.join":         "Unirse a sala (con código)",
+	"menu.browse":       "Explorar salas",
+	"menu.name":         "Cambiar nombre",
+	"menu.settings":     "Ajustes",
+	"menu.server":       "Servidor",
+	"menu.server_addr":  "Servidor: %s",
+	"menu.server_stats": "%d conectados (máximo hoy %d) · %d partidas · %d líneas",
+	"menu.tutorial":     "Tutorial",
+	"menu.history":      "Historial de partidas",
+	"menu.rejoin":       "Volver a la última sala (%s)",
+
+	// Shared hints
+	"hint.quit":    "Pulsa Q para salir",
+	"hint.cancel":  "Pulsa ESC para cancelar",
+	"hint.select":  "Seleccionar",
+	"hint.toggle":  "Cambiar",
+	"hint.back":    "Volver",
+	"hint.confirm": "Pulsa ENTER para confirmar",
+
+	// Edit name / join room
+	"name.title":      "=== Cambiar nombre ===",
+	"name.prompt":     "Escribe tu nombre: %s_",
+	"server.title":    "=== Servidor ===",
+	"server.prompt":   "Dirección del servidor: %s_",
+	"server.recent":   "Servidores recientes:",
+	"server.checking": "Comprobando el servidor...",
+	"server.pick":     "Elegir un servidor reciente, o crear una partida LAN",
+	"server.host_lan": "Crear una partida LAN",
+	"server.connect":  "Comprobar y usar este servidor",
+
+	"server.unreachable":  "No se puede contactar con el servidor. Revisa la dirección y tu conexión.",
+	"server.failed":       "El servidor tuvo un problema. Inténtalo de nuevo en un momento.",
+	"server.bad_response": "El servidor envió una respuesta que gotris no entiende. ¿Es un servidor de gotris?",
+
+	// Server error codes
+	"error.bad_request":        "El servidor no entendió la petición. ¿Está actualizado el cliente?",
+	"error.room_not_found":     "No hay ninguna sala con ese código. Revísalo e inténtalo de nuevo.",
+	"error.room_full":          "Esa sala está llena.",
+	"error.game_in_progress":   "Hay una partida en curso en esa sala. Inténtalo cuando termine.",
+	"error.invalid_token":      "Tu invitación a la sala ha caducado. Vuelve a unirte.",
+	"error.token_mismatch":     "Esa invitación es para otra sala.",
+	"error.not_host":           "Solo el anfitrión puede cambiar los ajustes de la sala.",
+	"error.not_in_lobby":       "Los ajustes solo se pueden cambiar entre partidas.",
+	"error.invalid_settings":   "El servidor no admite ese ajuste.",
+	"error.too_many_players":   "Ya hay más jugadores que eso en la sala.",
+	"error.server_full":        "El servidor está lleno ahora mismo. Vuelve a intentarlo en un rato.",
+	"error.server_full_retry":  "El servidor está lleno ahora mismo. Vuelve a intentarlo en %d segundos.",
+	"error.rate_limited":       "Has creado demasiadas salas últimamente. Vuelve a intentarlo en un rato.",
+	"error.rate_limited_retry": "Has creado demasiadas salas últimamente. Vuelve a intentarlo en %d segundos.",
+	"error.banned":             "Tienes prohibida la entrada a este servidor.",
+	"error.ranked_room":        "Las salas competitivas usan ajustes fijos.",
+
+	"join.title":      "=== Unirse a sala ===",
+	"join.prompt":     "Código de sala: %s_",
+	"join.confirm":    "Pulsa ENTER para unirte",
+	"join.paste_hint": "CTRL+V para pegar un código",
+
+	"create.title":   "=== Crear sala ===",
+	"create.prompt":  "Título de la sala (opcional): %s_",
+	"create.confirm": "Pulsa ENTER para crear",
+
+	// Match history
+	"history.title":         "=== Historial de partidas ===",
+	"history.empty":         "Aún no hay partidas en este servidor. ¡A jugar!",
+	"history.col_when":      "Cuándo",
+	"history.col_room":      "Sala",
+	"history.select":        "Elegir partida",
+	"history.details":       "Ver detalles",
+	"history.match":         "=== Partida en %s ===",
+	"history.played":        "Jugada el %s, duró %s",
+	"history.sent":          "Enviaste %d líneas de basura",
+	"history.export":        "Exportar tus estadísticas a CSV (mayús: JSON)",
+	"history.exported":      "%d partidas exportadas a %s",
+	"history.export_failed": "No se pudo exportar: %v",
+	"history.achievements":  "Ver tus logros",
+
+	// Room browser
+	"rooms.title":        "=== Explorar salas ===",
+	"rooms.empty":        "No hay salas disponibles. ¡Crea una!",
+	"rooms.col_room":     "Sala",
+	"rooms.col_title":    "Título",
+	"rooms.col_type":     "Tipo",
+	"rooms.col_players":  "Jugad.",
+	"rooms.col_status":   "Estado",
+	"rooms.lobby":        "Sala de espera",
+	"rooms.playing":      "Jugando",
+	"rooms.live_players": "Jugando: %s",
+	"rooms.starting":     "Empezando",
+	"rooms.finished":     "Terminada",
+	"rooms.page":         "Página %d / %d",
+	"rooms.select":       "Elegir sala",
+	"rooms.change_page":  "Cambiar página",
+	"rooms.join":         "Unirse a la sala elegida",
+	"rooms.refresh":      "Actualizar",
+	"rooms.search":       "Buscar: %s",
+	"rooms.search_hint":  "Buscar por código o título",
+	"rooms.no_match":     "Ninguna sala coincide con tu búsqueda.",
+	"rooms.filters":      "Solo con plazas libres: %s   Orden: %s",
+	"rooms.open_hint":    "Alternar solo con plazas libres",
+	"rooms.sort_hint":    "Cambiar orden",
+	"rooms.sort.code":    "código",
+	"rooms.sort.players": "más jugadores",
+	"rooms.sort.newest":  "más nuevas",
+	"rooms.in_progress":  "No se puede entrar: la partida ya ha empezado",
+	"rooms.type.casual":  "Casual",
+	"rooms.type.ranked":  "Competitiva",
+	"rooms.quick_hint":   "Partida rápida (competitiva o casual)",
+
+	"quick.title":  "=== Partida rápida ===",
+	"quick.ranked": "Competitiva: ajustes fijos, ataques comprobados",
+	"quick.casual": "Casual: los ajustes que elija el anfitrión",
+
+	// Lobby
+	"attract.hint":            "DEMO: pulsa cualquier tecla",
+	"lobby.title":             "=== SALA DE ESPERA ===",
+	"lobby.code":              "Código de sala: %s",
+	"lobby.share":             "¡Comparte este código con tus amigos!",
+	"lobby.copy_hint":         "Pulsa Y para copiarlo",
+	"lobby.qr_hint":           "Pulsa C para un código QR con el que unirse",
+	"lobby.invite_hint":       "Pulsa F para invitar a un amigo",
+	"qr.scan":                 "Escanéalo para unirte a la sala %s en el navegador",
+	"qr.copy_hint":            "Pulsa Y para copiar el enlace",
+	"qr.back_hint":            "Pulsa C para volver a la sala",
+	"lan.address":             "Partida LAN: tus amigos de la red usan el servidor %s",
+	"lan.failed":              "No se pudo iniciar un servidor LAN: %v",
+	"clipboard.copied":        "%s copiado al portapapeles",
+	"clipboard.copy_failed":   "No se pudo copiar: %v",
+	"clipboard.paste_failed":  "No se pudo pegar: %v",
+	"lobby.players":           "Jugadores en la sala:",
+	"lobby.ready_hint":        "Pulsa ESPACIO para marcarte listo",
+	"lobby.leave_hint":        "Pulsa ESC para salir de la sala",
+	"lobby.host":              "(anfitrión)",
+	"lobby.ranked":            "Sala competitiva: ajustes estándar, no se pueden cambiar",
+	"lobby.muted":             "(silenciado)",
+	"lobby.bot":               "(CPU)",
+	"lobby.mute_hint":         "Pulsa 1-9 para silenciar o no los emotes de un jugador",
+	"lobby.auto_start":        "La partida empieza en %ds",
+	"lobby.auto_start_paused": "Inicio automático en pausa (%ds): esperando a los nuevos",
+
+	// Room settings
+	"room.settings":          "Ajustes de la sala:",
+	"room.max_players":       "Máx. jugadores",
+	"room.targeting":         "Objetivos",
+	"room.attack_table":      "Tabla de ataque",
+	"room.randomizer":        "Generador",
+	"room.targeting.free":    "Libre",
+	"room.targeting.random":  "Solo aleatorio",
+	"room.attack.standard":   "Estándar",
+	"room.attack.aggressive": "Agresiva",
+	"room.attack.casual":     "Relajada",
+	"room.randomizer.7bag":   "Bolsa de 7",
+	"room.randomizer.random": "Aleatorio puro",
+	"room.on_join":           "Si alguien entra",
+	"room.on_join.reset":     "Reinicia el temporizador",
+	"room.on_join.pause":     "Pausa el temporizador",
+	"room.entry_delay":       "Retardo de entrada",
+	"room.line_clear_delay":  "Retardo al limpiar",
+	"room.delay_ms":          "%d ms",
+	"room.spins":             "Bonus de giro",
+	"room.spins.t":           "Estándar",
+	"room.spins.all":         "Todos los giros",
+	"room.garbage":           "Basura",
+	"room.garbage.clean":     "Limpia",
+	"room.garbage.cheese":    "Queso",
+	"room.garbage.seeded":    "Con semilla",
+	"room.items":             "Objetos",
+	"room.hold":              "Reserva",
+	"room.hold.normal":       "Una por pieza",
+	"room.hold.off":          "Desactivada",
+	"room.hold.infinite":     "Ilimitada",
+	"room.host_hint":         "Eres el anfitrión: pulsa una tecla para cambiar",
+	"room.bots":              "Rivales CPU",
+	"room.points":            "Por puntos",
+	"room.points.rule":       "Gana quien llegue a %d (%s, +%d por KO)",
+	"room.speed_race":        "Carrera de velocidad",
+	"room.speed_race.rule":   "Sube de nivel cada %ds",
+	"room.seed":              "Semilla",
+	"room.seed.random":       "Aleatoria en cada partida",
+	"room.seed_prompt":       "Semilla: %s",
+	"room.seed_hint":         "Escribe un número o una frase, ENTER para fijarla (vacía = aleatoria), ESC para cancelar",
+
+	// Connection widget
+	"conn.connected":    "conectado",
+	"conn.reconnecting": "reconectando",
+	"conn.disconnected": "desconectado",
+	"conn.rtt":          "%dms",
+	"conn.attempt":      "reconectando %d/%d",
+	"conn.rejoined":     "Conexión recuperada. La partida siguió sin ti; has vuelto a la sala.",
+	"conn.resumed_lost": "Has vuelto a tu sala, pero la partida en curso se perdió.",
+	"conn.missed":       "Has vuelto, pero pasó demasiado de la partida mientras no estabas para seguir.",
+
+	// In-game HUD
+	"info.player":            "Jugador: %s",
+	"info.score":             "Puntos: %d",
+	"info.level":             "Nivel: %d",
+	"info.lines":             "Líneas: %d",
+	"info.next":              "SIGUIENTE",
+	"info.hold":              "RESERVA",
+	"info.empty":             "Vacío",
+	"info.attack_from":       "+%d de %s",
+	"info.attack":            "+%d entrantes",
+	"info.incoming":          "ENTRANTE: %d",
+	"info.target":            "OBJETIVO: %s",
+	"info.target_hint":       "[Tab/1-8] cambiar objetivo",
+	"info.random_hint":       "[0] objetivo aleatorio",
+	"info.target_locked":     "En esta sala los objetivos son aleatorios",
+	"feed.tetris":            "¡%s hizo un Tetris!",
+	"feed.ko":                "%s fue eliminado por %s",
+	"feed.out":               "%s se quedó sin espacio",
+	"feed.suspect":           "⚠ El tablero de %s parece manipulado",
+	"info.item":              "Objeto: %s [E]",
+	"info.speed_up":          "¡Acelerado! %ds",
+	"item.clear":             "Limpiar",
+	"item.scramble":          "Revolver",
+	"item.speed":             "Acelerar",
+	"feed.item":              "%s usó %s contra %s",
+	"feed.item_self":         "%s usó %s",
+	"feed.attack":            "%s: %s",
+	"feed.b2b":               "B2B",
+	"feed.combo":             "combo de %d",
+	"clear.single":           "Simple",
+	"clear.double":           "Doble",
+	"clear.triple":           "Triple",
+	"clear.tetris":           "Tetris",
+	"clear.tspin_single":     "T-Spin Simple",
+	"clear.tspin_double":     "T-Spin Doble",
+	"clear.tspin_triple":     "T-Spin Triple",
+	"emote.glhf":             "¡Suerte!",
+	"emote.gg":               "¡GG!",
+	"emote.nice":             "¡Bien!",
+	"emote.oops":             "¡Uy!",
+	"emote.bring_it":         "¡Ven aquí!",
+	"emote.hint":             "[F1-F5] gestos",
+	"practice.title":         "PRÁCTICA",
+	"practice.auto":          "Auto: %s",
+	"practice.off":           "no",
+	"practice.every":         "cada %gs",
+	"practice.add_hint":      "[g/G] añadir 1/4 líneas",
+	"practice.interval_hint": "[i] cambiar intervalo",
+	"target.random":          "Aleatorio",
+	"opponent.page":          "◀ [ Página %d / %d ] ▶",
+	"opponent.attacks":       "↓%d ↑%d",
+	"opponent.remaining":     "Quedan %d jugadores",
+	"opponent.out":           "FUERA",
+	"opponent.stats":         "P:%d L:%d",
+
+	// Results
+	"result.winner":        "¡VICTORIA!",
+	"result.game_over":     "FIN DE LA PARTIDA",
+	"result.score":         "Puntos: %d",
+	"result.seed":          "Semilla: %s (úsala en una sala para jugar las mismas piezas)",
+	"result.replay":        "Repetición guardada en %s",
+	"result.champion":      "¡%s gana la serie!",
+	"result.points_target": "Gana la serie quien llegue a %d puntos",
+	"result.rank":          "Puesto: #%d",
+	"result.play_again":    "Jugar otra vez",
+	"result.main_menu":     "Menú principal",
+	"result.back_to_lobby": "Volver a la sala",
+	"result.leave_room":    "Salir de la sala",
+	"result.stats":         "Estadísticas",
+
+	"standings.title":  "=== CLASIFICACIÓN ===",
+	"standings.rank":   "Pos.",
+	"standings.player": "Jugador",
+	"standings.score":  "Puntos",
+	"standings.lines":  "Lín.",
+	"standings.kos":    "KOs",
+	"standings.points": "Puntos",
+	"standings.time":   "Tiempo",
+
+	"awards.title":           "=== PREMIOS ===",
+	"award.most_sent":        "Más basura enviada: %s (%d líneas)",
+	"award.most_kos":         "Más KOs: %s (%d)",
+	"award.fastest_tetris":   "Tetris más rápido: %s (%s)",
+	"award.longest_survival": "Mayor supervivencia: %s (%s)",
+
+	// Settings
+	"settings.title":          "=== Ajustes ===",
+	"settings.on":             "sí",
+	"settings.off":            "no",
+	"settings.sound":          "Avisos sonoros (campana)",
+	"settings.reduced_motion": "Reducir animaciones",
+	"settings.input_display":  "Mostrar teclas",
+	"settings.keymap":         "Teclas",
+	"keymap.guideline":        "Estándar (Z/X/C, Espacio)",
+	"keymap.classic":          "Clásico (solo flechas)",
+	"keymap.vim":              "Vim (hjkl)",
+	"settings.notify":         "Avisar cuando empiece una partida (en segundo plano)",
+	"notify.match_starting":   "La partida empieza en la sala %s",
+
+	// Controls help
+	"controls.title":       "Controles:",
+	"controls.move":        "Mover izquierda/derecha",
+	"controls.soft_drop":   "Bajada suave",
+	"controls.hard_drop":   "Caída rápida",
+	"controls.rotate":      "Rotar",
+	"controls.rotate_back": "Rotar al revés",
+	"controls.hold":        "Reservar pieza",
+	"controls.quit":        "Salir",
+
+	// Tutorial
+	"tutorial.step":          "LECCIÓN %d / %d",
+	"tutorial.next":          "Pulsa ENTER para la siguiente lección",
+	"tutorial.finished":      "¡Tutorial completado! Pulsa ENTER para volver al menú",
+	"tutorial.retry":         "Casi. El tablero se ha reiniciado, ¡inténtalo otra vez!",
+	"tutorial.quit":          "salir del tutorial",
+	"tutorial.move.title":    "Moverse",
+	"tutorial.move.prompt":   "Mueve la pieza a izquierda y derecha con %s y %s. %s la baja un poco.",
+	"tutorial.move.done":     "¡Buen movimiento!",
+	"tutorial.rotate.title":  "Girar",
+	"tutorial.rotate.prompt": "Gira la pieza con %s. Dale una vuelta completa.",
+	"tutorial.rotate.done":   "¡Vuelta completa!",
+	"tutorial.ghost.title":   "Pieza fantasma",
+	"tutorial.ghost.prompt":  "El contorno bajo la pieza es su fantasma: donde caerá. Alinea el fantasma con el hueco de la derecha.",
+	"tutorial.ghost.done":    "Justo en el blanco.",
+	"tutorial.drop.title":    "Caída rápida",
+	"tutorial.drop.prompt":   "%s deja caer la pieza hasta su fantasma. Rellena el hueco para limpiar las dos líneas.",
+	"tutorial.drop.done":     "¡Dos líneas limpias!",
+	"tutorial.hold.title":    "Reserva",
+	"tutorial.hold.prompt":   "¿No quieres esta pieza? Pulsa %s para guardarla en RESERVA y coger la siguiente. Puedes cambiar una vez por pieza.",
+	"tutorial.hold.done":     "Guardada para luego.",
+	"tutorial.attack.title":  "Atacar",
+	"tutorial.attack.prompt": "Limpiar 2 o más líneas a la vez envía basura a tus rivales, y 4 a la vez (un Tetris) es lo que más daño hace. Pon la pieza I de pie y déjala caer en el pozo.",
+	"tutorial.attack.done":   "¡Tetris! Eso envía 4 líneas de basura.",
+
+	// Post-game statistics
+	"stats.title":      "ESTADÍSTICAS",
+	"stats.time":       "Tiempo    %d:%02d",
+	"stats.pieces":     "Piezas    %d (%.2f PPS)",
+	"stats.attack":     "Basura    %d enviadas, %d recibidas (%.1f APM)",
+	"stats.clears":     "Simples %d  Dobles %d  Triples %d  Tetris %d",
+	"stats.tspins":     "T-spins %d",
+	"stats.combo":      "Combo máx. %d",
+	"stats.interval":   "una barra cada %ds",
+	"stats.no_samples": "Partida demasiado corta para gráficas",
+
+	// Input display
+	"inputs.title": "TECLAS",
+
+	// Debug overlay
+	"debug.title": "REGISTRO DE DEPURACIÓN",
+	"debug.hide":  "F12 para ocultar",
+
+	// Invites
+	"invite.prompt":      "Código de amigo: %s",
+	"invite.hint":        "ENTER para enviar (sin código = un enlace de un solo uso), ESC para cancelar",
+	"invite.your_code":   "Tu código de amigo: %s",
+	"invite.sent":        "Invitación enviada a %s",
+	"invite.link":        "Enlace de invitación de un solo uso: %s",
+	"invite.from":        "%s te invita a la sala %s",
+	"invite.answer_hint": "Pulsa A para aceptar, D para rechazar",
+	"invite.more":        "(+%d más)",
+
+	// Achievements
+	"achievements.title":            "=== Logros ===",
+	"achievements.count":            "%d de %d desbloqueados",
+	"achievements.unlocked":         "desbloqueado el %s",
+	"achievements.new":              "Logro desbloqueado: %s",
+	"achievement.first_tetris":      "¡Tetris!",
+	"achievement.first_tetris.desc": "Limpia cuatro líneas a la vez",
+	"achievement.first_tspin":       "Doctor del giro",
+	"achievement.first_tspin.desc":  "Limpia líneas con un T-spin",
+	"achievement.sprint":            "Velocista",
+	"achievement.sprint.desc":       "Limpia 40 líneas en los 2 primeros minutos de una partida",
+	"achievement.first_win":         "Primera sangre",
+	"achievement.first_win.desc":    "Gana una partida multijugador",
+	"achievement.ten_wins":          "Veterano",
+	"achievement.ten_wins.desc":     "Gana 10 partidas multijugador",
+	"achievement.close_call":        "Por los pelos",
+	"achievement.close_call.desc":   "Gana una partida con 3 o más líneas de basura en espera",
+}
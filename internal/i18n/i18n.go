@@ -0,0 +1,96 @@
+// Package i18n holds the message catalogs for all user-facing TUI strings.
+//
+// Strings are looked up by key with T. Each language is a Catalog in its own
+// file (en.go, es.go, ...); to add a translation, copy en.go, translate the
+// values, and register the catalog in catalogs below. Keys missing from a
+// translation fall back to English.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultLang is the language used when nothing else is selected.
+const DefaultLang = "en"
+
+// Catalog maps message keys to fmt format strings.
+type Catalog map[string]string
+
+var catalogs = map[string]Catalog{
+	"en": english,
+	"es": spanish,
+}
+
+var (
+	current     = english
+	currentLang = DefaultLang
+)
+
+// SetLang selects the active language, e.g. "es" or "es_ES.UTF-8".
+// It returns false (and leaves the language unchanged) if there is no
+// catalog for it.
+func SetLang(lang string) bool {
+	lang = normalize(lang)
+	c, ok := catalogs[lang]
+	if !ok {
+		return false
+	}
+	current = c
+	currentLang = lang
+	return true
+}
+
+// Lang returns the active language code.
+func Lang() string {
+	return currentLang
+}
+
+// Langs returns the available language codes, sorted.
+func Langs() []string {
+	langs := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Detect returns the language from the environment (LC_ALL, LC_MESSAGES,
+// LANG), or "" if none of them name an available catalog.
+func Detect() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		lang := normalize(os.Getenv(env))
+		if _, ok := catalogs[lang]; ok {
+			return lang
+		}
+	}
+	return ""
+}
+
+// T returns the message for key in the active language, formatted with args.
+// Missing keys fall back to English, then to the key itself.
+func T(key string, args ...interface{}) string {
+	format, ok := current[key]
+	if !ok {
+		format, ok = english[key]
+	}
+	if !ok {
+		format = key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// normalize turns locale names like "es_ES.UTF-8" into catalog codes ("es").
+func normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "_-."); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
@@ -0,0 +1,123 @@
+// Package logging keeps the client's logs out of the terminal, where they
+// would corrupt the TUI. Lines go to an optional file, rotated when it
+// grows too big, and the latest ones are kept in memory for the in-game
+// debug overlay.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	maxFileSize = 1 << 20 // rotate after 1 MiB
+	maxBackups  = 3       // path.1 (newest) to path.3
+	keepRecent  = 50      // lines kept for the overlay
+)
+
+// Writer is the destination for log output.
+type Writer struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	size   int64
+	recent []string // oldest first
+}
+
+// Open creates a Writer appending to path. With an empty path, lines are
+// only kept in memory.
+func Open(path string) (*Writer, error) {
+	w := &Writer{path: path}
+	if path == "" {
+		return w, nil
+	}
+	if err := w.openFile(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) openFile() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write records p, which holds whole log lines.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.recent = append(w.recent, line)
+	}
+	if over := len(w.recent) - keepRecent; over > 0 {
+		w.recent = append(w.recent[:0], w.recent[over:]...)
+	}
+
+	if w.f == nil {
+		return len(p), nil
+	}
+	if w.size+int64(len(p)) > maxFileSize && w.size > 0 {
+		// Keep logging to the old file if rotation fails.
+		if err := w.rotate(); err != nil && w.f == nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path to path.1, path.1 to path.2 and so on, dropping the
+// oldest, and starts a new file. Must be called with w.mu held.
+func (w *Writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+	for i := maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+	os.Rename(w.path, w.path+".1")
+	return w.openFile()
+}
+
+// Recent returns up to n of the latest lines, oldest first.
+func (w *Writer) Recent(n int) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	start := max(len(w.recent)-n, 0)
+	return append([]string(nil), w.recent[start:]...)
+}
+
+// Close closes the log file, if there is one.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// ParseLevel turns "debug", "info", "warn" or "error" into a slog level.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(s))
+	return l, err
+}
@@ -0,0 +1,53 @@
+// Package notify shows desktop notifications.
+//
+// Locally it uses notify-send on Linux and osascript on macOS. Over SSH,
+// where those would notify the wrong machine, or when neither is
+// installed, it asks the terminal instead, with an OSC 9 escape sequence
+// (iTerm2, kitty, WezTerm, Windows Terminal and others show it as a
+// notification; the rest ignore it).
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Send notifies the user with title and body, writing to term if the
+// terminal is to do it.
+func Send(term io.Writer, title, body string) error {
+	if os.Getenv("SSH_CONNECTION") == "" && native(title, body) == nil {
+		return nil
+	}
+	seq := fmt.Sprintf("\x1b]9;%s: %s\a", clean(title), clean(body))
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	_, err := io.WriteString(term, seq)
+	return err
+}
+
+// native notifies with the desktop's own tool.
+func native(title, body string) error {
+	if path, err := exec.LookPath("notify-send"); err == nil {
+		return exec.Command(path, "--app-name=gotris", title, body).Run()
+	}
+	if path, err := exec.LookPath("osascript"); err == nil {
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		return exec.Command(path, "-e", script).Run()
+	}
+	return exec.ErrNotFound
+}
+
+// clean drops control characters, which would end the escape sequence
+// early.
+func clean(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,128 @@
+// Package overlay publishes the client's live game state as JSON so
+// streamers can build OBS overlays without scraping the terminal. The
+// state can be served on a local HTTP endpoint, written to a file, or both.
+package overlay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// State is the snapshot published after every update.
+type State struct {
+	Screen string `json:"screen"` // menu, lobby, countdown, playing, game_over
+	Mode   string `json:"mode"`   // single, multi, or "" outside a game
+	Player string `json:"player"`
+	Room   string `json:"room,omitempty"`
+
+	Score   int    `json:"score"`
+	Level   int    `json:"level"`
+	Lines   int    `json:"lines"`
+	Alive   bool   `json:"alive"`
+	Winner  bool   `json:"winner"`
+	Current string `json:"current,omitempty"` // piece letters, e.g. "T"
+	Next    string `json:"next,omitempty"`
+	Hold    string `json:"hold,omitempty"`
+	Garbage int    `json:"garbage"` // incoming lines not yet applied
+
+	Pieces   int     `json:"pieces"`
+	Sent     int     `json:"sent"`
+	Received int     `json:"received"`
+	PPS      float64 `json:"pps"`
+	APM      float64 `json:"apm"`
+
+	Opponents []Opponent `json:"opponents,omitempty"`
+}
+
+// Opponent is one other player in a multiplayer match.
+type Opponent struct {
+	Name      string `json:"name"`
+	Score     int    `json:"score"`
+	Lines     int    `json:"lines"`
+	Alive     bool   `json:"alive"`
+	Target    bool   `json:"target"`      // currently targeted by this player
+	SentToYou int    `json:"sent_to_you"` // garbage traded this match
+	YouSent   int    `json:"you_sent"`
+}
+
+// Overlay holds the latest published state.
+type Overlay struct {
+	mu   sync.Mutex
+	data []byte
+	path string
+}
+
+// New creates an overlay. If path is not empty, every change is also
+// written there.
+func New(path string) *Overlay {
+	return &Overlay{data: []byte("{}"), path: path}
+}
+
+// Listen starts serving the state at addr (e.g. "localhost:7070") in the
+// background. The listener is opened before returning so a bad address
+// is reported straight away.
+func (o *Overlay) Listen(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go http.Serve(ln, o)
+	return nil
+}
+
+// ServeHTTP returns the latest state. Any origin may read it, so browser
+// sources loaded from a local file can poll it.
+func (o *Overlay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	data := o.data
+	o.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// Publish records s as the latest state. Unchanged states are ignored,
+// so it is cheap to call on every update.
+func (o *Overlay) Publish(s State) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if bytes.Equal(data, o.data) {
+		return
+	}
+	o.data = data
+	if o.path != "" {
+		writeFile(o.path, data)
+	}
+}
+
+// writeFile replaces path with data via a rename, so readers never see a
+// half-written file. Errors are dropped: the overlay is best effort.
+func writeFile(path string, data []byte) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".overlay-*.json")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
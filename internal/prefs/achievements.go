@@ -0,0 +1,37 @@
+package prefs
+
+import (
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// Unlock records the achievement id as unlocked now, reporting whether it
+// wasn't already. It leaves saving to the caller.
+func (p *Prefs) Unlock(id string) bool {
+	if _, ok := p.Achievements[id]; ok {
+		return false
+	}
+	var changed bool
+	p.Achievements, changed = protocol.MergeAchievements(p.Achievements, map[string]int64{id: time.Now().UnixMilli()})
+	return changed
+}
+
+// MergeAchievements adds the achievements unlocked elsewhere, as
+// protocol.MergeAchievements does, reporting whether that changed any.
+func (p *Prefs) MergeAchievements(unlocked map[string]int64) bool {
+	var changed bool
+	p.Achievements, changed = protocol.MergeAchievements(p.Achievements, unlocked)
+	return changed
+}
+
+// Wins is how many matches against others in the game log were won.
+func Wins(games []GameRecord) int {
+	n := 0
+	for _, g := range games {
+		if g.Mode == "multi" && g.Place == 1 && g.Players > 1 {
+			n++
+		}
+	}
+	return n
+}
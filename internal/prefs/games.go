@@ -0,0 +1,116 @@
+package prefs
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxGames is how many finished games are kept in the game log; recording
+// another drops the oldest.
+const maxGames = 1000
+
+// GameRecord is the statistics of one game played on this client, kept
+// in the game log so they can be exported. Place and Players are 0 in
+// single player.
+type GameRecord struct {
+	PlayedAt time.Time `json:"played_at"`
+	Mode     string    `json:"mode"` // "single" or "multi"
+	Server   string    `json:"server,omitempty"`
+	RoomID   string    `json:"room_id,omitempty"`
+	Seed     string    `json:"seed,omitempty"`
+	Place    int       `json:"place,omitempty"`
+	Players  int       `json:"players,omitempty"`
+	KOs      int       `json:"kos,omitempty"`
+
+	Score      int     `json:"score"`
+	Lines      int     `json:"lines"`
+	Level      int     `json:"level"`
+	DurationMs int64   `json:"duration_ms"`
+	Pieces     int     `json:"pieces"`
+	PPS        float64 `json:"pps"`
+	APM        float64 `json:"apm"`
+	Sent       int     `json:"sent"`
+	Received   int     `json:"received"`
+	Tetrises   int     `json:"tetrises"`
+	TSpins     int     `json:"tspins"`
+	MaxCombo   int     `json:"max_combo"`
+}
+
+// gamesPath is the game log, one GameRecord of JSON per line, oldest
+// first.
+func gamesPath() (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "games.jsonl"), nil
+}
+
+// Games returns the games in the game log, oldest first. A missing log
+// is no games; lines that don't parse are skipped.
+func Games() ([]GameRecord, error) {
+	path, err := gamesPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var games []GameRecord
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var g GameRecord
+		if json.Unmarshal(sc.Bytes(), &g) == nil {
+			games = append(games, g)
+		}
+	}
+	return games, sc.Err()
+}
+
+// AddGame adds g to the game log, dropping the oldest games past
+// maxGames.
+func AddGame(g GameRecord) error {
+	path, err := gamesPath()
+	if err != nil {
+		return err
+	}
+	games, err := Games()
+	if err != nil {
+		return err
+	}
+	games = append(games, g)
+	if len(games) > maxGames {
+		games = games[len(games)-maxGames:]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Written to a temporary file and renamed over the log, so a crash
+	// midway leaves the old log whole.
+	f, err := os.CreateTemp(filepath.Dir(path), ".games-*.jsonl")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails harmlessly once renamed
+	enc := json.NewEncoder(f)
+	for _, g := range games {
+		if err := enc.Encode(g); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,156 @@
+package prefs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Caps on how many recently used room codes, servers and player IDs are
+// remembered.
+const (
+	maxRecentRooms     = 5
+	maxRecentServers   = 5
+	maxRecentPlayerIDs = 20
+)
+
+// Prefs holds client settings that persist between launches.
+// It is stored as JSON in the user's config directory.
+type Prefs struct {
+	PlayerName    string   `json:"player_name,omitempty"`
+	LastServer    string   `json:"last_server,omitempty"`
+	RecentServers []string `json:"recent_servers,omitempty"` // most recent first
+	RecentRooms   []string `json:"recent_rooms,omitempty"`   // most recent first
+	Lang          string   `json:"lang,omitempty"`           // UI language code, "" = from environment
+	// RecentPlayerIDs are the IDs servers gave us, one per room joined,
+	// most recent first. The history screen looks up their matches.
+	RecentPlayerIDs []string `json:"recent_player_ids,omitempty"`
+
+	// ReducedMotion skips purely decorative animations.
+	ReducedMotion bool `json:"reduced_motion,omitempty"`
+	// Sound rings the terminal bell on Tetris clears, incoming garbage,
+	// countdown ticks and KOs.
+	Sound bool `json:"sound,omitempty"`
+	// InputDisplay shows the keys being pressed under the HUD.
+	InputDisplay bool `json:"input_display,omitempty"`
+	// Notify sends a desktop notification when a match starts counting
+	// down while the terminal is in the background.
+	Notify bool `json:"notify,omitempty"`
+	// SoftDropFactor is how many times faster than gravity a held soft
+	// drop falls; 0 = the default.
+	SoftDropFactor int `json:"soft_drop_factor,omitempty"`
+	// Keymap is the gameplay keys' preset, "" = the default.
+	Keymap string `json:"keymap,omitempty"`
+	// Monochrome draws without color, in fill patterns; nil = as the
+	// terminal calls for.
+	Monochrome *bool `json:"monochrome,omitempty"`
+
+	// ProfileKey is the secret the player's profile is kept under on
+	// servers; copied to another machine, it brings the profile along.
+	ProfileKey string `json:"profile_key,omitempty"`
+	// ProfileChanged is set when a setting in the profile changes here,
+	// until the profile is next sent to a server.
+	ProfileChanged bool `json:"profile_changed,omitempty"`
+	// Achievements are when each unlocked achievement was unlocked, in
+	// Unix milliseconds, by ID; see achievements.go.
+	Achievements map[string]int64 `json:"achievements,omitempty"`
+
+	path string
+}
+
+// Path returns the location of the preferences file,
+// e.g. ~/.config/gotris/prefs.json on Linux.
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gotris", "prefs.json"), nil
+}
+
+// Load reads the preferences file. A missing or unreadable file yields
+// empty preferences, so callers can always use the result.
+func Load() *Prefs {
+	p := &Prefs{}
+	path, err := Path()
+	if err != nil {
+		return p
+	}
+	p.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p
+	}
+	json.Unmarshal(data, p)
+	return p
+}
+
+// Save writes the preferences back to disk, creating the directory if needed.
+func (p *Prefs) Save() error {
+	if p.path == "" {
+		path, err := Path()
+		if err != nil {
+			return err
+		}
+		p.path = path
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, data, 0o644)
+}
+
+// AddRecentRoom moves code to the front of the recent rooms list.
+func (p *Prefs) AddRecentRoom(code string) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return
+	}
+
+	p.RecentRooms = pushRecent(p.RecentRooms, code, maxRecentRooms)
+}
+
+// AddRecentServer makes addr the last used server and moves it to the
+// front of the recent servers list.
+func (p *Prefs) AddRecentServer(addr string) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return
+	}
+	p.LastServer = addr
+	p.RecentServers = pushRecent(p.RecentServers, addr, maxRecentServers)
+}
+
+// AddPlayerID moves id to the front of the recent player IDs.
+func (p *Prefs) AddPlayerID(id string) {
+	if id == "" {
+		return
+	}
+	p.RecentPlayerIDs = pushRecent(p.RecentPlayerIDs, id, maxRecentPlayerIDs)
+}
+
+// pushRecent returns list with item moved to the front, capped at limit.
+func pushRecent(list []string, item string, limit int) []string {
+	out := []string{item}
+	for _, s := range list {
+		if s != item && len(out) < limit {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// LastRoom returns the most recently joined room code, or "".
+func (p *Prefs) LastRoom() string {
+	if len(p.RecentRooms) == 0 {
+		return ""
+	}
+	return p.RecentRooms[0]
+}
@@ -0,0 +1,55 @@
+package prefs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+const (
+	// profileKeyBytes is how much randomness goes into a profile key.
+	profileKeyBytes = 16
+	// MinProfileKey is the shortest profile key servers accept.
+	MinProfileKey = 16
+)
+
+// Profile returns the settings kept in the player's profile on servers.
+func (p *Prefs) Profile() protocol.Profile {
+	return protocol.Profile{
+		Name:           p.PlayerName,
+		Lang:           p.Lang,
+		Keymap:         p.Keymap,
+		SoftDropFactor: p.SoftDropFactor,
+		Sound:          p.Sound,
+		ReducedMotion:  p.ReducedMotion,
+		InputDisplay:   p.InputDisplay,
+		Notify:         p.Notify,
+	}
+}
+
+// ApplyProfile replaces the settings kept in the player's profile with
+// pr's. An empty name leaves the name alone.
+func (p *Prefs) ApplyProfile(pr protocol.Profile) {
+	if pr.Name != "" {
+		p.PlayerName = pr.Name
+	}
+	p.Lang = pr.Lang
+	p.Keymap = pr.Keymap
+	p.SoftDropFactor = pr.SoftDropFactor
+	p.Sound = pr.Sound
+	p.ReducedMotion = pr.ReducedMotion
+	p.InputDisplay = pr.InputDisplay
+	p.Notify = pr.Notify
+}
+
+// EnsureProfileKey returns the profile key, making up a random one the
+// first time.
+func (p *Prefs) EnsureProfileKey() string {
+	if p.ProfileKey == "" {
+		b := make([]byte, profileKeyBytes)
+		rand.Read(b)
+		p.ProfileKey = hex.EncodeToString(b)
+	}
+	return p.ProfileKey
+}
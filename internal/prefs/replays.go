@@ -0,0 +1,68 @@
+package prefs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// maxReplays is how many recorded games are kept; saving another removes
+// the oldest.
+const maxReplays = 50
+
+// ReplayDir returns the directory recorded games are saved in,
+// e.g. ~/.config/gotris/replays on Linux.
+func ReplayDir() (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "replays"), nil
+}
+
+// NewReplayPath returns where to save a game recorded at t, creating the
+// replay directory if needed and making room in it.
+func NewReplayPath(t time.Time) (string, error) {
+	dir, err := ReplayDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	replays, err := listReplays(dir)
+	if err != nil {
+		return "", err
+	}
+	for len(replays) >= maxReplays {
+		os.Remove(replays[0])
+		replays = replays[1:]
+	}
+	return filepath.Join(dir, t.Format("20060102-150405")+".json"), nil
+}
+
+// LatestReplay returns the path of the most recently saved replay.
+func LatestReplay() (string, error) {
+	dir, err := ReplayDir()
+	if err != nil {
+		return "", err
+	}
+	replays, err := listReplays(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(replays) == 0 {
+		return "", errors.New("no replays saved in " + dir)
+	}
+	return replays[len(replays)-1], nil
+}
+
+// listReplays lists the replays in dir, oldest first: their names are
+// the times they were recorded.
+func listReplays(dir string) ([]string, error) {
+	replays, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	slices.Sort(replays)
+	return replays, err
+}
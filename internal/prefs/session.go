@@ -0,0 +1,66 @@
+package prefs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Session is the room the client was last connected to, with the token
+// to resume it. It is kept in its own file next to the preferences,
+// because the network client updates it while the TUI owns Prefs.
+type Session struct {
+	Server string `json:"server"`
+	RoomID string `json:"room_id"`
+	Token  string `json:"token"`
+}
+
+func sessionPath() (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "session.json"), nil
+}
+
+// LoadSession returns the saved session, or nil if there is none.
+func LoadSession() *Session {
+	path, err := sessionPath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var s Session
+	if json.Unmarshal(data, &s) != nil || s.Token == "" {
+		return nil
+	}
+	return &s
+}
+
+// SaveSession saves s, or removes the saved session if s is nil.
+func SaveSession(s *Session) error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// The token lets anyone take over the seat, so keep it private.
+	return os.WriteFile(path, data, 0o600)
+}
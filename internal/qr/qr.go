@@ -0,0 +1,382 @@
+// Package qr encodes text as a QR code, small enough to draw in a
+// terminal: byte mode, error correction level L, versions 1 to 10, which
+// holds up to 271 bytes, plenty for a join link.
+package qr
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrTooLong is returned by Encode for text that doesn't fit in the
+// largest version supported.
+var ErrTooLong = errors.New("text too long for a QR code")
+
+// Code is a QR code: Size × Size modules, without the quiet zone around
+// it.
+type Code struct {
+	Size    int
+	modules []bool
+}
+
+// Black reports whether the module at column x, row y is dark. Outside
+// the code, in the quiet zone, modules are light.
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y*c.Size+x]
+}
+
+// version is a QR version's layout at level L: its codewords split into
+// blocks, each of data codewords and ecc error correction codewords.
+type version struct {
+	blocks []int // data codewords in each block
+	ecc    int
+	align  []int // alignment pattern centres, rows and columns
+}
+
+var versions = []version{
+	1:  {[]int{19}, 7, nil},
+	2:  {[]int{34}, 10, []int{6, 18}},
+	3:  {[]int{55}, 15, []int{6, 22}},
+	4:  {[]int{80}, 20, []int{6, 26}},
+	5:  {[]int{108}, 26, []int{6, 30}},
+	6:  {[]int{68, 68}, 18, []int{6, 34}},
+	7:  {[]int{78, 78}, 20, []int{6, 22, 38}},
+	8:  {[]int{97, 97}, 24, []int{6, 24, 42}},
+	9:  {[]int{116, 116}, 30, []int{6, 26, 46}},
+	10: {[]int{68, 68, 69, 69}, 18, []int{6, 28, 50}},
+}
+
+func (v version) dataLen() int {
+	n := 0
+	for _, b := range v.blocks {
+		n += b
+	}
+	return n
+}
+
+// Encode encodes text in the smallest version it fits.
+func Encode(text string) (*Code, error) {
+	for ver := 1; ver < len(versions); ver++ {
+		v := versions[ver]
+		if headerBits(ver)+8*len(text) <= 8*v.dataLen() {
+			return encode(ver, v, []byte(text)), nil
+		}
+	}
+	return nil, ErrTooLong
+}
+
+// headerBits is the length of the mode indicator and byte count.
+func headerBits(ver int) int {
+	if ver < 10 {
+		return 4 + 8
+	}
+	return 4 + 16
+}
+
+func encode(ver int, v version, data []byte) *Code {
+	// The data: byte mode, the count, the bytes, a terminator, then
+	// padding to fill the version.
+	var bb bitBuffer
+	bb.append(0b0100, 4)
+	bb.append(len(data), headerBits(ver)-4)
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	capacity := 8 * v.dataLen()
+	bb.append(0, min(4, capacity-bb.n))
+	bb.append(0, -bb.n&7)
+	for pad := 0xEC; bb.n < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	// Each block gets its error correction, then the codewords are
+	// interleaved across blocks.
+	var blocks, eccs [][]byte
+	rest := bb.bytes
+	for _, n := range v.blocks {
+		blocks = append(blocks, rest[:n])
+		eccs = append(eccs, reedSolomon(rest[:n], v.ecc))
+		rest = rest[n:]
+	}
+	var codewords []byte
+	for i := 0; i < v.blocks[len(v.blocks)-1]; i++ {
+		for _, b := range blocks {
+			if i < len(b) {
+				codewords = append(codewords, b[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecc; i++ {
+		for _, e := range eccs {
+			codewords = append(codewords, e[i])
+		}
+	}
+
+	g := newGrid(ver, v)
+	g.place(codewords)
+	best, bestPenalty := 0, math.MaxInt
+	for mask := range 8 {
+		g.mask(mask)
+		g.format(mask)
+		if p := g.penalty(); p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		g.mask(mask) // masking twice undoes it
+	}
+	g.mask(best)
+	g.format(best)
+	return &Code{Size: g.size, modules: g.dark}
+}
+
+// bitBuffer collects bits into bytes, most significant first.
+type bitBuffer struct {
+	bytes []byte
+	n     int // bits
+}
+
+func (b *bitBuffer) append(v, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+		if v>>i&1 == 1 {
+			b.bytes[b.n/8] |= 0x80 >> (b.n % 8)
+		}
+		b.n++
+	}
+}
+
+// grid is a code being drawn: its modules, and which of them are
+// function patterns rather than data.
+type grid struct {
+	ver      int
+	size     int
+	dark     []bool
+	function []bool
+}
+
+func newGrid(ver int, v version) *grid {
+	size := 17 + 4*ver
+	g := &grid{ver: ver, size: size, dark: make([]bool, size*size), function: make([]bool, size*size)}
+
+	for i := range size {
+		g.set(6, i, i%2 == 0)
+		g.set(i, 6, i%2 == 0)
+	}
+	g.finder(3, 3)
+	g.finder(size-4, 3)
+	g.finder(3, size-4)
+	last := len(v.align) - 1
+	for i, x := range v.align {
+		for j, y := range v.align {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue // the finders are there
+			}
+			g.alignment(x, y)
+		}
+	}
+	g.format(0) // reserves the format areas, drawn for real once masked
+	if ver >= 7 {
+		g.version()
+	}
+	return g
+}
+
+func (g *grid) set(x, y int, dark bool) {
+	g.dark[y*g.size+x] = dark
+	g.function[y*g.size+x] = true
+}
+
+// finder draws a finder pattern centred at (x, y) with its separator.
+func (g *grid) finder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= g.size || yy >= g.size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			g.set(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+// alignment draws an alignment pattern centred at (x, y).
+func (g *grid) alignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			g.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// format draws the level and mask, twice, with the dark module.
+func (g *grid) format(mask int) {
+	const levelL = 1
+	data := levelL<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		g.set(8, i, bit(i))
+	}
+	g.set(8, 7, bit(6))
+	g.set(8, 8, bit(7))
+	g.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		g.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		g.set(g.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		g.set(8, g.size-15+i, bit(i))
+	}
+	g.set(8, g.size-8, true)
+}
+
+// version draws the version, twice, for versions 7 and up.
+func (g *grid) version() {
+	rem := g.ver
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := g.ver<<12 | rem
+	for i := range 18 {
+		dark := bits>>i&1 == 1
+		a, b := g.size-11+i%3, i/3
+		g.set(a, b, dark)
+		g.set(b, a, dark)
+	}
+}
+
+// place lays codewords into the data modules, in two-module columns
+// zigzagging up and down from the bottom right.
+func (g *grid) place(codewords []byte) {
+	i := 0
+	for right := g.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the timing pattern's column
+		}
+		upward := (right+1)&2 == 0
+		for vert := range g.size {
+			y := vert
+			if upward {
+				y = g.size - 1 - vert
+			}
+			for x := right; x >= right-1; x-- {
+				if g.function[y*g.size+x] {
+					continue
+				}
+				if i < 8*len(codewords) {
+					g.dark[y*g.size+x] = codewords[i/8]>>(7-i%8)&1 == 1
+				}
+				i++
+			}
+		}
+	}
+}
+
+// mask flips the data modules mask picks out.
+func (g *grid) mask(mask int) {
+	for y := range g.size {
+		for x := range g.size {
+			if g.function[y*g.size+x] {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip {
+				g.dark[y*g.size+x] = !g.dark[y*g.size+x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code would be to scan, by the standard's
+// four rules; the mask with the lowest score is used.
+func (g *grid) penalty() int {
+	at := func(x, y int) bool { return g.dark[y*g.size+x] }
+	p := 0
+	for _, rows := range []bool{true, false} {
+		line := func(i, j int) bool {
+			if rows {
+				return at(j, i)
+			}
+			return at(i, j)
+		}
+		for i := range g.size {
+			// Runs of five or more modules alike.
+			run := 1
+			for j := 1; j <= g.size; j++ {
+				if j < g.size && line(i, j) == line(i, j-1) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					p += run - 2
+				}
+				run = 1
+			}
+			// Patterns like a finder's, light on one side.
+			for j := 0; j+11 <= g.size; j++ {
+				var bits int
+				for k := range 11 {
+					if line(i, j+k) {
+						bits |= 1 << (10 - k)
+					}
+				}
+				if bits == 0b10111010000 || bits == 0b00001011101 {
+					p += 40
+				}
+			}
+		}
+	}
+	// 2×2 blocks alike.
+	dark := 0
+	for y := range g.size {
+		for x := range g.size {
+			if at(x, y) {
+				dark++
+			}
+			if x > 0 && y > 0 && at(x, y) == at(x-1, y) && at(x, y) == at(x, y-1) && at(x, y) == at(x-1, y-1) {
+				p += 3
+			}
+		}
+	}
+	// Far from half dark.
+	percent := dark * 100 / (g.size * g.size)
+	p += abs(percent-50) / 5 * 10
+	return p
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,56 @@
+package qr
+
+// Error correction is Reed-Solomon over GF(256), with the field built
+// from the polynomial x⁸ + x⁴ + x³ + x² + 1.
+
+var gfExp, gfLog [256]byte
+
+func init() {
+	x := 1
+	for i := range 255 {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	gfExp[255] = gfExp[0]
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])+int(gfLog[b]))%255]
+}
+
+// reedSolomon returns the n error correction codewords for data: the
+// remainder of dividing it by the generator polynomial of degree n.
+func reedSolomon(data []byte, n int) []byte {
+	// The generator, (x - α⁰)(x - α¹)…(x - αⁿ⁻¹), highest term first
+	// with its 1 left out.
+	gen := make([]byte, n)
+	gen[n-1] = 1
+	root := byte(1)
+	for range n {
+		for j := range n {
+			gen[j] = gfMul(gen[j], root)
+			if j+1 < n {
+				gen[j] ^= gen[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+
+	rem := make([]byte, n)
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[n-1] = 0
+		for j := range n {
+			rem[j] ^= gfMul(gen[j], factor)
+		}
+	}
+	return rem
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Achievements ---
+//
+// Clients work out their players' achievements as they play, offline
+// too, and keep them under the profile key here as well, so they follow
+// the player to another machine. A POST adds the achievements the
+// client has to those kept and answers with them all, so one call both
+// sends and fetches. An achievement is never taken away, and its
+// earliest unlock time wins. New keys are rationed as they are for
+// profiles. With ACHIEVEMENTS_FILE set they're saved there, like the
+// profiles.
+
+// maxAchievementsBody caps a POST /achievements body.
+const maxAchievementsBody = 4 << 10
+
+// achievementStore is the server's achievements, by the hash of their
+// profile key.
+type achievementStore struct {
+	mu      sync.Mutex
+	path    string
+	players map[string]protocol.Achievements
+}
+
+// loadAchievements reads the achievements saved at path. A missing file
+// is none; an empty path keeps them in memory only.
+func loadAchievements(path string) (*achievementStore, error) {
+	s := &achievementStore{path: path, players: make(map[string]protocol.Achievements)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.players); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// get returns the achievements for key.
+func (s *achievementStore) get(key string) protocol.Achievements {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.players[profileID(key)]
+	if a.Unlocked == nil {
+		a.Unlocked = map[string]int64{}
+	}
+	return a
+}
+
+// has reports whether anything is kept for key.
+func (s *achievementStore) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.players[profileID(key)]
+	return ok
+}
+
+// add merges unlocked into the achievements for key and returns them all.
+// Like profileStore.put, it returns errStoreFull for a new key there's no
+// room for.
+func (s *achievementStore) add(key string, unlocked map[string]int64) (protocol.Achievements, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	id := profileID(key)
+	a, ok := s.players[id]
+	merged, changed := protocol.MergeAchievements(a.Unlocked, unlocked)
+	if !changed {
+		a.Unlocked = merged
+		return a, nil
+	}
+	if !ok && !makeRoom(s.players, func(a protocol.Achievements) int64 { return a.UpdatedAt }, now) {
+		return a, errStoreFull
+	}
+	a.Unlocked = merged
+	a.UpdatedAt = now.UnixMilli()
+	s.players[id] = a
+	return a, s.saveLocked()
+}
+
+// saveLocked writes the achievements to their file, if they have one, as
+// banList.saveLocked does. Must be called with s.mu held.
+func (s *achievementStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.players)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".achievements-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// handleAchievements returns (GET) or adds to and returns (POST) the
+// achievements for the request's profile key.
+func handleAchievements(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	key, ok := profileKey(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, hub.achievements.get(key))
+
+	case http.MethodPost:
+		var req protocol.Achievements
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAchievementsBody)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "invalid request body")
+			return
+		}
+		// An unlock can't come from the future.
+		now := time.Now().UnixMilli()
+		for id, at := range req.Unlocked {
+			req.Unlocked[id] = min(at, now)
+		}
+		if !hub.achievements.has(key) && len(req.Unlocked) > 0 && checkNewKey(hub, w, r) {
+			return
+		}
+		a, err := hub.achievements.add(key, req.Unlocked)
+		if errors.Is(err, errStoreFull) {
+			writeError(w, http.StatusServiceUnavailable, protocol.ErrCodeServerFull, "no room for new players' achievements; try again later")
+			return
+		}
+		if err != nil {
+			log.Printf("Saving achievements: %v", err)
+			writeError(w, http.StatusInternalServerError, "", "achievements stored but not saved")
+			return
+		}
+		writeJSON(w, http.StatusOK, a)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
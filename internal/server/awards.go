@@ -0,0 +1,56 @@
+package server
+
+import (
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Match awards ---
+//
+// Each match with two or more players hands out awards with the
+// standings: most garbage sent, most KOs, the fastest Tetris and the
+// longest survival. The winner always outlasts everyone, so that last
+// one goes to whoever lasted longest of the rest. An award needs
+// something to show for it (a match without a Tetris has no fastest
+// Tetris), and a tie goes to the better placed player.
+
+// matchAwards works out the awards for standings, sorted by rank.
+func matchAwards(standings []protocol.PlayerStanding, winnerID string) []protocol.MatchAward {
+	if len(standings) < 2 {
+		return nil
+	}
+	var awards []protocol.MatchAward
+	// award gives kind to the player with the highest value, or the
+	// lowest if fewest, among those whose value is above 0.
+	award := func(kind string, fewest bool, value func(protocol.PlayerStanding) int64) {
+		best := -1
+		for i, st := range standings {
+			v := value(st)
+			if v <= 0 {
+				continue
+			}
+			if best < 0 || (fewest && v < value(standings[best])) || (!fewest && v > value(standings[best])) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			st := standings[best]
+			awards = append(awards, protocol.MatchAward{
+				Kind:       kind,
+				PlayerID:   st.PlayerID,
+				PlayerName: st.PlayerName,
+				Value:      value(st),
+			})
+		}
+	}
+
+	award(protocol.AwardMostSent, false, func(st protocol.PlayerStanding) int64 { return int64(st.Sent) })
+	award(protocol.AwardMostKOs, false, func(st protocol.PlayerStanding) int64 { return int64(st.KOs) })
+	award(protocol.AwardFastestTetris, true, func(st protocol.PlayerStanding) int64 { return st.FirstTetrisMs })
+	award(protocol.AwardLongestSurvival, false, func(st protocol.PlayerStanding) int64 {
+		if st.PlayerID == winnerID {
+			return 0
+		}
+		return st.SurvivalMs
+	})
+	return awards
+}
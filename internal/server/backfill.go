@@ -0,0 +1,68 @@
+package server
+
+import (
+	"slices"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Backfill ---
+//
+// A player whose connection drops mid-match misses whatever the room sent
+// them in the meantime, and garbage or a knockout they never heard about
+// leaves their game out of step with everyone else's. Each player keeps
+// the last few critical messages sent to them, by Seq. A client
+// reconnecting passes the Seq of the last message it got as since=, and,
+// if it gets its seat back, is sent the critical messages after that
+// before anything else. If some of those have been pushed out of the
+// ring, its AssignIDPayload says so (Missed), and the client gives the
+// match up rather than play on out of step.
+
+// maxBackfill is how many critical messages each player keeps.
+const maxBackfill = 256
+
+// critical reports whether a message of type t is one a reconnecting
+// player has to be sent again if they missed it.
+func critical(t protocol.MessageType) bool {
+	switch t {
+	case protocol.MsgCountdown, protocol.MsgGameStart, protocol.MsgReceiveGarbage,
+		protocol.MsgItemEffect, protocol.MsgItemGranted, protocol.MsgLevelUp, protocol.MsgMatchEvent, protocol.MsgMatchOver:
+		return true
+	}
+	return false
+}
+
+// backfill is a player's ring of recently sent critical messages.
+type backfill struct {
+	msgs    []sentMsg // oldest first
+	dropped uint64    // Seq of the newest message pushed out of the ring
+}
+
+// sentMsg is one critical message, as it was sent.
+type sentMsg struct {
+	seq  uint64
+	data []byte
+}
+
+// add keeps the message data, sent as seq, pushing the oldest message
+// out once the ring is full.
+func (b *backfill) add(seq uint64, data []byte) {
+	b.msgs = append(b.msgs, sentMsg{seq: seq, data: data})
+	if n := len(b.msgs) - maxBackfill; n > 0 {
+		b.dropped = b.msgs[n-1].seq
+		b.msgs = slices.Delete(b.msgs, 0, n)
+	}
+}
+
+// since returns the messages kept that came after seq, oldest first,
+// and whether they're all of the critical ones that did: false if some
+// have been pushed out.
+func (b *backfill) since(seq uint64) ([][]byte, bool) {
+	var missed [][]byte
+	for _, m := range b.msgs {
+		if m.seq > seq {
+			missed = append(missed, m.data)
+		}
+	}
+	return missed, b.dropped <= seq
+}
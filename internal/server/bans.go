@@ -0,0 +1,296 @@
+package server
+
+import (
+	"cmp"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Bans ---
+
+// banList is the server-wide list of banned addresses, or, as
+// Hub.quarantine, of quarantined ones. With a path, it is saved there as
+// JSON on every change and loaded at startup, so bans survive a restart.
+type banList struct {
+	mu   sync.RWMutex
+	path string
+	bans map[string]protocol.Ban // IP -> Ban
+}
+
+// loadBans reads the ban list saved at path. A missing file is an empty
+// list; an empty path is a list kept in memory only.
+func loadBans(path string) (*banList, error) {
+	b := &banList{path: path, bans: make(map[string]protocol.Ban)}
+	if path == "" {
+		return b, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved protocol.BansResponse
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, ban := range saved.Bans {
+		ban.IP = canonicalIP(ban.IP)
+		b.bans[ban.IP] = ban
+	}
+	return b, nil
+}
+
+// banned reports whether ip is banned.
+func (b *banList) banned(ip string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.bans[canonicalIP(ip)]
+	return ok
+}
+
+// list returns every ban, oldest first.
+func (b *banList) list() []protocol.Ban {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	bans := make([]protocol.Ban, 0, len(b.bans))
+	for _, ban := range b.bans {
+		bans = append(bans, ban)
+	}
+	slices.SortFunc(bans, func(x, y protocol.Ban) int {
+		return cmp.Or(cmp.Compare(x.Since, y.Since), strings.Compare(x.IP, y.IP))
+	})
+	return bans
+}
+
+// add bans ban.IP, replacing any earlier ban of it.
+func (b *banList) add(ban protocol.Ban) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ban.IP = canonicalIP(ban.IP)
+	b.bans[ban.IP] = ban
+	return b.saveLocked()
+}
+
+// remove lifts the ban on ip, reporting whether there was one.
+func (b *banList) remove(ip string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ip = canonicalIP(ip)
+	if _, ok := b.bans[ip]; !ok {
+		return false, nil
+	}
+	delete(b.bans, ip)
+	return true, b.saveLocked()
+}
+
+// saveLocked writes the list to its file, if it has one, replacing the
+// old file only once the new one is complete. Must be called with b.mu
+// held.
+func (b *banList) saveLocked() error {
+	if b.path == "" {
+		return nil
+	}
+	bans := make([]protocol.Ban, 0, len(b.bans))
+	for _, ban := range b.bans {
+		bans = append(bans, ban)
+	}
+	data, err := json.MarshalIndent(protocol.BansResponse{Bans: bans}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".bans-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
+
+// remoteIP is the address a request came from, without its port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return canonicalIP(host)
+}
+
+// canonicalIP writes ip the one way Go does, so the same address always
+// matches however it was written: "2001:DB8::1" as "2001:db8::1", and an
+// IPv4 address mapped into IPv6 as plain IPv4. Anything that isn't an
+// address is left as it is.
+func canonicalIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+// checkBanned turns a request from a banned address away, reporting
+// whether it did.
+func checkBanned(hub *Hub, w http.ResponseWriter, r *http.Request) bool {
+	if !hub.bans.banned(remoteIP(r)) {
+		return false
+	}
+	writeError(w, http.StatusForbidden, protocol.ErrCodeBanned, "banned from this server")
+	return true
+}
+
+// kickIP disconnects every player playing from ip. Their readPump sees
+// the connection end, and as they're banned, their seat isn't held.
+func (h *Hub) kickIP(ip string) int {
+	h.mu.RLock()
+	players := make([]*Player, 0, len(h.players))
+	for _, p := range h.players {
+		players = append(players, p)
+	}
+	h.mu.RUnlock()
+
+	kicked := 0
+	for _, p := range players {
+		p.mu.Lock()
+		conn := p.Conn
+		match := p.ip == ip
+		p.mu.Unlock()
+		if match && conn != nil {
+			conn.Close()
+			kicked++
+		}
+	}
+	return kicked
+}
+
+// --- Admin API ---
+
+// requireAdmin checks a request's bearer token against the admin token,
+// answering it if they don't match. With no admin token set, the admin
+// API is off and every request gets a 404.
+func requireAdmin(adminToken string, w http.ResponseWriter, r *http.Request) bool {
+	if adminToken == "" {
+		http.NotFound(w, r)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		writeError(w, http.StatusUnauthorized, protocol.ErrCodeUnauthorized, "missing or wrong admin token")
+		return false
+	}
+	return true
+}
+
+// handleAdminBans lists bans (GET), bans an address or a connected
+// player's address (POST), or lifts the ban on ?ip= (DELETE).
+func handleAdminBans(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, protocol.BansResponse{Bans: hub.bans.list()})
+
+	case http.MethodPost:
+		var req protocol.BanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "invalid request body")
+			return
+		}
+		ip := requestedIP(hub, w, req)
+		if ip == "" {
+			return
+		}
+		ban := protocol.Ban{IP: ip, Reason: req.Reason, Since: time.Now().Unix()}
+		if err := hub.bans.add(ban); err != nil {
+			log.Printf("saving bans: %v", err)
+			writeError(w, http.StatusInternalServerError, "", "ban added but not saved")
+			return
+		}
+		kicked := hub.kickIP(ip)
+		log.Printf("Banned %s (%q), disconnected %d player(s)", ip, req.Reason, kicked)
+		writeJSON(w, http.StatusOK, ban)
+
+	case http.MethodDelete:
+		ip := canonicalIP(strings.TrimSpace(r.URL.Query().Get("ip")))
+		ok, err := hub.bans.remove(ip)
+		if err != nil {
+			log.Printf("saving bans: %v", err)
+			writeError(w, http.StatusInternalServerError, "", "ban lifted but not saved")
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, protocol.ErrCodeNotFound, fmt.Sprintf("%q is not banned", ip))
+			return
+		}
+		log.Printf("Lifted ban on %s", ip)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// requestedIP is the address req names: its IP, or the one the connected
+// player PlayerID plays from. If there's none, it answers the request and
+// returns "".
+func requestedIP(hub *Hub, w http.ResponseWriter, req protocol.BanRequest) string {
+	ip := strings.TrimSpace(req.IP)
+	if ip == "" && req.PlayerID != "" {
+		p := hub.getPlayer(req.PlayerID)
+		if p == nil {
+			writeError(w, http.StatusNotFound, protocol.ErrCodeNotFound, fmt.Sprintf("player %q not connected", req.PlayerID))
+			return ""
+		}
+		p.mu.Lock()
+		ip = p.ip
+		p.mu.Unlock()
+	}
+	if net.ParseIP(ip) == nil {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "ip or player_id required")
+		return ""
+	}
+	return canonicalIP(ip)
+}
+
+// handleAdminMutes mutes or unmutes a player's emotes in their room.
+func handleAdminMutes(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req protocol.MuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "invalid request body")
+		return
+	}
+	var room *Room
+	if p := hub.getPlayer(req.PlayerID); p != nil {
+		room = hub.getRoom(p.roomCode())
+	}
+	if room == nil {
+		writeError(w, http.StatusNotFound, protocol.ErrCodeNotFound, fmt.Sprintf("player %q not in a room", req.PlayerID))
+		return
+	}
+	if err := room.setMuted("", req.PlayerID, req.Muted); err != nil {
+		writeError(w, http.StatusNotFound, protocol.ErrCodeNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
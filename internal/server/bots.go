@@ -0,0 +1,234 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- CPU opponents ---
+//
+// A room's host can fill seats with bots (RoomSettings.Bots). A bot is a
+// Player with no connection: it is always ready, plays its own game on the
+// server from the match seed, and attacks, takes garbage and gets knocked
+// out through the same room code as everyone else.
+
+const (
+	// botPieceDelay is how long a bot takes over each piece by default,
+	// give or take up to botPieceJitter, so bots play at a beatable pace.
+	botPieceDelay  = 550 * time.Millisecond
+	botPieceJitter = 300 * time.Millisecond
+	// botInbox is how many messages a bot can have waiting.
+	botInbox = 16
+)
+
+// bot is the part of a Player that only bots have: the messages sent to
+// the player, waiting for its game loop.
+type bot struct {
+	inbox chan protocol.Envelope
+}
+
+// deliver passes the messages a bot acts on to its game loop; the rest
+// are for human eyes and dropped.
+func (b *bot) deliver(env protocol.Envelope) {
+	if env.Type != protocol.MsgReceiveGarbage && env.Type != protocol.MsgItemEffect && env.Type != protocol.MsgItemGranted && env.Type != protocol.MsgLevelUp {
+		return
+	}
+	select {
+	case b.inbox <- env:
+	default:
+	}
+}
+
+// gameRules are the game.Rules that settings play by.
+func gameRules(s protocol.RoomSettings) game.Rules {
+	return game.Rules{
+		AttackTable:    s.AttackTable,
+		Randomizer:     s.Randomizer,
+		EntryDelay:     time.Duration(s.EntryDelayMs) * time.Millisecond,
+		LineClearDelay: time.Duration(s.LineClearDelayMs) * time.Millisecond,
+		AllSpin:        s.AllSpin,
+		GarbageStyle:   s.GarbageStyle,
+		Items:          s.Items,
+		GivenItems:     s.Items,
+		Hold:           s.HoldMode,
+		SharedLevel:    s.SpeedRaceSecs > 0,
+	}
+}
+
+// humans counts the players who aren't bots.
+func (r *Room) humans() int {
+	n := 0
+	for _, p := range r.players {
+		if p.bot == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// syncBots adds or removes bots until the room has as many as its
+// settings ask for, removing the newest first.
+func (r *Room) syncBots() {
+	var bots []*Player
+	for _, p := range r.players {
+		if p.bot != nil {
+			bots = append(bots, p)
+		}
+	}
+	for len(bots) > r.settings.Bots {
+		newest := 0
+		for i, p := range bots {
+			if p.botNum > bots[newest].botNum {
+				newest = i
+			}
+		}
+		r.removePlayer(bots[newest].ID)
+		bots = append(bots[:newest], bots[newest+1:]...)
+	}
+	for len(bots) < r.settings.Bots {
+		r.nextBot++
+		p := &Player{
+			ID:     fmt.Sprintf("bot_%s_%d", r.code, r.nextBot),
+			Name:   fmt.Sprintf("CPU %d", r.nextBot),
+			Ready:  true,
+			Alive:  true,
+			roomID: r.code,
+			bot:    &bot{inbox: make(chan protocol.Envelope, botInbox)},
+			botNum: r.nextBot,
+		}
+		r.players[p.ID] = p
+		bots = append(bots, p)
+	}
+}
+
+// startBots starts every bot's game for a match dealt from seed.
+func (r *Room) startBots(seed int64) {
+	rules := gameRules(r.settings)
+	for _, p := range r.players {
+		if p.bot != nil {
+			go r.runBot(p, game.NewSeededGameStateWithRules(p.ID, p.Name, seed, rules))
+		}
+	}
+}
+
+// runBot plays gs for the bot p until it tops out or the match ends. The
+// bot's game runs on a game.Loop like a player's does, so gravity and the
+// piece delays apply to it too; think is when it next places a piece.
+func (r *Room) runBot(p *Player, gs *game.GameState) {
+	loop := game.NewLoop(gs)
+	think := time.NewTimer(r.config.Load().botPieceDelay())
+	defer think.Stop()
+	tick := time.NewTimer(loop.Wait(time.Now()))
+	defer tick.Stop()
+
+	for {
+		placing := false
+		select {
+		case env := <-p.bot.inbox:
+			switch payload := env.Payload.(type) {
+			case protocol.ReceiveGarbagePayload:
+				loop.ReceiveGarbage(payload.Lines)
+			case protocol.ItemEffectPayload:
+				loop.ApplyItem(payload.Item, time.Now())
+			case protocol.ItemGrantedPayload:
+				loop.GiveItem(payload.Item, time.Now())
+			case protocol.LevelUpPayload:
+				loop.SetLevel(payload.Level, time.Now())
+			}
+			continue
+		case <-think.C:
+			think.Reset(r.config.Load().botPieceDelay() - botPieceJitter/2 + time.Duration(rand.Int63n(int64(botPieceJitter))))
+			placing = true
+		case <-tick.C:
+		case <-r.stopCh:
+			return
+		}
+
+		playing := false
+		r.do(func() { playing = r.phase == PhasePlaying && r.players[p.ID] == p && p.Alive })
+		if !playing {
+			return
+		}
+
+		now := time.Now()
+		events := loop.Advance(now)
+		if placing && gs.Phase == game.PhaseFalling && !gs.IsGameOver {
+			loop.AutoPlace()
+			events = append(events, loop.Advance(now)...)
+		}
+		for _, ev := range events {
+			if ev.Kind != game.EventLock || ev.Clear.Lines == 0 {
+				continue
+			}
+			a := ev.Clear
+			cleared := protocol.LinesClearedPayload{
+				Count:       a.Lines,
+				AttackPower: ev.Attack,
+				ClearType:   protocol.ClearTypeFor(a.Lines, a.TSpin),
+				Combo:       a.Combo,
+				B2B:         a.B2B,
+				Spin:        a.Spin,
+			}
+			r.do(func() { r.handleLinesCleared(p.ID, cleared) })
+		}
+		if item := gs.TakeItem(); item != "" {
+			r.do(func() { r.handleUseItem(p, item) })
+			if !game.IsTargeted(item) {
+				gs.ApplyItem(item, now)
+			}
+		}
+		if !tick.Stop() {
+			select {
+			case <-tick.C:
+			default:
+			}
+		}
+		tick.Reset(loop.Wait(now))
+
+		p.mu.Lock()
+		p.Snapshot = &protocol.BoardSnapshotPayload{
+			Score: gs.Score,
+			Level: gs.Level,
+			Lines: gs.Lines,
+			Alive: !gs.IsGameOver,
+			Board: gs.Board.ToFlat(),
+		}
+		p.version++
+		p.mu.Unlock()
+
+		if gs.IsGameOver {
+			r.do(func() { r.handlePlayerDead(p.ID) })
+			return
+		}
+	}
+}
+
+// finishBots ends a match once only bots are left in it, as they
+// would play on with no one to play against: the bots are placed by
+// score, and the best of them is returned as the only one still alive.
+func (r *Room) finishBots(bots []*Player) []*Player {
+	score := func(p *Player) int {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.Snapshot == nil {
+			return 0
+		}
+		return p.Snapshot.Score
+	}
+	slices.SortFunc(bots, func(a, b *Player) int {
+		return cmp.Compare(score(b), score(a))
+	})
+	now := time.Now()
+	for i, p := range bots[1:] {
+		p.Alive = false
+		p.placement = i + 2
+		p.diedAt = now
+	}
+	return bots[:1]
+}
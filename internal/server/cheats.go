@@ -0,0 +1,177 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Snapshot checks ---
+//
+// Clients play their own games and report them in snapshots, so a
+// modified client could claim anything. Each snapshot is checked against
+// the player's previous one, and changes no real game could make in the
+// time between them are flagged: lines or score going down, more lines or
+// points than the pieces placed meanwhile could earn, or cells filling
+// faster than those pieces and the garbage sent to the player can fill
+// them. CHEAT_ACTION picks what's done about it.
+
+// What to do about a flagged snapshot.
+const (
+	cheatLog        = "log"        // log it
+	cheatWarn       = "warn"       // log it, and tell the room in the kill feed
+	cheatDisconnect = "disconnect" // log it, and disconnect the player for good
+	cheatQuarantine = "quarantine" // log it, and quarantine the player's address
+)
+
+var cheatActions = []string{cheatLog, cheatWarn, cheatDisconnect, cheatQuarantine}
+
+const (
+	// maxPiecesPerSecond is faster than anyone places pieces. One piece
+	// more is always allowed, for a snapshot sent just after a lock.
+	maxPiecesPerSecond = 15
+	// garbageCells is how many cells a line of garbage fills.
+	garbageCells = game.BoardWidth - 1
+	// snapSlack is how much longer than the server saw between two
+	// snapshots the client's clock may say they were apart, so snapshots
+	// the network bunches up aren't taken for impossibly fast play.
+	snapSlack = time.Second
+)
+
+// snapCheck is what a player's next snapshot is checked against.
+type snapCheck struct {
+	prev    *protocol.BoardSnapshotPayload
+	at      time.Time // when prev arrived
+	sent    time.Time // when prev was sent, by the client's clock; zero if unknown
+	garbage int       // cells of garbage sent to the player, not yet seen on their board
+	warned  bool      // the room has been told about the player this match
+}
+
+// check compares snap, which arrived at at and was sent at sent by the
+// client's clock (zero if it didn't say), with the previous snapshot, and
+// returns what's impossible about it, or "" if nothing is. With items, a
+// board can change in ways no piece or garbage explains, so cells aren't
+// counted.
+func (c *snapCheck) check(snap *protocol.BoardSnapshotPayload, at, sent time.Time, items bool) string {
+	prev, since := c.prev, snapInterval(c.at, c.sent, at, sent)
+	c.prev, c.at, c.sent = snap, at, sent
+	if prev == nil {
+		return ""
+	}
+	pieces := 1 + int(since.Seconds()*maxPiecesPerSecond)
+	lines := snap.Lines - prev.Lines
+	// A clear scores at most a Tetris's 200 a line, times the level, and
+	// a piece at most 2 a row for a hard drop.
+	maxScore := 200*max(snap.Level, 1)*lines + 2*game.BoardHeight*pieces
+
+	switch {
+	case lines < 0:
+		return fmt.Sprintf("lines went from %d to %d", prev.Lines, snap.Lines)
+	case snap.Score < prev.Score:
+		return fmt.Sprintf("score went from %d to %d", prev.Score, snap.Score)
+	case lines > 4*pieces:
+		return fmt.Sprintf("%d lines in %v", lines, since.Round(time.Millisecond))
+	case snap.Score-prev.Score > maxScore:
+		return fmt.Sprintf("%d points for %d lines in %v", snap.Score-prev.Score, lines, since.Round(time.Millisecond))
+	}
+
+	size := game.BoardWidth * game.BoardHeight
+	if items || len(snap.Board) != size || len(prev.Board) != size {
+		return ""
+	}
+	// Cells filled since, counting the full rows cleared away
+	added := filled(snap.Board) - filled(prev.Board) + game.BoardWidth*lines
+	if added > 4*pieces+c.garbage {
+		return fmt.Sprintf("%d cells filled in %v", added, since.Round(time.Millisecond))
+	}
+	c.garbage = max(0, c.garbage-max(0, added-4*pieces))
+	return ""
+}
+
+// snapInterval is how far apart two snapshots were, arriving at
+// prevAt and at. The client's clock, sent at prevSent and sent, is
+// trusted to stretch that by no more than snapSlack, and never below 0:
+// a client can't buy itself time, and one whose clock steps back isn't
+// held to an interval it can't have played in.
+func snapInterval(prevAt, prevSent, at, sent time.Time) time.Duration {
+	since := at.Sub(prevAt)
+	if !prevSent.IsZero() && !sent.IsZero() {
+		since = min(sent.Sub(prevSent), since+snapSlack)
+	}
+	return max(since, 0)
+}
+
+// filled counts a flat board's filled cells, leaving out full rows: with
+// a line clear delay, cleared rows stay on the board for a moment after
+// they've been counted in the player's lines.
+func filled(board []int) int {
+	n := 0
+	for row := range slices.Chunk(board, game.BoardWidth) {
+		cells := 0
+		for _, c := range row {
+			if c != 0 {
+				cells++
+			}
+		}
+		if cells < game.BoardWidth {
+			n += cells
+		}
+	}
+	return n
+}
+
+// checkSnapshot checks a snapshot p sent during a match, which arrived at
+// at and was sent at sent by p's clock, returning what's impossible about
+// it, or "".
+func (r *Room) checkSnapshot(p *Player, snap *protocol.BoardSnapshotPayload, at, sent time.Time) string {
+	var playing, items bool
+	r.do(func() {
+		playing, items = r.phase == PhasePlaying, r.settings.Items
+		if playing {
+			r.touch()
+		}
+	})
+	if !playing {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.check.check(snap, at, sent, items)
+}
+
+// flagCheat deals with a player whose snapshot failed its checks, as
+// CHEAT_ACTION says.
+func (h *Hub) flagCheat(room *Room, p *Player, reason string) {
+	log.Printf("Player %s (%s) in room %s sent an impossible snapshot: %s", p.Name, p.ID, room.code, reason)
+
+	switch h.cheatAction {
+	case cheatWarn:
+		p.mu.Lock()
+		warned := p.check.warned
+		p.check.warned = true
+		p.mu.Unlock()
+		if !warned {
+			room.do(func() {
+				room.sendEvent(protocol.MatchEventPayload{
+					Kind:       protocol.EventSuspect,
+					PlayerID:   p.ID,
+					PlayerName: p.Name,
+				})
+			})
+		}
+	case cheatDisconnect:
+		p.mu.Lock()
+		p.kicked = true
+		conn := p.Conn
+		p.mu.Unlock()
+		if conn != nil {
+			conn.Close()
+		}
+	case cheatQuarantine:
+		h.quarantinePlayer(p, reason)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- Reloadable settings ---
+//
+// A few settings can change while the server runs. They're read from the
+// JSON file named by CONFIG_FILE at startup, and again whenever the server
+// gets SIGHUP, so tuning them doesn't mean a restart that ends every match
+// in progress. Settings the file leaves out keep their defaults. A file
+// that fails to load on reload is logged and the settings in force kept.
+
+// config is the server's reloadable settings.
+type config struct {
+	// BroadcastIntervalMs is how often, at most, opponent updates go out
+	// during a match.
+	BroadcastIntervalMs int `json:"broadcast_interval_ms"`
+	// RoomsPerIP and MaxEmptyRooms ration room creation; 0 = no limit.
+	RoomsPerIP    int `json:"rooms_per_ip"`
+	MaxEmptyRooms int `json:"max_empty_rooms"`
+	// MOTD is shown to players in the lobby when they join a room.
+	MOTD string `json:"motd"`
+	// AllowedOrigins are the web origins /play accepts connections from,
+	// as scheme://host[:port]; none means any. Clients that send no
+	// Origin, like the terminal client, and the server's own web client
+	// are always accepted.
+	AllowedOrigins []string `json:"allowed_origins"`
+	// BotPieceDelayMs is how long bots take over each piece: the lower,
+	// the harder they are to beat.
+	BotPieceDelayMs int `json:"bot_piece_delay_ms"`
+}
+
+// Bounds on the timings a config file may set.
+const (
+	minBroadcastInterval = 10 * time.Millisecond
+	maxBroadcastInterval = opponentKeepalive
+	minBotPieceDelay     = 200 * time.Millisecond
+	maxBotPieceDelay     = 5 * time.Second
+)
+
+func defaultConfig() *config {
+	return &config{
+		BroadcastIntervalMs: int(broadcastInterval / time.Millisecond),
+		RoomsPerIP:          roomsPerIP,
+		MaxEmptyRooms:       maxEmptyRooms,
+		BotPieceDelayMs:     int(botPieceDelay / time.Millisecond),
+	}
+}
+
+// loadConfig reads the config file at path over the defaults. An empty
+// path means the defaults.
+func loadConfig(path string) (*config, error) {
+	c := defaultConfig()
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, o := range c.AllowedOrigins {
+		c.AllowedOrigins[i] = strings.ToLower(strings.TrimSuffix(o, "/"))
+	}
+	return c, nil
+}
+
+func (c *config) validate() error {
+	if d := c.broadcastInterval(); d < minBroadcastInterval || d > maxBroadcastInterval {
+		return fmt.Errorf("broadcast_interval_ms must be between %d and %d", minBroadcastInterval.Milliseconds(), maxBroadcastInterval.Milliseconds())
+	}
+	if d := c.botPieceDelay(); d < minBotPieceDelay || d > maxBotPieceDelay {
+		return fmt.Errorf("bot_piece_delay_ms must be between %d and %d", minBotPieceDelay.Milliseconds(), maxBotPieceDelay.Milliseconds())
+	}
+	if c.RoomsPerIP < 0 || c.MaxEmptyRooms < 0 {
+		return fmt.Errorf("rooms_per_ip and max_empty_rooms can't be negative")
+	}
+	for _, o := range c.AllowedOrigins {
+		if !strings.HasPrefix(o, "http://") && !strings.HasPrefix(o, "https://") {
+			return fmt.Errorf("allowed origin %q must start with http:// or https://", o)
+		}
+	}
+	return nil
+}
+
+func (c *config) broadcastInterval() time.Duration {
+	return time.Duration(c.BroadcastIntervalMs) * time.Millisecond
+}
+
+func (c *config) botPieceDelay() time.Duration {
+	return time.Duration(c.BotPieceDelayMs) * time.Millisecond
+}
+
+// allowOrigin reports whether a /play request may be upgraded, going by
+// its Origin header.
+func (c *config) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(c.AllowedOrigins) == 0 {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true // a page the server served itself
+	}
+	return slices.Contains(c.AllowedOrigins, strings.ToLower(origin))
+}
+
+// checkOrigin is the WebSocket upgrader's origin check, by the settings
+// in force.
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	return h.config.Load().allowOrigin(r)
+}
+
+// reloadConfig rereads the config file at path, keeping the settings in
+// force if it can't be loaded.
+func (h *Hub) reloadConfig(path string) {
+	c, err := loadConfig(path)
+	if err != nil {
+		log.Printf("Config not reloaded, keeping the current settings: %v", err)
+		return
+	}
+	h.config.Store(c)
+	log.Printf("Config reloaded from %s", path)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Diagnostics ---
+//
+// net/http/pprof profiles and expvar counters, for chasing CPU spikes and
+// goroutine leaks on a live server. They're never on the public routes:
+// with DEBUG_ADDR set they're served on that address alone (keep it
+// private, e.g. 127.0.0.1:6060), otherwise under /debug/ behind the admin
+// token, and with neither they're off.
+
+// diagnosticsHandler serves /debug/pprof/ and /debug/vars.
+func diagnosticsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// publishVars adds the server's own gauges to /debug/vars, next to
+// expvar's memstats and cmdline.
+func publishVars(hub *Hub) {
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("hub", expvar.Func(func() any {
+		return hub.vars()
+	}))
+}
+
+// vars counts what the hub is holding on to right now.
+func (h *Hub) vars() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	playing := 0
+	for _, room := range h.rooms {
+		if info, ok := room.info(); ok && info.Phase != protocol.RoomPhaseLobby {
+			playing++
+		}
+	}
+	return map[string]int{
+		"rooms":         len(h.rooms),
+		"rooms_playing": playing,
+		"rooms_empty":   h.emptyRoomsLocked(),
+		"players":       len(h.players),
+		"connections":   h.conns,
+		"pending_joins": len(h.pendingJoins),
+	}
+}
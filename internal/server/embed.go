@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// --- Embedding ---
+//
+// New runs a server in another program, which serves it on a listener of
+// its own: pkg/servertest runs one per test this way. An embedded server
+// reads nothing from the environment and writes no files. Its bans are
+// kept in memory, it keeps no results log, and room creation isn't
+// rationed, as its clients usually share an address.
+
+// Options configure a server made with New. The zero value is a server
+// with the default settings and no capacity limits.
+type Options struct {
+	// Speed runs the timers this many times faster than normal: the
+	// countdown, the auto-start timer, the pause after a match, the
+	// reconnect grace period and the watchdog. 0 means normal speed.
+	Speed int
+	// MaxRooms and MaxConns cap rooms and WebSocket connections, as
+	// MAX_ROOMS and MAX_CONNECTIONS do; 0 is no limit.
+	MaxRooms int
+	MaxConns int
+	// AdminToken turns on the admin API, as ADMIN_TOKEN does.
+	AdminToken string
+}
+
+// Server is a server made with New. It's an http.Handler serving the
+// same routes cmd/server does, without the diagnostics.
+type Server struct {
+	hub *Hub
+	mux *http.ServeMux
+}
+
+// New makes a server with opts.
+func New(opts Options) *Server {
+	bans, _ := loadBans("") // in memory, so it can't fail
+	hub := newHub(opts.MaxRooms, opts.MaxConns, bans, nil)
+	hub.speed = max(time.Duration(opts.Speed), 1)
+	hub.cheatAction = cheatLog
+	cfg := defaultConfig()
+	cfg.RoomsPerIP = 0
+	cfg.MaxEmptyRooms = 0
+	hub.config.Store(cfg)
+	go hub.watchRooms()
+	return &Server{hub: hub, mux: routes(hub, opts.AdminToken)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Drop cuts a player's connection without a close frame, as a network
+// failure would, so their client can reconnect and resume. It reports
+// false if the player isn't connected.
+func (s *Server) Drop(playerID string) bool {
+	p := s.hub.getPlayer(playerID)
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	conn := p.Conn
+	p.mu.Unlock()
+	if conn == nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Close drops every player's connection and stops the watchdog. The rooms
+// go once their players' reconnect grace periods have run out.
+func (s *Server) Close() {
+	s.hub.mu.RLock()
+	players := make([]*Player, 0, len(s.hub.players))
+	for _, p := range s.hub.players {
+		players = append(players, p)
+	}
+	s.hub.mu.RUnlock()
+	for _, p := range players {
+		p.mu.Lock()
+		conn := p.Conn
+		p.mu.Unlock()
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	close(s.hub.done)
+}
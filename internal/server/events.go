@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Room event stream ---
+//
+// GET /rooms/{code}/events streams what happens in a room as server-sent
+// events, for dashboards, casters' tools and bots that only want to
+// watch. Each event is named for its message type and carries the
+// payload players get: lobby_update (joins, leaves, readiness, settings),
+// auto_start, countdown, game_start, match_event (the kill feed) and
+// match_over, plus attack for every garbage sent. The stream opens with
+// the room's current lobby_update. Watchers only read; they take no seat,
+// and a room with no players left is removed whoever is watching it.
+
+const (
+	// maxWatchers is how many event streams one room serves at once.
+	maxWatchers = 50
+	// watcherBuffer is how many events a watcher can fall behind by
+	// before it's dropped.
+	watcherBuffer = 64
+	// sseKeepalive is how often an idle stream gets a comment, so
+	// proxies don't time it out.
+	sseKeepalive = 15 * time.Second
+)
+
+// watchers are a room's event stream subscribers.
+type watchers struct {
+	mu   sync.Mutex
+	subs map[chan []byte]bool
+}
+
+// subscribe adds a watcher, returning the channel its events come on, or
+// nil if the room has maxWatchers already.
+func (ws *watchers) subscribe() chan []byte {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.subs) >= maxWatchers {
+		return nil
+	}
+	if ws.subs == nil {
+		ws.subs = make(map[chan []byte]bool)
+	}
+	ch := make(chan []byte, watcherBuffer)
+	ws.subs[ch] = true
+	return ch
+}
+
+func (ws *watchers) unsubscribe(ch chan []byte) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.subs[ch] {
+		delete(ws.subs, ch)
+		close(ch)
+	}
+}
+
+// publish sends env to every watcher. One that has fallen too far behind
+// is dropped rather than sent a feed with holes in it.
+func (ws *watchers) publish(env protocol.Envelope) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.subs) == 0 {
+		return
+	}
+	ev, err := sseEvent(env)
+	if err != nil {
+		log.Printf("marshal %s event: %v", env.Type, err)
+		return
+	}
+	for ch := range ws.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(ws.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// sseEvent formats env as a server-sent event named for its type.
+func sseEvent(env protocol.Envelope) ([]byte, error) {
+	data, err := json.Marshal(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, "event: %s\ndata: %s\n\n", env.Type, data), nil
+}
+
+func handleRoomEvents(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if checkBanned(hub, w, r) {
+		return
+	}
+	code := strings.ToUpper(r.PathValue("code"))
+	if hub.instances.redirect(w, r, code) {
+		return
+	}
+	room := hub.getRoom(code)
+	if room == nil {
+		writeError(w, http.StatusNotFound, protocol.ErrCodeRoomNotFound, "room not found")
+		return
+	}
+	// The opening lobby_update and the subscription happen together on
+	// the loop, which is where events are published, so the stream
+	// carries on from exactly that snapshot.
+	var ch chan []byte
+	var first []byte
+	var err error
+	if !room.do(func() {
+		first, err = sseEvent(protocol.Envelope{Type: protocol.MsgLobbyUpdate, Payload: room.lobbyUpdate()})
+		if err == nil {
+			ch = room.watchers.subscribe()
+		}
+	}) {
+		writeError(w, http.StatusNotFound, protocol.ErrCodeRoomNotFound, "room not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", "couldn't read the room")
+		return
+	}
+	if ch == nil {
+		writeServerFull(w)
+		return
+	}
+	defer room.watchers.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	w.Write(first)
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+	for {
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return // fell behind
+			}
+			if _, err := w.Write(ev); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+		case <-room.stopCh:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
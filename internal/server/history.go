@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Match history ---
+
+const (
+	// maxMatchHistory is how many finished matches the server remembers,
+	// across all rooms; older ones are forgotten.
+	maxMatchHistory = 1000
+	// defaultHistoryLimit and maxHistoryLimit are how many matches one
+	// /players/{id}/matches reply holds by default and at most.
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// matchHistory keeps the most recent finished matches in memory, oldest
+// first.
+type matchHistory struct {
+	mu      sync.RWMutex
+	matches []protocol.MatchRecord
+	nextID  int
+}
+
+func newMatchHistory() *matchHistory {
+	return &matchHistory{}
+}
+
+// record adds a finished match, giving it its MatchID, which it returns.
+func (h *matchHistory) record(rec protocol.MatchRecord) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	rec.MatchID = fmt.Sprintf("match_%d", h.nextID)
+	h.matches = append(h.matches, rec)
+	if len(h.matches) > maxMatchHistory {
+		h.matches = slices.Delete(h.matches, 0, len(h.matches)-maxMatchHistory)
+	}
+	return rec.MatchID
+}
+
+// forPlayer returns up to limit of playerID's matches, most recent first.
+func (h *matchHistory) forPlayer(playerID string, limit int) []protocol.MatchRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	matches := []protocol.MatchRecord{}
+	for i := len(h.matches) - 1; i >= 0 && len(matches) < limit; i-- {
+		rec := h.matches[i]
+		if slices.ContainsFunc(rec.Standings, func(st protocol.PlayerStanding) bool {
+			return st.PlayerID == playerID
+		}) {
+			matches = append(matches, rec)
+		}
+	}
+	return matches
+}
+
+// handlePlayerMatches serves GET /players/{id}/matches?limit=N.
+func handlePlayerMatches(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r.URL.Query(), "limit", defaultHistoryLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, err.Error())
+		return
+	}
+	limit = min(limit, maxHistoryLimit)
+	writeJSON(w, http.StatusOK, protocol.MatchHistoryResponse{
+		Matches: hub.history.forPlayer(r.PathValue("id"), limit),
+	})
+}
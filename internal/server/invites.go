@@ -0,0 +1,221 @@
+package server
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Invites ---
+//
+// A player in a room can invite a friend rather than pass the room code
+// on. An invite is a token that joins the room once, in place of its
+// code, for inviteTTL. It's either a link for whoever it's given to, or
+// addressed to a friend code: the public half of a profile key, which
+// the friend's client polls GET /invites with to show the invite on its
+// main menu. Invites are made over the player's WebSocket, so only
+// someone with a seat in the room can make one, from their own name.
+// They're kept in memory only.
+
+const (
+	// inviteTTL is how long an invite can be used.
+	inviteTTL = 15 * time.Minute
+	// maxInvites caps the invites kept at once, and maxFriendInvites
+	// those to any one friend code; a new invite past either drops the
+	// oldest.
+	maxInvites       = 10000
+	maxFriendInvites = 5
+	// friendCodeLength is how many hex digits of the key's hash a friend
+	// code has.
+	friendCodeLength = 10
+)
+
+// invite is an invite as kept: to is the friend code it's for, "" for a
+// link.
+type invite struct {
+	protocol.Invite
+	to string
+}
+
+// inviteStore is the server's invites, by token.
+type inviteStore struct {
+	mu      sync.Mutex
+	invites map[string]invite
+}
+
+func newInviteStore() *inviteStore {
+	return &inviteStore{invites: make(map[string]invite)}
+}
+
+// friendCode is the friend code of the player with a profile key: a
+// hash of it, so it can be shared without giving the key away.
+func friendCode(key string) string {
+	sum := sha256.Sum256([]byte("friend:" + key))
+	return strings.ToUpper(hex.EncodeToString(sum[:])[:friendCodeLength])
+}
+
+// add makes an invite to room from fromName, for the friend code to or
+// for a link, and returns it.
+func (s *inviteStore) add(room, fromName, to string) protocol.Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.expireLocked(now)
+	if len(s.invites) >= maxInvites {
+		s.dropOldestLocked("")
+	}
+	if to != "" && len(s.forLocked(to)) >= maxFriendInvites {
+		s.dropOldestLocked(to)
+	}
+	inv := invite{protocol.Invite{
+		Token:     rand.Text(),
+		RoomID:    room,
+		FromName:  fromName,
+		ExpiresAt: now.Add(inviteTTL).UnixMilli(),
+	}, to}
+	s.invites[inv.Token] = inv
+	return inv.Invite
+}
+
+// get returns the invite token without using it up.
+func (s *inviteStore) get(token string) (protocol.Invite, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked(time.Now())
+	inv, ok := s.invites[token]
+	return inv.Invite, ok
+}
+
+// take uses up the invite token, returning it.
+func (s *inviteStore) take(token string) (protocol.Invite, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked(time.Now())
+	inv, ok := s.invites[token]
+	delete(s.invites, token)
+	return inv.Invite, ok
+}
+
+// decline removes the invite token to the friend code to, reporting
+// whether there was one.
+func (s *inviteStore) decline(token, to string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.invites[token]
+	if !ok || inv.to != to {
+		return false
+	}
+	delete(s.invites, token)
+	return true
+}
+
+// list returns the invites to the friend code to, oldest first.
+func (s *inviteStore) list(to string) []protocol.Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked(time.Now())
+	invites := s.forLocked(to)
+	list := make([]protocol.Invite, len(invites))
+	for i, inv := range invites {
+		list[i] = inv.Invite
+	}
+	return list
+}
+
+// forLocked returns the invites to the friend code to, or all of them
+// for "", oldest first. Must be called with s.mu held.
+func (s *inviteStore) forLocked(to string) []invite {
+	var invites []invite
+	for _, inv := range s.invites {
+		if to == "" || inv.to == to {
+			invites = append(invites, inv)
+		}
+	}
+	slices.SortFunc(invites, func(a, b invite) int {
+		return cmp.Compare(a.ExpiresAt, b.ExpiresAt)
+	})
+	return invites
+}
+
+// dropOldestLocked removes the oldest invite to the friend code to, or
+// of all for "". Must be called with s.mu held.
+func (s *inviteStore) dropOldestLocked(to string) {
+	if invites := s.forLocked(to); len(invites) > 0 {
+		delete(s.invites, invites[0].Token)
+	}
+}
+
+// expireLocked removes the invites expired at now. Must be called with
+// s.mu held.
+func (s *inviteStore) expireLocked(now time.Time) {
+	for token, inv := range s.invites {
+		if now.UnixMilli() >= inv.ExpiresAt {
+			delete(s.invites, token)
+		}
+	}
+}
+
+// createInvite makes an invite from p to the room they're in, for the
+// friend code to or for a link, answering with MsgInviteCreated or, if
+// they're in no room or the code is malformed, MsgRoomError.
+func (h *Hub) createInvite(p *Player, to string) {
+	to = strings.ToUpper(strings.TrimSpace(to))
+	var err error
+	room := h.getRoom(p.roomCode())
+	switch {
+	case room == nil:
+		err = newRoomError(protocol.ErrCodeRoomNotFound, "not in a room")
+	case to != "" && len(to) != friendCodeLength:
+		err = newRoomError(protocol.ErrCodeBadRequest, "a friend code has %d characters", friendCodeLength)
+	}
+	if err != nil {
+		p.send(protocol.Envelope{Type: protocol.MsgRoomError, Payload: roomErrorPayload(err)})
+		return
+	}
+
+	name := clipText(cmp.Or(strings.TrimSpace(p.Name), "Player"))
+	inv := h.invites.add(room.code, name, to)
+	if to != "" {
+		log.Printf("Player %s (%s) invited %s to room %s", p.Name, p.ID, to, room.code)
+	} else {
+		log.Printf("Player %s (%s) made an invite link to room %s", p.Name, p.ID, room.code)
+	}
+	p.send(protocol.Envelope{
+		Type:    protocol.MsgInviteCreated,
+		Payload: protocol.InviteCreatedPayload{Invite: inv, To: to},
+	})
+}
+
+// handleInvites lists the invites to the request's profile key's friend
+// code (GET /invites).
+func handleInvites(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	key, ok := profileKey(w, r)
+	if !ok {
+		return
+	}
+	to := friendCode(key)
+	writeJSON(w, http.StatusOK, protocol.InvitesResponse{FriendCode: to, Invites: hub.invites.list(to)})
+}
+
+// handleDeclineInvite declines an invite to the request's profile key's
+// friend code (DELETE /invites/{token}).
+func handleDeclineInvite(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	key, ok := profileKey(w, r)
+	if !ok {
+		return
+	}
+	if !hub.invites.decline(r.PathValue("token"), friendCode(key)) {
+		writeError(w, http.StatusNotFound, protocol.ErrCodeNotFound, "no such invite")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
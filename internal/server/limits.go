@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Room creation limits ---
+//
+// A room takes memory from the moment it's made, before anyone is in it,
+// so making them is rationed. Each address may create a few rooms per
+// createWindow; the server keeps only so many rooms with no players in
+// them (both reloadable, see config); and a room whose creator never opens
+// its WebSocket is removed once their join token has expired.
+
+const (
+	// roomsPerIP is how many rooms one address may create per
+	// createWindow, through /create-room or quick play, by default.
+	roomsPerIP   = 5
+	createWindow = time.Minute
+	// maxEmptyRooms is the most rooms with no players the server keeps by
+	// default. Past it, creating a room is turned away as if the server
+	// were full.
+	maxEmptyRooms = 50
+	// emptyRoomGrace is how long past its join token's expiry a new room
+	// is kept for a creator who used the token just in time and is still
+	// connecting.
+	emptyRoomGrace = 10 * time.Second
+)
+
+// creationLimiter remembers when each address created rooms, or
+// anything else rationed per address over a window.
+type creationLimiter struct {
+	mu     sync.Mutex
+	window time.Duration
+	byIP   map[string][]time.Time // oldest first, within window
+}
+
+func newCreationLimiter(window time.Duration) *creationLimiter {
+	return &creationLimiter{window: window, byIP: make(map[string][]time.Time)}
+}
+
+// allow counts a room created by ip at now, unless ip has already
+// created limit in the window, in which case it returns how long until it
+// may create another and false. A limit of 0 allows any number.
+func (l *creationLimiter) allow(ip string, now time.Time, limit int) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Forget creations that have left the window while we're here
+	for addr, times := range l.byIP {
+		i := 0
+		for i < len(times) && now.Sub(times[i]) >= l.window {
+			i++
+		}
+		if i == len(times) {
+			delete(l.byIP, addr)
+		} else {
+			l.byIP[addr] = times[i:]
+		}
+	}
+	times := l.byIP[ip]
+	if limit > 0 && len(times) >= limit {
+		return l.window - now.Sub(times[len(times)-limit]), false
+	}
+	l.byIP[ip] = append(times, now)
+	return 0, true
+}
+
+// checkCreateRate turns a request that would create a room away if its
+// address has created too many lately, reporting whether it did.
+func checkCreateRate(hub *Hub, w http.ResponseWriter, r *http.Request) bool {
+	wait, ok := hub.creations.allow(remoteIP(r), time.Now(), hub.config.Load().RoomsPerIP)
+	if ok {
+		return false
+	}
+	writeRateLimited(w, wait, "too many rooms created; try again later")
+	return true
+}
+
+// writeRateLimited answers a request turned away for wait by a
+// creationLimiter.
+func writeRateLimited(w http.ResponseWriter, wait time.Duration, msg string) {
+	secs := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeJSON(w, http.StatusTooManyRequests, protocol.ErrorResponse{
+		Code:       protocol.ErrCodeRateLimited,
+		Error:      msg,
+		RetryAfter: secs,
+	})
+}
+
+// emptyRoomsLocked counts the rooms with no players in them. Must be
+// called with h.mu held.
+func (h *Hub) emptyRoomsLocked() int {
+	n := 0
+	for _, room := range h.rooms {
+		if room.playerCount() == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// expireIfUnjoined removes room code if it's still empty once the token
+// its creator was given has expired.
+func (h *Hub) expireIfUnjoined(code string) {
+	time.AfterFunc(joinTokenTTL+emptyRoomGrace, func() {
+		h.removeRoomIfEmpty(code)
+	})
+}
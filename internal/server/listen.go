@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// --- Listeners ---
+//
+// The server listens on every address in LISTEN (comma-separated), or on
+// PORT when that's unset. An address is host:port, :port, or unix:/path
+// for a Unix socket, for a reverse proxy on the same machine. With
+// ADMIN_LISTEN set, the admin API and diagnostics move to addresses of
+// their own, off the public ones, so a firewall can keep them private.
+
+// unixPrefix marks a Unix socket's path in an address.
+const unixPrefix = "unix:"
+
+// listenAddrs splits a comma-separated list of addresses.
+func listenAddrs(s string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(s, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// listen opens a listener on addr, first removing a Unix socket left
+// behind by an earlier run.
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	return net.Listen("unix", path)
+}
+
+// serve serves handler on each of addrs, exiting if one can't be opened,
+// and returns the servers so they can be closed on shutdown.
+func serve(addrs []string, handler http.Handler) []*http.Server {
+	var servers []*http.Server
+	for _, addr := range addrs {
+		ln, err := listen(addr)
+		if err != nil {
+			log.Fatalf("listening on %s: %v", addr, err)
+		}
+		srv := &http.Server{Handler: handler}
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("server error on %s: %v", addr, err)
+			}
+		}()
+		servers = append(servers, srv)
+	}
+	return servers
+}
+
+// baseURL is how the logs link to addr, e.g. http://localhost:8080.
+func baseURL(addr string) string {
+	if strings.HasPrefix(addr, unixPrefix) {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Points play ---
+//
+// With a points target set, a room plays a series of matches: each one
+// awards points by placement plus a bonus per knockout, and the totals
+// carry over until someone reaches the target and wins the series.
+
+const (
+	// maxPointsTarget and maxPointsAward bound the target and any one
+	// award a room may set.
+	maxPointsTarget = 1000
+	maxPointsAward  = 100
+	defaultKOPoints = 1
+)
+
+// defaultPlacementPoints are the points for first, second, ... place
+// when a room doesn't set its own; lower places earn nothing.
+var defaultPlacementPoints = []int{5, 3, 2, 1}
+
+// validatePoints checks a room's points play settings.
+func validatePoints(s protocol.RoomSettings) error {
+	if s.PointsTarget < 0 || s.PointsTarget > maxPointsTarget {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "points target must be between 0 and %d", maxPointsTarget)
+	}
+	if len(s.PlacementPoints) > maxPlayersPerRoom {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "at most %d placement points", maxPlayersPerRoom)
+	}
+	for _, n := range append([]int{s.KOPoints}, s.PlacementPoints...) {
+		if n < 0 || n > maxPointsAward {
+			return newRoomError(protocol.ErrCodeInvalidSettings, "points awards must be between 0 and %d", maxPointsAward)
+		}
+	}
+	return nil
+}
+
+// awardPoints adds the points each player earned in a finished
+// match to the room's totals, filling them into standings (sorted by
+// rank). If someone reached the target, the one with the most points
+// (the better placed of any tied) wins the series, which is returned, and
+// the totals start over.
+func (r *Room) awardPoints(standings []protocol.PlayerStanding) (champion *protocol.PlayerStanding) {
+	if r.settings.PointsTarget == 0 {
+		return nil
+	}
+	placement := r.settings.PlacementPoints
+	if len(placement) == 0 {
+		placement = defaultPlacementPoints
+	}
+	for i := range standings {
+		st := &standings[i]
+		if st.Rank <= len(placement) {
+			st.Points = placement[st.Rank-1]
+		}
+		st.Points += st.KOs * r.settings.KOPoints
+		r.points[st.PlayerID] += st.Points
+		st.TotalPoints = r.points[st.PlayerID]
+		if st.TotalPoints >= r.settings.PointsTarget && (champion == nil || st.TotalPoints > champion.TotalPoints) {
+			champion = st
+		}
+	}
+	if champion != nil {
+		clear(r.points)
+	}
+	return champion
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Player profiles ---
+//
+// A profile is a player's client settings (protocol.Profile), kept so
+// they follow the player to another machine. There are no accounts: the
+// client makes up a random profile key and sends it as a bearer token,
+// and whoever has the key has the profile. Only a hash of each key is
+// kept. With PROFILES_FILE set, profiles are saved there as JSON on every
+// change, like the ban list, and survive a restart.
+//
+// Anyone can make up a key, so new keys are rationed: each address may
+// start newKeysPerIP per newKeyWindow, and once maxProfiles are kept a
+// new one only gets in by pushing out a profile nobody has stored for
+// profileMaxIdle. Otherwise it's turned away, and the profiles already
+// kept stay.
+
+const (
+	// maxProfiles is how many profiles the server keeps.
+	maxProfiles = 10000
+	// profileMaxIdle is how long a profile must have gone unstored for a
+	// new one to push it out once maxProfiles are kept.
+	profileMaxIdle = 365 * 24 * time.Hour
+	// newKeysPerIP is how many profile keys one address may start storing
+	// profiles (or achievements) under per newKeyWindow.
+	newKeysPerIP = 10
+	newKeyWindow = time.Hour
+	// minProfileKey and maxProfileKey bound a profile key's length, so
+	// keys are hard to guess and cheap to hash.
+	minProfileKey = 16
+	maxProfileKey = 128
+	// maxProfileBody caps a PUT /profile body.
+	maxProfileBody = 4 << 10
+	// maxProfileText caps the profile's strings, in runes.
+	maxProfileText = 32
+)
+
+// errStoreFull means a profile store has no room for another key.
+var errStoreFull = errors.New("no room for another profile key")
+
+// profileStore is the server's profiles, by the hash of their key.
+type profileStore struct {
+	mu       sync.Mutex
+	path     string
+	profiles map[string]protocol.Profile
+}
+
+// loadProfiles reads the profiles saved at path. A missing file is no
+// profiles; an empty path keeps them in memory only.
+func loadProfiles(path string) (*profileStore, error) {
+	s := &profileStore{path: path, profiles: make(map[string]protocol.Profile)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.profiles); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// profileID is the hash a profile is stored under.
+func profileID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the profile for key.
+func (s *profileStore) get(key string) (protocol.Profile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.profiles[profileID(key)]
+	return p, ok
+}
+
+// put stores p as the profile for key, stamping it, and returns it as
+// stored. It returns errStoreFull for a new key there's no room for.
+func (s *profileStore) put(key string, p protocol.Profile) (protocol.Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	id := profileID(key)
+	if _, ok := s.profiles[id]; !ok && !makeRoom(s.profiles, func(p protocol.Profile) int64 { return p.UpdatedAt }, now) {
+		return p, errStoreFull
+	}
+	p.UpdatedAt = now.UnixMilli()
+	s.profiles[id] = p
+	return p, s.saveLocked()
+}
+
+// makeRoom makes room in a profile store's entries m, stamped by
+// updatedAt, for one more: below maxProfiles there is room, and at it
+// the least recently stored entry is dropped if it has gone unstored for
+// profileMaxIdle at now. It reports whether there's room.
+func makeRoom[V any](m map[string]V, updatedAt func(V) int64, now time.Time) bool {
+	if len(m) < maxProfiles {
+		return true
+	}
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	oldest := slices.MinFunc(ids, func(a, b string) int {
+		return cmp.Compare(updatedAt(m[a]), updatedAt(m[b]))
+	})
+	if now.Sub(time.UnixMilli(updatedAt(m[oldest]))) < profileMaxIdle {
+		return false
+	}
+	delete(m, oldest)
+	return true
+}
+
+// checkNewKey turns a request that would store something under a new
+// profile key away if its address has started too many lately,
+// reporting whether it did.
+func checkNewKey(hub *Hub, w http.ResponseWriter, r *http.Request) bool {
+	wait, ok := hub.newKeys.allow(remoteIP(r), time.Now(), newKeysPerIP)
+	if ok {
+		return false
+	}
+	writeRateLimited(w, wait, "too many new profile keys; try again later")
+	return true
+}
+
+// saveLocked writes the profiles to their file, if they have one, as
+// banList.saveLocked does. Must be called with s.mu held.
+func (s *profileStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.profiles)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".profiles-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// handleProfile returns (GET) or replaces (PUT) the profile for the
+// request's profile key.
+func handleProfile(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	key, ok := profileKey(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		p, ok := hub.profiles.get(key)
+		if !ok {
+			writeError(w, http.StatusNotFound, protocol.ErrCodeNotFound, "no profile for this key")
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+
+	case http.MethodPut:
+		var p protocol.Profile
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxProfileBody)).Decode(&p); err != nil {
+			writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "invalid request body")
+			return
+		}
+		p.Name = clipText(strings.TrimSpace(p.Name))
+		p.Lang = clipText(p.Lang)
+		p.Keymap = clipText(p.Keymap)
+		if _, ok := hub.profiles.get(key); !ok && checkNewKey(hub, w, r) {
+			return
+		}
+		p, err := hub.profiles.put(key, p)
+		if errors.Is(err, errStoreFull) {
+			writeError(w, http.StatusServiceUnavailable, protocol.ErrCodeServerFull, "no room for new profiles; try again later")
+			return
+		}
+		if err != nil {
+			log.Printf("Saving profiles: %v", err)
+			writeError(w, http.StatusInternalServerError, "", "profile stored but not saved")
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// profileKey is the profile key a request sends as its bearer token. If
+// it's missing or malformed, it answers the request and returns false.
+func profileKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || len(key) < minProfileKey || len(key) > maxProfileKey {
+		writeError(w, http.StatusUnauthorized, protocol.ErrCodeUnauthorized, "missing or malformed profile key")
+		return "", false
+	}
+	return key, true
+}
+
+// clipText cuts s to maxProfileText runes.
+func clipText(s string) string {
+	if runes := []rune(s); len(runes) > maxProfileText {
+		return string(runes[:maxProfileText])
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// --- Trusted proxies ---
+//
+// Behind a reverse proxy every request comes from the proxy's address,
+// so one ban, quarantine or creation limit would hit every player at
+// once. TRUSTED_PROXIES lists the proxies' addresses or CIDR ranges,
+// comma-separated. A request from one of them, or over a Unix socket,
+// which only a process on this machine can open, is taken to come from
+// the address the proxies put in X-Forwarded-For: the rightmost one that
+// isn't a trusted proxy itself. Anyone else's X-Forwarded-For is
+// ignored, as a client can write anything there.
+
+// trustedProxies are the ranges of the reverse proxies in front of the
+// server.
+type trustedProxies []netip.Prefix
+
+// parseTrustedProxies parses a comma-separated list of addresses and
+// CIDR ranges.
+func parseTrustedProxies(s string) (trustedProxies, error) {
+	var proxies trustedProxies
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", field, err)
+			}
+			addr = addr.Unmap()
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", field, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// trusts reports whether ip is a trusted proxy's address.
+func (t trustedProxies) trusts(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// handler passes requests on to next, from the address their proxies
+// forwarded them for when they came through a trusted one.
+func (t trustedProxies) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client := t.forwardedFor(r); client != "" {
+			r = r.Clone(r.Context())
+			r.RemoteAddr = net.JoinHostPort(client, "0")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedFor is the address a request through trusted proxies was
+// forwarded for, or "" if it didn't come through one or doesn't say.
+func (t trustedProxies) forwardedFor(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	unix := err != nil // a Unix socket's peer has no address
+	if !unix && !t.trusts(peer) {
+		return ""
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			return ""
+		}
+		if !t.trusts(hop) {
+			return canonicalIP(hop)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Quarantine ---
+//
+// A quarantined address is soft-banned: players from it can still play,
+// and aren't told, but quick play only matches them with each other, in
+// shadow rooms of their own that the room browser doesn't list, and
+// their results are left out of ranked match records. With CHEAT_ACTION
+// set to quarantine, a player whose snapshot fails its checks is
+// quarantined; operators review the list, and add or release addresses,
+// at /admin/quarantine. It's saved to quarantine.json (or
+// QUARANTINE_FILE) like the ban list.
+
+const defaultQuarantineFile = "quarantine.json"
+
+// quarantinePlayer quarantines the address p plays from for reason, unless
+// it's quarantined already.
+func (h *Hub) quarantinePlayer(p *Player, reason string) {
+	p.mu.Lock()
+	ip := p.ip
+	p.mu.Unlock()
+	if ip == "" || h.quarantine.banned(ip) {
+		return
+	}
+	if err := h.quarantine.add(protocol.Ban{IP: ip, Reason: reason, Since: time.Now().Unix()}); err != nil {
+		log.Printf("saving quarantine: %v", err)
+	}
+	log.Printf("Quarantined %s (%s)", ip, p.Name)
+}
+
+// recordedStandings are a match's standings as recorded: in a ranked
+// match, without the quarantined players, whose results don't count.
+// Must be called on the room's loop.
+func (r *Room) recordedStandings(standings []protocol.PlayerStanding) []protocol.PlayerStanding {
+	if r.roomType != protocol.RoomTypeRanked || r.quarantined == nil {
+		return standings
+	}
+	return slices.DeleteFunc(slices.Clone(standings), func(st protocol.PlayerStanding) bool {
+		p := r.players[st.PlayerID]
+		if p == nil {
+			return false
+		}
+		p.mu.Lock()
+		ip := p.ip
+		p.mu.Unlock()
+		return ip != "" && r.quarantined(ip)
+	})
+}
+
+// handleAdminQuarantine lists quarantined addresses (GET), quarantines an
+// address or a connected player's address (POST), or releases ?ip=
+// (DELETE).
+func handleAdminQuarantine(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, protocol.QuarantineResponse{Quarantined: hub.quarantine.list()})
+
+	case http.MethodPost:
+		var req protocol.BanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "invalid request body")
+			return
+		}
+		ip := requestedIP(hub, w, req)
+		if ip == "" {
+			return
+		}
+		entry := protocol.Ban{IP: ip, Reason: req.Reason, Since: time.Now().Unix()}
+		if err := hub.quarantine.add(entry); err != nil {
+			log.Printf("saving quarantine: %v", err)
+			writeError(w, http.StatusInternalServerError, "", "address quarantined but not saved")
+			return
+		}
+		log.Printf("Quarantined %s (%q)", ip, req.Reason)
+		writeJSON(w, http.StatusOK, entry)
+
+	case http.MethodDelete:
+		ip := canonicalIP(strings.TrimSpace(r.URL.Query().Get("ip")))
+		ok, err := hub.quarantine.remove(ip)
+		if err != nil {
+			log.Printf("saving quarantine: %v", err)
+			writeError(w, http.StatusInternalServerError, "", "address released but not saved")
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, protocol.ErrCodeNotFound, fmt.Sprintf("%q is not quarantined", ip))
+			return
+		}
+		log.Printf("Released %s from quarantine", ip)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
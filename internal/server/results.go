@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Results log ---
+//
+// With RESULTS_LOG set, every finished match is appended to that file as
+// a line of JSON, a protocol.MatchRecord: the room, who played and where
+// they placed, how long it took and the settings it was played with. It's
+// a lasting record for operators who'd rather not run a database. The file
+// is only ever appended to, and is reopened on SIGHUP so it can be rotated
+// by moving it aside.
+
+// resultsBuffer is how many matches can wait to be written before
+// finishing another one waits for the disk.
+const resultsBuffer = 256
+
+// resultsLog appends finished matches to a file. A nil *resultsLog
+// writes nothing.
+type resultsLog struct {
+	path string
+	recs chan protocol.MatchRecord
+	done chan struct{}
+
+	closeMu sync.RWMutex // held to add, so close waits for adds under way
+	closed  bool
+
+	mu sync.Mutex // guards f
+	f  *os.File
+}
+
+// openResultsLog opens the results log at path for appending, creating
+// it if need be. An empty path means no log, and returns nil.
+func openResultsLog(path string) (*resultsLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &resultsLog{
+		path: path,
+		recs: make(chan protocol.MatchRecord, resultsBuffer),
+		done: make(chan struct{}),
+		f:    f,
+	}
+	go l.run()
+	return l, nil
+}
+
+// add queues a finished match to be written. Matches finishing once the
+// log is closed are dropped.
+func (l *resultsLog) add(rec protocol.MatchRecord) {
+	if l == nil {
+		return
+	}
+	l.closeMu.RLock()
+	defer l.closeMu.RUnlock()
+	if !l.closed {
+		l.recs <- rec
+	}
+}
+
+func (l *resultsLog) run() {
+	defer close(l.done)
+	for rec := range l.recs {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			log.Printf("results log: %v", err)
+			continue
+		}
+		l.mu.Lock()
+		_, err = l.f.Write(append(data, '\n'))
+		l.mu.Unlock()
+		if err != nil {
+			log.Printf("results log: match %s not written: %v", rec.MatchID, err)
+		}
+	}
+}
+
+// reopen starts writing to a fresh file at the log's path, for after the
+// old one has been moved aside.
+func (l *resultsLog) reopen() {
+	if l == nil {
+		return
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		log.Printf("results log: reopening %s: %v", l.path, err)
+		return
+	}
+	l.mu.Lock()
+	old := l.f
+	l.f = f
+	l.mu.Unlock()
+	old.Close()
+}
+
+// close writes out the matches still queued and closes the file.
+func (l *resultsLog) close() {
+	if l == nil {
+		return
+	}
+	l.closeMu.Lock()
+	l.closed = true
+	close(l.recs)
+	l.closeMu.Unlock()
+	<-l.done
+	l.f.Close()
+}
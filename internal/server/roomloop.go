@@ -0,0 +1,236 @@
+package server
+
+import (
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Room loop ---
+//
+// Each room runs on its own goroutine, run, which owns the room's state:
+// who is in it and how they're doing, its phase, settings and timers. No
+// other goroutine touches that state. Connections, bots, timers, HTTP
+// handlers and the hub hand the room work as functions for do to run on
+// the loop, one at a time, so each sees the room as the one before it
+// left it: two players readying together start one countdown, a countdown
+// that has lost its players goes back to the lobby instead of starting a
+// match, and nothing has to think about which lock to take first. The
+// Room methods below are the ones to call from off the loop; the rest of
+// the Room's methods run on it.
+//
+// The loop never waits on the hub, or on another room, so the hub may
+// call into a room while holding Hub.mu. Player.mu still guards what a
+// player's connection shares with the room.
+
+// matchOverPause is how long the results stay up before the room goes
+// back to the lobby.
+const matchOverPause = 2 * time.Second
+
+// run handles the room's work until the room is removed.
+func (r *Room) run() {
+	for {
+		// Stopping is done on the loop, and wins over work waiting.
+		select {
+		case <-r.stopCh:
+			return
+		default:
+		}
+		select {
+		case fn := <-r.events:
+			fn()
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// do runs fn on the room's loop and waits for it, reporting false if the
+// room was removed first. It mustn't be called from the loop itself.
+func (r *Room) do(fn func()) bool {
+	done := make(chan struct{})
+	select {
+	case r.events <- func() { fn(); close(done) }:
+		<-done
+		return true
+	case <-r.stopCh:
+		return false
+	}
+}
+
+// after runs fn on the room's loop once d has passed, sped up as the
+// hub's timers are, unless the room has been removed by then.
+func (r *Room) after(d time.Duration, fn func()) {
+	time.AfterFunc(d/r.speed, func() { r.do(fn) })
+}
+
+// info describes the room for the room browser and the hub, reporting
+// false if it has been removed. Who is playing is only listed during a
+// countdown or match.
+func (r *Room) info() (protocol.RoomInfo, bool) {
+	info := protocol.RoomInfo{
+		RoomID: r.code,
+		Title:  r.title,
+		Type:   r.roomType,
+	}
+	ok := r.do(func() {
+		info.PlayerCount = len(r.players)
+		info.MaxPlayers = r.settings.MaxPlayers
+		info.Phase = r.phase.String()
+		info.LastActivity = r.lastActivity.UnixMilli()
+		if r.phase == PhaseCountdown || r.phase == PhasePlaying {
+			info.Players = r.playerNames()
+		}
+	})
+	return info, ok
+}
+
+// stopIfEmpty stops the room if no one is in it, reporting whether it
+// did. A stopped room's loop, broadcastLoop and bots all return, and do
+// runs nothing more on it.
+func (r *Room) stopIfEmpty() bool {
+	stopped := false
+	r.do(func() {
+		if len(r.players) == 0 {
+			close(r.stopCh)
+			stopped = true
+		}
+	})
+	return stopped
+}
+
+// seat puts p in the room, unless it has filled up since p's join was
+// checked. A player holding a seat after a drop keeps it.
+func (r *Room) seat(p *Player) bool {
+	seated := false
+	r.do(func() {
+		if r.players[p.ID] != p && len(r.players) >= r.settings.MaxPlayers {
+			return
+		}
+		if r.players[p.ID] != p {
+			r.addPlayer(p)
+		}
+		seated = true
+	})
+	return seated
+}
+
+// leave takes a player out of the room and tells the rest.
+func (r *Room) leave(id string) {
+	r.do(func() {
+		r.removePlayer(id)
+		r.lobbyChanged()
+	})
+}
+
+// releaseSeat removes p if they are still on connection conn, i.e. they
+// didn't reconnect during the grace period, and tells the rest.
+func (r *Room) releaseSeat(p *Player, conn int) bool {
+	released := false
+	r.do(func() {
+		p.mu.Lock()
+		stale := p.conns == conn
+		p.mu.Unlock()
+		if r.players[p.ID] == p && stale {
+			r.removePlayer(p.ID)
+			r.lobbyChanged()
+			released = true
+		}
+	})
+	return released
+}
+
+// setReady marks p ready or not, starting the countdown once everyone is.
+// Readiness only counts in the lobby.
+func (r *Room) setReady(p *Player, ready bool) {
+	r.do(func() {
+		if r.phase != PhaseLobby || r.players[p.ID] != p {
+			return
+		}
+		p.Ready = ready
+		r.touch()
+		r.broadcastLobbyUpdate()
+		if r.canStart() {
+			r.startCountdown()
+		} else {
+			r.refreshAutoStart()
+		}
+	})
+}
+
+// changeSettings applies the host's new settings and tells the room.
+func (r *Room) changeSettings(playerID string, s protocol.RoomSettings) error {
+	var err error
+	r.do(func() {
+		if err = r.updateSettings(playerID, s); err == nil {
+			r.touch()
+			r.broadcastLobbyUpdate()
+			r.refreshAutoStart()
+		}
+	})
+	return err
+}
+
+// lobbyChanged tells everyone who is in the room now, after a join or a
+// leave. A room left empty goes back to the lobby, as does a countdown
+// left without enough players to start.
+func (r *Room) lobbyChanged() {
+	r.touch()
+	if r.phase == PhaseCountdown && len(r.players) < minPlayers {
+		r.phase = PhaseLobby
+		r.countdownGen++
+	}
+	if len(r.players) == 0 {
+		r.resetToLobby()
+	}
+	r.broadcastLobbyUpdate()
+	r.refreshAutoStart()
+}
+
+// startCountdown counts the room down to a match, if it's in the lobby
+// with enough players.
+func (r *Room) startCountdown() {
+	// Both the last ready-up and the auto-start timer can get here.
+	if r.phase != PhaseLobby || len(r.players) < minPlayers {
+		return
+	}
+	r.phase = PhaseCountdown
+	r.touch()
+	r.countdown = 3
+	r.countdownGen++
+	r.stopAutoStart()
+	r.sendCountdown(r.countdownGen)
+}
+
+// sendCountdown tells everyone the count, and takes it down one a second
+// later.
+func (r *Room) sendCountdown(gen int) {
+	r.broadcastToAll(protocol.Envelope{
+		Type:    protocol.MsgCountdown,
+		Payload: protocol.CountdownPayload{Value: r.countdown},
+	})
+	r.after(time.Second, func() { r.countdownTick(gen) })
+}
+
+// countdownTick takes the count down one, starting the match once it
+// reaches 0, unless countdown gen has been called off.
+func (r *Room) countdownTick(gen int) {
+	if r.phase != PhaseCountdown || gen != r.countdownGen {
+		return
+	}
+	r.countdown--
+	if r.countdown == 0 {
+		r.startGame()
+		return
+	}
+	r.sendCountdown(gen)
+}
+
+// backToLobby ends the post-match pause.
+func (r *Room) backToLobby() {
+	if r.phase != PhaseGameOver {
+		return
+	}
+	r.resetToLobby()
+	r.broadcastLobbyUpdate()
+}
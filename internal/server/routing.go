@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// --- Room affinity ---
+//
+// Several instances can run behind one load balancer, each hosting its own
+// rooms. Every room code belongs to one instance, picked by hashing the
+// code, and each instance only hands out codes it owns. A /join-room or
+// /play request for a room that lands on the wrong instance is redirected
+// to the right one, with the owner's URL in instanceHeader for proxies
+// that would rather route the request themselves.
+
+// instanceHeader names the instance hosting the room a request was for.
+const instanceHeader = "X-Gotris-Instance"
+
+// instances is the set of instances rooms are spread over.
+type instances struct {
+	urls []string // every instance's base URL, in the same order on all of them
+	self int      // this instance's index in urls
+}
+
+// loadInstances reads the comma-separated instance URLs in list, of which
+// self is this instance's. An empty list means a single instance, which
+// owns every room, and returns nil.
+func loadInstances(list, self string) (*instances, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	in := &instances{}
+	for _, u := range strings.Split(list, ",") {
+		if u = strings.TrimSuffix(strings.TrimSpace(u), "/"); u != "" {
+			in.urls = append(in.urls, u)
+		}
+	}
+	in.self = slices.Index(in.urls, strings.TrimSuffix(strings.TrimSpace(self), "/"))
+	if in.self < 0 {
+		return nil, fmt.Errorf("INSTANCE_URL %q is not one of INSTANCES", self)
+	}
+	return in, nil
+}
+
+// owner is the index of the instance hosting room code: the FNV-1a hash
+// of the code, modulo the number of instances.
+func (in *instances) owner(code string) int {
+	h := fnv.New32a()
+	h.Write([]byte(code))
+	return int(h.Sum32() % uint32(len(in.urls)))
+}
+
+// owns reports whether this instance hosts room code.
+func (in *instances) owns(code string) bool {
+	return in == nil || in.owner(code) == in.self
+}
+
+// redirect sends a request for room code on to the instance hosting it,
+// reporting whether it did. Requests for this instance's rooms are left
+// alone.
+func (in *instances) redirect(w http.ResponseWriter, r *http.Request, code string) bool {
+	if in.owns(code) {
+		return false
+	}
+	url := in.urls[in.owner(code)]
+	w.Header().Set(instanceHeader, url)
+	http.Redirect(w, r, url+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	return true
+}
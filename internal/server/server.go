@@ -0,0 +1,2650 @@
+// Package server is the gotris game server: the room browser's HTTP API,
+// the WebSocket games are played over, and the rooms and matches behind
+// them. cmd/server runs it with Main; New runs one inside another
+// program, as pkg/servertest does for tests.
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/websocket"
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Configuration ---
+
+const (
+	defaultPort       = "8080"
+	defaultBansFile   = "bans.json"
+	broadcastInterval = 100 * time.Millisecond // default; see config
+	// opponentKeepalive is the longest a match goes without an opponent
+	// update, even when nothing has changed.
+	opponentKeepalive = time.Second
+	writeWait         = 10 * time.Second
+	pongWait          = 60 * time.Second
+	pingInterval      = (pongWait * 9) / 10
+	maxMessageSize    = 16384
+	minPlayers        = 2
+	maxPlayersPerRoom = 99
+	defaultMaxPlayers = 8
+	roomCodeLength    = 5
+	autoStartDelay    = 30 * time.Second
+	maxPieceDelayMs   = 1000 // longest entry or line clear delay a room may set
+	// reconnectGrace is how long a player who drops mid-match keeps their
+	// seat, waiting for them to reconnect.
+	reconnectGrace = 30 * time.Second
+	// lateMessage is how old (by the client's clock) a message can be on
+	// arrival before it's logged.
+	lateMessage = 2 * time.Second
+	// emoteCooldown is how often one player may fire an emote.
+	emoteCooldown = time.Second
+	// feedCombo is the shortest combo that makes the kill feed.
+	feedCombo = 3
+	// maxRoomTitle is the longest room title kept, in characters.
+	maxRoomTitle = 32
+	// fullBoardRoom is the most players a room can have and still send
+	// everyone every board. In bigger rooms each player gets the boards
+	// of their target, their last attacker and boardSample others, taken
+	// in turn, and only the stats of the rest.
+	fullBoardRoom = 16
+	boardSample   = 6
+	// fanOutBatch is the fewest recipients worth handing a worker of
+	// their own when sending opponent updates.
+	fanOutBatch = 8
+	// maxSeed is the longest seed a room may set, in characters.
+	maxSeed = 32
+	// maxListRooms is the most rooms one /list-rooms reply holds.
+	maxListRooms = 100
+	// batchWindow is how long a client with CapBatch may have a message
+	// held back while more are gathered into the same frame, and maxBatch
+	// the most messages in one frame.
+	batchWindow = 5 * time.Millisecond
+	maxBatch    = 32
+	// serverFullRetry is how long clients turned away by a full server
+	// are told to wait before trying again.
+	serverFullRetry = 30 * time.Second
+	// joinTokenTTL is how long a join token from /create-room, /join-room
+	// or quick play stays good for.
+	joinTokenTTL = 60 * time.Second
+)
+
+// serverCapabilities are the optional protocol features this server
+// supports, announced to each client in its AssignID.
+var serverCapabilities = []protocol.Capability{protocol.CapReconnect, protocol.CapChat, protocol.CapBatch}
+
+// parseCapabilities reads the caps query parameter of /play: the features
+// the client supports that this server does too. A client that doesn't
+// send the parameter predates capabilities.
+func parseCapabilities(r *http.Request) map[protocol.Capability]bool {
+	caps := make(map[protocol.Capability]bool)
+	if !r.URL.Query().Has("caps") {
+		for _, c := range protocol.LegacyCapabilities {
+			caps[c] = true
+		}
+		return caps
+	}
+	for _, c := range strings.Split(r.URL.Query().Get("caps"), ",") {
+		if slices.Contains(serverCapabilities, protocol.Capability(c)) {
+			caps[protocol.Capability(c)] = true
+		}
+	}
+	return caps
+}
+
+// --- Player (server-side) ---
+
+type Player struct {
+	ID       string
+	Name     string
+	Ready    bool
+	Alive    bool
+	Conn     *websocket.Conn
+	sendCh   chan []byte
+	roomID   string // the room they're in, "" for none (guarded by mu)
+	ip       string // address the player connected from (guarded by mu)
+	TargetID string // who this player wants to attack, "" = random (guarded by mu)
+	// Per-match stats for the final standings
+	KOs          int
+	lastAttacker string         // last player to send garbage here (guarded by mu)
+	sentTo       map[string]int // garbage lines sent to each player (guarded by mu)
+	placement    int            // final rank, set when knocked out (0 = still alive)
+	diedAt       time.Time      // when the player was knocked out
+	firstTetris  time.Duration  // how far into the match their first Tetris came (0 = none yet)
+	item         string         // the item the server gave them and they haven't used, in item mode
+	// Latest snapshot from this client
+	mu       sync.Mutex
+	Snapshot *protocol.BoardSnapshotPayload
+	conns    int    // connections so far; a resume starts a new one (guarded by mu)
+	sendSeq  uint64 // last Seq stamped on a message to this player (guarded by mu)
+	recvSeq  uint64 // highest Seq seen from this player's client (guarded by mu)
+	// version counts changes to what opponents see of this player: their
+	// snapshot, who they've attacked and whether they're alive (guarded
+	// by mu)
+	version uint64
+	// lastEmote is when the player last fired an emote (guarded by mu)
+	lastEmote time.Time
+	// caps are the optional features both the player's client and the
+	// server support (guarded by mu)
+	caps map[protocol.Capability]bool
+	// bot is set for CPU opponents, numbered botNum in their room
+	bot    *bot
+	botNum int
+	// backfill is the critical messages lately sent to the player, for
+	// a reconnect (guarded by mu)
+	backfill backfill
+	// check is what the player's next snapshot is checked against, and
+	// kicked is set once they're disconnected for failing a check
+	// (guarded by mu)
+	check  snapCheck
+	kicked bool
+}
+
+func newPlayer(id string, conn *websocket.Conn) *Player {
+	return &Player{
+		ID:    id,
+		Conn:  conn,
+		Alive: true,
+	}
+}
+
+// writePump sends messages from sendCh to conn. Each connection gets its
+// own pump, so a resumed player's old pump can't pick up new messages.
+func (p *Player) writePump(conn *websocket.Conn, sendCh chan []byte) {
+	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+	batch := p.supports(protocol.CapBatch)
+
+	for {
+		select {
+		case msg, ok := <-sendCh:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if batch {
+				open, err := writeBatch(conn, msg, sendCh)
+				if err != nil {
+					return
+				}
+				if !open {
+					conn.WriteMessage(websocket.CloseMessage, []byte{})
+					return
+				}
+				continue
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeBatch writes first along with whatever else arrives on sendCh
+// within batchWindow, as few frames as fit in maxMessageSize. It reports
+// whether sendCh is still open.
+func writeBatch(conn *websocket.Conn, first []byte, sendCh chan []byte) (bool, error) {
+	msgs, size := [][]byte{first}, len(first)
+	window := time.NewTimer(batchWindow)
+	defer window.Stop()
+
+	for len(msgs) < maxBatch {
+		select {
+		case msg, ok := <-sendCh:
+			if !ok {
+				return false, writeFrame(conn, msgs)
+			}
+			// +2 for the brackets, +1 per comma
+			if size+len(msg)+len(msgs)+2 > maxMessageSize {
+				if err := writeFrame(conn, msgs); err != nil {
+					return true, err
+				}
+				msgs, size = nil, 0
+			}
+			msgs = append(msgs, msg)
+			size += len(msg)
+		case <-window.C:
+			return true, writeFrame(conn, msgs)
+		}
+	}
+	return true, writeFrame(conn, msgs)
+}
+
+// writeFrame writes msgs in one frame: a lone message as it is, several
+// as a JSON array.
+func writeFrame(conn *websocket.Conn, msgs [][]byte) error {
+	if len(msgs) == 1 {
+		return conn.WriteMessage(websocket.TextMessage, msgs[0])
+	}
+	w, err := conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	w.Write([]byte{'['})
+	for i, msg := range msgs {
+		if i > 0 {
+			w.Write([]byte{','})
+		}
+		w.Write(msg)
+	}
+	w.Write([]byte{']'})
+	return w.Close()
+}
+
+// attach makes conn the player's connection, with a fresh send channel
+// and the capabilities its client supports, and returns the channel along
+// with the connection number. The channel starts out holding the critical
+// messages sent to the player after Seq since, which a reconnecting
+// client missed, with room for them all; complete is false if some are
+// no longer kept.
+func (p *Player) attach(conn *websocket.Conn, ip string, caps map[protocol.Capability]bool, since uint64) (sendCh chan []byte, connNum int, complete bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Conn = conn
+	p.ip = ip
+	p.caps = caps
+	var missed [][]byte
+	complete = true
+	if since > 0 {
+		missed, complete = p.backfill.since(since)
+	}
+	p.sendCh = make(chan []byte, 64+len(missed))
+	p.conns++
+	p.recvSeq = 0 // a restarted client counts from 1 again
+	for _, data := range missed {
+		p.queueLocked(data)
+	}
+	return p.sendCh, p.conns, complete
+}
+
+// roomCode returns the code of the room the player is in, or "" if
+// they're in none.
+func (p *Player) roomCode() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.roomID
+}
+
+// setRoom records the room the player is in, "" for none.
+func (p *Player) setRoom(code string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.roomID = code
+}
+
+// supports reports whether the player's client supports cap.
+func (p *Player) supports(cap protocol.Capability) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.caps[cap]
+}
+
+// send stamps an envelope with the player's next sequence number and the
+// time, marshals it and queues it. Critical messages are also kept for
+// backfill. It all happens under p.mu, so a reconnect's attach sees each
+// message either kept or queued on the new connection, never in between.
+func (p *Player) send(env protocol.Envelope) {
+	if p.bot != nil {
+		p.bot.deliver(env)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sendSeq++
+	env.Seq = p.sendSeq
+	env.TS = time.Now().UnixMilli()
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("marshal error for player %s: %v", p.ID, err)
+		return
+	}
+	if critical(env.Type) {
+		p.backfill.add(env.Seq, data)
+	}
+	p.queueLocked(data)
+}
+
+// queueLocked queues data for the player's connection, dropping it if
+// the connection is backed up. Must be called with p.mu held.
+func (p *Player) queueLocked(data []byte) {
+	// Recover from panic if sendCh was closed (player disconnected).
+	defer func() { recover() }()
+	select {
+	case p.sendCh <- data:
+	default:
+		log.Printf("send channel full for player %s, dropping message", p.ID)
+	}
+}
+
+// closeSend closes a send channel attach returned. It takes p.mu so the
+// close can't race a send from queueLocked, which holds it too.
+func (p *Player) closeSend(ch chan []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	close(ch)
+}
+
+// --- Room ---
+
+type RoomPhase int
+
+const (
+	PhaseLobby RoomPhase = iota
+	PhaseCountdown
+	PhasePlaying
+	PhaseGameOver
+)
+
+// String is the phase's name in RoomInfo.
+func (p RoomPhase) String() string {
+	switch p {
+	case PhaseCountdown:
+		return protocol.RoomPhaseCountdown
+	case PhasePlaying:
+		return protocol.RoomPhasePlaying
+	case PhaseGameOver:
+		return protocol.RoomPhaseGameOver
+	}
+	return protocol.RoomPhaseLobby
+}
+
+// Room is a game room. All but its fixed fields (code, title, roomType,
+// shadow, quarantined, createdAt) belong to the room's loop, and are got
+// at from elsewhere through do.
+type Room struct {
+	code      string
+	title     string // optional name shown in the room browser
+	roomType  string // protocol.RoomTypeCasual or RoomTypeRanked
+	createdAt time.Time
+	phase     RoomPhase
+	players   map[string]*Player
+	seed      int64
+	countdown int
+	winnerID  string
+	startedAt time.Time
+	stopCh    chan struct{}
+	events    chan func() // run one at a time by run
+	hostID    string      // player who can change settings
+	settings  protocol.RoomSettings
+	muted     map[string]bool // players whose emotes aren't passed on
+	nextBot   int             // number of the last bot added
+	points    map[string]int  // points play totals so far, by player
+	watchers  watchers        // event stream subscribers
+	config    *atomic.Pointer[config]
+	// onMatchOver, if set, is called with each finished match, on the
+	// room's loop
+	onMatchOver func(rec protocol.MatchRecord)
+	// shadow is set on the rooms quick play makes for quarantined
+	// players, and quarantined, if set, reports whether an address is
+	// quarantined
+	shadow      bool
+	quarantined func(ip string) bool
+
+	// Lobby auto-start timer
+	autoStartGen    int             // bumped to stop the running timer
+	autoStartLeft   time.Duration   // time left, 0 = not running
+	autoStartPaused bool            // held while a late joiner is unready
+	lateJoiners     map[string]bool // joined while the timer was running
+
+	// Match countdown
+	countdownGen int // bumped to call off the running countdown
+
+	// Speed race: the level everyone is on this match
+	level int
+
+	speed time.Duration // how many times faster timers run, see Hub
+
+	// Last time a player did something or the phase changed, for the
+	// watchdog and the room browser
+	lastActivity time.Time
+}
+
+func newRoom(code, title, roomType string) *Room {
+	now := time.Now()
+	return &Room{
+		code:        code,
+		title:       title,
+		roomType:    roomType,
+		createdAt:   now,
+		phase:       PhaseLobby,
+		players:     make(map[string]*Player),
+		stopCh:      make(chan struct{}),
+		events:      make(chan func()),
+		settings:    defaultRoomSettings(),
+		muted:       make(map[string]bool),
+		points:      make(map[string]int),
+		lateJoiners: make(map[string]bool),
+
+		lastActivity: now,
+		speed:        1,
+	}
+}
+
+// defaultRoomSettings returns the settings a new room starts with.
+func defaultRoomSettings() protocol.RoomSettings {
+	return protocol.RoomSettings{
+		MaxPlayers:   defaultMaxPlayers,
+		Targeting:    protocol.TargetingFree,
+		AttackTable:  "standard",
+		Randomizer:   game.RandomizerBag,
+		OnJoin:       protocol.OnJoinReset,
+		GarbageStyle: game.GarbageClean,
+		HoldMode:     game.HoldNormal,
+		// Only used once the host sets a points target
+		PlacementPoints: slices.Clone(defaultPlacementPoints),
+		KOPoints:        defaultKOPoints,
+	}
+}
+
+// roomError is a failed room operation, with the code clients get in the
+// MsgRoomError sent back.
+type roomError struct {
+	code protocol.ErrorCode
+	msg  string
+}
+
+func (e *roomError) Error() string { return e.msg }
+
+func newRoomError(code protocol.ErrorCode, format string, args ...interface{}) *roomError {
+	return &roomError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// roomErrorPayload describes err for a MsgRoomError.
+func roomErrorPayload(err error) protocol.RoomErrorPayload {
+	payload := protocol.RoomErrorPayload{Message: err.Error()}
+	if re, ok := err.(*roomError); ok {
+		payload.Code = re.code
+	}
+	return payload
+}
+
+// validateRoomSettings checks that every option is one the server supports.
+func validateRoomSettings(s protocol.RoomSettings) error {
+	if s.MaxPlayers < minPlayers || s.MaxPlayers > maxPlayersPerRoom {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "max players must be between %d and %d", minPlayers, maxPlayersPerRoom)
+	}
+	if s.Targeting != protocol.TargetingFree && s.Targeting != protocol.TargetingRandom {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "unknown targeting mode %q", s.Targeting)
+	}
+	if _, ok := game.AttackTables[s.AttackTable]; !ok {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "unknown attack table %q", s.AttackTable)
+	}
+	validRandomizer := false
+	for _, name := range game.Randomizers {
+		if s.Randomizer == name {
+			validRandomizer = true
+		}
+	}
+	if !validRandomizer {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "unknown randomizer %q", s.Randomizer)
+	}
+	if s.OnJoin != protocol.OnJoinReset && s.OnJoin != protocol.OnJoinPause {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "unknown on-join behaviour %q", s.OnJoin)
+	}
+	if s.GarbageStyle != "" && !slices.Contains(game.GarbageStyles, s.GarbageStyle) {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "unknown garbage style %q", s.GarbageStyle)
+	}
+	if s.HoldMode != "" && !slices.Contains(game.HoldModes, s.HoldMode) {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "unknown hold mode %q", s.HoldMode)
+	}
+	if len([]rune(s.Seed)) > maxSeed || strings.ContainsFunc(s.Seed, unicode.IsControl) {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "seed must be at most %d printable characters", maxSeed)
+	}
+	if s.Bots < 0 || s.Bots >= maxPlayersPerRoom {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "bots must be between 0 and %d", maxPlayersPerRoom-1)
+	}
+	if s.EntryDelayMs < 0 || s.EntryDelayMs > maxPieceDelayMs || s.LineClearDelayMs < 0 || s.LineClearDelayMs > maxPieceDelayMs {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "delays must be between 0 and %dms", maxPieceDelayMs)
+	}
+	if err := validateSpeedRace(s); err != nil {
+		return err
+	}
+	return validatePoints(s)
+}
+
+// updateSettings applies new settings on behalf of playerID. Only the host
+// may change settings, and only in the lobby. Everyone is un-readied so no
+// one starts a match under settings they haven't seen.
+func (r *Room) updateSettings(playerID string, s protocol.RoomSettings) error {
+	if playerID != r.hostID {
+		return newRoomError(protocol.ErrCodeNotHost, "only the host can change room settings")
+	}
+	if r.roomType == protocol.RoomTypeRanked {
+		return newRoomError(protocol.ErrCodeRankedRoom, "ranked rooms use fixed settings")
+	}
+	if r.phase != PhaseLobby {
+		return newRoomError(protocol.ErrCodeNotInLobby, "settings can only be changed in the lobby")
+	}
+	if err := validateRoomSettings(s); err != nil {
+		return err
+	}
+	if humans := r.humans(); s.MaxPlayers < humans+s.Bots {
+		return newRoomError(protocol.ErrCodeTooManyPlayers, "room has %d players, no seats for %d bots", humans, s.Bots)
+	}
+
+	// A new target starts a new series.
+	if s.PointsTarget != r.settings.PointsTarget {
+		clear(r.points)
+	}
+	r.settings = s
+	r.syncBots()
+	for _, p := range r.players {
+		p.Ready = p.bot != nil
+	}
+	return nil
+}
+
+func (r *Room) isFull() bool {
+	full := false
+	r.do(func() { full = len(r.players) >= r.settings.MaxPlayers })
+	return full
+}
+
+func (r *Room) addPlayer(p *Player) {
+	r.players[p.ID] = p
+	p.setRoom(r.code)
+	// Players arriving mid-match (or reconnecting) sit it out until the
+	// next round.
+	p.Alive = r.phase != PhasePlaying
+	if r.hostID == "" {
+		r.hostID = p.ID
+	}
+
+	if r.autoStartLeft > 0 {
+		if r.settings.OnJoin == protocol.OnJoinPause {
+			r.lateJoiners[p.ID] = true
+		} else {
+			r.autoStartLeft = autoStartDelay
+		}
+	}
+}
+
+func (r *Room) removePlayer(id string) {
+	if p, ok := r.players[id]; ok {
+		p.setRoom("")
+		delete(r.players, id)
+	}
+	delete(r.lateJoiners, id)
+	delete(r.muted, id)
+	delete(r.points, id)
+
+	// Bots don't stay in a room with no one to play
+	if r.humans() == 0 {
+		for pid, p := range r.players {
+			p.setRoom("")
+			delete(r.players, pid)
+		}
+	}
+
+	// Hand host over to the longest-connected remaining player
+	// (IDs embed the connect time, so the smallest ID is the oldest).
+	if r.hostID == id {
+		r.hostID = ""
+		for pid, p := range r.players {
+			if p.bot == nil && (r.hostID == "" || pid < r.hostID) {
+				r.hostID = pid
+			}
+		}
+	}
+
+	// If we're playing and a player leaves, mark them dead
+	if r.phase == PhasePlaying {
+		r.checkWinCondition()
+	}
+}
+
+// setMuted mutes or unmutes a player's emotes for the rest of the room,
+// and tells the room. Only the host may do it; byID "" is the server's
+// admin.
+func (r *Room) setMuted(byID, playerID string, muted bool) error {
+	var err error
+	r.do(func() {
+		if byID != "" && byID != r.hostID {
+			err = newRoomError(protocol.ErrCodeNotHost, "only the host can mute players")
+			return
+		}
+		if _, ok := r.players[playerID]; !ok {
+			err = newRoomError(protocol.ErrCodeBadRequest, "no player %q in room %s", playerID, r.code)
+			return
+		}
+		if muted {
+			r.muted[playerID] = true
+		} else {
+			delete(r.muted, playerID)
+		}
+		r.broadcastLobbyUpdate()
+	})
+	return err
+}
+
+// holdSeat reports whether a player whose connection dropped should keep
+// their place for reconnectGrace: only while a match is counting down or
+// being played.
+func (r *Room) holdSeat() bool {
+	hold := false
+	r.do(func() { hold = r.phase == PhaseCountdown || r.phase == PhasePlaying })
+	return hold
+}
+
+// seated returns the player with this ID if they are still in the room
+// (e.g. holding a seat after a drop), or nil.
+func (r *Room) seated(id string) *Player {
+	var p *Player
+	r.do(func() { p = r.players[id] })
+	return p
+}
+
+func (r *Room) playerCount() int {
+	n := 0
+	r.do(func() { n = len(r.players) })
+	return n
+}
+
+// playerNames lists the players' names in the order they joined.
+func (r *Room) playerNames() []string {
+	players := slices.Collect(maps.Values(r.players))
+	slices.SortFunc(players, func(a, b *Player) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	names := make([]string, len(players))
+	for i, p := range players {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func (r *Room) broadcastLobbyUpdate() {
+	env := protocol.Envelope{Type: protocol.MsgLobbyUpdate, Payload: r.lobbyUpdate()}
+	for _, p := range r.players {
+		p.send(env)
+	}
+	r.watchers.publish(env)
+}
+
+// lobbyUpdate is the room's lobby as a LobbyUpdate.
+func (r *Room) lobbyUpdate() protocol.LobbyUpdatePayload {
+	var players []protocol.LobbyPlayer
+	for _, p := range r.players {
+		players = append(players, protocol.LobbyPlayer{
+			PlayerID: p.ID,
+			Name:     p.Name,
+			Ready:    p.Ready,
+			Muted:    r.muted[p.ID],
+			Bot:      p.bot != nil,
+		})
+	}
+	// In the order they joined (IDs embed the connect time), so the list
+	// doesn't shuffle between updates, with bots last.
+	slices.SortFunc(players, func(a, b protocol.LobbyPlayer) int {
+		if a.Bot != b.Bot {
+			if a.Bot {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.PlayerID, b.PlayerID)
+	})
+
+	return protocol.LobbyUpdatePayload{
+		Players:  players,
+		HostID:   r.hostID,
+		Settings: r.settings,
+		RoomType: r.roomType,
+	}
+}
+
+func (r *Room) canStart() bool {
+	if len(r.players) < minPlayers {
+		return false
+	}
+	for _, p := range r.players {
+		if !p.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// refreshAutoStart starts, pauses or stops the lobby auto-start timer to
+// match who is ready, then tells everyone where it stands. The timer runs
+// while at least minPlayers are ready but someone is still holding out;
+// once everyone is ready the normal countdown takes over.
+func (r *Room) refreshAutoStart() {
+	readyCount, allReady := 0, true
+	for _, p := range r.players {
+		if p.bot != nil {
+			continue // always ready, so they don't count towards starting
+		}
+		if p.Ready {
+			readyCount++
+		} else {
+			allReady = false
+		}
+	}
+	wanted := r.phase == PhaseLobby && readyCount >= minPlayers && !allReady
+
+	switch {
+	case !wanted && r.autoStartLeft == 0:
+		return
+	case !wanted:
+		r.stopAutoStart()
+	case r.autoStartLeft == 0:
+		r.autoStartLeft = autoStartDelay
+		r.autoStartGen++
+		gen := r.autoStartGen
+		r.after(time.Second, func() { r.autoStartTick(gen) })
+	}
+
+	r.autoStartPaused = false
+	for id := range r.lateJoiners {
+		if p, ok := r.players[id]; ok && !p.Ready {
+			r.autoStartPaused = true
+		}
+	}
+	r.broadcastToAll(protocol.Envelope{Type: protocol.MsgAutoStart, Payload: r.autoStartPayload()})
+}
+
+// autoStartTick takes a second off the auto-start timer, and does so
+// again a second later, until it is stopped (gen changes) or runs out, in
+// which case the match starts with everyone in the room, ready or not.
+func (r *Room) autoStartTick(gen int) {
+	if gen != r.autoStartGen || r.phase != PhaseLobby {
+		return
+	}
+	if !r.autoStartPaused {
+		r.autoStartLeft -= time.Second
+	}
+	if r.autoStartLeft <= 0 {
+		r.stopAutoStart()
+		log.Printf("Room %s: auto-start timer expired", r.code)
+		r.startCountdown()
+		return
+	}
+	r.broadcastToAll(protocol.Envelope{Type: protocol.MsgAutoStart, Payload: r.autoStartPayload()})
+	r.after(time.Second, func() { r.autoStartTick(gen) })
+}
+
+// stopAutoStart cancels the auto-start timer.
+func (r *Room) stopAutoStart() {
+	r.autoStartGen++
+	r.autoStartLeft = 0
+	r.autoStartPaused = false
+	r.lateJoiners = make(map[string]bool)
+}
+
+// autoStartPayload describes the timer for clients.
+func (r *Room) autoStartPayload() protocol.AutoStartPayload {
+	return protocol.AutoStartPayload{
+		Seconds: int((r.autoStartLeft + time.Second - 1) / time.Second),
+		Paused:  r.autoStartPaused,
+	}
+}
+
+func (r *Room) startGame() {
+	r.phase = PhasePlaying
+	r.touch()
+	r.seed = rand.Int63()
+	if r.settings.Seed != "" {
+		r.seed = game.SeedFromText(r.settings.Seed)
+	}
+	r.winnerID = ""
+	r.startedAt = time.Now()
+
+	var playerIDs []string
+	for id, p := range r.players {
+		playerIDs = append(playerIDs, id)
+		p.Alive = true
+		p.Ready = false
+		p.KOs = 0
+		p.placement = 0
+		p.diedAt = time.Time{}
+		p.firstTetris = 0
+		p.item = ""
+		p.mu.Lock()
+		p.Snapshot = nil
+		p.check = snapCheck{}
+		p.lastAttacker = ""
+		p.sentTo = make(map[string]int)
+		p.version++
+		p.mu.Unlock()
+	}
+	r.startBots(r.seed)
+
+	r.broadcastToAll(protocol.Envelope{
+		Type: protocol.MsgGameStart,
+		Payload: protocol.GameStartPayload{
+			Seed:     r.seed,
+			Players:  playerIDs,
+			Settings: r.settings,
+		},
+	})
+	r.startSpeedRace()
+
+	// Start the broadcast loop
+	go r.broadcastLoop()
+}
+
+// broadcastLoop sends OpponentUpdate to all players every broadcast
+// interval in which someone's state changed, and at least every
+// opponentKeepalive. A reloaded interval takes effect from the next tick.
+// It runs beside the room's loop, sending the updates from it.
+func (r *Room) broadcastLoop() {
+	interval := r.config.Load().broadcastInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	versions := make(map[string]uint64) // each player's version last sent
+	var sentAt time.Time
+	round := 0
+
+	for {
+		select {
+		case <-ticker.C:
+			if d := r.config.Load().broadcastInterval(); d != interval {
+				interval = d
+				ticker.Reset(interval)
+			}
+			playing := false
+			r.do(func() {
+				if playing = r.phase == PhasePlaying; !playing {
+					return
+				}
+				if r.sendOpponentUpdates(versions, time.Since(sentAt) >= opponentKeepalive, round) {
+					sentAt = time.Now()
+					round++
+				}
+			})
+			if !playing {
+				return
+			}
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// sendOpponentUpdates builds and sends each player their opponents' states,
+// unless no one's version has moved on from versions (and the room's
+// players are the same), or force is set. It records the versions sent in
+// versions, and reports whether it sent anything. round counts the updates
+// sent so far, and picks the sample of boards a large room sends.
+func (r *Room) sendOpponentUpdates(versions map[string]uint64, force bool, round int) bool {
+	changed := force || len(versions) != len(r.players)
+	current := make(map[string]uint64, len(r.players))
+	for _, p := range r.players {
+		p.mu.Lock()
+		current[p.ID] = p.version
+		p.mu.Unlock()
+		if v, ok := versions[p.ID]; !ok || v != current[p.ID] {
+			changed = true
+		}
+	}
+	if !changed {
+		return false
+	}
+	clear(versions)
+	maps.Copy(versions, current)
+
+	// Collect all snapshots and attack tallies
+	allStates := make(map[string]protocol.OpponentState)
+	sentTo := make(map[string]map[string]int)
+	remaining := 0
+	for _, p := range r.players {
+		p.mu.Lock()
+		snap := p.Snapshot
+		tally := make(map[string]int, len(p.sentTo))
+		for id, n := range p.sentTo {
+			tally[id] = n
+		}
+		p.mu.Unlock()
+		sentTo[p.ID] = tally
+
+		state := protocol.OpponentState{
+			PlayerID:   p.ID,
+			PlayerName: p.Name,
+			Alive:      p.Alive,
+		}
+		if snap != nil {
+			state.Score = snap.Score
+			state.Level = snap.Level
+			state.Lines = snap.Lines
+			state.Board = snap.Board
+			state.Alive = snap.Alive
+		}
+		if state.Alive {
+			remaining++
+		}
+		allStates[p.ID] = state
+	}
+
+	// Marshal each state once: recipients' copies differ only in the
+	// garbage tallies, which are spliced in. A large room also needs each
+	// state without its board.
+	large := len(r.players) > fullBoardRoom
+	ids := slices.Sorted(maps.Keys(allStates))
+	encoded := make(map[string][]byte, len(ids))
+	statsOnly := make(map[string][]byte)
+	var alive []string
+	for _, id := range ids {
+		state := allStates[id]
+		data, err := json.Marshal(state)
+		if err != nil {
+			log.Printf("marshal error for player %s's state: %v", id, err)
+			continue
+		}
+		encoded[id] = data
+		if large {
+			state.Board = nil
+			statsOnly[id], _ = json.Marshal(state)
+			if state.Alive {
+				alive = append(alive, id)
+			}
+		}
+	}
+
+	// Everyone in a large room gets the same sample of boards, moving on
+	// through the players still alive each round.
+	sample := make(map[string]bool, boardSample)
+	for i := range min(boardSample, len(alive)) {
+		sample[alive[(round*boardSample+i)%len(alive)]] = true
+	}
+
+	var recipients []*Player
+	for _, p := range r.players {
+		if p.bot == nil { // bots don't look at the other boards
+			recipients = append(recipients, p)
+		}
+	}
+
+	// Send each player everyone else's state (sorted by ID for stable order)
+	fanOut(recipients, func(p *Player) {
+		p.mu.Lock()
+		target, attacker := p.TargetID, p.lastAttacker
+		p.mu.Unlock()
+
+		var buf bytes.Buffer
+		buf.WriteString(`{"opponents":[`)
+		first := true
+		for _, id := range ids {
+			if id == p.ID || encoded[id] == nil {
+				continue
+			}
+			state := encoded[id]
+			if large && !sample[id] && id != target && id != attacker {
+				state = statsOnly[id]
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.Write(withTallies(state, sentTo[id][p.ID], sentTo[p.ID][id]))
+		}
+		fmt.Fprintf(&buf, `],"remaining":%d}`, remaining)
+		p.send(protocol.Envelope{
+			Type:    protocol.MsgOpponentUpdate,
+			Payload: json.RawMessage(buf.Bytes()),
+		})
+	})
+	return true
+}
+
+// fanOut calls send for each of players, spread over a pool of up to
+// GOMAXPROCS workers once there are enough players to share out.
+func fanOut(players []*Player, send func(*Player)) {
+	workers := min(runtime.GOMAXPROCS(0), len(players)/fanOutBatch)
+	if workers <= 1 {
+		for _, p := range players {
+			send(p)
+		}
+		return
+	}
+	next := make(chan *Player)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for p := range next {
+				send(p)
+			}
+		})
+	}
+	for _, p := range players {
+		next <- p
+	}
+	close(next)
+	wg.Wait()
+}
+
+// zeroTallies is how the garbage tallies read in an OpponentState
+// marshaled with both at zero. Strings in the state are escaped, so a
+// player's name can't make this appear anywhere else.
+var zeroTallies = []byte(`"sent_to_you":0,"you_sent":0`)
+
+// withTallies fills the garbage traded with one recipient into a state
+// marshaled with zero tallies.
+func withTallies(state []byte, sentToYou, youSent int) []byte {
+	if sentToYou == 0 && youSent == 0 {
+		return state
+	}
+	return bytes.Replace(state, zeroTallies, fmt.Appendf(nil, `"sent_to_you":%d,"you_sent":%d`, sentToYou, youSent), 1)
+}
+
+// broadcastToAll sends env to every player, and the room's watchers.
+func (r *Room) broadcastToAll(env protocol.Envelope) {
+	for _, p := range r.players {
+		p.send(env)
+	}
+	r.watchers.publish(env)
+}
+
+// handleLinesCleared works out the garbage a clear sends and routes it to
+// the attacker's target.
+func (r *Room) handleLinesCleared(attackerID string, payload protocol.LinesClearedPayload) {
+	if payload.ClearType != "" && protocol.ClearTypeLines(payload.ClearType) != payload.Count {
+		log.Printf("Player %s sent a %q clear of %d lines, ignoring", attackerID, payload.ClearType, payload.Count)
+		return
+	}
+
+	// The attack is worked out from the clear, whatever the client says
+	// it's worth. Clients too old to say what they cleared are taken at
+	// their word up to the most a clear of that many lines could send, and
+	// not at all in ranked rooms.
+	attack, ok := expectedAttack(r.settings, payload)
+	switch {
+	case ok && attack != payload.AttackPower:
+		log.Printf("Player %s sent %d lines for a %q clear worth %d in room %s", attackerID, payload.AttackPower, payload.ClearType, attack, r.code)
+	case !ok && r.roomType == protocol.RoomTypeRanked:
+		log.Printf("Player %s sent %d lines without a clear type in ranked room %s, ignoring", attackerID, payload.AttackPower, r.code)
+		return
+	case !ok:
+		attack = min(payload.AttackPower, gameRules(r.settings).Attack(payload.Count, true))
+	}
+
+	attacker := r.players[attackerID]
+	if attacker == nil {
+		return
+	}
+	r.maybeGiveItem(attacker, payload.Count)
+	if attack <= 0 {
+		return
+	}
+	payload.AttackPower = attack
+	if payload.Count == 4 && attacker.firstTetris == 0 {
+		attacker.firstTetris = time.Since(r.startedAt)
+	}
+
+	if kind := attackEventKind(payload); kind != "" {
+		r.sendEvent(protocol.MatchEventPayload{
+			Kind:       kind,
+			PlayerID:   attacker.ID,
+			PlayerName: attacker.Name,
+			ClearType:  payload.ClearType,
+			Combo:      payload.Combo,
+			B2B:        payload.B2B,
+		})
+	}
+
+	target := r.pickTarget(attacker)
+	if target != nil {
+		targetID := target.ID
+		target.mu.Lock()
+		target.lastAttacker = attackerID
+		target.check.garbage += payload.AttackPower * garbageCells
+		target.mu.Unlock()
+		attacker.mu.Lock()
+		if attacker.sentTo == nil {
+			attacker.sentTo = make(map[string]int)
+		}
+		attacker.sentTo[targetID] += payload.AttackPower
+		attacker.version++
+		attacker.mu.Unlock()
+		target.send(protocol.Envelope{
+			Type: protocol.MsgReceiveGarbage,
+			Payload: protocol.ReceiveGarbagePayload{
+				Lines:      payload.AttackPower,
+				AttackerID: attackerID,
+			},
+		})
+		r.watchers.publish(protocol.Envelope{
+			Type: protocol.MsgAttack,
+			Payload: protocol.AttackPayload{
+				AttackerID:   attackerID,
+				AttackerName: attacker.Name,
+				TargetID:     targetID,
+				TargetName:   target.Name,
+				Lines:        payload.AttackPower,
+			},
+		})
+	}
+}
+
+// pickTarget chooses who attacker's attacks and items go to: their
+// stored target if it's alive and the room lets players choose, else a
+// random alive opponent. It returns nil if nobody is left.
+func (r *Room) pickTarget(attacker *Player) *Player {
+	attacker.mu.Lock()
+	targetID := attacker.TargetID
+	attacker.mu.Unlock()
+	if r.settings.Targeting == protocol.TargetingRandom {
+		targetID = ""
+	}
+	if targetID != "" {
+		if t, ok := r.players[targetID]; !ok || !t.Alive || targetID == attacker.ID {
+			targetID = "" // target invalid, fall back to random
+		}
+	}
+
+	if targetID == "" {
+		// Pick a random alive opponent
+		var candidates []string
+		for id, p := range r.players {
+			if id != attacker.ID && p.Alive {
+				candidates = append(candidates, id)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+		targetID = candidates[rand.Intn(len(candidates))]
+	}
+	return r.players[targetID]
+}
+
+// expectedAttack is the garbage the clear p reports sends under settings.
+// It reports false for clients too old to say what they cleared.
+func expectedAttack(settings protocol.RoomSettings, p protocol.LinesClearedPayload) (int, bool) {
+	if p.ClearType == "" {
+		return 0, false
+	}
+	tspin := p.ClearType == protocol.ClearTypeFor(p.Count, true)
+	return gameRules(settings).Attack(p.Count, p.Spin || tspin), true
+}
+
+// maybeGiveItem gives p an item for clearing lines, in rooms with item
+// mode on, if they hold none: the chance per line is game.ItemChance, as
+// in a game on its own. Items are handed out here rather than by the
+// client's game, so a client can only fire the ones it has been given.
+func (r *Room) maybeGiveItem(p *Player, lines int) {
+	if !r.settings.Items || r.phase != PhasePlaying || !p.Alive || p.item != "" {
+		return
+	}
+	for range lines {
+		if rand.Float64() < game.ItemChance {
+			p.item = game.Items[rand.Intn(len(game.Items))]
+			p.send(protocol.Envelope{
+				Type:    protocol.MsgItemGranted,
+				Payload: protocol.ItemGrantedPayload{Item: p.item},
+			})
+			return
+		}
+	}
+}
+
+// handleUseItem fires a targeted item at the player's target, in rooms
+// with item mode on, if it's the item the player holds. Items used on the
+// player's own board only make the kill feed.
+func (r *Room) handleUseItem(p *Player, item string) {
+	if !r.settings.Items || r.phase != PhasePlaying || !p.Alive {
+		return
+	}
+	if item == "" || item != p.item {
+		log.Printf("Player %s (%s) used a %q item they weren't given in room %s, ignoring", p.Name, p.ID, item, r.code)
+		return
+	}
+	p.item = ""
+	ev := protocol.MatchEventPayload{
+		Kind:       protocol.EventItem,
+		PlayerID:   p.ID,
+		PlayerName: p.Name,
+		Item:       item,
+	}
+	if game.IsTargeted(item) {
+		target := r.pickTarget(p)
+		if target == nil {
+			return
+		}
+		target.send(protocol.Envelope{
+			Type:    protocol.MsgItemEffect,
+			Payload: protocol.ItemEffectPayload{Item: item, FromID: p.ID},
+		})
+		ev.ByID, ev.ByName = target.ID, target.Name
+	}
+	r.sendEvent(ev)
+}
+
+// attackEventKind is the kill feed event an attack makes, if any: a
+// Tetris, or a T-spin, back-to-back or long combo.
+func attackEventKind(p protocol.LinesClearedPayload) string {
+	switch {
+	case p.Count == 4:
+		return protocol.EventTetris
+	case protocol.ClearTypeLines(p.ClearType) > 0 && p.ClearType != protocol.ClearTypeFor(p.Count, false),
+		p.B2B, p.Combo >= feedCombo:
+		return protocol.EventAttack
+	}
+	return ""
+}
+
+// handleEmote passes a player's emote on to everyone in the room. Unknown
+// emotes, emotes fired faster than emoteCooldown and emotes from a muted
+// player are ignored.
+func (r *Room) handleEmote(p *Player, emote string) {
+	if !slices.Contains(protocol.Emotes, emote) {
+		return
+	}
+	p.mu.Lock()
+	if time.Since(p.lastEmote) < emoteCooldown {
+		p.mu.Unlock()
+		return
+	}
+	p.lastEmote = time.Now()
+	p.mu.Unlock()
+
+	env := protocol.Envelope{
+		Type:    protocol.MsgEmote,
+		Payload: protocol.EmotePayload{Emote: emote, PlayerID: p.ID},
+	}
+	if r.muted[p.ID] {
+		return
+	}
+	for _, other := range r.players {
+		if other.supports(protocol.CapChat) {
+			other.send(env)
+		}
+	}
+}
+
+// handlePlayerDead marks a player as dead and checks for a winner.
+func (r *Room) handlePlayerDead(playerID string) {
+	p, ok := r.players[playerID]
+	if !ok || !p.Alive || r.phase != PhasePlaying {
+		return
+	}
+	p.Alive = false
+	p.diedAt = time.Now()
+	p.placement = r.countAlive() + 1
+	r.touch()
+
+	// Credit the KO to whoever last sent garbage to this player.
+	p.mu.Lock()
+	attackerID := p.lastAttacker
+	p.version++
+	p.mu.Unlock()
+	event := protocol.MatchEventPayload{Kind: protocol.EventOut, PlayerID: p.ID, PlayerName: p.Name}
+	if a, ok := r.players[attackerID]; ok && attackerID != playerID {
+		a.KOs++
+		event.Kind = protocol.EventKO
+		event.ByID = a.ID
+		event.ByName = a.Name
+	}
+	r.sendEvent(event)
+
+	r.checkWinCondition()
+}
+
+// sendEvent broadcasts a kill-feed event.
+func (r *Room) sendEvent(ev protocol.MatchEventPayload) {
+	env := protocol.Envelope{Type: protocol.MsgMatchEvent, Payload: ev}
+	for _, p := range r.players {
+		p.send(env)
+	}
+	r.watchers.publish(env)
+}
+
+// countAlive counts the players still in the match.
+func (r *Room) countAlive() int {
+	n := 0
+	for _, p := range r.players {
+		if p.Alive {
+			n++
+		}
+	}
+	return n
+}
+
+// checkWinCondition ends the match once at most one player is left
+// standing.
+func (r *Room) checkWinCondition() {
+	var alive []*Player
+	for _, p := range r.players {
+		if p.Alive {
+			alive = append(alive, p)
+		}
+	}
+	if len(alive) > 1 && !slices.ContainsFunc(alive, func(p *Player) bool { return p.bot == nil }) {
+		alive = r.finishBots(alive)
+	}
+
+	if len(alive) <= 1 && len(r.players) >= minPlayers {
+		r.endMatch(alive)
+	}
+}
+
+// endMatch ends the match with alive, if it's one player, as the winner:
+// everyone is sent the standings and the room goes back to the lobby
+// after matchOverPause.
+func (r *Room) endMatch(alive []*Player) {
+	r.phase = PhaseGameOver
+	r.touch()
+	winnerID := ""
+	winnerName := ""
+	if len(alive) == 1 {
+		winnerID = alive[0].ID
+		winnerName = alive[0].Name
+		r.winnerID = winnerID
+	}
+
+	standings := r.buildStandings(winnerID)
+	champion := r.awardPoints(standings)
+	championID, championName := "", ""
+	if champion != nil {
+		championID, championName = champion.PlayerID, champion.PlayerName
+		log.Printf("Room %s: %s wins the series with %d points", r.code, championName, champion.TotalPoints)
+	}
+	if r.onMatchOver != nil {
+		recorded := r.recordedStandings(standings)
+		recordedWinner := winnerID
+		if !slices.ContainsFunc(recorded, func(st protocol.PlayerStanding) bool { return st.PlayerID == winnerID }) {
+			recordedWinner = ""
+		}
+		r.onMatchOver(protocol.MatchRecord{
+			RoomID:     r.code,
+			RoomTitle:  r.title,
+			RoomType:   r.roomType,
+			StartedAt:  r.startedAt.UnixMilli(),
+			DurationMs: time.Since(r.startedAt).Milliseconds(),
+			WinnerID:   recordedWinner,
+			Settings:   r.settings,
+			Standings:  recorded,
+		})
+	}
+	result := protocol.MatchOverPayload{
+		WinnerID:   winnerID,
+		WinnerName: winnerName,
+		Standings:  standings,
+
+		PointsTarget: r.settings.PointsTarget,
+		ChampionID:   championID,
+		ChampionName: championName,
+		Awards:       matchAwards(standings, winnerID),
+	}
+	for _, p := range r.players {
+		result.YourRank = len(r.players)
+		for _, st := range standings {
+			if st.PlayerID == p.ID {
+				result.YourRank = st.Rank
+				break
+			}
+		}
+		p.send(protocol.Envelope{Type: protocol.MsgMatchOver, Payload: result})
+	}
+	// Watchers have no rank of their own.
+	result.YourRank = 0
+	r.watchers.publish(protocol.Envelope{Type: protocol.MsgMatchOver, Payload: result})
+
+	// Reset for next round
+	r.after(matchOverPause, r.backToLobby)
+}
+
+// buildStandings returns the final standings sorted by rank.
+// The winner ranks first; everyone else keeps the placement they had
+// when knocked out.
+func (r *Room) buildStandings(winnerID string) []protocol.PlayerStanding {
+	now := time.Now()
+	standings := make([]protocol.PlayerStanding, 0, len(r.players))
+	for _, p := range r.players {
+		rank := p.placement
+		end := p.diedAt
+		if p.ID == winnerID || rank == 0 {
+			end = now
+		}
+		if p.ID == winnerID {
+			rank = 1
+		} else if rank == 0 {
+			rank = len(r.players)
+		}
+
+		st := protocol.PlayerStanding{
+			PlayerID:   p.ID,
+			PlayerName: p.Name,
+			Rank:       rank,
+			KOs:        p.KOs,
+			SurvivalMs: end.Sub(r.startedAt).Milliseconds(),
+
+			FirstTetrisMs: p.firstTetris.Milliseconds(),
+		}
+		p.mu.Lock()
+		if p.Snapshot != nil {
+			st.Score = p.Snapshot.Score
+			st.Lines = p.Snapshot.Lines
+		}
+		for _, n := range p.sentTo {
+			st.Sent += n
+		}
+		p.mu.Unlock()
+		standings = append(standings, st)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Rank != standings[j].Rank {
+			return standings[i].Rank < standings[j].Rank
+		}
+		return standings[i].Score > standings[j].Score
+	})
+	return standings
+}
+
+// resetToLobby puts the room back in the lobby, with everyone alive and
+// only the bots ready.
+func (r *Room) resetToLobby() {
+	r.phase = PhaseLobby
+	r.touch()
+	for _, p := range r.players {
+		p.Ready = p.bot != nil
+		p.Alive = true
+	}
+}
+
+// --- Hub ---
+
+// PendingJoin tracks a player who created/joined a room via HTTP
+// and is expected to connect via WebSocket with the given token.
+type PendingJoin struct {
+	RoomCode   string
+	PlayerName string
+	PlayerID   string
+	CreatedAt  time.Time
+}
+
+type Hub struct {
+	mu           sync.RWMutex
+	rooms        map[string]*Room        // code -> Room
+	players      map[string]*Player      // playerID -> Player
+	pendingJoins map[string]*PendingJoin // token -> PendingJoin
+	nextID       int
+
+	// Capacity: the most rooms and open WebSocket connections at once,
+	// 0 = no limit.
+	maxRooms int
+	maxConns int
+	conns    int
+
+	bans      *banList
+	config    atomic.Pointer[config]
+	creations *creationLimiter
+	newKeys   *creationLimiter // new profile keys, see profiles.go
+	history   *matchHistory
+	results   *resultsLog // nil = no results log
+	profiles  *profileStore
+	invites   *inviteStore
+	stats     *serverStats
+	// achievements are kept under profile keys, see achievements.go
+	achievements *achievementStore
+	// quarantine is the quarantined addresses, see quarantine.go
+	quarantine *banList
+	// instances, when running several, says which hosts each room (nil =
+	// this one hosts them all)
+	instances *instances
+	// cheatAction is what's done about impossible snapshots, one of
+	// cheatActions
+	cheatAction string
+	upgrader    websocket.Upgrader
+	// speed is how many times faster than normal the rooms' timers run,
+	// 1 but for tests
+	speed time.Duration
+	done  chan struct{} // closed to stop the watchdog
+}
+
+func newHub(maxRooms, maxConns int, bans *banList, instances *instances) *Hub {
+	h := &Hub{
+		rooms:        make(map[string]*Room),
+		players:      make(map[string]*Player),
+		pendingJoins: make(map[string]*PendingJoin),
+		maxRooms:     maxRooms,
+		maxConns:     maxConns,
+		bans:         bans,
+		quarantine:   &banList{bans: make(map[string]protocol.Ban)},
+		instances:    instances,
+		creations:    newCreationLimiter(createWindow),
+		newKeys:      newCreationLimiter(newKeyWindow),
+		history:      newMatchHistory(),
+		profiles:     &profileStore{profiles: make(map[string]protocol.Profile)},
+		invites:      newInviteStore(),
+		achievements: &achievementStore{players: make(map[string]protocol.Achievements)},
+		stats:        newServerStats(),
+		speed:        1,
+		done:         make(chan struct{}),
+	}
+	h.config.Store(defaultConfig())
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     h.checkOrigin,
+	}
+	return h
+}
+
+// full reports whether the server is at its connection cap, or with
+// newRoom set, at its room cap.
+func (h *Hub) full(newRoom bool) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.fullLocked(newRoom)
+}
+
+func (h *Hub) fullLocked(newRoom bool) bool {
+	if h.maxConns > 0 && h.conns >= h.maxConns {
+		return true
+	}
+	return newRoom && h.maxRooms > 0 && len(h.rooms) >= h.maxRooms
+}
+
+// acquireConn counts a new WebSocket connection, unless the server is at
+// its cap. Each successful call must be matched by releaseConn.
+func (h *Hub) acquireConn() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fullLocked(false) {
+		return false
+	}
+	h.conns++
+	return true
+}
+
+func (h *Hub) releaseConn() {
+	h.mu.Lock()
+	h.conns--
+	h.mu.Unlock()
+}
+
+func (h *Hub) generatePlayerID() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	return fmt.Sprintf("player_%d_%d", time.Now().UnixMilli(), h.nextID)
+}
+
+func (h *Hub) generateRoomCode() string {
+	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	for {
+		code := make([]byte, roomCodeLength)
+		for i := range code {
+			code[i] = charset[rand.Intn(len(charset))]
+		}
+		c := string(code)
+		if _, exists := h.rooms[c]; !exists && h.instances.owns(c) {
+			return c
+		}
+	}
+}
+
+// createRoom makes a new room, or returns nil if the server is full or
+// has too many empty rooms already. A room no one has joined by the time
+// its creator's token expires is removed.
+func (h *Hub) createRoom(title, roomType string, shadow bool) *Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if maxEmpty := h.config.Load().MaxEmptyRooms; h.fullLocked(true) || maxEmpty > 0 && h.emptyRoomsLocked() >= maxEmpty {
+		return nil
+	}
+	code := h.generateRoomCode()
+	room := newRoom(code, title, roomType)
+	room.shadow = shadow
+	room.quarantined = h.quarantine.banned
+	room.onMatchOver = h.matchOver
+	room.config = &h.config
+	room.speed = h.speed
+	h.rooms[code] = room
+	go room.run()
+	h.expireIfUnjoined(code)
+	log.Printf("Room %s created", code)
+	return room
+}
+
+// openRoom returns the lobby of roomType with a free seat and the most
+// players, so quick play fills rooms up rather than spreading players out,
+// or nil if there is none. With shadow set, only shadow rooms count, and
+// otherwise only the rest.
+func (h *Hub) openRoom(roomType string, shadow bool) *Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var best *Room
+	bestPlayers := -1
+	for _, room := range h.rooms {
+		if room.roomType != roomType || room.shadow != shadow {
+			continue
+		}
+		info, ok := room.info()
+		n := info.PlayerCount
+		open := ok && info.Phase == protocol.RoomPhaseLobby && n < info.MaxPlayers
+		if open && (n > bestPlayers || n == bestPlayers && room.createdAt.Before(best.createdAt)) {
+			best, bestPlayers = room, n
+		}
+	}
+	return best
+}
+
+func (h *Hub) getRoom(code string) *Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.rooms[strings.ToUpper(code)]
+}
+
+func (h *Hub) removeRoomIfEmpty(code string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if room, ok := h.rooms[code]; ok {
+		if room.stopIfEmpty() {
+			delete(h.rooms, code)
+			log.Printf("Room %s removed (empty)", code)
+			// Return freed memory to the OS in the background.
+			go debug.FreeOSMemory()
+		}
+	}
+}
+
+func (h *Hub) generateToken() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	return fmt.Sprintf("tok_%d_%d", time.Now().UnixNano(), h.nextID)
+}
+
+func (h *Hub) addPendingJoin(token string, pj *PendingJoin) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Clean up expired tokens while we're here
+	now := time.Now()
+	for t, p := range h.pendingJoins {
+		if now.Sub(p.CreatedAt) > joinTokenTTL {
+			delete(h.pendingJoins, t)
+		}
+	}
+	h.pendingJoins[token] = pj
+}
+
+func (h *Hub) consumeToken(token string) *PendingJoin {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pj, ok := h.pendingJoins[token]
+	if !ok {
+		return nil
+	}
+	delete(h.pendingJoins, token)
+	if time.Since(pj.CreatedAt) > joinTokenTTL {
+		return nil
+	}
+	return pj
+}
+
+func (h *Hub) addPlayer(p *Player) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.players[p.ID] = p
+	h.stats.online(len(h.players))
+}
+
+// matchOver records a room's finished match in the history and totals.
+func (h *Hub) matchOver(rec protocol.MatchRecord) {
+	rec.MatchID = h.history.record(rec)
+	h.stats.addMatch(rec)
+	h.results.add(rec)
+}
+
+func (h *Hub) getPlayer(id string) *Player {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.players[id]
+}
+
+func (h *Hub) removePlayer(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.players, id)
+}
+
+// --- HTTP Handlers (Front Desk) ---
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends an ErrorResponse with a machine-readable code.
+func writeError(w http.ResponseWriter, status int, code protocol.ErrorCode, msg string) {
+	writeJSON(w, status, protocol.ErrorResponse{Code: code, Error: msg})
+}
+
+// writeServerFull turns a request away because the server is at
+// capacity, telling the client when to try again.
+func writeServerFull(w http.ResponseWriter) {
+	secs := int(serverFullRetry.Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeJSON(w, http.StatusServiceUnavailable, protocol.ErrorResponse{
+		Code:       protocol.ErrCodeServerFull,
+		Error:      "server is full",
+		RetryAfter: secs,
+	})
+}
+
+func handleCreateRoom(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if checkBanned(hub, w, r) {
+		return
+	}
+
+	var req protocol.CreateRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.PlayerName) == "" {
+		req.PlayerName = "Player"
+	}
+	if checkCreateRate(hub, w, r) {
+		return
+	}
+
+	room := hub.createRoom(cleanRoomTitle(req.Title), protocol.RoomTypeCasual, false)
+	if room == nil {
+		writeServerFull(w)
+		return
+	}
+	playerID := hub.generatePlayerID()
+	token := hub.generateToken()
+
+	hub.addPendingJoin(token, &PendingJoin{
+		RoomCode:   room.code,
+		PlayerName: req.PlayerName,
+		PlayerID:   playerID,
+		CreatedAt:  time.Now(),
+	})
+
+	log.Printf("Room %s created via HTTP for player %q (pending token)", room.code, req.PlayerName)
+
+	writeJSON(w, http.StatusOK, protocol.CreateRoomResponse{
+		RoomID:    room.code,
+		JoinToken: token,
+	})
+}
+
+// cleanRoomTitle collapses runs of whitespace in a room title, drops
+// control characters and cuts it to maxRoomTitle characters.
+func cleanRoomTitle(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, title)
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > maxRoomTitle {
+		title = strings.TrimSpace(string(runes[:maxRoomTitle]))
+	}
+	return title
+}
+
+func handleJoinRoom(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if checkBanned(hub, w, r) {
+		return
+	}
+
+	var req protocol.JoinRoomHTTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "invalid request body")
+		return
+	}
+
+	// An invite is only used up once the join is sure to be let in, below.
+	code := strings.ToUpper(strings.TrimSpace(req.RoomID))
+	if req.Invite != "" {
+		inv, ok := hub.invites.get(req.Invite)
+		if !ok {
+			writeError(w, http.StatusNotFound, protocol.ErrCodeInvalidToken, "invite unknown, used or expired")
+			return
+		}
+		code = inv.RoomID
+	}
+	if hub.instances.redirect(w, r, code) {
+		return
+	}
+	var info protocol.RoomInfo
+	room := hub.getRoom(code)
+	found := room != nil
+	if found {
+		info, found = room.info() // it may have been removed just now
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, protocol.ErrCodeRoomNotFound, fmt.Sprintf("room %q not found", code))
+		return
+	}
+
+	if info.Phase != protocol.RoomPhaseLobby {
+		writeError(w, http.StatusConflict, protocol.ErrCodeInProgress, "game already in progress")
+		return
+	}
+	if info.PlayerCount >= info.MaxPlayers {
+		writeError(w, http.StatusConflict, protocol.ErrCodeRoomFull, "room is full")
+		return
+	}
+	if hub.full(false) {
+		writeServerFull(w)
+		return
+	}
+
+	if req.Invite != "" {
+		if _, ok := hub.invites.take(req.Invite); !ok {
+			writeError(w, http.StatusNotFound, protocol.ErrCodeInvalidToken, "invite unknown, used or expired")
+			return
+		}
+	}
+
+	if strings.TrimSpace(req.PlayerName) == "" {
+		req.PlayerName = "Player"
+	}
+
+	playerID := hub.generatePlayerID()
+	token := hub.generateToken()
+
+	hub.addPendingJoin(token, &PendingJoin{
+		RoomCode:   code,
+		PlayerName: req.PlayerName,
+		PlayerID:   playerID,
+		CreatedAt:  time.Now(),
+	})
+
+	log.Printf("Player %q joining room %s via HTTP (pending token)", req.PlayerName, code)
+
+	writeJSON(w, http.StatusOK, protocol.JoinRoomHTTPResponse{
+		RoomID:    code,
+		JoinToken: token,
+	})
+}
+
+// handleQuickPlay puts a player in the fullest open lobby of the room
+// type they asked for, creating a room if there's none.
+func handleQuickPlay(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if checkBanned(hub, w, r) {
+		return
+	}
+
+	var req protocol.QuickPlayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "invalid request body")
+		return
+	}
+	if req.Type != protocol.RoomTypeCasual && req.Type != protocol.RoomTypeRanked {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, fmt.Sprintf("unknown room type %q", req.Type))
+		return
+	}
+	if strings.TrimSpace(req.PlayerName) == "" {
+		req.PlayerName = "Player"
+	}
+
+	// Quarantined players are only matched with each other.
+	shadow := hub.quarantine.banned(remoteIP(r))
+	room := hub.openRoom(req.Type, shadow)
+	if room == nil {
+		if checkCreateRate(hub, w, r) {
+			return
+		}
+		room = hub.createRoom("", req.Type, shadow)
+	}
+	if room == nil || hub.full(false) {
+		writeServerFull(w)
+		return
+	}
+
+	playerID := hub.generatePlayerID()
+	token := hub.generateToken()
+
+	hub.addPendingJoin(token, &PendingJoin{
+		RoomCode:   room.code,
+		PlayerName: req.PlayerName,
+		PlayerID:   playerID,
+		CreatedAt:  time.Now(),
+	})
+
+	log.Printf("Player %q quick-joining %s room %s (pending token)", req.PlayerName, req.Type, room.code)
+
+	writeJSON(w, http.StatusOK, protocol.JoinRoomHTTPResponse{
+		RoomID:    room.code,
+		JoinToken: token,
+	})
+}
+
+func handleListRooms(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	query := strings.ToLower(strings.TrimSpace(q.Get("q")))
+	phase := q.Get("phase")
+	openOnly := q.Get("open") == "1"
+	roomType := q.Get("type")
+	sortBy := cmp.Or(q.Get("sort"), protocol.RoomSortCode)
+	if sortBy != protocol.RoomSortCode && sortBy != protocol.RoomSortPlayers && sortBy != protocol.RoomSortNewest {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, fmt.Sprintf("unknown sort %q", sortBy))
+		return
+	}
+	limit, err := queryInt(q, "limit", maxListRooms)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, err.Error())
+		return
+	}
+	offset, err := queryInt(q, "offset", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, err.Error())
+		return
+	}
+	limit = min(limit, maxListRooms)
+
+	type listed struct {
+		info    protocol.RoomInfo
+		created time.Time
+	}
+	hub.mu.RLock()
+	matches := make([]listed, 0, len(hub.rooms))
+	for _, room := range hub.rooms {
+		if room.shadow {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(room.code), query) &&
+			!strings.Contains(strings.ToLower(room.title), query) {
+			continue
+		}
+		info, ok := room.info()
+		if !ok {
+			continue
+		}
+		if phase != "" && info.Phase != phase {
+			continue
+		}
+		if openOnly && (info.Phase != protocol.RoomPhaseLobby || info.PlayerCount >= info.MaxPlayers) {
+			continue
+		}
+		if roomType != "" && info.Type != roomType {
+			continue
+		}
+		matches = append(matches, listed{info, room.createdAt})
+	}
+	hub.mu.RUnlock()
+
+	slices.SortFunc(matches, func(a, b listed) int {
+		switch sortBy {
+		case protocol.RoomSortPlayers:
+			if c := cmp.Compare(b.info.PlayerCount, a.info.PlayerCount); c != 0 {
+				return c
+			}
+		case protocol.RoomSortNewest:
+			if c := b.created.Compare(a.created); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.info.RoomID, b.info.RoomID)
+	})
+
+	rooms := make([]protocol.RoomInfo, 0, limit)
+	for _, m := range matches[min(offset, len(matches)):] {
+		if len(rooms) == limit {
+			break
+		}
+		rooms = append(rooms, m.info)
+	}
+	writeJSON(w, http.StatusOK, protocol.ListRoomsResponse{Rooms: rooms, Total: len(matches)})
+}
+
+// queryInt reads a non-negative integer query parameter, or def if it's
+// absent.
+func queryInt(q url.Values, name string, def int) (int, error) {
+	s := q.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+// --- WebSocket Handler (Game Room) ---
+
+// handlePlay upgrades to WebSocket for a player who already has a join token.
+func handlePlay(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	roomCode := r.URL.Query().Get("room")
+	token := r.URL.Query().Get("token")
+
+	if roomCode == "" || token == "" {
+		writeError(w, http.StatusBadRequest, protocol.ErrCodeBadRequest, "missing room or token query parameter")
+		return
+	}
+	if checkBanned(hub, w, r) {
+		return
+	}
+	if hub.instances.redirect(w, r, strings.ToUpper(roomCode)) {
+		return
+	}
+
+	// Checked before the token is used up, so it still works on a retry.
+	if !hub.acquireConn() {
+		writeServerFull(w)
+		return
+	}
+	defer hub.releaseConn()
+
+	// Validate and consume token
+	pj := hub.consumeToken(token)
+	if pj == nil {
+		writeError(w, http.StatusUnauthorized, protocol.ErrCodeInvalidToken, "invalid or expired token")
+		return
+	}
+
+	if pj.RoomCode != strings.ToUpper(roomCode) {
+		writeError(w, http.StatusForbidden, protocol.ErrCodeTokenMismatch, "token does not match room")
+		return
+	}
+
+	room := hub.getRoom(pj.RoomCode)
+	if room == nil {
+		writeError(w, http.StatusNotFound, protocol.ErrCodeRoomNotFound, "room not found")
+		return
+	}
+	// A player holding a seat after a drop picks up where they left off.
+	p := room.seated(pj.PlayerID)
+	resumed := p != nil
+	if !resumed && room.isFull() {
+		writeError(w, http.StatusConflict, protocol.ErrCodeRoomFull, "room is full")
+		return
+	}
+
+	// Upgrade to WebSocket
+	conn, err := hub.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("upgrade error: %v", err)
+		return
+	}
+
+	if !resumed {
+		// Create the player from pending join info
+		p = newPlayer(pj.PlayerID, conn)
+		p.Name = pj.PlayerName
+		p.Ready = false
+	}
+	// A player getting their seat back is sent what they missed.
+	var since uint64
+	if resumed {
+		since, _ = strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	}
+	ip := remoteIP(r)
+	sendCh, connNum, complete := p.attach(conn, ip, parseCapabilities(r), since)
+
+	// Someone else may have taken the last seat since the check above.
+	if !room.seat(p) {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "room is full"))
+		conn.Close()
+		return
+	}
+	hub.addPlayer(p)
+	if resumed {
+		log.Printf("Player %s (%s) reconnected to room %s", p.Name, p.ID, room.code)
+	} else {
+		log.Printf("Player %s (%s) connected to room %s via WebSocket", p.Name, p.ID, room.code)
+	}
+
+	// Send player their ID, and a token to come back with if the
+	// connection drops
+	reconnectToken := ""
+	if p.supports(protocol.CapReconnect) {
+		reconnectToken = hub.generateToken()
+	}
+	p.send(protocol.Envelope{
+		Type: protocol.MsgAssignID,
+		Payload: protocol.AssignIDPayload{
+			PlayerID:       p.ID,
+			ReconnectToken: reconnectToken,
+			Resumed:        resumed,
+			Missed:         resumed && !complete,
+			Capabilities:   serverCapabilities,
+			MOTD:           hub.config.Load().MOTD,
+		},
+	})
+
+	// Start write pump
+	go p.writePump(conn, sendCh)
+
+	// Broadcast lobby update so everyone sees the new player
+	room.do(room.lobbyChanged)
+
+	// Read pump (blocking)
+	err = readPump(p, conn, hub)
+	p.closeSend(sendCh) // immediately stops writePump goroutine
+
+	// A dropped (rather than closed) connection may come back: the
+	// reconnect token becomes a join token for the same player, valid
+	// for as long as any other pending join. Mid-match, their seat is
+	// kept for reconnectGrace so they can carry on playing. A player
+	// kicked by a ban or for cheating isn't coming back.
+	p.mu.Lock()
+	kicked := p.kicked
+	p.mu.Unlock()
+	if reconnectToken != "" && !websocket.IsCloseError(err, websocket.CloseNormalClosure) && !hub.bans.banned(ip) && !kicked {
+		hub.addPendingJoin(reconnectToken, &PendingJoin{
+			RoomCode:   room.code,
+			PlayerName: p.Name,
+			PlayerID:   p.ID,
+			CreatedAt:  time.Now(),
+		})
+		if room.holdSeat() {
+			log.Printf("Player %s (%s) dropped, holding their seat in room %s", p.Name, p.ID, room.code)
+			time.AfterFunc(reconnectGrace/hub.speed, func() {
+				if room.releaseSeat(p, connNum) {
+					log.Printf("Player %s (%s) did not reconnect to room %s", p.Name, p.ID, room.code)
+					leaveRoom(hub, room, p)
+				}
+			})
+			return
+		}
+	}
+
+	room.leave(p.ID)
+	leaveRoom(hub, room, p)
+}
+
+// leaveRoom finishes a player's departure once they are out of the room,
+// removing the room if they were the last.
+func leaveRoom(hub *Hub, room *Room, p *Player) {
+	p.mu.Lock()
+	p.Snapshot = nil // free board data
+	p.version++
+	p.mu.Unlock()
+	log.Printf("Player %s (%s) left room %s", p.Name, p.ID, room.code)
+	if room.playerCount() == 0 {
+		hub.removeRoomIfEmpty(room.code)
+	}
+	hub.removePlayer(p.ID)
+	log.Printf("Player %s (%s) disconnected", p.Name, p.ID)
+}
+
+// readPump reads messages from conn and dispatches them until the
+// connection ends, and returns the error that ended it.
+func readPump(p *Player, conn *websocket.Conn, hub *Hub) error {
+	defer conn.Close()
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("read error for %s: %v", p.ID, err)
+			}
+			return err
+		}
+
+		var env protocol.RawEnvelope
+		if err := json.Unmarshal(message, &env); err != nil {
+			log.Printf("unmarshal error from %s: %v", p.ID, err)
+			continue
+		}
+		checkTiming(p, env)
+
+		handleMessage(p, hub, env)
+	}
+}
+
+// checkTiming logs messages from a client that arrive out of order, or
+// long after the client stamped them, to help track down desyncs.
+func checkTiming(p *Player, env protocol.RawEnvelope) {
+	if env.Seq != 0 {
+		p.mu.Lock()
+		last := p.recvSeq
+		if env.Seq > last {
+			p.recvSeq = env.Seq
+		}
+		p.mu.Unlock()
+		if env.Seq <= last {
+			log.Printf("out-of-order %s from %s: seq %d after %d", env.Type, p.ID, env.Seq, last)
+		}
+	}
+	if env.TS != 0 {
+		if lag := time.Since(time.UnixMilli(env.TS)); lag > lateMessage {
+			log.Printf("late %s from %s: sent %v ago (seq %d)", env.Type, p.ID, lag.Round(time.Millisecond), env.Seq)
+		}
+	}
+}
+
+// handleMessage dispatches a client message.
+func handleMessage(p *Player, hub *Hub, env protocol.RawEnvelope) {
+	switch env.Type {
+	case protocol.MsgLeaveRoom:
+		if code := p.roomCode(); code != "" {
+			room := hub.getRoom(code)
+			if room != nil {
+				room.leave(p.ID)
+				log.Printf("Player %s (%s) left room %s via message", p.Name, p.ID, code)
+				if room.playerCount() == 0 {
+					hub.removeRoomIfEmpty(code)
+				}
+			}
+		}
+
+	case protocol.MsgReady:
+		if payload, err := protocol.DecodePayload[protocol.ReadyPayload](env.Type, env.Payload); err == nil {
+			room := hub.getRoom(p.roomCode())
+			if room == nil {
+				return
+			}
+			room.setReady(p, payload.Ready)
+		}
+
+	case protocol.MsgBoardSnapshot:
+		if payload, err := protocol.DecodePayload[protocol.BoardSnapshotPayload](env.Type, env.Payload); err == nil {
+			// Timed by the server's clock; see snapInterval for what
+			// the client's may change.
+			var sent time.Time
+			if env.TS != 0 {
+				sent = time.UnixMilli(env.TS)
+			}
+			if room := hub.getRoom(p.roomCode()); room != nil {
+				if reason := room.checkSnapshot(p, &payload, time.Now(), sent); reason != "" {
+					hub.flagCheat(room, p, reason)
+				}
+			}
+			p.mu.Lock()
+			p.Snapshot = &payload
+			p.version++
+			p.mu.Unlock()
+		}
+
+	case protocol.MsgLinesCleared:
+		if payload, err := protocol.DecodePayload[protocol.LinesClearedPayload](env.Type, env.Payload); err == nil {
+			room := hub.getRoom(p.roomCode())
+			if room != nil {
+				room.do(func() { room.handleLinesCleared(p.ID, payload) })
+			}
+		}
+
+	case protocol.MsgSetTarget:
+		if payload, err := protocol.DecodePayload[protocol.SetTargetPayload](env.Type, env.Payload); err == nil {
+			p.mu.Lock()
+			p.TargetID = payload.TargetID
+			p.mu.Unlock()
+		}
+
+	case protocol.MsgRoomSettings:
+		if payload, err := protocol.DecodePayload[protocol.RoomSettings](env.Type, env.Payload); err == nil {
+			room := hub.getRoom(p.roomCode())
+			if room == nil {
+				return
+			}
+			if err := room.changeSettings(p.ID, payload); err != nil {
+				p.send(protocol.Envelope{
+					Type:    protocol.MsgRoomError,
+					Payload: roomErrorPayload(err),
+				})
+			}
+		}
+
+	case protocol.MsgEmote:
+		if payload, err := protocol.DecodePayload[protocol.EmotePayload](env.Type, env.Payload); err == nil {
+			room := hub.getRoom(p.roomCode())
+			if room != nil {
+				room.do(func() { room.handleEmote(p, payload.Emote) })
+			}
+		}
+
+	case protocol.MsgUseItem:
+		if payload, err := protocol.DecodePayload[protocol.UseItemPayload](env.Type, env.Payload); err == nil {
+			room := hub.getRoom(p.roomCode())
+			if room != nil {
+				room.do(func() { room.handleUseItem(p, payload.Item) })
+			}
+		}
+
+	case protocol.MsgCreateInvite:
+		if payload, err := protocol.DecodePayload[protocol.CreateInvitePayload](env.Type, env.Payload); err == nil {
+			hub.createInvite(p, payload.To)
+		}
+
+	case protocol.MsgMutePlayer:
+		if payload, err := protocol.DecodePayload[protocol.MutePlayerPayload](env.Type, env.Payload); err == nil {
+			room := hub.getRoom(p.roomCode())
+			if room == nil {
+				return
+			}
+			if err := room.setMuted(p.ID, payload.PlayerID, payload.Muted); err != nil {
+				p.send(protocol.Envelope{
+					Type:    protocol.MsgRoomError,
+					Payload: roomErrorPayload(err),
+				})
+			}
+		}
+
+	case protocol.MsgPlayerDead:
+		room := hub.getRoom(p.roomCode())
+		if room != nil {
+			room.do(func() { room.handlePlayerDead(p.ID) })
+		}
+
+	default:
+		log.Printf("unknown message type from %s: %s", p.ID, env.Type)
+	}
+}
+
+// --- Main ---
+
+// envInt reads a non-negative integer setting from the environment, 0 if
+// it's unset.
+func envInt(name string) int {
+	s := os.Getenv(name)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		log.Fatalf("%s must be a non-negative integer, got %q", name, s)
+	}
+	return n
+}
+
+// routes sets up hub's HTTP API, WebSocket and web client, with the
+// admin API behind adminToken (off if it's "").
+func routes(hub *Hub, adminToken string) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// --- HTTP endpoints (Front Desk) ---
+	mux.HandleFunc("/create-room", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateRoom(hub, w, r)
+	})
+	mux.HandleFunc("/join-room", func(w http.ResponseWriter, r *http.Request) {
+		handleJoinRoom(hub, w, r)
+	})
+	mux.HandleFunc("/quick-play", func(w http.ResponseWriter, r *http.Request) {
+		handleQuickPlay(hub, w, r)
+	})
+	mux.HandleFunc("/list-rooms", func(w http.ResponseWriter, r *http.Request) {
+		handleListRooms(hub, w, r)
+	})
+
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		handleStats(hub, w, r)
+	})
+	mux.HandleFunc("GET /players/{id}/matches", func(w http.ResponseWriter, r *http.Request) {
+		handlePlayerMatches(hub, w, r)
+	})
+	mux.HandleFunc("GET /rooms/{code}/events", func(w http.ResponseWriter, r *http.Request) {
+		handleRoomEvents(hub, w, r)
+	})
+	mux.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		handleProfile(hub, w, r)
+	})
+	mux.HandleFunc("/achievements", func(w http.ResponseWriter, r *http.Request) {
+		handleAchievements(hub, w, r)
+	})
+	mux.HandleFunc("GET /invites", func(w http.ResponseWriter, r *http.Request) {
+		handleInvites(hub, w, r)
+	})
+	mux.HandleFunc("DELETE /invites/{token}", func(w http.ResponseWriter, r *http.Request) {
+		handleDeclineInvite(hub, w, r)
+	})
+
+	// --- WebSocket endpoint (Game Room) ---
+	mux.HandleFunc("/play", func(w http.ResponseWriter, r *http.Request) {
+		handlePlay(hub, w, r)
+	})
+
+	// --- Admin API (off unless ADMIN_TOKEN is set) ---
+	adminRoutes(mux, hub, adminToken)
+
+	// --- Web client ---
+	mux.Handle("GET /web/", webHandler())
+
+	// Simple health check
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+// adminRoutes adds hub's admin API, behind adminToken (off if it's ""),
+// to mux.
+func adminRoutes(mux *http.ServeMux, hub *Hub, adminToken string) {
+	mux.HandleFunc("/admin/bans", func(w http.ResponseWriter, r *http.Request) {
+		if requireAdmin(adminToken, w, r) {
+			handleAdminBans(hub, w, r)
+		}
+	})
+	mux.HandleFunc("/admin/mutes", func(w http.ResponseWriter, r *http.Request) {
+		if requireAdmin(adminToken, w, r) {
+			handleAdminMutes(hub, w, r)
+		}
+	})
+	mux.HandleFunc("/admin/quarantine", func(w http.ResponseWriter, r *http.Request) {
+		if requireAdmin(adminToken, w, r) {
+			handleAdminQuarantine(hub, w, r)
+		}
+	})
+}
+
+// Main runs the server configured from the environment (PORT, BANS_FILE,
+// CONFIG_FILE and the rest, see the README) until it's interrupted.
+func Main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = defaultPort
+	}
+	addrs := listenAddrs(os.Getenv("LISTEN"))
+	if len(addrs) == 0 {
+		addrs = []string{":" + port}
+	}
+	adminAddrs := listenAddrs(os.Getenv("ADMIN_LISTEN"))
+
+	maxRooms := envInt("MAX_ROOMS")
+	maxConns := envInt("MAX_CONNECTIONS")
+
+	bansFile := os.Getenv("BANS_FILE")
+	if bansFile == "" {
+		bansFile = defaultBansFile
+	}
+	bans, err := loadBans(bansFile)
+	if err != nil {
+		log.Fatalf("loading bans: %v", err)
+	}
+	quarantineFile := cmp.Or(os.Getenv("QUARANTINE_FILE"), defaultQuarantineFile)
+	quarantine, err := loadBans(quarantineFile)
+	if err != nil {
+		log.Fatalf("loading quarantine: %v", err)
+	}
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if len(adminAddrs) > 0 && adminToken == "" {
+		log.Fatalf("ADMIN_LISTEN needs ADMIN_TOKEN")
+	}
+	instances, err := loadInstances(os.Getenv("INSTANCES"), os.Getenv("INSTANCE_URL"))
+	if err != nil {
+		log.Fatalf("loading instances: %v", err)
+	}
+
+	proxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
+	}
+
+	configFile := os.Getenv("CONFIG_FILE")
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+
+	resultsFile := os.Getenv("RESULTS_LOG")
+	results, err := openResultsLog(resultsFile)
+	if err != nil {
+		log.Fatalf("opening results log: %v", err)
+	}
+
+	profilesFile := os.Getenv("PROFILES_FILE")
+	profiles, err := loadProfiles(profilesFile)
+	if err != nil {
+		log.Fatalf("loading profiles: %v", err)
+	}
+	achievements, err := loadAchievements(os.Getenv("ACHIEVEMENTS_FILE"))
+	if err != nil {
+		log.Fatalf("loading achievements: %v", err)
+	}
+
+	hub := newHub(maxRooms, maxConns, bans, instances)
+	hub.results = results
+	hub.profiles = profiles
+	hub.achievements = achievements
+	hub.quarantine = quarantine
+	hub.config.Store(cfg)
+	hub.cheatAction = cmp.Or(os.Getenv("CHEAT_ACTION"), cheatLog)
+	if !slices.Contains(cheatActions, hub.cheatAction) {
+		log.Fatalf("CHEAT_ACTION must be one of %s, got %q", strings.Join(cheatActions, ", "), hub.cheatAction)
+	}
+	go hub.watchRooms()
+
+	// With ADMIN_LISTEN the admin API and diagnostics are only served
+	// there.
+	var mux, adminMux *http.ServeMux
+	if len(adminAddrs) > 0 {
+		mux, adminMux = routes(hub, ""), http.NewServeMux()
+		adminRoutes(adminMux, hub, adminToken)
+	} else {
+		mux = routes(hub, adminToken)
+		adminMux = mux
+	}
+
+	// --- Diagnostics (off unless DEBUG_ADDR or ADMIN_TOKEN is set) ---
+	debugAddr := os.Getenv("DEBUG_ADDR")
+	publishVars(hub)
+	diagnostics := diagnosticsHandler()
+	if debugAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(debugAddr, diagnostics); err != nil {
+				log.Fatalf("diagnostics server error: %v", err)
+			}
+		}()
+	} else {
+		adminMux.HandleFunc("/debug/", func(w http.ResponseWriter, r *http.Request) {
+			if requireAdmin(adminToken, w, r) {
+				diagnostics.ServeHTTP(w, r)
+			}
+		})
+	}
+
+	base, adminBase := baseURL(addrs[0]), baseURL(addrs[0])
+	if len(adminAddrs) > 0 {
+		adminBase = baseURL(adminAddrs[0])
+	}
+	log.Printf("Gotris server starting on %s", strings.Join(addrs, ", "))
+	if len(adminAddrs) > 0 {
+		log.Printf("Admin listeners: %s", strings.Join(adminAddrs, ", "))
+	}
+	if maxRooms > 0 || maxConns > 0 {
+		log.Printf("Capacity: %d rooms, %d connections (0 = no limit)", maxRooms, maxConns)
+	}
+	log.Printf("Bans: %d, saved in %s", len(bans.list()), bansFile)
+	log.Printf("Quarantine: %d, saved in %s", len(quarantine.list()), quarantineFile)
+	if configFile != "" {
+		log.Printf("Config: %s, reloaded on SIGHUP", configFile)
+	}
+	if results != nil {
+		log.Printf("Results log: %s, reopened on SIGHUP", resultsFile)
+	}
+	if profilesFile != "" {
+		log.Printf("Profiles: %d, saved in %s", len(profiles.profiles), profilesFile)
+	}
+	if instances != nil {
+		log.Printf("Instance %d of %d (%s), redirecting requests for other instances' rooms", instances.self+1, len(instances.urls), instances.urls[instances.self])
+	}
+	if adminToken != "" {
+		log.Printf("Admin API: %s/admin/bans, /admin/mutes, /admin/quarantine", adminBase)
+	}
+	switch {
+	case debugAddr != "":
+		log.Printf("Diagnostics: http://%s/debug/pprof/, /debug/vars", debugAddr)
+	case adminToken != "":
+		log.Printf("Diagnostics (admin token): %s/debug/pprof/, /debug/vars", adminBase)
+	}
+	log.Printf("HTTP endpoints: %s/create-room, /join-room, /quick-play, /list-rooms, /stats, /players/{id}/matches, /rooms/{code}/events", base)
+	log.Printf("WebSocket endpoint: %s/play?room=XXXXX&token=...", strings.Replace(base, "http", "ws", 1))
+	log.Printf("Web client: %s/web/", base)
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			hub.results.reopen()
+			if configFile != "" {
+				hub.reloadConfig(configFile)
+			}
+		}
+	}()
+
+	servers := serve(addrs, proxies.handler(mux))
+	if len(adminAddrs) > 0 {
+		servers = append(servers, serve(adminAddrs, adminMux)...)
+	}
+
+	<-done
+	log.Println("Server shutting down...")
+	for _, srv := range servers {
+		srv.Close() // also removes Unix sockets
+	}
+	hub.results.close()
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"time"
+
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Speed race ---
+//
+// With RoomSettings.SpeedRaceSecs set, a match is a speed race: the
+// players' games don't level up with the lines they clear, and the room
+// raises everyone's level together on its own clock instead, sending
+// MsgLevelUp, so at any moment every player faces the same gravity. The
+// level stops rising where the standard gravity curve ends.
+
+// minSpeedRaceSecs and maxSpeedRaceSecs bound how often a speed race may
+// level up.
+const (
+	minSpeedRaceSecs = 5
+	maxSpeedRaceSecs = 300
+)
+
+// validateSpeedRace checks a room's speed race setting.
+func validateSpeedRace(s protocol.RoomSettings) error {
+	if s.SpeedRaceSecs != 0 && (s.SpeedRaceSecs < minSpeedRaceSecs || s.SpeedRaceSecs > maxSpeedRaceSecs) {
+		return newRoomError(protocol.ErrCodeInvalidSettings, "speed race levels must last between %d and %d seconds", minSpeedRaceSecs, maxSpeedRaceSecs)
+	}
+	return nil
+}
+
+// startSpeedRace starts the match's level clock, if it's a speed race.
+func (r *Room) startSpeedRace() {
+	r.level = 1
+	if r.settings.SpeedRaceSecs == 0 {
+		return
+	}
+	started := r.startedAt
+	r.after(time.Duration(r.settings.SpeedRaceSecs)*time.Second, func() { r.speedRaceTick(started) })
+}
+
+// speedRaceTick levels everyone up, unless the match that started at
+// started is over, and sets the clock for the next level.
+func (r *Room) speedRaceTick(started time.Time) {
+	if r.phase != PhasePlaying || !r.startedAt.Equal(started) || r.level >= len(game.StandardGravity) {
+		return
+	}
+	r.level++
+	r.broadcastToAll(protocol.Envelope{
+		Type:    protocol.MsgLevelUp,
+		Payload: protocol.LevelUpPayload{Level: r.level},
+	})
+	r.after(time.Duration(r.settings.SpeedRaceSecs)*time.Second, func() { r.speedRaceTick(started) })
+}
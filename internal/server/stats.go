@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Server statistics ---
+
+// serverStats keeps the running totals served by /stats.
+type serverStats struct {
+	mu      sync.Mutex
+	matches int64
+	lines   int64
+	garbage int64
+	// peak is the most players online at once on peakDay (a local date,
+	// "2006-01-02"); a new day starts again from whoever is online.
+	peak    int
+	peakDay string
+}
+
+func newServerStats() *serverStats {
+	return &serverStats{peakDay: today()}
+}
+
+// today is the server's local date.
+func today() string {
+	return time.Now().Format(time.DateOnly)
+}
+
+// addMatch adds a finished match to the totals.
+func (s *serverStats) addMatch(rec protocol.MatchRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matches++
+	for _, st := range rec.Standings {
+		s.lines += int64(st.Lines)
+		s.garbage += int64(st.Sent)
+	}
+}
+
+// online notes that n players are connected, for the day's peak.
+func (s *serverStats) online(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peakLocked(n)
+}
+
+// peakLocked updates and returns the day's peak with n players online.
+// Must be called with s.mu held.
+func (s *serverStats) peakLocked(n int) int {
+	if day := today(); day != s.peakDay {
+		s.peakDay = day
+		s.peak = 0
+	}
+	s.peak = max(s.peak, n)
+	return s.peak
+}
+
+// snapshot returns the totals, with online players and rooms as given.
+func (s *serverStats) snapshot(online, rooms int) protocol.ServerStatsResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return protocol.ServerStatsResponse{
+		MatchesPlayed: s.matches,
+		LinesCleared:  s.lines,
+		GarbageSent:   s.garbage,
+		OnlinePlayers: online,
+		PeakToday:     s.peakLocked(online),
+		Rooms:         rooms,
+	}
+}
+
+// handleStats serves GET /stats.
+func handleStats(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	hub.mu.RLock()
+	online, rooms := len(hub.players), len(hub.rooms)
+	hub.mu.RUnlock()
+	writeJSON(w, http.StatusOK, hub.stats.snapshot(online, rooms))
+}
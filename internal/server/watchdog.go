@@ -0,0 +1,80 @@
+package server
+
+import (
+	"log"
+	"maps"
+	"slices"
+	"time"
+)
+
+// --- Room watchdog ---
+//
+// Every phase but the lobby ends by itself: the countdown starts the
+// match, the last player standing ends it, and the results give way to
+// the lobby after matchOverPause. Should one not, say its timer was lost
+// or a match was left with no one sending snapshots, the room would sit
+// in the room browser forever. The watchdog looks the rooms over every
+// watchdogInterval, moves on any that has gone quiet in one phase for too
+// long, and removes rooms that have been empty a while.
+
+const watchdogInterval = 30 * time.Second
+
+// stuckAfter is how long a room may go with nothing happening in each
+// phase before the watchdog moves it on. Playing counts snapshots, which
+// every client sends many times a second.
+var stuckAfter = map[RoomPhase]time.Duration{
+	PhaseCountdown: 30 * time.Second,
+	PhasePlaying:   2 * time.Minute,
+	PhaseGameOver:  matchOverPause + 30*time.Second,
+}
+
+// touch notes that something just happened in the room.
+func (r *Room) touch() {
+	r.lastActivity = time.Now()
+}
+
+// watchRooms runs the watchdog until the hub is closed.
+func (h *Hub) watchRooms() {
+	ticker := time.NewTicker(watchdogInterval / h.speed)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-h.done:
+			return
+		}
+		h.mu.RLock()
+		rooms := slices.Collect(maps.Values(h.rooms))
+		h.mu.RUnlock()
+		for _, room := range rooms {
+			if room.unstick(now) {
+				h.removeRoomIfEmpty(room.code)
+			}
+		}
+	}
+}
+
+// unstick moves the room on if it has been stuck in its phase: a match
+// ends with no winner, and a countdown or results go back to the lobby.
+// It reports whether the room has been empty long enough to remove; a new
+// room is given until its creator's join token has expired.
+func (r *Room) unstick(now time.Time) bool {
+	idle := false
+	r.do(func() {
+		quiet := now.Sub(r.lastActivity)
+		if limit, ok := stuckAfter[r.phase]; ok && quiet > limit/r.speed {
+			log.Printf("Room %s stuck in %s for %s, moving it on", r.code, r.phase, quiet.Round(time.Second))
+			if r.phase == PhasePlaying {
+				r.endMatch(nil)
+			} else {
+				r.countdownGen++
+				r.resetToLobby()
+				r.broadcastLobbyUpdate()
+				r.refreshAutoStart()
+			}
+		}
+		idle = len(r.players) == 0 && quiet > (joinTokenTTL+emptyRoomGrace)/r.speed
+	})
+	return idle
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// --- Web client ---
+//
+// /web/ serves a small browser client, built into the server binary, that
+// speaks the same HTTP and WebSocket protocol as the terminal client. A
+// link to /web/?room=CODE lets someone without the terminal client join a
+// friend's room from their browser. The page's own origin may always open
+// game connections; other sites need listing in allowed_origins.
+
+//go:embed web
+var webFiles embed.FS
+
+// webHandler serves the web client under /web/.
+func webHandler() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err) // the directory is embedded above
+	}
+	return http.StripPrefix("/web/", http.FileServerFS(files))
+}
@@ -0,0 +1,398 @@
+// Gotris web client: joins a room over the same HTTP and WebSocket
+// protocol as the terminal client, and plays the game in the page.
+"use strict";
+
+const W = 10, H = 20, CELL = 24;
+const COLORS = ["#000", "#f00", "#0f0", "#ff0", "#00f", "#f0f", "#0ff", "#aaa", "#888"];
+const SHAPES = [
+  [[0, 0, 0, 0], [1, 1, 1, 1], [0, 0, 0, 0], [0, 0, 0, 0]], // I
+  [[1, 1], [1, 1]], // O
+  [[0, 1, 0], [1, 1, 1], [0, 0, 0]], // T
+  [[0, 1, 1], [1, 1, 0], [0, 0, 0]], // S
+  [[1, 1, 0], [0, 1, 1], [0, 0, 0]], // Z
+  [[1, 0, 0], [1, 1, 1], [0, 0, 0]], // J
+  [[0, 0, 1], [1, 1, 1], [0, 0, 0]], // L
+];
+const PIECE_COLORS = [6, 3, 5, 2, 1, 4, 3];
+const GARBAGE = 8;
+const GRAVITY = [800, 720, 630, 550, 470, 380, 300, 220, 130, 100, 80, 80, 80, 70, 70, 70, 50, 50, 50, 30];
+const ATTACK_TABLES = { standard: [0, 1, 2, 4], aggressive: [1, 2, 3, 5], casual: [0, 0, 1, 2] };
+const LINE_SCORES = [0, 100, 300, 500, 800];
+const SNAPSHOT_EVERY = 100; // ms
+
+const $ = (id) => document.getElementById(id);
+
+let ws = null;
+let me = "";
+let ready = false;
+let game = null;
+let lastSnap = "";
+
+// --- Front desk ---
+
+function show(section) {
+  for (const id of ["join", "lobby", "game"]) {
+    $(id).classList.toggle("hidden", id !== section);
+  }
+  $("help").classList.toggle("hidden", section !== "game");
+}
+
+function error(msg) {
+  $("error").textContent = msg || "";
+}
+
+async function post(path, body) {
+  const resp = await fetch(path, { method: "POST", body: JSON.stringify(body) });
+  const data = await resp.json().catch(() => ({}));
+  if (!resp.ok) {
+    throw new Error(data.error || resp.statusText);
+  }
+  return data;
+}
+
+async function enter(create) {
+  error("");
+  const name = $("name").value.trim() || "Player";
+  localStorage.setItem("gotris.name", name);
+  try {
+    const res = create
+      ? await post("/create-room", { player_name: name })
+      : await post("/join-room", { room_id: $("room").value.trim().toUpperCase(), player_name: name, invite });
+    invite = "";
+    history.replaceState(null, "", "?room=" + res.room_id);
+    $("code").textContent = res.room_id;
+    connect(res.room_id, res.join_token);
+  } catch (e) {
+    error(e.message);
+  }
+}
+
+function connect(room, token) {
+  const scheme = location.protocol === "https:" ? "wss:" : "ws:";
+  // No capabilities: messages come one per frame, unbatched.
+  ws = new WebSocket(`${scheme}//${location.host}/play?room=${room}&token=${encodeURIComponent(token)}&caps=`);
+  ws.onmessage = (ev) => handle(JSON.parse(ev.data));
+  ws.onclose = () => {
+    error("Disconnected from the server.");
+    game = null;
+    show("join");
+  };
+}
+
+function send(type, payload) {
+  if (ws && ws.readyState === WebSocket.OPEN) {
+    ws.send(JSON.stringify({ type, ts: Date.now(), payload: payload || {} }));
+  }
+}
+
+// --- Server messages ---
+
+function handle(env) {
+  const p = env.payload || {};
+  switch (env.type) {
+    case "assign_id":
+      me = p.player_id;
+      $("motd").textContent = p.motd || "";
+      show("lobby");
+      break;
+    case "lobby_update":
+      renderLobby(p);
+      break;
+    case "countdown":
+      $("countdown").textContent = p.value > 0 ? `Starting in ${p.value}...` : "";
+      break;
+    case "game_start":
+      $("countdown").textContent = "";
+      startGame(p.seed, p.settings || {});
+      break;
+    case "level_up":
+      if (game) game.level = p.level;
+      break;
+    case "receive_garbage":
+      if (game) game.garbage += p.lines;
+      break;
+    case "opponent_update":
+      renderOpponents(p.opponents || [], p.remaining || 0);
+      break;
+    case "match_over":
+      game = null;
+      $("countdown").textContent = p.winner_id === me ? "You win!" : `${p.winner_name || "Nobody"} wins. You placed #${p.your_rank}.`;
+      ready = false;
+      show("lobby");
+      break;
+    case "room_error":
+      error(p.message);
+      break;
+  }
+}
+
+function renderLobby(p) {
+  const list = $("players");
+  list.replaceChildren();
+  for (const pl of p.players || []) {
+    const li = document.createElement("li");
+    li.textContent = `${pl.ready ? "[x]" : "[ ]"} ${pl.name}${pl.player_id === p.host_id ? " (host)" : ""}${pl.bot ? " (CPU)" : ""}${pl.player_id === me ? " <" : ""}`;
+    if (pl.ready) li.className = "ready";
+    list.append(li);
+    if (pl.player_id === me) ready = pl.ready;
+  }
+  $("ready-btn").textContent = ready ? "Not ready" : "Ready";
+}
+
+function renderOpponents(opponents, remaining) {
+  const box = $("opponents");
+  box.replaceChildren();
+  for (const o of opponents) {
+    const div = document.createElement("div");
+    div.className = "opponent" + (o.alive ? "" : " out");
+    const canvas = document.createElement("canvas");
+    canvas.width = W * 8;
+    canvas.height = H * 8;
+    if (o.board) drawCells(canvas.getContext("2d"), o.board, 8);
+    const label = document.createElement("div");
+    label.textContent = `${o.player_name} ${o.score}`;
+    div.append(canvas, label);
+    box.append(div);
+  }
+  if (remaining > 0) {
+    const left = document.createElement("div");
+    left.textContent = `${remaining} still in`;
+    box.append(left);
+  }
+}
+
+// --- The game ---
+
+// mulberry32, seeded from the match seed so each match deals differently.
+function rng(seed) {
+  let a = Number(BigInt.asUintN(32, BigInt(seed)));
+  return () => {
+    a = (a + 0x6d2b79f5) | 0;
+    let t = Math.imul(a ^ (a >>> 15), 1 | a);
+    t = (t + Math.imul(t ^ (t >>> 7), 61 | t)) ^ t;
+    return ((t ^ (t >>> 14)) >>> 0) / 4294967296;
+  };
+}
+
+function startGame(seed, settings) {
+  const rand = rng(seed);
+  game = {
+    board: new Array(W * H).fill(0),
+    rand,
+    bag: [],
+    bagged: settings.randomizer !== "random",
+    attack: ATTACK_TABLES[settings.attack_table] || ATTACK_TABLES.standard,
+    sharedLevel: settings.speed_race_secs > 0, // the server sends level_up
+    piece: null,
+    score: 0,
+    lines: 0,
+    level: 1,
+    combo: 0,
+    b2b: false,
+    garbage: 0,
+    alive: true,
+    fallAt: performance.now(),
+  };
+  spawn();
+  show("game");
+  requestAnimationFrame(frame);
+}
+
+function nextType() {
+  if (!game.bagged) return Math.floor(game.rand() * 7);
+  if (game.bag.length === 0) {
+    game.bag = [0, 1, 2, 3, 4, 5, 6];
+    for (let i = 6; i > 0; i--) {
+      const j = Math.floor(game.rand() * (i + 1));
+      [game.bag[i], game.bag[j]] = [game.bag[j], game.bag[i]];
+    }
+  }
+  return game.bag.pop();
+}
+
+function spawn() {
+  const type = nextType();
+  const shape = SHAPES[type].map((row) => row.slice());
+  game.piece = { shape, color: PIECE_COLORS[type], x: Math.floor(W / 2) - Math.floor(shape[0].length / 2), y: 0 };
+  if (!fits(game.piece, 0, 0)) die();
+}
+
+function fits(piece, dx, dy, shape = piece.shape) {
+  for (let y = 0; y < shape.length; y++) {
+    for (let x = 0; x < shape[y].length; x++) {
+      if (!shape[y][x]) continue;
+      const bx = piece.x + x + dx, by = piece.y + y + dy;
+      if (bx < 0 || bx >= W || by >= H) return false;
+      if (by >= 0 && game.board[by * W + bx]) return false;
+    }
+  }
+  return true;
+}
+
+function move(dx, dy) {
+  if (!fits(game.piece, dx, dy)) return false;
+  game.piece.x += dx;
+  game.piece.y += dy;
+  return true;
+}
+
+function rotate(dir) {
+  const s = game.piece.shape, n = s.length;
+  const r = s.map((row, y) => row.map((_, x) => (dir > 0 ? s[n - 1 - x][y] : s[x][n - 1 - y])));
+  for (const kick of [0, -1, 1, -2, 2]) {
+    if (fits(game.piece, kick, 0, r)) {
+      game.piece.shape = r;
+      game.piece.x += kick;
+      return;
+    }
+  }
+}
+
+function lock() {
+  const p = game.piece;
+  p.shape.forEach((row, y) => row.forEach((c, x) => {
+    if (c && p.y + y >= 0) game.board[(p.y + y) * W + p.x + x] = p.color;
+  }));
+
+  let cleared = 0;
+  for (let y = H - 1; y >= 0; y--) {
+    if (game.board.slice(y * W, y * W + W).every((c) => c)) {
+      game.board.splice(y * W, W);
+      game.board.unshift(...new Array(W).fill(0));
+      cleared++;
+      y++;
+    }
+  }
+
+  if (cleared > 0) {
+    game.lines += cleared;
+    game.score += LINE_SCORES[cleared] * game.level;
+    if (!game.sharedLevel) game.level = Math.floor(game.lines / 10) + 1;
+    const b2b = cleared === 4 && game.b2b;
+    const attack = game.attack[cleared - 1];
+    send("lines_cleared", {
+      count: cleared,
+      attack_power: attack,
+      clear_type: ["single", "double", "triple", "tetris"][cleared - 1],
+      combo: game.combo,
+      b2b,
+    });
+    game.combo++;
+    game.b2b = cleared === 4;
+  } else {
+    game.combo = 0;
+    addGarbage();
+  }
+  spawn();
+}
+
+function addGarbage() {
+  if (game.garbage === 0) return;
+  const lines = Math.min(game.garbage, H);
+  game.garbage = 0;
+  const hole = Math.floor(Math.random() * W);
+  for (let i = 0; i < lines; i++) {
+    game.board.splice(0, W);
+    const row = new Array(W).fill(GARBAGE);
+    row[hole] = 0;
+    game.board.push(...row);
+  }
+}
+
+function die() {
+  game.alive = false;
+  snapshot(true);
+  send("player_dead");
+}
+
+function snapshot(force) {
+  const snap = { score: game.score, level: game.level, lines: game.lines, alive: game.alive, board: game.board };
+  const json = JSON.stringify(snap);
+  if (!force && json === lastSnap) return;
+  lastSnap = json;
+  send("board_snapshot", snap);
+}
+
+let snapAt = 0;
+
+function frame(now) {
+  if (!game) return;
+  if (game.alive && now >= game.fallAt) {
+    if (!move(0, 1)) lock();
+    game.fallAt = now + GRAVITY[Math.min(game.level, GRAVITY.length) - 1];
+  }
+  if (now - snapAt >= SNAPSHOT_EVERY) {
+    snapAt = now;
+    snapshot(false);
+  }
+  draw();
+  requestAnimationFrame(frame);
+}
+
+function drawCells(ctx, cells, size) {
+  ctx.fillStyle = COLORS[0];
+  ctx.fillRect(0, 0, W * size, H * size);
+  cells.forEach((c, i) => {
+    if (!c) return;
+    ctx.fillStyle = COLORS[c] || COLORS[GARBAGE];
+    ctx.fillRect((i % W) * size, Math.floor(i / W) * size, size - 1, size - 1);
+  });
+}
+
+function draw() {
+  const ctx = $("board").getContext("2d");
+  const cells = game.board.slice();
+  const p = game.piece;
+  if (game.alive) {
+    p.shape.forEach((row, y) => row.forEach((c, x) => {
+      if (c && p.y + y >= 0) cells[(p.y + y) * W + p.x + x] = p.color;
+    }));
+  }
+  drawCells(ctx, cells, CELL);
+  $("stats").innerHTML = "";
+  for (const [k, v] of [["Score", game.score], ["Lines", game.lines], ["Level", game.level], ["Incoming", game.garbage]]) {
+    const div = document.createElement("div");
+    div.textContent = `${k}: ${v}`;
+    $("stats").append(div);
+  }
+  if (!game.alive) {
+    ctx.fillStyle = "rgba(0,0,0,0.6)";
+    ctx.fillRect(0, 0, W * CELL, H * CELL);
+    ctx.fillStyle = "#f55";
+    ctx.font = "bold 24px monospace";
+    ctx.textAlign = "center";
+    ctx.fillText("GAME OVER", (W * CELL) / 2, (H * CELL) / 2);
+  }
+}
+
+document.addEventListener("keydown", (ev) => {
+  if (!game || !game.alive) return;
+  switch (ev.key) {
+    case "ArrowLeft": move(-1, 0); break;
+    case "ArrowRight": move(1, 0); break;
+    case "ArrowDown":
+      if (move(0, 1)) game.score++;
+      break;
+    case "ArrowUp": case "x": case "X": rotate(1); break;
+    case "z": case "Z": rotate(-1); break;
+    case " ":
+      while (move(0, 1)) game.score += 2;
+      lock();
+      break;
+    default:
+      return;
+  }
+  ev.preventDefault();
+});
+
+// --- Setup ---
+
+$("name").value = localStorage.getItem("gotris.name") || "";
+const params = new URLSearchParams(location.search);
+$("room").value = params.get("room") || "";
+// An invite link's one-use token, sent with the first join.
+let invite = params.get("invite") || "";
+$("join-btn").onclick = () => enter(false);
+$("create-btn").onclick = () => enter(true);
+$("room").onkeydown = (ev) => { if (ev.key === "Enter") enter(false); };
+$("ready-btn").onclick = () => send("ready", { ready: !ready });
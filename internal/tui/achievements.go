@@ -0,0 +1,135 @@
+package tui
+
+import (
+	"maps"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/prefs"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Achievements ---
+//
+// Achievements are checked as each finished game is recorded, so they're
+// earned offline too, and kept in prefs. Each unlock, and each switch of
+// server, also sends them to the server under the profile key, which
+// answers with any earned on other machines. The results screen names
+// the ones a game unlocked, and A on the match history screen lists them
+// all.
+
+const (
+	// sprintTime is how soon the sprint achievement's lines must be
+	// cleared.
+	sprintTime = 2 * time.Minute
+	// closeCallGarbage is how many lines of garbage must be waiting for a
+	// win to be a close call.
+	closeCallGarbage = 3
+	// manyWins is how many wins the ten wins achievement takes.
+	manyWins = 10
+)
+
+// AchievementsSyncedMsg is the result of sending the achievements to a
+// server: all those it keeps for the player.
+type AchievementsSyncedMsg struct {
+	Server   string
+	Unlocked map[string]int64
+	Err      error
+}
+
+// achievementsSyncCmd sends the achievements in p to the client's server.
+func achievementsSyncCmd(c *client.Client, p *prefs.Prefs) tea.Cmd {
+	if c == nil || p == nil || p.ProfileKey == "" {
+		return nil
+	}
+	server, key, unlocked := c.Server(), p.ProfileKey, maps.Clone(p.Achievements)
+	return func() tea.Msg {
+		a, err := c.AddAchievements(key, unlocked)
+		return AchievementsSyncedMsg{Server: server, Unlocked: a.Unlocked, Err: err}
+	}
+}
+
+func (m Model) handleAchievementsSynced(msg AchievementsSyncedMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil || m.prefs == nil || m.client == nil || msg.Server != m.client.Server() {
+		return m, nil
+	}
+	if m.prefs.MergeAchievements(msg.Unlocked) {
+		m.prefs.Save()
+	}
+	return m, nil
+}
+
+// unlockAchievements unlocks what the game just recorded earned, noting
+// the new ones for the results screen, and sends any to the server.
+func (m *Model) unlockAchievements() tea.Cmd {
+	m.unlocked = nil
+	if m.prefs == nil || m.gameState == nil {
+		return nil
+	}
+	gs := m.gameState
+	st := &gs.Stats
+	earned := map[string]bool{
+		protocol.AchievementFirstTetris: st.Clears[4] > 0,
+		protocol.AchievementFirstTSpin:  st.TSpins > 0,
+		protocol.AchievementSprint:      st.Sprint > 0 && st.Sprint < sprintTime,
+	}
+	if r := m.matchResult; m.mode == ModeMulti && r != nil && r.WinnerID == m.playerID && len(r.Standings) > 1 {
+		earned[protocol.AchievementFirstWin] = true
+		earned[protocol.AchievementCloseCall] = gs.GarbageQueue >= closeCallGarbage
+		if _, ok := m.prefs.Achievements[protocol.AchievementTenWins]; !ok {
+			games, _ := prefs.Games()
+			earned[protocol.AchievementTenWins] = prefs.Wins(games) >= manyWins
+		}
+	}
+	for _, id := range protocol.AchievementIDs {
+		if earned[id] && m.prefs.Unlock(id) {
+			m.unlocked = append(m.unlocked, id)
+		}
+	}
+	if len(m.unlocked) == 0 {
+		return nil
+	}
+	m.prefs.Save()
+	return achievementsSyncCmd(m.client, m.prefs)
+}
+
+func (m Model) handleAchievementsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "esc" {
+		m.screen = ScreenHistory
+	}
+	return m, nil
+}
+
+// RenderUnlocked is the results screen's lines about the achievements a
+// game unlocked.
+func RenderUnlocked(ids []string) string {
+	var sb strings.Builder
+	for _, id := range ids {
+		sb.WriteString(readyStyle.Render(i18n.T("achievements.new", i18n.T("achievement."+id))) + "\n")
+	}
+	return sb.String()
+}
+
+// RenderAchievements renders the achievements screen: every achievement,
+// with when it was unlocked or what it takes.
+func RenderAchievements(unlocked map[string]int64) string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(i18n.T("achievements.title")) + "\n\n")
+	sb.WriteString(infoStyle.Render(i18n.T("achievements.count", len(unlocked), len(protocol.AchievementIDs))) + "\n\n")
+	for _, id := range protocol.AchievementIDs {
+		name, desc := i18n.T("achievement."+id), i18n.T("achievement."+id+".desc")
+		if at, ok := unlocked[id]; ok {
+			sb.WriteString(winnerStyle.Render("★ "+name) + "  " +
+				infoStyle.Render(i18n.T("achievements.unlocked", time.UnixMilli(at).Format("2006-01-02"))) + "\n")
+		} else {
+			sb.WriteString(infoStyle.Render("☆ "+name) + "\n")
+		}
+		sb.WriteString(infoStyle.Render("  "+desc) + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(hintLine("ESC", i18n.T("hint.back")))
+	return sb.String()
+}
@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/internal/i18n"
+)
+
+// --- End-screen animations ---
+//
+// When a game ends we play a short tick-driven animation before showing the
+// result: tetromino confetti for the winner, and the board greying out and
+// collapsing for everyone else. Positions are pure functions of the frame
+// number so the Model stays a plain value.
+
+// AnimTickMsg advances the end-screen animation by one frame.
+type AnimTickMsg time.Time
+
+const (
+	animFrameInterval = 50 * time.Millisecond
+	confettiFrames    = 36
+	collapseFrames    = 2 * game.BoardHeight
+	confettiCount     = 14
+	confettiWidth     = game.BoardWidth * 2 // columns, same width as the playfield
+	confettiHeight    = game.BoardHeight
+)
+
+// endAnim is the state of the end-screen animation.
+type endAnim struct {
+	victory  bool
+	frame    int
+	board    *game.Board // final board, for the collapse
+	confetti []confettiPiece
+}
+
+// confettiPiece is one falling tetromino in the victory animation.
+type confettiPiece struct {
+	piece  *game.Piece
+	x      int
+	startY int
+	speed  int
+}
+
+func animTickCmd() tea.Cmd {
+	return tea.Tick(animFrameInterval, func(t time.Time) tea.Msg {
+		return AnimTickMsg(t)
+	})
+}
+
+// newEndAnim builds the animation for a finished game.
+func newEndAnim(victory bool, board *game.Board) *endAnim {
+	a := &endAnim{victory: victory, board: board}
+	if victory {
+		types := []game.PieceType{game.PieceI, game.PieceO, game.PieceT, game.PieceS, game.PieceZ, game.PieceJ, game.PieceL}
+		for i := 0; i < confettiCount; i++ {
+			a.confetti = append(a.confetti, confettiPiece{
+				piece:  game.NewPiece(types[rand.Intn(len(types))]),
+				x:      rand.Intn(confettiWidth - 2),
+				startY: -rand.Intn(confettiHeight),
+				speed:  1 + rand.Intn(2),
+			})
+		}
+	}
+	return a
+}
+
+// done reports whether the animation has played all of its frames.
+func (a *endAnim) done() bool {
+	if a.victory {
+		return a.frame >= confettiFrames
+	}
+	return a.frame >= collapseFrames
+}
+
+// render draws the current frame.
+func (a *endAnim) render() string {
+	if a.victory {
+		return renderConfetti(a.confetti, a.frame) + "\n\n" + winnerStyle.Render(i18n.T("result.winner"))
+	}
+	return renderCollapse(a.board, a.frame) + "\n\n" + gameOverStyle.Render(i18n.T("result.game_over"))
+}
+
+// renderConfetti draws tetrominoes falling through an empty field.
+func renderConfetti(pieces []confettiPiece, frame int) string {
+	grid := make([][]string, confettiHeight)
+	for y := range grid {
+		grid[y] = make([]string, confettiWidth)
+	}
+
+	for _, c := range pieces {
+		// Wrap around so the field stays busy for the whole animation.
+		span := confettiHeight + len(c.piece.Shape)
+		top := (c.startY+frame*c.speed)%span - len(c.piece.Shape)
+		for py, row := range c.piece.Shape {
+			for px, filled := range row {
+				x, y := c.x+px, top+py
+				if filled && y >= 0 && y < confettiHeight && x < confettiWidth {
+					grid[y][x] = lipgloss.NewStyle().Foreground(lipgloss.Color(colors[c.piece.Color])).Render(halfBlock(c.piece.Color))
+				}
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for y, row := range grid {
+		for _, cell := range row {
+			if cell == "" {
+				sb.WriteString(" ")
+				continue
+			}
+			sb.WriteString(cell)
+		}
+		if y < len(grid)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// renderCollapse greys the board out from the bottom up, then lets the
+// grey rows sink out of the frame.
+func renderCollapse(b *game.Board, frame int) string {
+	var sb strings.Builder
+	grey := lipgloss.NewStyle().Foreground(lipgloss.Color(colors[8]))
+
+	greyFrom := game.BoardHeight - frame - 1 // rows at or below this are grey
+	sunk := frame - game.BoardHeight + 1     // rows dropped off the bottom
+	if sunk < 0 {
+		sunk = 0
+	}
+
+	for y := 0; y < game.BoardHeight; y++ {
+		srcY := y - sunk
+		for x := 0; x < game.BoardWidth; x++ {
+			if b == nil || srcY < 0 || !b.Cells[srcY][x].Filled {
+				sb.WriteString("  ")
+				continue
+			}
+			cell := b.Cells[srcY][x]
+			if srcY >= greyFrom || sunk > 0 {
+				sb.WriteString(grey.Render("▓▓"))
+			} else {
+				sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(colors[cell.Color])).Render(block(cell.Color)))
+			}
+		}
+		if y < game.BoardHeight-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return boardStyle.Render(sb.String())
+}
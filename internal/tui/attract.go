@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/internal/i18n"
+)
+
+// --- Attract mode ---
+//
+// Left on the main menu for attractIdle, the menu is flanked by a demo:
+// two CPU players, the same AutoPlace bots the server runs, playing each
+// other in faded colors and sending each other garbage. It's driven by
+// the menu's general tick. Any key or click stops it (and does nothing
+// else); with reduced motion on it never starts.
+
+const (
+	// attractIdle is how long the main menu waits for a key before the
+	// demo starts.
+	attractIdle = 30 * time.Second
+	// attractPieceDelay is how long a demo bot takes over each piece,
+	// give or take up to attractPieceJitter.
+	attractPieceDelay  = 300 * time.Millisecond
+	attractPieceJitter = 200 * time.Millisecond
+	// attractStack and attractPiece are the faded colors the demo boards
+	// are drawn in.
+	attractStack = "238"
+	attractPiece = "243"
+)
+
+// attractDemo is a demo match between two bots.
+type attractDemo struct {
+	loops [2]*game.Loop
+	think [2]time.Time // when each bot next places a piece
+}
+
+func newAttractDemo(now time.Time) *attractDemo {
+	seed := rand.Int63()
+	d := &attractDemo{}
+	for i := range d.loops {
+		gs := game.NewSeededGameStateWithRules("demo", "CPU", seed, game.Rules{})
+		d.loops[i] = game.NewLoop(gs)
+		d.think[i] = now.Add(time.Duration(i) * attractPieceDelay / 2)
+	}
+	return d
+}
+
+// advance plays the demo up to now. A bot's attacks go to the other, and
+// once one tops out a new match starts.
+func (d *attractDemo) advance(now time.Time) *attractDemo {
+	for i, loop := range d.loops {
+		events := loop.Advance(now)
+		gs := loop.State()
+		if !now.Before(d.think[i]) && gs.Phase == game.PhaseFalling && !gs.IsGameOver {
+			loop.AutoPlace()
+			events = append(events, loop.Advance(now)...)
+			d.think[i] = now.Add(attractPieceDelay - attractPieceJitter/2 + time.Duration(rand.Int63n(int64(attractPieceJitter))))
+		}
+		for _, ev := range events {
+			if ev.Kind == game.EventLock && ev.Attack > 0 {
+				d.loops[1-i].ReceiveGarbage(ev.Attack)
+			}
+		}
+		if gs.IsGameOver {
+			return newAttractDemo(now)
+		}
+	}
+	return d
+}
+
+// attractTick runs the demo on the main menu, starting it once the menu
+// has been idle long enough.
+func (m *Model) attractTick(now time.Time) {
+	if m.screen != ScreenMainMenu || (m.prefs != nil && m.prefs.ReducedMotion) {
+		m.attract = nil
+		return
+	}
+	if m.idleSince.IsZero() {
+		m.idleSince = now
+	}
+	switch {
+	case m.attract != nil:
+		m.attract = m.attract.advance(now)
+	case now.Sub(m.idleSince) >= attractIdle:
+		m.attract = newAttractDemo(now)
+	}
+}
+
+// wake notes a key or click: it stops the demo, reporting whether one
+// was running, and restarts the idle clock.
+func (m *Model) wake() bool {
+	running := m.attract != nil
+	m.attract = nil
+	m.idleSince = time.Now()
+	return running
+}
+
+// renderAttract sets menu between the demo's boards, if they fit in
+// width.
+func renderAttract(d *attractDemo, menu string, width int) string {
+	left, right := RenderDemoBoard(d.loops[0].State()), RenderDemoBoard(d.loops[1].State())
+	joined := lipgloss.JoinHorizontal(lipgloss.Center, left, "   ", menu, "   ", right)
+	if lipgloss.Width(joined) > width {
+		return menu
+	}
+	return joined + "\n\n" + infoStyle.Render(i18n.T("attract.hint"))
+}
+
+// RenderDemoBoard renders a demo board in faded grays, without the
+// ghost piece, so it stays in the background.
+func RenderDemoBoard(gs *game.GameState) string {
+	stack := lipgloss.NewStyle().Foreground(lipgloss.Color(attractStack))
+	piece := lipgloss.NewStyle().Foreground(lipgloss.Color(attractPiece))
+	falling := gs.Phase == game.PhaseFalling && gs.CurrentPiece != nil
+
+	var sb strings.Builder
+	for y := range game.BoardHeight {
+		for x := range game.BoardWidth {
+			switch {
+			case falling && pieceAt(gs.CurrentPiece, x, y):
+				sb.WriteString(piece.Render(block(gs.CurrentPiece.Color)))
+			case gs.Board.Cells[y][x].Filled:
+				sb.WriteString(stack.Render(block(gs.Board.Cells[y][x].Color)))
+			default:
+				sb.WriteString("  ")
+			}
+		}
+		if y < game.BoardHeight-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return boardStyle.BorderForeground(lipgloss.Color(attractStack)).Render(sb.String())
+}
+
+// pieceAt reports whether p covers the board cell (x, y).
+func pieceAt(p *game.Piece, x, y int) bool {
+	py, px := y-p.Y, x-p.X
+	return py >= 0 && py < len(p.Shape) && px >= 0 && px < len(p.Shape[py]) && p.Shape[py][px]
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/clipboard"
+	"github.com/hersh/gotris/internal/i18n"
+)
+
+// --- Clipboard ---
+//
+// Y in the lobby copies the room code, or the join link on the QR code
+// screen, to send to friends, and CTRL+V on the join screen pastes one.
+// Both run as commands: the clipboard tools are separate programs.
+
+// CopiedMsg is the result of copying the room's code or join link.
+type CopiedMsg struct {
+	Code string // the room's
+	Text string // what was copied
+	Err  error
+}
+
+// PastedMsg is the text read from the clipboard.
+type PastedMsg struct {
+	Text string
+	Err  error
+}
+
+func copyCmd(code, text string) tea.Cmd {
+	return func() tea.Msg {
+		return CopiedMsg{Code: code, Text: text, Err: clipboard.Copy(os.Stderr, text)}
+	}
+}
+
+func pasteCmd() tea.Cmd {
+	return func() tea.Msg {
+		text, err := clipboard.Paste()
+		return PastedMsg{Text: text, Err: err}
+	}
+}
+
+func (m Model) handleCopied(msg CopiedMsg) (tea.Model, tea.Cmd) {
+	if m.screen != ScreenLobby || msg.Code != m.roomCode {
+		return m, nil
+	}
+	if msg.Err != nil {
+		m.lobbyNote = i18n.T("clipboard.copy_failed", msg.Err)
+	} else {
+		m.lobbyNote = i18n.T("clipboard.copied", msg.Text)
+	}
+	return m, nil
+}
+
+func (m Model) handlePasted(msg PastedMsg) (tea.Model, tea.Cmd) {
+	if m.screen != ScreenJoinRoom {
+		return m, nil
+	}
+	if msg.Err != nil {
+		m.roomError = i18n.T("clipboard.paste_failed", msg.Err)
+		return m, nil
+	}
+	m.roomError = ""
+	if token := inviteFromPaste(msg.Text); token != "" && m.client != nil {
+		return m.joinInvite(token)
+	}
+	m.roomInput = roomCodeFromPaste(m.roomInput, msg.Text)
+	return m, nil
+}
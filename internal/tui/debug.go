@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/logging"
+)
+
+// --- Debug overlay ---
+//
+// A hidden panel, toggled with F12, that covers the bottom of the screen
+// with the latest log lines, for looking into network trouble without
+// leaving the game.
+
+const debugLines = 8
+
+// WithDebugLog returns the model with w attached, so F12 can show its
+// latest lines.
+func (m Model) WithDebugLog(w *logging.Writer) Model {
+	m.debugLog = w
+	return m
+}
+
+// overlayDebug draws the log panel over the bottom lines of view.
+func (m Model) overlayDebug(view string) string {
+	line := lipgloss.NewStyle().MaxWidth(max(m.width, 20))
+	panel := []string{line.Render(titleStyle.Render(i18n.T("debug.title")) + infoStyle.Render("  "+i18n.T("debug.hide")))}
+	for _, l := range m.debugLog.Recent(debugLines) {
+		panel = append(panel, line.Render(infoStyle.Render(l)))
+	}
+
+	lines := strings.Split(view, "\n")
+	lines = lines[:max(len(lines)-len(panel), 0)]
+	return strings.Join(append(lines, panel...), "\n")
+}
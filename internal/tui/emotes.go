@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Emotes ---
+//
+// Quick predefined messages fired with F1-F5 during a match. The server
+// passes each one to the whole room, and it pops up as a bubble over the
+// sender's mini-board (or under our own HUD) for a couple of seconds.
+
+// emoteKeys maps hotkeys to the emote they fire.
+var emoteKeys = map[string]string{
+	"f1": protocol.EmoteGLHF,
+	"f2": protocol.EmoteGG,
+	"f3": protocol.EmoteNice,
+	"f4": protocol.EmoteOops,
+	"f5": protocol.EmoteBringIt,
+}
+
+// emoteDuration is how long an emote bubble stays up.
+const emoteDuration = 2500 * time.Millisecond
+
+var emoteStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.Color("0")).
+	Background(lipgloss.Color("226"))
+
+// emoteBubble is an emote on show, numbered so a newer emote from the
+// same player outlasts the timer of the one it replaced.
+type emoteBubble struct {
+	emote string
+	seq   int
+}
+
+// EmoteDoneMsg takes down a player's emote bubble, unless they have fired
+// another since.
+type EmoteDoneMsg struct {
+	playerID string
+	seq      int
+}
+
+func emoteDoneCmd(playerID string, seq int) tea.Cmd {
+	return tea.Tick(emoteDuration, func(time.Time) tea.Msg {
+		return EmoteDoneMsg{playerID: playerID, seq: seq}
+	})
+}
+
+// showEmote puts up a bubble for a player's emote.
+func (m *Model) showEmote(payload protocol.EmotePayload) tea.Cmd {
+	if m.emotes == nil {
+		m.emotes = make(map[string]emoteBubble)
+	}
+	m.emoteSeq++
+	m.emotes[payload.PlayerID] = emoteBubble{emote: payload.Emote, seq: m.emoteSeq}
+	return emoteDoneCmd(payload.PlayerID, m.emoteSeq)
+}
+
+func (m Model) handleEmoteDone(msg EmoteDoneMsg) (tea.Model, tea.Cmd) {
+	if b, ok := m.emotes[msg.playerID]; ok && b.seq == msg.seq {
+		delete(m.emotes, msg.playerID)
+	}
+	return m, nil
+}
+
+// emoteTexts returns the text of each emote on show, by player ID.
+func (m Model) emoteTexts() map[string]string {
+	texts := make(map[string]string, len(m.emotes))
+	for id, b := range m.emotes {
+		texts[id] = emoteText(b.emote)
+	}
+	return texts
+}
+
+// emoteText is the localized text for an emote.
+func emoteText(emote string) string {
+	return i18n.T("emote." + emote)
+}
+
+// renderEmote draws an emote bubble no wider than width cells.
+func renderEmote(text string, width int) string {
+	return emoteStyle.MaxWidth(width).Render("«" + text + "»")
+}
@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hersh/gotris/internal/export"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/prefs"
+)
+
+// --- Statistics export ---
+//
+// Every finished game's statistics go in the game log (prefs.AddGame).
+// From the match history screen, X exports the log together with the
+// matches on screen, as CSV, or JSON with shift; cmd/client's
+// --export-stats does the same from the command line.
+
+// recordGame adds the game just finished to the game log. In a match it
+// is called once the results are in, for the player's place.
+func (m *Model) recordGame() {
+	if m.gameState == nil {
+		return
+	}
+	gs := m.gameState
+	st := &gs.Stats
+	rec := prefs.GameRecord{
+		PlayedAt:   st.Start,
+		Mode:       "single",
+		Score:      gs.Score,
+		Lines:      gs.Lines,
+		Level:      gs.Level,
+		DurationMs: st.Duration().Milliseconds(),
+		Pieces:     st.Pieces,
+		PPS:        st.PPS(),
+		APM:        st.APM(),
+		Sent:       st.Sent,
+		Received:   st.Received,
+		Tetrises:   st.Clears[4],
+		TSpins:     st.TSpins,
+		MaxCombo:   st.MaxCombo,
+	}
+	if m.mode == ModeMulti {
+		rec.Mode = "multi"
+		rec.RoomID = m.roomCode
+		rec.Seed = m.seedText()
+		if m.client != nil {
+			rec.Server = m.client.Server()
+		}
+		if r := m.matchResult; r != nil {
+			rec.Place = r.YourRank
+			rec.Players = len(r.Standings)
+			for _, s := range r.Standings {
+				if s.PlayerID == m.playerID {
+					rec.KOs = s.KOs
+				}
+			}
+		}
+	}
+	prefs.AddGame(rec)
+}
+
+// exportStats writes the game log and the matches on the history screen
+// to a file in format in the current directory, and says where.
+func (m *Model) exportStats(format string) {
+	m.roomError, m.historyNote = "", ""
+	games, err := prefs.Games()
+	if err != nil {
+		m.roomError = i18n.T("history.export_failed", err)
+		return
+	}
+	var ownIDs []string
+	if m.prefs != nil {
+		ownIDs = m.prefs.RecentPlayerIDs
+	}
+	rows := export.Rows(games, m.history, ownIDs)
+
+	path := fmt.Sprintf("gotris-stats-%s.%s", time.Now().Format("20060102-150405"), format)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		m.roomError = i18n.T("history.export_failed", err)
+		return
+	}
+	err = export.Write(f, format, rows)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		m.roomError = i18n.T("history.export_failed", err)
+		return
+	}
+	m.historyNote = i18n.T("history.exported", len(rows), path)
+}
@@ -0,0 +1,248 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/export"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Match history ---
+//
+// The server gives us a new player ID for every room we join, and keeps
+// each ID's matches. The history screen asks for the matches of the IDs
+// we've had lately (saved in prefs) and lists them together, newest
+// first; ENTER shows one match's standings and settings.
+
+// historyLength is the most matches the history screen lists.
+const historyLength = 20
+
+// HistoryLoadedMsg is the result of looking up our recent matches.
+type HistoryLoadedMsg struct {
+	Matches []protocol.MatchRecord
+	Err     error
+}
+
+// historyCmd fetches the matches of each of playerIDs and merges them,
+// newest first. It stops at the first error: the server is unreachable
+// or doesn't keep history, and the other IDs would fail the same way.
+func historyCmd(c *client.Client, playerIDs []string) tea.Cmd {
+	return func() tea.Msg {
+		var matches []protocol.MatchRecord
+		for _, id := range playerIDs {
+			recs, err := c.MatchHistory(id, historyLength)
+			if err != nil {
+				return HistoryLoadedMsg{Err: err}
+			}
+			matches = append(matches, recs...)
+		}
+		slices.SortFunc(matches, func(a, b protocol.MatchRecord) int {
+			return cmp.Compare(b.StartedAt, a.StartedAt)
+		})
+		if len(matches) > historyLength {
+			matches = matches[:historyLength]
+		}
+		return HistoryLoadedMsg{Matches: matches}
+	}
+}
+
+// rememberPlayerID saves the ID the server gave us, so the history screen
+// can find this room's matches later.
+func (m Model) rememberPlayerID(id string) {
+	if m.prefs == nil {
+		return
+	}
+	m.prefs.AddPlayerID(id)
+	m.prefs.Save()
+}
+
+// openHistory loads the history screen.
+func (m Model) openHistory() (tea.Model, tea.Cmd) {
+	if m.client == nil {
+		return m, nil
+	}
+	m.history = nil
+	m.historyCursor = 0
+	m.historyDetail = false
+	m.roomError = ""
+	m.historyNote = ""
+	if m.prefs == nil || len(m.prefs.RecentPlayerIDs) == 0 {
+		m.screen = ScreenHistory
+		return m, nil
+	}
+	m.screen = ScreenConnecting
+	return m, historyCmd(m.client, m.prefs.RecentPlayerIDs)
+}
+
+func (m Model) handleHistoryLoaded(msg HistoryLoadedMsg) (tea.Model, tea.Cmd) {
+	if m.screen != ScreenConnecting && m.screen != ScreenHistory {
+		return m, nil
+	}
+	m.screen = ScreenHistory
+	if msg.Err != nil {
+		m.roomError = requestErrorText(msg.Err)
+		return m, nil
+	}
+	m.history = msg.Matches
+	m.historyCursor = min(m.historyCursor, max(0, len(msg.Matches)-1))
+	return m, nil
+}
+
+func (m Model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.historyDetail {
+		if msg.String() == "esc" || msg.String() == "enter" {
+			m.historyDetail = false
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "esc":
+		m.screen = ScreenMainMenu
+		m.history = nil
+		m.roomError = ""
+	case "up", "k":
+		m.historyCursor = moveCursor(m.historyCursor, -1, len(m.history))
+	case "down", "j":
+		m.historyCursor = moveCursor(m.historyCursor, 1, len(m.history))
+	case "enter":
+		if m.historyCursor < len(m.history) {
+			m.historyDetail = true
+		}
+	case "r":
+		return m.openHistory()
+	case "x":
+		m.exportStats(export.CSV)
+	case "X":
+		m.exportStats(export.JSON)
+	case "a":
+		if m.prefs != nil {
+			m.screen = ScreenAchievements
+		}
+	}
+	return m, nil
+}
+
+// ownStanding finds our row in a match's standings, given the player IDs
+// we've had.
+func ownStanding(rec protocol.MatchRecord, ownIDs []string) (protocol.PlayerStanding, bool) {
+	for _, st := range rec.Standings {
+		if slices.Contains(ownIDs, st.PlayerID) {
+			return st, true
+		}
+	}
+	return protocol.PlayerStanding{}, false
+}
+
+func (m Model) renderHistory() string {
+	var ownIDs []string
+	if m.prefs != nil {
+		ownIDs = m.prefs.RecentPlayerIDs
+	}
+	content := RenderHistory(m.history, ownIDs, m.historyCursor, m.roomError, m.historyNote)
+	if m.historyDetail && m.historyCursor < len(m.history) {
+		content = RenderMatchDetail(m.history[m.historyCursor], ownIDs)
+	}
+	return m.renderCentered(content)
+}
+
+// RenderHistory renders the list of our recent matches. note, if set,
+// says where the last export went.
+func RenderHistory(matches []protocol.MatchRecord, ownIDs []string, cursor int, errMsg, note string) string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render(i18n.T("history.title")) + "\n\n")
+
+	if errMsg != "" {
+		sb.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render(errMsg) + "\n\n")
+	}
+
+	if len(matches) == 0 {
+		if errMsg == "" {
+			sb.WriteString(infoStyle.Render(i18n.T("history.empty")) + "\n")
+		}
+	} else {
+		sb.WriteString(infoStyle.Render(fmt.Sprintf("    %-16s  %-16s  %-7s  %8s  %5s  %5s",
+			i18n.T("history.col_when"), i18n.T("history.col_room"), i18n.T("standings.rank"),
+			i18n.T("standings.score"), i18n.T("standings.lines"), i18n.T("standings.time"))) + "\n")
+		sb.WriteString(infoStyle.Render("    ----------------  ----------------  -------  --------  -----  -----") + "\n")
+
+		for i, rec := range matches {
+			room := rec.RoomID
+			if rec.RoomTitle != "" {
+				room = rec.RoomTitle
+			}
+			if utf8.RuneCountInString(room) > 16 {
+				room = string([]rune(room)[:15]) + "…"
+			}
+			rank, score, lines, survival := "-", 0, 0, "-"
+			if st, ok := ownStanding(rec, ownIDs); ok {
+				rank = fmt.Sprintf("#%d/%d", st.Rank, len(rec.Standings))
+				score, lines, survival = st.Score, st.Lines, formatDuration(st.SurvivalMs)
+			}
+
+			prefix := "  "
+			rowStyle := infoStyle
+			if i == cursor {
+				prefix = "> "
+				rowStyle = cursorStyle
+			}
+			when := time.UnixMilli(rec.StartedAt).Format("2006-01-02 15:04")
+			sb.WriteString(rowStyle.Render(fmt.Sprintf("%s  %-16s  %-16s  %-7s  %8d  %5d  %5s",
+				prefix, when, room, rank, score, lines, survival)) + "\n")
+		}
+	}
+
+	sb.WriteString("\n")
+	if len(matches) > 0 {
+		sb.WriteString(hintLine("↑/↓", i18n.T("history.select")))
+		sb.WriteString(hintLine("ENTER", i18n.T("history.details")))
+	}
+	if note != "" {
+		sb.WriteString(readyStyle.Render(note) + "\n\n")
+	}
+	sb.WriteString(hintLine("R", i18n.T("rooms.refresh")))
+	sb.WriteString(hintLine("X", i18n.T("history.export")))
+	sb.WriteString(hintLine("A", i18n.T("history.achievements")))
+	sb.WriteString(hintLine("ESC", i18n.T("hint.back")))
+
+	return sb.String()
+}
+
+// RenderMatchDetail renders one match from the history: when and where it
+// was played, the final standings and the room's settings.
+func RenderMatchDetail(rec protocol.MatchRecord, ownIDs []string) string {
+	var sb strings.Builder
+
+	room := rec.RoomID
+	if rec.RoomTitle != "" {
+		room = rec.RoomTitle + " (" + rec.RoomID + ")"
+	}
+	sb.WriteString(titleStyle.Render(i18n.T("history.match", room)) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("history.played",
+		time.UnixMilli(rec.StartedAt).Format("2006-01-02 15:04"), formatDuration(rec.DurationMs))) + "\n")
+	sb.WriteString(infoStyle.Render(roomTypeName(rec.RoomType)) + "\n\n")
+
+	own, _ := ownStanding(rec, ownIDs)
+	sb.WriteString(RenderStandings(rec.Standings, own.PlayerID))
+	if own.PlayerID != "" && own.Sent > 0 {
+		sb.WriteString("\n" + infoStyle.Render(i18n.T("history.sent", own.Sent)) + "\n")
+	}
+
+	sb.WriteString("\n" + RenderRoomSettings(rec.Settings, false))
+
+	sb.WriteString("\n")
+	sb.WriteString(hintLine("ESC", i18n.T("hint.back")))
+
+	return sb.String()
+}
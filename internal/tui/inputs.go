@@ -0,0 +1,102 @@
+package tui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/i18n"
+)
+
+// --- Input display ---
+//
+// An on-screen pad under the HUD that lights up each action as it is
+// pressed, plus a strip of the most recent actions, for streams, tutorials
+// and reviewing finesse. Terminals don't report key releases, so a key
+// stays lit for a short flash after each press instead of while held.
+
+// inputAction is one gameplay action shown on the pad.
+type inputAction int
+
+const (
+	inputLeft inputAction = iota
+	inputRight
+	inputDown
+	inputRotate
+	inputDrop
+	inputHold
+	inputRotateBack
+	inputActionCount
+)
+
+// inputGlyphs are the symbols drawn for each action.
+var inputGlyphs = [inputActionCount]string{"←", "→", "↓", "↻", "⤓", "H", "↺"}
+
+const (
+	inputFlashDuration = 150 * time.Millisecond
+	maxInputHistory    = 10
+)
+
+// InputFlashDoneMsg unlights an action on the input display, unless it was
+// pressed again since (a newer sequence number).
+type InputFlashDoneMsg struct {
+	action inputAction
+	seq    int
+}
+
+func inputFlashCmd(action inputAction, seq int) tea.Cmd {
+	return tea.Tick(inputFlashDuration, func(time.Time) tea.Msg {
+		return InputFlashDoneMsg{action: action, seq: seq}
+	})
+}
+
+// showInputs reports whether the input display is turned on.
+func (m Model) showInputs() bool {
+	return m.prefs != nil && m.prefs.InputDisplay
+}
+
+// recordInput lights up the action for key, if it is one, and adds it to
+// the history.
+func (m *Model) recordInput(key string) tea.Cmd {
+	action, ok := m.keymap().action(key)
+	if !ok || !m.showInputs() {
+		return nil
+	}
+	m.inputSeq++
+	m.inputLit[action] = m.inputSeq
+	m.inputHistory = append(m.inputHistory, action)
+	if len(m.inputHistory) > maxInputHistory {
+		m.inputHistory = m.inputHistory[len(m.inputHistory)-maxInputHistory:]
+	}
+	return inputFlashCmd(action, m.inputSeq)
+}
+
+func (m Model) handleInputFlashDone(msg InputFlashDoneMsg) (tea.Model, tea.Cmd) {
+	if m.inputLit[msg.action] == msg.seq {
+		m.inputLit[msg.action] = 0
+	}
+	return m, nil
+}
+
+// renderInputDisplay draws the pad and the recent history.
+func renderInputDisplay(lit [inputActionCount]int, history []inputAction) string {
+	key := func(a inputAction) string {
+		if lit[a] != 0 {
+			return cursorStyle.Render(" " + inputGlyphs[a] + " ")
+		}
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(colors[7])).Render("[" + inputGlyphs[a] + "]")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(i18n.T("inputs.title")) + "\n")
+	sb.WriteString(key(inputLeft) + " " + key(inputDown) + " " + key(inputRight) + "\n")
+	sb.WriteString(key(inputRotateBack) + " " + key(inputRotate) + " " + key(inputDrop) + " " + key(inputHold) + "\n")
+
+	glyphs := make([]string, len(history))
+	for i, a := range history {
+		glyphs[i] = inputGlyphs[a]
+	}
+	sb.WriteString(infoStyle.Render(strings.Join(glyphs, " ")))
+	return sb.String()
+}
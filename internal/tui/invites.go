@@ -0,0 +1,193 @@
+package tui
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/prefs"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Invites ---
+//
+// F in the lobby invites a friend: typing their friend code sends them
+// an invite, and leaving it empty makes a one-use link instead. The main
+// menu polls the server every invitesInterval for invites to this
+// player's friend code, the public half of their profile key, and shows
+// the oldest, which A accepts and D declines. Pasting an invite link on
+// the join screen accepts it.
+
+// invitesInterval is how often the main menu checks for invites.
+const invitesInterval = 10 * time.Second
+
+// friendCodeLength is how long a friend code is.
+const friendCodeLength = 10
+
+// InvitesMsg is the result of a GET /invites.
+type InvitesMsg struct {
+	Server string
+	Resp   protocol.InvitesResponse
+	Err    error
+}
+
+// invitesTickMsg is time to check for invites.
+type invitesTickMsg struct{}
+
+func invitesCmd(c *client.Client, p *prefs.Prefs) tea.Cmd {
+	if c == nil || p == nil || p.ProfileKey == "" {
+		return nil
+	}
+	server, key := c.Server(), p.ProfileKey
+	return func() tea.Msg {
+		resp, err := c.Invites(key)
+		return InvitesMsg{Server: server, Resp: resp, Err: err}
+	}
+}
+
+func invitesTickCmd() tea.Cmd {
+	return tea.Tick(invitesInterval, func(time.Time) tea.Msg {
+		return invitesTickMsg{}
+	})
+}
+
+func declineInviteCmd(c *client.Client, key, token string) tea.Cmd {
+	return func() tea.Msg {
+		c.DeclineInvite(key, token)
+		return nil
+	}
+}
+
+func joinInviteCmd(c *client.Client, token, playerName string) tea.Cmd {
+	return func() tea.Msg {
+		roomID, err := c.JoinInvite(token, playerName)
+		if err != nil {
+			return RoomJoinedHTTPMsg{Err: err}
+		}
+		return RoomJoinedHTTPMsg{RoomID: roomID}
+	}
+}
+
+func (m Model) handleInvites(msg InvitesMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil || m.client == nil || msg.Server != m.client.Server() {
+		return m, nil
+	}
+	m.friendCode = msg.Resp.FriendCode
+	m.invites = msg.Resp.Invites
+	return m, nil
+}
+
+// handleInvitesTick checks for invites if the main menu is up, and waits
+// for the next check either way.
+func (m Model) handleInvitesTick() (tea.Model, tea.Cmd) {
+	if m.screen != ScreenMainMenu {
+		return m, invitesTickCmd()
+	}
+	return m, tea.Batch(invitesCmd(m.client, m.prefs), invitesTickCmd())
+}
+
+// handleInviteKeys answers the main menu's invite, reporting whether key
+// was for it.
+func (m Model) handleInviteKeys(key string) (Model, tea.Cmd, bool) {
+	if len(m.invites) == 0 || m.client == nil || (key != "a" && key != "d") {
+		return m, nil, false
+	}
+	inv := m.invites[0]
+	m.invites = m.invites[1:]
+	if key == "d" {
+		return m, declineInviteCmd(m.client, m.prefs.ProfileKey, inv.Token), true
+	}
+	m, cmd := m.joinInvite(inv.Token)
+	return m, cmd, true
+}
+
+// joinInvite joins the room the invite token is for.
+func (m Model) joinInvite(token string) (Model, tea.Cmd) {
+	m.mode = ModeMulti
+	m.screen = ScreenConnecting
+	m.roomError = ""
+	return m, joinInviteCmd(m.client, token, m.playerName)
+}
+
+// handleInvitingKeys edits the friend code being invited. Enter sends the
+// invite, or with no code, makes a link.
+func (m Model) handleInvitingKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.inviting = false
+		if m.client != nil && m.roomCode != "" {
+			m.client.CreateInvite(m.inviteInput)
+		}
+	case "esc":
+		m.inviting = false
+	case "backspace":
+		if len(m.inviteInput) > 0 {
+			m.inviteInput = m.inviteInput[:len(m.inviteInput)-1]
+		}
+	default:
+		for _, r := range strings.ToUpper(string(msg.Runes)) {
+			if strings.ContainsRune("0123456789ABCDEF", r) && len(m.inviteInput) < friendCodeLength {
+				m.inviteInput += string(r)
+			}
+		}
+	}
+	return m, nil
+}
+
+// handleInviteCreated shows the invite the server made for the lobby.
+func (m *Model) handleInviteCreated(p protocol.InviteCreatedPayload) {
+	if m.screen != ScreenLobby || p.Invite.RoomID != m.roomCode {
+		return
+	}
+	if p.To != "" {
+		m.lobbyNote = i18n.T("invite.sent", p.To)
+	} else {
+		m.lobbyNote = i18n.T("invite.link", m.inviteLink(p.Invite.Token))
+	}
+}
+
+// inviteLink is the link to accept the invite token on the web client.
+func (m Model) inviteLink(token string) string {
+	base := m.lanAddress()
+	if base == "" {
+		base = m.client.Server()
+	}
+	return base + "/web/?invite=" + url.QueryEscape(token)
+}
+
+// inviteFromPaste is the invite token in a pasted invite link, or "".
+func inviteFromPaste(pasted string) string {
+	for _, word := range strings.Fields(pasted) {
+		if u, err := url.Parse(word); err == nil && u.Query().Get("invite") != "" {
+			return u.Query().Get("invite")
+		}
+	}
+	return ""
+}
+
+// renderInvite is the main menu's line about the oldest invite, or "".
+func renderInvite(invites []protocol.Invite) string {
+	if len(invites) == 0 {
+		return ""
+	}
+	inv := invites[0]
+	s := targetStyle.Render(i18n.T("invite.from", inv.FromName, inv.RoomID)) + "\n" +
+		infoStyle.Render(i18n.T("invite.answer_hint"))
+	if len(invites) > 1 {
+		s += "\n" + infoStyle.Render(i18n.T("invite.more", len(invites)-1))
+	}
+	return s
+}
+
+// renderInviting is the lobby's friend code prompt.
+func renderInviting(input, friendCode string) string {
+	s := cursorStyle.Render(i18n.T("invite.prompt", input+"_")) + "\n" +
+		infoStyle.Render(i18n.T("invite.hint")) + "\n"
+	if friendCode != "" {
+		s += infoStyle.Render(i18n.T("invite.your_code", friendCode)) + "\n"
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+)
+
+// --- Keymaps ---
+//
+// The gameplay keys come from a preset picked in Settings (or with
+// --keymap): guideline, the modern standard of arrows to move, X and Z
+// to rotate either way, Space to hard drop and C to hold; classic, arrows
+// only, with Space and Enter for the extras; and vim, on hjkl. Terminals
+// don't report Shift on its own, so guideline's Shift hold isn't offered.
+// The other gameplay keys (targeting, emotes, items) are the same in
+// every preset.
+
+// The keymap presets, by the name saved in prefs.
+const (
+	KeymapGuideline = "guideline"
+	KeymapClassic   = "classic"
+	KeymapVim       = "vim"
+)
+
+// keymap is a preset: the keys for each action, the first of which hints
+// show.
+type keymap struct {
+	name string
+	keys [inputActionCount][]string
+}
+
+var keymaps = []keymap{
+	{KeymapGuideline, [inputActionCount][]string{
+		inputLeft:       {"left"},
+		inputRight:      {"right"},
+		inputDown:       {"down"},
+		inputRotate:     {"up", "x"},
+		inputRotateBack: {"z"},
+		inputDrop:       {" "},
+		inputHold:       {"c"},
+	}},
+	{KeymapClassic, [inputActionCount][]string{
+		inputLeft:   {"left"},
+		inputRight:  {"right"},
+		inputDown:   {"down"},
+		inputRotate: {"up"},
+		inputDrop:   {" "},
+		inputHold:   {"enter"},
+	}},
+	{KeymapVim, [inputActionCount][]string{
+		inputLeft:       {"h"},
+		inputRight:      {"l"},
+		inputDown:       {"j"},
+		inputRotate:     {"k"},
+		inputRotateBack: {"u"},
+		inputDrop:       {" "},
+		inputHold:       {"y"},
+	}},
+}
+
+// KeymapNames lists the presets' names.
+func KeymapNames() []string {
+	var names []string
+	for _, k := range keymaps {
+		names = append(names, k.name)
+	}
+	return names
+}
+
+// keymapNamed returns the preset called name, or guideline for any
+// other name.
+func keymapNamed(name string) keymap {
+	for _, k := range keymaps {
+		if k.name == name {
+			return k
+		}
+	}
+	return keymaps[0]
+}
+
+// keymap is the player's preset.
+func (m Model) keymap() keymap {
+	if m.prefs == nil {
+		return keymaps[0]
+	}
+	return keymapNamed(m.prefs.Keymap)
+}
+
+// cycleKeymap switches to the next preset, leaving it to the caller to
+// save.
+func (m *Model) cycleKeymap() {
+	if m.prefs == nil {
+		return
+	}
+	i := slices.Index(KeymapNames(), m.keymap().name)
+	m.prefs.Keymap = keymaps[(i+1)%len(keymaps)].name
+}
+
+// action returns the action key performs.
+func (k keymap) action(key string) (inputAction, bool) {
+	for a, keys := range k.keys {
+		if slices.Contains(keys, key) {
+			return inputAction(a), true
+		}
+	}
+	return 0, false
+}
+
+// label is how hints write the keys for a, "-" if it has none.
+func (k keymap) label(a inputAction) string {
+	if len(k.keys[a]) == 0 {
+		return "-"
+	}
+	var names []string
+	for _, key := range k.keys[a] {
+		names = append(names, keyName(key))
+	}
+	return strings.Join(names, "/")
+}
+
+// keyName is how hints write key.
+func keyName(key string) string {
+	switch key {
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	case "down":
+		return "↓"
+	case "up":
+		return "↑"
+	case " ":
+		return "SPACE"
+	case "enter":
+		return "ENTER"
+	}
+	return strings.ToUpper(key)
+}
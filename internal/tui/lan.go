@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/server"
+	"github.com/hersh/gotris/pkg/client"
+)
+
+// --- LAN games ---
+//
+// The last entry on the server screen hosts a LAN game: the client runs a
+// server of its own, listening on every interface, switches to it and
+// makes a room. Friends on the same network join at the address the lobby
+// shows, and nobody has to deploy a server. The server runs until the
+// client quits; hosting again reuses it.
+
+// lanPort is the port a LAN server tries first, the usual server port,
+// so friends can guess it. If it's taken, any free port will do.
+const lanPort = 8080
+
+// lanHost is a server running in the client.
+type lanHost struct {
+	local string // the server's URL for this client
+	addr  string // its URL on the network, for friends
+}
+
+// LANHostedMsg is the result of starting a LAN server.
+type LANHostedMsg struct {
+	Host *lanHost
+	Err  error
+}
+
+func hostLANCmd() tea.Cmd {
+	return func() tea.Msg {
+		ln, err := net.Listen("tcp", ":"+strconv.Itoa(lanPort))
+		if err != nil {
+			ln, err = net.Listen("tcp", ":0")
+		}
+		if err != nil {
+			return LANHostedMsg{Err: err}
+		}
+		port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+		go http.Serve(ln, server.New(server.Options{}))
+		return LANHostedMsg{Host: &lanHost{
+			local: client.NormalizeServer("http://127.0.0.1:" + port),
+			addr:  "http://" + net.JoinHostPort(lanIP(), port),
+		}}
+	}
+}
+
+// lanIP is this machine's address on the local network: its first
+// private IPv4 address, else its host name.
+func lanIP() string {
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipn, ok := a.(*net.IPNet); ok && ipn.IP.To4() != nil && ipn.IP.IsPrivate() {
+				return ipn.IP.String()
+			}
+		}
+	}
+	if name, err := os.Hostname(); err == nil {
+		return name
+	}
+	return "localhost"
+}
+
+// hostLAN starts hosting, or goes back to the server already hosted.
+func (m Model) hostLAN() (tea.Model, tea.Cmd) {
+	if m.client == nil {
+		return m, nil
+	}
+	m.serverChecking = true
+	m.roomError = ""
+	if m.lan != nil {
+		return m.handleLANHosted(LANHostedMsg{Host: m.lan})
+	}
+	return m, hostLANCmd()
+}
+
+// handleLANHosted switches to the LAN server and makes a room on it. A
+// server started after the player gave up waiting is kept for next time.
+func (m Model) handleLANHosted(msg LANHostedMsg) (tea.Model, tea.Cmd) {
+	if msg.Host != nil {
+		m.lan = msg.Host
+	}
+	if m.screen != ScreenServer || !m.serverChecking {
+		return m, nil
+	}
+	m.serverChecking = false
+	if msg.Err != nil {
+		m.roomError = i18n.T("lan.failed", msg.Err)
+		return m, nil
+	}
+	m.client.SetServer(m.lan.local)
+	m.serverStats = nil
+	m.mode = ModeMulti
+	m.screen = ScreenConnecting
+	return m, tea.Batch(createRoomCmd(m.client, m.playerName, ""), profileSyncCmd(m.client, m.prefs), achievementsSyncCmd(m.client, m.prefs))
+}
+
+// lanAddress is the address friends join the LAN game at, or "" when
+// not playing on one.
+func (m Model) lanAddress() string {
+	if m.lan == nil || m.client == nil || m.client.Server() != m.lan.local {
+		return ""
+	}
+	return m.lan.addr
+}
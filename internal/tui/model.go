@@ -1,16 +1,26 @@
 package tui
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/hersh/gotris/internal/game"
-	"github.com/hersh/gotris/internal/netclient"
-	"github.com/hersh/gotris/internal/protocol"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/logging"
+	"github.com/hersh/gotris/internal/overlay"
+	"github.com/hersh/gotris/internal/prefs"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
 )
 
 // --- Custom tea.Msg types ---
@@ -19,9 +29,90 @@ type TickMsg time.Time
 type GameTickMsg time.Time
 type CountdownMsg time.Time
 
-// SnapshotTickMsg triggers sending board snapshots to the server.
+// SnapshotTickMsg checks whether the board needs sending to the server.
 type SnapshotTickMsg time.Time
 
+// The board is sent straight after each lock, and otherwise only when it
+// changed (e.g. garbage rose) or as a keepalive, so an idle board costs
+// one snapshot a second rather than ten.
+const (
+	snapshotCheckInterval = 100 * time.Millisecond
+	snapshotKeepalive     = time.Second
+)
+
+// GoFlashDoneMsg ends the GO! signal shown when a game starts.
+type GoFlashDoneMsg time.Time
+
+// goFlashDuration is how long GO! is shown before the playfield appears.
+const goFlashDuration = 600 * time.Millisecond
+
+// AttackFlashDoneMsg clears the "+N from X" note for the attack with the
+// same sequence number; later attacks keep their own note up.
+type AttackFlashDoneMsg int
+
+// RoomCreatedHTTPMsg is the result of an HTTP POST /create-room + WS
+// connect. RoomID is set if the room was created, even if connecting failed.
+type RoomCreatedHTTPMsg struct {
+	RoomID string
+	Err    error
+}
+
+// RoomJoinedHTTPMsg is the result of an HTTP POST /join-room + WS connect,
+// or of resuming a saved session (Resumed).
+type RoomJoinedHTTPMsg struct {
+	RoomID  string
+	Resumed bool
+	Err     error
+}
+
+// RoomsListedMsg is the result of an HTTP GET /list-rooms: page Page of
+// the matching rooms, with the cursor to go on row Cursor (-1 = last).
+type RoomsListedMsg struct {
+	Rooms  []protocol.RoomInfo
+	Total  int
+	Page   int
+	Cursor int
+	Err    error
+}
+
+// ServerCheckedMsg is the result of probing a server's GET /health.
+type ServerCheckedMsg struct {
+	Addr string
+	Err  error
+}
+
+// PracticeGarbageMsg injects a garbage line on the practice timer with the
+// same sequence number; changing the interval starts a new sequence.
+type PracticeGarbageMsg int
+
+// practiceIntervals are the automatic garbage rates cycled with "i" in
+// single player. Zero turns the timer off.
+var practiceIntervals = []time.Duration{0, 10 * time.Second, 5 * time.Second, 2 * time.Second}
+
+// PieceDelayDoneMsg ends an entry or line clear delay in the game engine.
+type PieceDelayDoneMsg time.Time
+
+// Options the host cycles a room's entry and line clear delays through,
+// in milliseconds: off, then roughly NES, TGM and slower pacing.
+var (
+	entryDelayOptions     = []int{0, 100, 167, 300, 500}
+	lineClearDelayOptions = []int{0, 200, 333, 500}
+)
+
+// pointsTargetOptions are the points targets the host cycles through; 0
+// is off.
+var pointsTargetOptions = []int{0, 10, 20, 30, 50}
+
+// speedRaceOptions are the seconds per level of a speed race the host
+// cycles through; 0 is off.
+var speedRaceOptions = []int{0, 15, 30, 60}
+
+// maxFeedEvents is how many kill-feed lines are shown during a match.
+const maxFeedEvents = 5
+
+// attackFlashDuration is how long an incoming attack's sender is highlighted.
+const attackFlashDuration = 1500 * time.Millisecond
+
 // --- Screens and modes ---
 
 type Screen int
@@ -36,8 +127,50 @@ const (
 	ScreenCountdown
 	ScreenPlaying
 	ScreenGameOver
+	ScreenSettings
+	ScreenServer
+	ScreenTutorial
+	ScreenStats
+	ScreenCreateRoom
+	ScreenHistory
+	ScreenQuickPlay
+	ScreenAchievements
+)
+
+// maxOpponentPanels is the most opponent boards shown at once. Bigger rooms
+// (or smaller terminals) page through them with [ and ]. Each visible panel
+// can be targeted directly with keys 1..maxOpponentPanels.
+const maxOpponentPanels = 8
+
+// Opponent panel layout, used to work out how many previews fit on screen.
+const (
+	opponentCols      = 4  // previews per row at most
+	opponentRows      = 2  // rows of previews at most
+	opponentCellW     = 12 // preview width including padding
+	opponentCellH     = 13 // preview height: name, 10 rows, stats, attacks
+	playfieldW        = 54 // HUD + board + right panel padding
+	opponentReservedH = 10 // panel padding, page indicator and kill feed
+)
+
+// Text input limits.
+const (
+	roomCodeLength = 5
+	maxNameLength  = 20
+	maxTitleLength = 32 // the server cuts longer room titles
+	maxSeedLength  = 32 // the server rejects longer room seeds
+)
+
+// Bounds the host can cycle a room's max players through; these mirror
+// the server's limits. Up to stepRoomPlayers the count goes up one at a
+// time, then through largeRoomPlayers.
+const (
+	minRoomPlayers  = 2
+	stepRoomPlayers = 16
+	maxRoomPlayers  = 99
 )
 
+var largeRoomPlayers = []int{25, 50, maxRoomPlayers}
+
 type GameMode int
 
 const (
@@ -54,50 +187,143 @@ type Model struct {
 	playerID   string
 	playerName string
 	gameState  *game.GameState
+	engine     *game.Loop // runs gameState
+	replayPath string     // where the last game's replay was saved, "" = not saved
+	unlocked   []string   // achievements the last game unlocked
 	width      int
 	height     int
+	blurred    bool // the terminal reported losing focus
 	countdown  int
 
 	// Network
-	client *netclient.Client
+	client *client.Client
+
+	// Saved settings (nil = don't persist anything)
+	prefs *prefs.Prefs
+
+	// Live state for stream overlays (nil = off)
+	overlay *overlay.Overlay
 
 	// Lobby state (from server)
 	lobbyPlayers []protocol.LobbyPlayer
+	hostID       string
+	roomSettings protocol.RoomSettings
+	autoStart    protocol.AutoStartPayload
+	seedEditing  bool   // the host is typing a room seed
+	seedInput    string // the seed being typed
+	inviting     bool   // typing a friend code to invite
+	inviteInput  string // the friend code being typed
+
+	// Invites to this player, shown on the main menu
+	friendCode string
+	invites    []protocol.Invite
 
 	// Multiplayer state
 	opponents    []protocol.OpponentState
+	remaining    int // players still alive, from the last opponent update
 	seed         int64
 	matchPlayers []string
 	ready        bool
 	matchResult  *protocol.MatchOverPayload
+	goFlash      bool                         // show GO! in place of the board
+	attackerID   string                       // sender of the latest garbage, while flashing
+	attackLines  int                          // lines in that attack
+	attackSeq    int                          // numbers attacks so stale flash timers are ignored
+	events       []protocol.MatchEventPayload // kill feed, oldest first
+	emotes       map[string]emoteBubble       // emotes on show, by sender's player ID
+	emoteSeq     int
+	endAnim      *endAnim                       // end-screen animation, nil once finished
+	tutorial     *tutorial                      // lesson progress on ScreenTutorial
+	attract      *attractDemo                   // the main menu's demo, nil when not running
+	idleSince    time.Time                      // the last key or click
+	lastSnap     *protocol.BoardSnapshotPayload // last board sent to the server
+	lastSnapAt   time.Time
+
+	// Practice garbage (single player)
+	practiceInterval time.Duration // 0 = only on demand
+	practiceSeq      int
+
+	// Held soft drop
+	softDropAt   time.Time // last soft drop key press
+	softDropping bool      // held: the tick loop is dropping the piece
+	softDropSeq  int
+
+	// Input display
+	inputLit     [inputActionCount]int // per action: seq of the press lighting it, 0 = off
+	inputSeq     int
+	inputHistory []inputAction // most recent last
 
 	// Error
 	err          error
 	disconnected bool
 
+	// Connection widget (driven by netclient heartbeats)
+	connStatus client.ConnStatus
+	rtt        time.Duration
+	reconnects int // current reconnect attempt, 0 = not reconnecting
+
+	// Debug overlay (F12)
+	debugLog  *logging.Writer
+	showDebug bool
+
 	// Room state
-	roomCode       string
-	roomInput      string
-	nameInput      string
-	roomError      string
-	availableRooms []protocol.RoomInfo
-	roomListCursor int
-	roomListPage   int
+	roomCode        string
+	roomInput       string
+	nameInput       string
+	titleInput      string // title for the room being created
+	roomError       string
+	availableRooms  []protocol.RoomInfo
+	roomListCursor  int
+	roomListPage    int
+	roomTotal       int    // rooms matching the browser's search and filters
+	roomQuery       string // room browser search
+	roomSearching   bool   // typing into the room browser search
+	roomOpenOnly    bool   // room browser: only lobbies with a free seat
+	roomSort        string // room browser order, a protocol.RoomSort*
+	roomType        string // the lobby's protocol.RoomType*
+	motd            string // the server's message of the day, shown in the lobby
+	lobbyNote       string // whether the room code was copied
+	showQR          bool   // the lobby shows the join link's QR code
+	quickPlayCursor int
+
+	// Server totals on the main menu, nil = not (yet) known
+	serverStats *protocol.ServerStatsResponse
+
+	// Match history screen
+	history       []protocol.MatchRecord
+	historyCursor int
+	historyDetail bool   // showing the match under the cursor
+	historyNote   string // where the last export went
+
+	// Server screen
+	serverInput    string
+	serverCursor   int  // index into recent servers picked with up/down
+	serverChecking bool // waiting for /health, or for the LAN server to start
+	lan            *lanHost
+
+	// Menu cursors (number keys still work as shortcuts)
+	menuCursor     int
+	settingsCursor int
+	gameOverCursor int
 
 	// Targeting
 	targetID    string // "" = random, otherwise a player ID
 	targetIndex int    // -1 = random, 0..N-1 = index into opponents
+
+	opponentPage int // page of opponent previews being shown
 }
 
 // NewModel creates a model for the client TUI.
 // If client is nil, only single-player mode is available.
 // The client no longer needs a WebSocket at startup; it connects on demand.
-func NewModel(playerName string, client *netclient.Client) Model {
+// If p is non-nil, name changes and joined rooms are saved to it.
+func NewModel(playerName string, c *client.Client, p *prefs.Prefs) Model {
 	return Model{
 		screen:      ScreenMainMenu,
 		playerName:  playerName,
 		nameInput:   playerName,
-		client:      client,
+		client:      c,
+		prefs:       p,
 		ready:       false,
 		targetIndex: -1,
 	}
@@ -106,6 +332,12 @@ func NewModel(playerName string, client *netclient.Client) Model {
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		tickCmd(),
+		serverStatsCmd(m.client),
+		serverStatsTickCmd(),
+		profileSyncCmd(m.client, m.prefs),
+		achievementsSyncCmd(m.client, m.prefs),
+		invitesCmd(m.client, m.prefs),
+		invitesTickCmd(),
 	)
 }
 
@@ -127,8 +359,37 @@ func countdownCmd() tea.Cmd {
 	})
 }
 
+func goFlashCmd() tea.Cmd {
+	return tea.Tick(goFlashDuration, func(t time.Time) tea.Msg {
+		return GoFlashDoneMsg(t)
+	})
+}
+
+func practiceGarbageCmd(interval time.Duration, seq int) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return PracticeGarbageMsg(seq)
+	})
+}
+
+func attackFlashCmd(seq int) tea.Cmd {
+	return tea.Tick(attackFlashDuration, func(time.Time) tea.Msg {
+		return AttackFlashDoneMsg(seq)
+	})
+}
+
+// pieceDelayCmd wakes the game up when the engine's running delay ends,
+// if there is one.
+func pieceDelayCmd(gs *game.GameState) tea.Cmd {
+	if gs.Phase == game.PhaseFalling {
+		return nil
+	}
+	return tea.Tick(gs.DelayLeft(time.Now()), func(t time.Time) tea.Msg {
+		return PieceDelayDoneMsg(t)
+	})
+}
+
 func snapshotTickCmd() tea.Cmd {
-	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg {
+	return tea.Tick(snapshotCheckInterval, func(t time.Time) tea.Msg {
 		return SnapshotTickMsg(t)
 	})
 }
@@ -136,13 +397,35 @@ func snapshotTickCmd() tea.Cmd {
 // --- Update ---
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if nm, ok := next.(Model); ok && nm.overlay != nil {
+		nm.overlay.Publish(nm.overlayState())
+	}
+	return next, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.wake() && msg.String() != "ctrl+c" {
+			return m, nil
+		}
 		return m.handleKeyPress(msg)
+	case tea.MouseMsg:
+		if m.wake() {
+			return m, nil
+		}
+		return m.handleMouse(msg)
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		return m, nil
+	case tea.FocusMsg:
+		m.blurred = false
+		return m, nil
+	case tea.BlurMsg:
+		m.blurred = true
+		return m, nil
 	case TickMsg:
 		return m.handleTick()
 	case GameTickMsg:
@@ -151,39 +434,134 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleCountdown()
 	case SnapshotTickMsg:
 		return m.handleSnapshotTick()
+	case GoFlashDoneMsg:
+		m.goFlash = false
+		return m, nil
+	case PracticeGarbageMsg:
+		return m.handlePracticeGarbage(int(msg))
+	case InputFlashDoneMsg:
+		return m.handleInputFlashDone(msg)
+	case EmoteDoneMsg:
+		return m.handleEmoteDone(msg)
+	case SoftDropTickMsg:
+		return m.handleSoftDropTick(int(msg))
+	case PieceDelayDoneMsg:
+		return m.handlePieceDelayDone(time.Time(msg))
+	case AttackFlashDoneMsg:
+		if int(msg) == m.attackSeq {
+			m.attackerID = ""
+			m.attackLines = 0
+		}
+		return m, nil
+	case AnimTickMsg:
+		return m.handleAnimTick()
 
 	// Network messages
-	case netclient.ConnectedMsg:
+	case client.ConnectedMsg:
 		return m.handleConnected(msg)
-	case netclient.DisconnectedMsg:
+	case client.DisconnectedMsg:
 		m.disconnected = true
+		m.connStatus = client.ConnDisconnected
 		m.err = msg.Err
 		return m, nil
-	case netclient.ServerMsg:
+	case client.ConnStatusMsg:
+		m.connStatus = msg.Status
+		m.rtt = msg.RTT
+		return m, nil
+	case client.ReconnectingMsg:
+		m.connStatus = client.ConnReconnecting
+		m.reconnects = msg.Attempt
+		return m, nil
+	case client.ReconnectedMsg:
+		return m.handleReconnected()
+	case client.ServerMsg:
 		return m.handleServerMsg(msg)
 
 	// HTTP response messages
-	case netclient.RoomCreatedHTTPMsg:
+	case RoomCreatedHTTPMsg:
 		return m.handleRoomCreatedHTTP(msg)
-	case netclient.RoomJoinedHTTPMsg:
+	case RoomJoinedHTTPMsg:
 		return m.handleRoomJoinedHTTP(msg)
-	case netclient.RoomsListedMsg:
+	case RoomsListedMsg:
 		return m.handleRoomsListed(msg)
+	case ServerCheckedMsg:
+		return m.handleServerChecked(msg)
+	case ProfileSyncedMsg:
+		return m.handleProfileSynced(msg)
+	case AchievementsSyncedMsg:
+		return m.handleAchievementsSynced(msg)
+	case HistoryLoadedMsg:
+		return m.handleHistoryLoaded(msg)
+	case LANHostedMsg:
+		return m.handleLANHosted(msg)
+	case CopiedMsg:
+		return m.handleCopied(msg)
+	case PastedMsg:
+		return m.handlePasted(msg)
+	case ServerStatsMsg:
+		return m.handleServerStats(msg)
+	case serverStatsTickMsg:
+		return m.handleServerStatsTick()
+	case InvitesMsg:
+		return m.handleInvites(msg)
+	case invitesTickMsg:
+		return m.handleInvitesTick()
 	}
 	return m, nil
 }
 
 // --- Network message handlers ---
 
-func (m Model) handleConnected(msg netclient.ConnectedMsg) (tea.Model, tea.Cmd) {
+func (m Model) handleConnected(msg client.ConnectedMsg) (tea.Model, tea.Cmd) {
 	m.playerID = msg.PlayerID
+	m.motd = msg.MOTD
 	// Don't change screen here; the HTTP response handler already moved us to ScreenLobby
+	if msg.Resumed {
+		switch {
+		case m.client == nil:
+		case m.screen != ScreenPlaying && m.screen != ScreenCountdown:
+			// Resumed after a restart: the seat was kept, but the board
+			// went with the old process, so give the match up.
+			m.client.SendDead()
+			m.roomError = i18n.T("conn.resumed_lost")
+		case msg.Missed:
+			// Away too long for the server to send everything we missed:
+			// the board is out of step with the match, so give it up.
+			if m.gameState != nil {
+				m.gameState.IsGameOver = true
+			}
+			m.client.SendDead()
+			m.roomError = i18n.T("conn.missed")
+		}
+		return m, nil
+	}
+	// A fresh join (first connect, or the seat wasn't kept): we're
+	// un-readied, and a match in progress carries on without us.
+	m.rememberPlayerID(msg.PlayerID)
+	m.ready = false
+	if m.screen == ScreenPlaying || m.screen == ScreenCountdown {
+		m.screen = ScreenLobby
+		m.gameState = nil
+		m.opponents = nil
+		m.remaining = 0
+		m.matchResult = nil
+		m.goFlash = false
+		m.roomError = i18n.T("conn.rejoined")
+		return m, tickCmd()
+	}
 	return m, nil
 }
 
-func (m Model) handleRoomCreatedHTTP(msg netclient.RoomCreatedHTTPMsg) (tea.Model, tea.Cmd) {
+// handleReconnected picks up after the connection came back. Whether we
+// kept our seat is only known once the ConnectedMsg arrives.
+func (m Model) handleReconnected() (tea.Model, tea.Cmd) {
+	m.reconnects = 0
+	return m, nil
+}
+
+func (m Model) handleRoomCreatedHTTP(msg RoomCreatedHTTPMsg) (tea.Model, tea.Cmd) {
 	if msg.Err != nil {
-		m.roomError = msg.Err.Error()
+		m.roomError = requestErrorText(msg.Err)
 		m.screen = ScreenMainMenu
 		return m, nil
 	}
@@ -191,97 +569,250 @@ func (m Model) handleRoomCreatedHTTP(msg netclient.RoomCreatedHTTPMsg) (tea.Mode
 	m.roomError = ""
 	m.screen = ScreenLobby
 	m.ready = false
+	m.rememberRoom(msg.RoomID)
 	return m, nil
 }
 
-func (m Model) handleRoomJoinedHTTP(msg netclient.RoomJoinedHTTPMsg) (tea.Model, tea.Cmd) {
+func (m Model) handleRoomJoinedHTTP(msg RoomJoinedHTTPMsg) (tea.Model, tea.Cmd) {
 	if msg.Err != nil {
-		m.roomError = msg.Err.Error()
+		m.roomError = requestErrorText(msg.Err)
 		if m.screen == ScreenConnecting {
 			m.screen = ScreenJoinRoom
 		}
 		return m, nil
 	}
 	m.roomCode = msg.RoomID
-	m.roomError = ""
+	// After a resume, handleConnected may already have said the game was lost.
+	if !msg.Resumed {
+		m.roomError = ""
+	}
 	m.screen = ScreenLobby
 	m.ready = false
+	m.rememberRoom(msg.RoomID)
 	return m, nil
 }
 
-func (m Model) handleRoomsListed(msg netclient.RoomsListedMsg) (tea.Model, tea.Cmd) {
+func (m Model) handleRoomsListed(msg RoomsListedMsg) (tea.Model, tea.Cmd) {
 	if msg.Err != nil {
-		m.roomError = msg.Err.Error()
+		m.roomError = requestErrorText(msg.Err)
 		m.screen = ScreenMainMenu
 		return m, nil
 	}
+	// Rooms went away since the last page was fetched: show the last one.
+	if len(msg.Rooms) == 0 && msg.Total > 0 && msg.Page > 0 {
+		return m, m.fetchRooms((msg.Total-1)/roomsPerPage, 0)
+	}
 	m.availableRooms = msg.Rooms
+	m.roomTotal = msg.Total
 	m.roomError = ""
-	m.roomListCursor = 0
-	m.roomListPage = 0
+	m.roomListPage = msg.Page
+	m.roomListCursor = msg.Cursor
+	if msg.Cursor < 0 || msg.Cursor >= len(msg.Rooms) {
+		m.roomListCursor = max(0, len(msg.Rooms)-1)
+	}
 	m.screen = ScreenListRooms
 	return m, nil
 }
 
+func (m Model) handleServerChecked(msg ServerCheckedMsg) (tea.Model, tea.Cmd) {
+	if m.screen != ScreenServer || !m.serverChecking {
+		return m, nil
+	}
+	m.serverChecking = false
+	if msg.Err != nil {
+		m.roomError = requestErrorText(msg.Err)
+		return m, nil
+	}
+
+	m.client.SetServer(msg.Addr)
+	m.serverStats = nil
+	if m.prefs != nil {
+		m.prefs.AddRecentServer(m.client.Server())
+		m.prefs.Save()
+	}
+	m.roomError = ""
+	m.screen = ScreenMainMenu
+	return m, tea.Batch(serverStatsCmd(m.client), profileSyncCmd(m.client, m.prefs), achievementsSyncCmd(m.client, m.prefs))
+}
+
+// recentServers returns the saved servers offered on the server screen.
+func (m Model) recentServers() []string {
+	if m.prefs == nil {
+		return nil
+	}
+	return m.prefs.RecentServers
+}
+
+// server returns the address shown on the main menu, or "" offline.
+func (m Model) server() string {
+	if m.client == nil {
+		return ""
+	}
+	return m.client.Server()
+}
+
+// rememberRoom records a successfully joined room in the saved settings.
+func (m Model) rememberRoom(code string) {
+	if m.prefs == nil {
+		return
+	}
+	m.prefs.AddRecentRoom(code)
+	m.prefs.Save()
+}
+
+// lastRoom returns the room code offered by "rejoin last room", or "".
+func (m Model) lastRoom() string {
+	if m.prefs == nil || m.client == nil {
+		return ""
+	}
+	return m.prefs.LastRoom()
+}
+
+// Forward delivers the client's events to p as messages. It never
+// returns, so run it in its own goroutine.
+func Forward(c *client.Client, p *tea.Program) {
+	for ev := range c.Events() {
+		p.Send(ev)
+	}
+}
+
 // --- HTTP tea.Cmd helpers ---
 
-func createRoomCmd(client *netclient.Client, playerName string) tea.Cmd {
-	return func() tea.Msg {
-		roomID, token, err := client.CreateRoom(playerName)
-		if err != nil {
-			return netclient.RoomCreatedHTTPMsg{Err: err}
-		}
-		if err := client.ConnectToRoom(roomID, token); err != nil {
-			return netclient.RoomCreatedHTTPMsg{RoomID: roomID, Err: err}
+// errorCodeKeys maps the server's error codes to their messages.
+var errorCodeKeys = map[protocol.ErrorCode]string{
+	protocol.ErrCodeBadRequest:      "error.bad_request",
+	protocol.ErrCodeRoomNotFound:    "error.room_not_found",
+	protocol.ErrCodeRoomFull:        "error.room_full",
+	protocol.ErrCodeInProgress:      "error.game_in_progress",
+	protocol.ErrCodeInvalidToken:    "error.invalid_token",
+	protocol.ErrCodeTokenMismatch:   "error.token_mismatch",
+	protocol.ErrCodeNotHost:         "error.not_host",
+	protocol.ErrCodeNotInLobby:      "error.not_in_lobby",
+	protocol.ErrCodeInvalidSettings: "error.invalid_settings",
+	protocol.ErrCodeTooManyPlayers:  "error.too_many_players",
+	protocol.ErrCodeServerFull:      "error.server_full",
+	protocol.ErrCodeBanned:          "error.banned",
+	protocol.ErrCodeRankedRoom:      "error.ranked_room",
+	protocol.ErrCodeRateLimited:     "error.rate_limited",
+}
+
+// serverErrorText words an error the server reported with code and msg,
+// using the server's message for codes this client doesn't know.
+func serverErrorText(code protocol.ErrorCode, msg string) string {
+	if key, ok := errorCodeKeys[code]; ok {
+		return i18n.T(key)
+	}
+	return msg
+}
+
+// requestErrorText words a failed server call for the player. When the
+// server turned the request down, its own message is shown.
+func requestErrorText(err error) string {
+	if wait := client.RetryAfter(err); wait > 0 {
+		switch client.ErrorCode(err) {
+		case protocol.ErrCodeServerFull:
+			return i18n.T("error.server_full_retry", int(wait.Seconds()))
+		case protocol.ErrCodeRateLimited:
+			return i18n.T("error.rate_limited_retry", int(wait.Seconds()))
 		}
-		return netclient.RoomCreatedHTTPMsg{RoomID: roomID, Token: token}
+	}
+	if code := client.ErrorCode(err); code != "" {
+		return serverErrorText(code, err.Error())
+	}
+	switch {
+	case errors.Is(err, client.ErrUnreachable):
+		return i18n.T("server.unreachable")
+	case errors.Is(err, client.ErrServer):
+		return i18n.T("server.failed")
+	case errors.Is(err, client.ErrBadResponse):
+		return i18n.T("server.bad_response")
+	}
+	return err.Error()
+}
+
+func checkServerCmd(c *client.Client, addr string) tea.Cmd {
+	return func() tea.Msg {
+		return ServerCheckedMsg{Addr: addr, Err: c.CheckServer(addr)}
+	}
+}
+
+func createRoomCmd(c *client.Client, playerName, title string) tea.Cmd {
+	return func() tea.Msg {
+		roomID, err := c.CreateTitled(playerName, title)
+		return RoomCreatedHTTPMsg{RoomID: roomID, Err: err}
 	}
 }
 
-func joinRoomHTTPCmd(client *netclient.Client, roomID, playerName string) tea.Cmd {
+func joinRoomHTTPCmd(c *client.Client, roomID, playerName string) tea.Cmd {
 	return func() tea.Msg {
-		token, err := client.JoinRoom(roomID, playerName)
-		if err != nil {
-			return netclient.RoomJoinedHTTPMsg{Err: err}
+		// Coming back to the room we were in when the client last quit
+		// uncleanly: try to pick up the session first.
+		if s := prefs.LoadSession(); s != nil && s.Server == c.Server() && strings.EqualFold(s.RoomID, roomID) {
+			if c.Resume(s.RoomID, s.Token) == nil {
+				return RoomJoinedHTTPMsg{RoomID: s.RoomID, Resumed: true}
+			}
 		}
-		if err := client.ConnectToRoom(roomID, token); err != nil {
-			return netclient.RoomJoinedHTTPMsg{RoomID: roomID, Err: err}
+		if err := c.Join(roomID, playerName); err != nil {
+			return RoomJoinedHTTPMsg{Err: err}
 		}
-		return netclient.RoomJoinedHTTPMsg{RoomID: roomID, Token: token}
+		return RoomJoinedHTTPMsg{RoomID: roomID}
 	}
 }
 
-func listRoomsCmd(client *netclient.Client) tea.Cmd {
+func listRoomsCmd(c *client.Client, q client.RoomQuery, page, cursor int) tea.Cmd {
 	return func() tea.Msg {
-		rooms, err := client.ListRooms()
-		return netclient.RoomsListedMsg{Rooms: rooms, Err: err}
+		resp, err := c.QueryRooms(q)
+		return RoomsListedMsg{Rooms: resp.Rooms, Total: resp.Total, Page: page, Cursor: cursor, Err: err}
 	}
 }
 
-func (m Model) handleServerMsg(msg netclient.ServerMsg) (tea.Model, tea.Cmd) {
+func (m Model) handleServerMsg(msg client.ServerMsg) (tea.Model, tea.Cmd) {
 	switch msg.Type {
 	case protocol.MsgLobbyUpdate:
-		var payload protocol.LobbyUpdatePayload
-		if json.Unmarshal(msg.Raw, &payload) == nil {
+		if payload, err := protocol.DecodePayload[protocol.LobbyUpdatePayload](msg.Type, msg.Raw); err == nil {
 			m.lobbyPlayers = payload.Players
+			m.hostID = payload.HostID
+			m.roomSettings = payload.Settings
+			m.roomType = payload.RoomType
+			// The server un-readies everyone when settings change.
+			for _, p := range payload.Players {
+				if p.PlayerID == m.playerID {
+					m.ready = p.Ready
+				}
+			}
+		}
+
+	case protocol.MsgAutoStart:
+		if payload, err := protocol.DecodePayload[protocol.AutoStartPayload](msg.Type, msg.Raw); err == nil {
+			m.autoStart = payload
+		}
+
+	case protocol.MsgRoomError:
+		if payload, err := protocol.DecodePayload[protocol.RoomErrorPayload](msg.Type, msg.Raw); err == nil {
+			m.roomError = serverErrorText(payload.Code, payload.Message)
 		}
 
 	case protocol.MsgCountdown:
-		var payload protocol.CountdownPayload
-		if json.Unmarshal(msg.Raw, &payload) == nil {
+		if payload, err := protocol.DecodePayload[protocol.CountdownPayload](msg.Type, msg.Raw); err == nil {
 			// Only transition to countdown from lobby/countdown screens.
 			// Ignore late countdown messages if we're already playing.
 			if m.screen == ScreenLobby || m.screen == ScreenCountdown {
+				var cue tea.Cmd
+				if m.screen == ScreenLobby {
+					cue = m.matchStartCue()
+				}
+				m.autoStart = protocol.AutoStartPayload{}
 				m.countdown = payload.Value
 				m.screen = ScreenCountdown
+				return m, tea.Batch(m.bell(), cue)
 			}
 		}
 
 	case protocol.MsgGameStart:
-		var payload protocol.GameStartPayload
-		if json.Unmarshal(msg.Raw, &payload) == nil {
+		if payload, err := protocol.DecodePayload[protocol.GameStartPayload](msg.Type, msg.Raw); err == nil {
 			m.seed = payload.Seed
 			m.matchPlayers = payload.Players
+			m.roomSettings = payload.Settings
 			m.matchResult = nil
 			// Don't clear m.opponents here — keep stale data until
 			// the first MsgOpponentUpdate arrives, preventing a layout
@@ -290,40 +821,115 @@ func (m Model) handleServerMsg(msg netclient.ServerMsg) (tea.Model, tea.Cmd) {
 			// Reset targeting
 			m.targetID = ""
 			m.targetIndex = -1
+			m.opponentPage = 0
+			m.attackerID = ""
+			m.attackLines = 0
+			m.events = nil
+			m.emotes = nil
 
 			// Create seeded game state - local authority
-			m.gameState = game.NewSeededGameState(m.playerID, m.playerName, m.seed)
+			m.newEngine(game.NewSeededGameStateWithRules(m.playerID, m.playerName, m.seed, game.Rules{
+				AttackTable:    payload.Settings.AttackTable,
+				Randomizer:     payload.Settings.Randomizer,
+				EntryDelay:     time.Duration(payload.Settings.EntryDelayMs) * time.Millisecond,
+				LineClearDelay: time.Duration(payload.Settings.LineClearDelayMs) * time.Millisecond,
+				AllSpin:        payload.Settings.AllSpin,
+				GarbageStyle:   payload.Settings.GarbageStyle,
+				Items:          payload.Settings.Items,
+				GivenItems:     payload.Settings.Items,
+				Hold:           payload.Settings.HoldMode,
+				SharedLevel:    payload.Settings.SpeedRaceSecs > 0,
+			}))
 			m.screen = ScreenPlaying
+			m.goFlash = true
+			m.lastSnap = nil
 
 			return m, tea.Batch(
-				gameTickCmd(m.gameState.GetDropSpeed()),
+				gameTickCmd(m.engine.Wait(time.Now())),
 				snapshotTickCmd(),
+				goFlashCmd(),
 			)
 		}
 
 	case protocol.MsgOpponentUpdate:
-		var payload protocol.OpponentUpdatePayload
-		if json.Unmarshal(msg.Raw, &payload) == nil {
+		if payload, err := protocol.DecodePayload[protocol.OpponentUpdatePayload](msg.Type, msg.Raw); err == nil {
+			cue := m.koCue(m.opponents, payload.Opponents)
+			keepBoards(m.opponents, payload.Opponents)
 			m.opponents = payload.Opponents
+			m.remaining = payload.Remaining
+			return m, cue
+		}
+
+	case protocol.MsgItemEffect:
+		if payload, err := protocol.DecodePayload[protocol.ItemEffectPayload](msg.Type, msg.Raw); err == nil {
+			if m.gameState != nil {
+				m.engine.ApplyItem(payload.Item, time.Now())
+				m.sendSnapshot()
+				return m, m.bell()
+			}
+		}
+
+	case protocol.MsgInviteCreated:
+		if payload, err := protocol.DecodePayload[protocol.InviteCreatedPayload](msg.Type, msg.Raw); err == nil {
+			m.handleInviteCreated(payload)
+		}
+
+	case protocol.MsgItemGranted:
+		if payload, err := protocol.DecodePayload[protocol.ItemGrantedPayload](msg.Type, msg.Raw); err == nil {
+			if m.gameState != nil && !m.gameState.IsGameOver {
+				m.engine.GiveItem(payload.Item, time.Now())
+			}
+		}
+
+	case protocol.MsgLevelUp:
+		if payload, err := protocol.DecodePayload[protocol.LevelUpPayload](msg.Type, msg.Raw); err == nil {
+			if m.gameState != nil && !m.gameState.IsGameOver {
+				m.engine.SetLevel(payload.Level, time.Now())
+				m.sendSnapshot()
+			}
 		}
 
 	case protocol.MsgReceiveGarbage:
-		var payload protocol.ReceiveGarbagePayload
-		if json.Unmarshal(msg.Raw, &payload) == nil {
+		if payload, err := protocol.DecodePayload[protocol.ReceiveGarbagePayload](msg.Type, msg.Raw); err == nil {
 			if m.gameState != nil && !m.gameState.IsGameOver {
 				// Buffer garbage - it applies on next piece lock
-				m.gameState.ReceiveGarbage(payload.Lines)
+				m.engine.ReceiveGarbage(payload.Lines)
+
+				// Flash who sent it so the player knows who to hit back.
+				m.attackSeq++
+				m.attackerID = payload.AttackerID
+				m.attackLines = payload.Lines
+				return m, tea.Batch(m.bell(), attackFlashCmd(m.attackSeq))
+			}
+		}
+
+	case protocol.MsgMatchEvent:
+		if payload, err := protocol.DecodePayload[protocol.MatchEventPayload](msg.Type, msg.Raw); err == nil {
+			m.events = append(m.events, payload)
+			if len(m.events) > maxFeedEvents {
+				m.events = m.events[len(m.events)-maxFeedEvents:]
 			}
 		}
 
+	case protocol.MsgEmote:
+		if payload, err := protocol.DecodePayload[protocol.EmotePayload](msg.Type, msg.Raw); err == nil {
+			return m, m.showEmote(payload)
+		}
+
 	case protocol.MsgMatchOver:
-		var payload protocol.MatchOverPayload
-		if json.Unmarshal(msg.Raw, &payload) == nil {
+		if payload, err := protocol.DecodePayload[protocol.MatchOverPayload](msg.Type, msg.Raw); err == nil {
 			m.matchResult = &payload
-			if payload.WinnerID == m.playerID && m.gameState != nil {
-				m.gameState.IsWinner = true
+			var unlockCmd tea.Cmd
+			if m.gameState != nil {
+				m.gameState.IsWinner = payload.WinnerID == m.playerID
+				m.gameState.Stats.Finish()
+				m.saveReplay()
+				m.recordGame()
+				unlockCmd = m.unlockAchievements()
 			}
 			m.screen = ScreenGameOver
+			m.gameOverCursor = 0
+			return m, tea.Batch(m.startEndAnim(payload.WinnerID == m.playerID), unlockCmd)
 		}
 
 	}
@@ -340,9 +946,13 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.client.Close()
 		}
 		return m, tea.Quit
+	case "f12":
+		m.showDebug = !m.showDebug
+		return m, nil
 	case "q":
-		if m.screen == ScreenPlaying {
-			// Don't quit during gameplay with q
+		if m.screen == ScreenPlaying || m.screen == ScreenServer || m.screen == ScreenCreateRoom || m.roomSearching || m.seedEditing || m.inviting {
+			// Don't quit during gameplay, or while typing a server address,
+			// a room title, a room search, a seed or a friend code
 			break
 		}
 		if m.client != nil {
@@ -358,6 +968,8 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleEditNameKeys(msg)
 	case ScreenJoinRoom:
 		return m.handleJoinRoomKeys(msg)
+	case ScreenCreateRoom:
+		return m.handleCreateRoomKeys(msg)
 	case ScreenListRooms:
 		return m.handleListRoomsKeys(msg)
 	case ScreenLobby:
@@ -366,12 +978,57 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handlePlayingKeys(msg)
 	case ScreenGameOver:
 		return m.handleGameOverKeys(msg)
+	case ScreenSettings:
+		return m.handleSettingsKeys(msg)
+	case ScreenServer:
+		return m.handleServerKeys(msg)
+	case ScreenTutorial:
+		return m.handleTutorialKeys(msg)
+	case ScreenStats:
+		return m.handleStatsKeys(msg)
+	case ScreenHistory:
+		return m.handleHistoryKeys(msg)
+	case ScreenQuickPlay:
+		return m.handleQuickPlayKeys(msg)
+	case ScreenAchievements:
+		return m.handleAchievementsKeys(msg)
 	}
 	return m, nil
 }
 
+// mainMenuItems is the number of entries on the main menu.
+func (m Model) mainMenuItems() int {
+	if m.lastRoom() != "" {
+		return 10
+	}
+	return 9
+}
+
+// moveCursor moves cursor by delta, clamped to n items.
+func moveCursor(cursor, delta, n int) int {
+	cursor += delta
+	if cursor >= n {
+		cursor = n - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	return cursor
+}
+
 func (m Model) handleMainMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m, cmd, ok := m.handleInviteKeys(msg.String()); ok {
+		return m, cmd
+	}
 	switch msg.String() {
+	case "up", "k":
+		m.menuCursor = moveCursor(m.menuCursor, -1, m.mainMenuItems())
+		return m, nil
+	case "down", "j":
+		m.menuCursor = moveCursor(m.menuCursor, 1, m.mainMenuItems())
+		return m, nil
+	case "enter":
+		return m.handleMainMenuKeys(runeKey(fmt.Sprint((m.menuCursor + 1) % 10)))
 	case "1", "s":
 		// Single player - local only, no network
 		m.mode = ModeSingle
@@ -379,17 +1036,21 @@ func (m Model) handleMainMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.playerID == "" {
 			m.playerID = "local"
 		}
-		m.gameState = game.NewGameState(m.playerID, m.playerName)
-		return m, gameTickCmd(m.gameState.GetDropSpeed())
+		// Uniformly random pieces, as ever, but from a seed so the game
+		// can be replayed.
+		m.newEngine(game.NewSeededGameStateWithRules(m.playerID, m.playerName, rand.Int63(), game.Rules{Randomizer: game.RandomizerRandom}))
+		m.goFlash = true
+		return m, tea.Batch(gameTickCmd(m.engine.Wait(time.Now())), goFlashCmd(), m.restartPracticeGarbage())
 	case "2":
-		// Create a room via HTTP, then connect WS
+		// Ask for an optional title, then create the room
 		if m.client == nil {
 			return m, nil
 		}
 		m.mode = ModeMulti
-		m.screen = ScreenConnecting
+		m.screen = ScreenCreateRoom
+		m.titleInput = ""
 		m.roomError = ""
-		return m, createRoomCmd(m.client, m.playerName)
+		return m, nil
 	case "3":
 		// Join a room by code
 		if m.client == nil {
@@ -407,12 +1068,102 @@ func (m Model) handleMainMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		m.screen = ScreenConnecting
 		m.roomError = ""
-		return m, listRoomsCmd(m.client)
+		m.roomQuery = ""
+		m.roomSearching = false
+		return m, m.fetchRooms(0, 0)
 	case "5":
 		// Edit name
 		m.screen = ScreenEditName
 		m.nameInput = m.playerName
 		return m, nil
+	case "6":
+		// Settings
+		if m.prefs == nil {
+			return m, nil
+		}
+		m.screen = ScreenSettings
+		m.settingsCursor = 0
+		return m, nil
+	case "7":
+		// Choose the server
+		if m.client == nil {
+			return m, nil
+		}
+		m.screen = ScreenServer
+		m.serverInput = m.client.Server()
+		m.serverCursor = -1
+		m.serverChecking = false
+		m.roomError = ""
+		return m, nil
+	case "8":
+		// Tutorial - local, no gravity
+		m.mode = ModeNone
+		m.screen = ScreenTutorial
+		m.tutorial = &tutorial{}
+		m.gameState = m.tutorial.start(0)
+		return m, nil
+	case "9":
+		// Match history
+		return m.openHistory()
+	case "0":
+		// Rejoin the most recently joined room
+		if m.client == nil || m.lastRoom() == "" {
+			return m, nil
+		}
+		m.mode = ModeMulti
+		m.screen = ScreenConnecting
+		m.roomError = ""
+		return m, joinRoomHTTPCmd(m.client, m.lastRoom(), m.playerName)
+	}
+	return m, nil
+}
+
+// setting is one on/off option on the settings screen.
+type setting struct {
+	label string
+	value *bool
+}
+
+// settings lists the toggles shown on the settings screen, backed by prefs.
+func (m Model) settings() []setting {
+	if m.prefs == nil {
+		return nil
+	}
+	return []setting{
+		{i18n.T("settings.sound"), &m.prefs.Sound},
+		{i18n.T("settings.reduced_motion"), &m.prefs.ReducedMotion},
+		{i18n.T("settings.input_display"), &m.prefs.InputDisplay},
+		{i18n.T("settings.notify"), &m.prefs.Notify},
+	}
+}
+
+func (m Model) handleSettingsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	items := m.settings()
+	switch msg.String() {
+	case "esc":
+		m.screen = ScreenMainMenu
+	case "up", "k":
+		if m.settingsCursor > 0 {
+			m.settingsCursor--
+		}
+	case "down", "j":
+		// The keymap comes after the toggles.
+		if m.settingsCursor < len(items) {
+			m.settingsCursor++
+		}
+	case "enter", " ":
+		if m.settingsCursor < len(items) {
+			item := items[m.settingsCursor]
+			*item.value = !*item.value
+		} else {
+			m.cycleKeymap()
+		}
+		return m, m.profileChanged()
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		if idx := int(msg.String()[0] - '1'); idx <= len(items) {
+			m.settingsCursor = idx
+			return m.handleSettingsKeys(tea.KeyMsg{Type: tea.KeyEnter})
+		}
 	}
 	return m, nil
 }
@@ -423,6 +1174,11 @@ func (m Model) handleEditNameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		name := strings.TrimSpace(m.nameInput)
 		if name != "" {
 			m.playerName = name
+			if m.prefs != nil {
+				m.prefs.PlayerName = name
+				m.screen = ScreenMainMenu
+				return m, m.profileChanged()
+			}
 		}
 		m.screen = ScreenMainMenu
 		return m, nil
@@ -431,17 +1187,103 @@ func (m Model) handleEditNameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "backspace":
 		if len(m.nameInput) > 0 {
-			m.nameInput = m.nameInput[:len(m.nameInput)-1]
+			_, size := utf8.DecodeLastRuneInString(m.nameInput)
+			m.nameInput = m.nameInput[:len(m.nameInput)-size]
 		}
 		return m, nil
 	default:
-		if len(msg.String()) == 1 && len(m.nameInput) < 20 {
+		if msg.Paste {
+			text := strings.Join(strings.Fields(string(msg.Runes)), " ")
+			m.nameInput = truncateRunes(m.nameInput+text, maxNameLength)
+		} else if len(msg.String()) == 1 && utf8.RuneCountInString(m.nameInput) < maxNameLength {
 			m.nameInput += msg.String()
 		}
 		return m, nil
 	}
 }
 
+func (m Model) handleServerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.serverChecking {
+		if msg.String() == "esc" {
+			m.serverChecking = false
+		}
+		return m, nil
+	}
+
+	recent := m.recentServers()
+	switch msg.String() {
+	case "enter":
+		if m.serverCursor == len(recent) {
+			return m.hostLAN()
+		}
+		addr := strings.TrimSpace(m.serverInput)
+		if addr == "" || m.client == nil {
+			return m, nil
+		}
+		m.serverChecking = true
+		m.roomError = ""
+		return m, checkServerCmd(m.client, addr)
+	case "esc":
+		m.screen = ScreenMainMenu
+		m.roomError = ""
+		return m, nil
+	case "up", "down":
+		// Pick a recent server into the input, or hosting a LAN game
+		// after them.
+		delta := 1
+		if msg.String() == "up" {
+			delta = -1
+		}
+		m.serverCursor = moveCursor(m.serverCursor, delta, len(recent)+1)
+		if m.serverCursor < len(recent) {
+			m.serverInput = recent[m.serverCursor]
+		}
+		return m, nil
+	case "backspace":
+		if len(m.serverInput) > 0 {
+			m.serverInput = m.serverInput[:len(m.serverInput)-1]
+		}
+		m.serverCursor = -1
+		return m, nil
+	default:
+		if msg.Paste {
+			m.serverInput += strings.TrimSpace(string(msg.Runes))
+			m.serverCursor = -1
+		} else if len(msg.String()) == 1 {
+			m.serverInput += msg.String()
+			m.serverCursor = -1
+		}
+		return m, nil
+	}
+}
+
+func (m Model) handleCreateRoomKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		if m.client != nil {
+			m.screen = ScreenConnecting
+			return m, createRoomCmd(m.client, m.playerName, strings.TrimSpace(m.titleInput))
+		}
+	case "esc":
+		m.screen = ScreenMainMenu
+		m.titleInput = ""
+		m.roomError = ""
+	case "backspace":
+		if len(m.titleInput) > 0 {
+			_, size := utf8.DecodeLastRuneInString(m.titleInput)
+			m.titleInput = m.titleInput[:len(m.titleInput)-size]
+		}
+	default:
+		if msg.Paste {
+			text := strings.Join(strings.Fields(string(msg.Runes)), " ")
+			m.titleInput = truncateRunes(m.titleInput+text, maxTitleLength)
+		} else if len(msg.String()) == 1 && utf8.RuneCountInString(m.titleInput) < maxTitleLength {
+			m.titleInput += msg.String()
+		}
+	}
+	return m, nil
+}
+
 func (m Model) handleJoinRoomKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "enter":
@@ -456,34 +1298,83 @@ func (m Model) handleJoinRoomKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.roomInput = ""
 		m.roomError = ""
 		return m, nil
+	case "ctrl+v":
+		return m, pasteCmd()
 	case "backspace":
 		if len(m.roomInput) > 0 {
 			m.roomInput = m.roomInput[:len(m.roomInput)-1]
 		}
 		return m, nil
 	default:
-		if len(msg.String()) == 1 && len(m.roomInput) < 5 {
+		if token := inviteFromPaste(string(msg.Runes)); msg.Paste && token != "" && m.client != nil {
+			return m.joinInvite(token)
+		}
+		if msg.Paste {
+			m.roomInput = roomCodeFromPaste(m.roomInput, string(msg.Runes))
+		} else if len(msg.String()) == 1 && len(m.roomInput) < roomCodeLength {
 			m.roomInput += strings.ToUpper(msg.String())
 		}
 		return m, nil
 	}
 }
 
-func (m Model) handleListRoomsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	const roomsPerPage = 10
-	totalRooms := len(m.availableRooms)
-	totalPages := (totalRooms + roomsPerPage - 1) / roomsPerPage
-	if totalPages < 1 {
-		totalPages = 1
+// roomCodeFromPaste works out the room code input after text is pasted.
+// If the paste contains a whole room code as a word (as in "Room Code:
+// ABCDE") or a join link with the code in it, that code replaces the
+// input; otherwise the pasted letters and digits are appended up to the
+// code length.
+func roomCodeFromPaste(input, pasted string) string {
+	for _, word := range strings.Fields(pasted) {
+		if u, err := url.Parse(word); err == nil && u.Query().Get("room") != "" {
+			return truncateRunes(strings.ToUpper(u.Query().Get("room")), roomCodeLength)
+		}
+	}
+
+	var code string
+	for _, word := range strings.Fields(strings.ToUpper(pasted)) {
+		word = strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if utf8.RuneCountInString(word) == roomCodeLength && isAlnum(word) {
+			code = word
+		}
 	}
-	pageStart := m.roomListPage * roomsPerPage
-	pageEnd := pageStart + roomsPerPage
-	if pageEnd > totalRooms {
-		pageEnd = totalRooms
+	if code != "" {
+		return code
 	}
-	pageCount := pageEnd - pageStart
 
-	switch msg.String() {
+	for _, r := range strings.ToUpper(pasted) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			input += string(r)
+		}
+	}
+	return truncateRunes(input, roomCodeLength)
+}
+
+func isAlnum(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// truncateRunes cuts s down to at most n runes.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func (m Model) handleListRoomsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.roomSearching {
+		return m.handleRoomSearchKeys(msg)
+	}
+	totalPages := m.roomBrowser().Pages()
+
+	switch msg.String() {
 	case "esc":
 		m.screen = ScreenMainMenu
 		m.availableRooms = nil
@@ -493,84 +1384,174 @@ func (m Model) handleListRoomsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Refresh room list
 		if m.client != nil {
 			m.screen = ScreenConnecting
-			return m, listRoomsCmd(m.client)
+			return m, m.fetchRooms(m.roomListPage, m.roomListCursor)
 		}
 		return m, nil
+	case "/":
+		m.roomSearching = true
+		return m, nil
+	case "p":
+		m.screen = ScreenQuickPlay
+		m.roomError = ""
+		return m, nil
+	case "o":
+		m.roomOpenOnly = !m.roomOpenOnly
+		return m, m.fetchRooms(0, 0)
+	case "s":
+		m.roomSort = nextOption([]string{protocol.RoomSortCode, protocol.RoomSortPlayers, protocol.RoomSortNewest}, m.roomSort)
+		return m, m.fetchRooms(0, 0)
 	case "up", "k":
 		if m.roomListCursor > 0 {
 			m.roomListCursor--
 		} else if m.roomListPage > 0 {
 			// Wrap to bottom of previous page
-			m.roomListPage--
-			newStart := m.roomListPage * roomsPerPage
-			newEnd := newStart + roomsPerPage
-			if newEnd > totalRooms {
-				newEnd = totalRooms
-			}
-			m.roomListCursor = newEnd - newStart - 1
+			return m, m.fetchRooms(m.roomListPage-1, -1)
 		}
 		return m, nil
 	case "down", "j":
-		if m.roomListCursor < pageCount-1 {
+		if m.roomListCursor < len(m.availableRooms)-1 {
 			m.roomListCursor++
 		} else if m.roomListPage < totalPages-1 {
 			// Wrap to top of next page
-			m.roomListPage++
-			m.roomListCursor = 0
+			return m, m.fetchRooms(m.roomListPage+1, 0)
 		}
 		return m, nil
 	case "left", "h":
 		if m.roomListPage > 0 {
-			m.roomListPage--
-			m.roomListCursor = 0
+			return m, m.fetchRooms(m.roomListPage-1, 0)
 		}
 		return m, nil
 	case "right", "l":
 		if m.roomListPage < totalPages-1 {
-			m.roomListPage++
-			m.roomListCursor = 0
-			// Clamp cursor to new page size
-			newStart := m.roomListPage * roomsPerPage
-			newEnd := newStart + roomsPerPage
-			if newEnd > totalRooms {
-				newEnd = totalRooms
-			}
-			if m.roomListCursor >= newEnd-newStart {
-				m.roomListCursor = newEnd - newStart - 1
-			}
+			return m, m.fetchRooms(m.roomListPage+1, 0)
 		}
 		return m, nil
 	case "enter":
-		if totalRooms > 0 && m.client != nil {
-			idx := pageStart + m.roomListCursor
-			if idx < totalRooms {
-				room := m.availableRooms[idx]
-				if room.Phase != "lobby" {
-					m.roomError = "Cannot join: game already in progress"
-					return m, nil
-				}
-				m.mode = ModeMulti
-				m.screen = ScreenConnecting
-				m.roomError = ""
-				return m, joinRoomHTTPCmd(m.client, room.RoomID, m.playerName)
+		if m.roomListCursor < len(m.availableRooms) && m.client != nil {
+			room := m.availableRooms[m.roomListCursor]
+			if room.Phase != protocol.RoomPhaseLobby {
+				m.roomError = i18n.T("rooms.in_progress")
+				return m, nil
 			}
+			m.mode = ModeMulti
+			m.screen = ScreenConnecting
+			m.roomError = ""
+			return m, joinRoomHTTPCmd(m.client, room.RoomID, m.playerName)
 		}
 		return m, nil
 	}
 	return m, nil
 }
 
+// roomBrowser gathers what the room browser shows.
+func (m Model) roomBrowser() RoomBrowser {
+	return RoomBrowser{
+		Rooms:     m.availableRooms,
+		Total:     m.roomTotal,
+		Page:      m.roomListPage,
+		Cursor:    m.roomListCursor,
+		Query:     m.roomQuery,
+		Searching: m.roomSearching,
+		OpenOnly:  m.roomOpenOnly,
+		Sort:      m.roomSort,
+		Error:     m.roomError,
+	}
+}
+
+// fetchRooms asks the server for a page of the room list, with the cursor
+// on row cursor once it arrives (-1 for the last row).
+func (m Model) fetchRooms(page, cursor int) tea.Cmd {
+	if m.client == nil {
+		return nil
+	}
+	q := client.RoomQuery{
+		Search:   m.roomQuery,
+		OpenOnly: m.roomOpenOnly,
+		Sort:     m.roomSort,
+		Limit:    roomsPerPage,
+		Offset:   page * roomsPerPage,
+	}
+	return listRoomsCmd(m.client, q, page, cursor)
+}
+
+// handleRoomSearchKeys edits the room browser search. Enter asks the
+// server for the matching rooms.
+func (m Model) handleRoomSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.roomSearching = false
+		m.roomQuery = strings.TrimSpace(m.roomQuery)
+		if m.client != nil {
+			m.screen = ScreenConnecting
+			return m, m.fetchRooms(0, 0)
+		}
+	case "esc":
+		m.roomSearching = false
+	case "backspace":
+		if len(m.roomQuery) > 0 {
+			_, size := utf8.DecodeLastRuneInString(m.roomQuery)
+			m.roomQuery = m.roomQuery[:len(m.roomQuery)-size]
+		}
+	default:
+		if msg.Paste {
+			m.roomQuery = truncateRunes(m.roomQuery+strings.Join(strings.Fields(string(msg.Runes)), " "), maxTitleLength)
+		} else if len(msg.String()) == 1 && utf8.RuneCountInString(m.roomQuery) < maxTitleLength {
+			m.roomQuery += msg.String()
+		}
+	}
+	return m, nil
+}
+
 func (m Model) handleLobbyKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.seedEditing {
+		return m.handleSeedKeys(msg)
+	}
+	if m.inviting {
+		return m.handleInvitingKeys(msg)
+	}
 	switch msg.String() {
 	case " ":
 		m.ready = !m.ready
 		if m.client != nil {
-			m.client.Send(protocol.Envelope{
-				Type: protocol.MsgReady,
-				Payload: protocol.ReadyPayload{
-					Ready: m.ready,
-				},
-			})
+			m.client.SetReady(m.ready)
+		}
+		return m, nil
+	case "m", "t", "a", "r", "j", "e", "l", "s", "g", "i", "h", "b", "p", "v":
+		if m.isHost() && m.roomType != protocol.RoomTypeRanked {
+			m.sendRoomSettings(m.cycleRoomSetting(msg.String()))
+		}
+		return m, nil
+	case "y":
+		if m.showQR {
+			return m, copyCmd(m.roomCode, m.joinLink())
+		}
+		if m.roomCode != "" {
+			return m, copyCmd(m.roomCode, m.roomCode)
+		}
+		return m, nil
+	case "c":
+		m.showQR = !m.showQR && m.roomCode != ""
+		return m, nil
+	case "d":
+		if m.isHost() && m.roomType != protocol.RoomTypeRanked {
+			m.seedEditing = true
+			m.seedInput = m.roomSettings.Seed
+		}
+		return m, nil
+	case "f":
+		if m.roomCode != "" {
+			m.inviting = true
+			m.inviteInput = ""
+		}
+		return m, nil
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		// The host mutes or unmutes the player at that place in the list
+		n := int(msg.String()[0] - '1')
+		if m.isHost() && m.client != nil && n < len(m.lobbyPlayers) {
+			p := m.lobbyPlayers[n]
+			if p.PlayerID != m.playerID && !p.Bot {
+				m.client.MutePlayer(p.PlayerID, !p.Muted)
+			}
 		}
 		return m, nil
 	case "esc":
@@ -582,58 +1563,272 @@ func (m Model) handleLobbyKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.roomCode = ""
 		m.ready = false
 		m.lobbyPlayers = nil
+		m.hostID = ""
+		m.roomSettings = protocol.RoomSettings{}
+		m.roomType = ""
+		m.autoStart = protocol.AutoStartPayload{}
+		m.seedEditing = false
+		m.inviting = false
+		m.roomError = ""
+		m.lobbyNote = ""
+		m.showQR = false
 		m.disconnected = false
+		m.connStatus = client.ConnDisconnected
+		m.rtt = 0
 		m.err = nil
 		return m, nil
 	}
 	return m, nil
 }
 
+// handleSeedKeys edits the room seed. Enter sends it to the server; an
+// empty seed goes back to a random one each match.
+func (m Model) handleSeedKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.seedEditing = false
+		s := m.roomSettings
+		s.Seed = strings.TrimSpace(m.seedInput)
+		m.sendRoomSettings(s)
+	case "esc":
+		m.seedEditing = false
+	case "backspace":
+		if len(m.seedInput) > 0 {
+			_, size := utf8.DecodeLastRuneInString(m.seedInput)
+			m.seedInput = m.seedInput[:len(m.seedInput)-size]
+		}
+	default:
+		if msg.Paste {
+			m.seedInput = truncateRunes(m.seedInput+strings.Join(strings.Fields(string(msg.Runes)), " "), maxSeedLength)
+		} else if msg.Type == tea.KeyRunes && utf8.RuneCountInString(m.seedInput) < maxSeedLength {
+			m.seedInput += string(msg.Runes)
+		}
+	}
+	return m, nil
+}
+
+// isHost reports whether this client may change the room settings.
+func (m Model) isHost() bool {
+	return m.playerID != "" && m.playerID == m.hostID
+}
+
+// cycleRoomSetting returns the room settings with the option bound to key
+// advanced to its next value.
+func (m Model) cycleRoomSetting(key string) protocol.RoomSettings {
+	s := m.roomSettings
+	switch key {
+	case "m":
+		if s.MaxPlayers < stepRoomPlayers {
+			s.MaxPlayers++
+		} else if i := slices.IndexFunc(largeRoomPlayers, func(n int) bool { return n > s.MaxPlayers }); i >= 0 {
+			s.MaxPlayers = largeRoomPlayers[i]
+		} else {
+			// Never offer fewer seats than there are players already here.
+			s.MaxPlayers = max(minRoomPlayers, len(m.lobbyPlayers))
+		}
+	case "t":
+		s.Targeting = nextOption([]string{protocol.TargetingFree, protocol.TargetingRandom}, s.Targeting)
+	case "a":
+		s.AttackTable = nextOption(game.AttackTableNames, s.AttackTable)
+	case "r":
+		s.Randomizer = nextOption(game.Randomizers, s.Randomizer)
+	case "j":
+		s.OnJoin = nextOption([]string{protocol.OnJoinReset, protocol.OnJoinPause}, s.OnJoin)
+	case "e":
+		s.EntryDelayMs = nextIntOption(entryDelayOptions, s.EntryDelayMs)
+	case "l":
+		s.LineClearDelayMs = nextIntOption(lineClearDelayOptions, s.LineClearDelayMs)
+	case "s":
+		s.AllSpin = !s.AllSpin
+	case "g":
+		s.GarbageStyle = nextOption(game.GarbageStyles, s.GarbageStyle)
+	case "i":
+		s.Items = !s.Items
+	case "h":
+		s.HoldMode = nextOption(game.HoldModes, s.HoldMode)
+	case "p":
+		s.PointsTarget = nextIntOption(pointsTargetOptions, s.PointsTarget)
+	case "v":
+		s.SpeedRaceSecs = nextIntOption(speedRaceOptions, s.SpeedRaceSecs)
+	case "b":
+		// Add bots until the seats run out, then start over at none.
+		humans := 0
+		for _, p := range m.lobbyPlayers {
+			if !p.Bot {
+				humans++
+			}
+		}
+		s.Bots++
+		if humans+s.Bots > s.MaxPlayers {
+			s.Bots = 0
+		}
+	}
+	return s
+}
+
+// nextIntOption is nextOption for numeric options.
+func nextIntOption(options []int, cur int) int {
+	for i, o := range options {
+		if o == cur {
+			return options[(i+1)%len(options)]
+		}
+	}
+	return options[0]
+}
+
+// nextOption returns the option after cur, wrapping around.
+func nextOption(options []string, cur string) string {
+	for i, o := range options {
+		if o == cur {
+			return options[(i+1)%len(options)]
+		}
+	}
+	return options[0]
+}
+
+func (m *Model) sendRoomSettings(s protocol.RoomSettings) {
+	m.roomError = ""
+	if m.client != nil {
+		m.client.UpdateSettings(s)
+	}
+}
+
+// targetingLocked reports whether the room picks targets for everyone.
+func (m Model) targetingLocked() bool {
+	return m.roomSettings.Targeting == protocol.TargetingRandom
+}
+
 func (m Model) handlePlayingKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.gameState == nil || m.gameState.IsGameOver {
 		return m, nil
 	}
+	inputCmd := m.recordInput(msg.String())
+
+	if action, ok := m.keymap().action(msg.String()); ok {
+		switch action {
+		case inputLeft:
+			m.input(game.InputLeft)
+		case inputRight:
+			m.input(game.InputRight)
+		case inputDown:
+			return m, tea.Batch(inputCmd, m.pressSoftDrop())
+		case inputRotate:
+			m.input(game.InputRotate)
+		case inputRotateBack:
+			m.input(game.InputRotateBack)
+		case inputDrop:
+			m.input(game.InputHardDrop)
+			m.sendSnapshot()
+			return m, tea.Batch(inputCmd, m.lockCue(), pieceDelayCmd(m.gameState))
+		case inputHold:
+			m.input(game.InputHold)
+		}
+		return m, inputCmd
+	}
 
 	switch msg.String() {
-	case "left", "h":
-		m.gameState.MoveLeft()
-	case "right", "l":
-		m.gameState.MoveRight()
-	case "down", "j":
-		m.gameState.MoveDown()
-	case "up", "x":
-		m.gameState.Rotate()
-	case " ", "c":
-		m.gameState.HardDrop()
-		// After hard drop, check for attack
-		m.sendAttackIfNeeded()
-		m.checkLocalGameOver()
-	case "z":
-		m.gameState.Hold()
+	case "v":
+		// Toggle the input display
+		if m.prefs != nil {
+			m.prefs.InputDisplay = !m.prefs.InputDisplay
+			return m, tea.Batch(inputCmd, m.profileChanged())
+		}
 	case "tab":
 		m.cycleTarget()
+	case "0":
+		m.selectTarget(-1)
+	case "1", "2", "3", "4", "5", "6", "7", "8":
+		m.selectTarget(int(msg.String()[0] - '1'))
+	case "g", "G":
+		// Practice garbage on demand
+		if m.mode == ModeSingle {
+			lines := 1
+			if msg.String() == "G" {
+				lines = 4
+			}
+			m.engine.ReceiveGarbage(lines)
+		}
+	case "i":
+		if m.mode == ModeSingle {
+			next := 0
+			for i, d := range practiceIntervals {
+				if d == m.practiceInterval {
+					next = (i + 1) % len(practiceIntervals)
+				}
+			}
+			m.practiceInterval = practiceIntervals[next]
+			return m, m.restartPracticeGarbage()
+		}
+	case "[":
+		m.flipOpponentPage(-1)
+	case "]":
+		m.flipOpponentPage(1)
+	case "f1", "f2", "f3", "f4", "f5":
+		if m.mode == ModeMulti && m.client != nil {
+			m.client.SendEmote(emoteKeys[msg.String()])
+		}
+	case "e":
+		m.useItem()
 	}
-	return m, nil
+	return m, inputCmd
+}
+
+// gameOverItems lists the choices on the game-over screen.
+func (m Model) gameOverItems() []string {
+	if m.mode == ModeSingle {
+		return []string{i18n.T("result.play_again"), i18n.T("result.main_menu"), i18n.T("result.stats")}
+	}
+	return []string{i18n.T("result.back_to_lobby"), i18n.T("result.leave_room"), i18n.T("result.stats")}
 }
 
 func (m Model) handleGameOverKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Any key skips the end-screen animation.
+	if m.endAnim != nil {
+		m.endAnim = nil
+		return m, nil
+	}
+
+	choice := -1
 	switch msg.String() {
+	case "up", "k":
+		m.gameOverCursor = moveCursor(m.gameOverCursor, -1, len(m.gameOverItems()))
+		return m, nil
+	case "down", "j":
+		m.gameOverCursor = moveCursor(m.gameOverCursor, 1, len(m.gameOverItems()))
+		return m, nil
 	case "enter":
-		if m.mode == ModeSingle {
-			m.screen = ScreenMainMenu
-			m.mode = ModeNone
-			m.gameState = nil
-		} else {
-			// Return to lobby - wait for server lobby update
-			m.screen = ScreenLobby
-			m.ready = false
-			m.matchResult = nil
-			m.opponents = nil
-			m.gameState = nil
-		}
-		return m, tickCmd()
+		choice = m.gameOverCursor
+	case "1":
+		choice = 0
+	case "2":
+		choice = 1
+	case "3":
+		choice = 2
 	case "esc":
-		// Leave the room and return to main menu
+		// Always leaves: to the main menu, or out of the room.
+		choice = 1
+	}
+
+	switch {
+	case choice == 2 && m.gameState != nil:
+		m.screen = ScreenStats
+		return m, nil
+	case choice == 0 && m.mode == ModeSingle:
+		// Play again
+		m.gameState = nil
+		m.screen = ScreenMainMenu
+		return m.handleMainMenuKeys(runeKey("1"))
+	case choice == 0:
+		// Return to lobby - wait for server lobby update
+		m.screen = ScreenLobby
+		m.ready = false
+		m.matchResult = nil
+		m.opponents = nil
+		m.remaining = 0
+		m.gameState = nil
+		return m, tickCmd()
+	case choice == 1:
+		// Leave the room (if any) and return to main menu
 		if m.client != nil && m.mode == ModeMulti {
 			m.client.DisconnectFromRoom()
 		}
@@ -643,22 +1838,35 @@ func (m Model) handleGameOverKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.ready = false
 		m.matchResult = nil
 		m.opponents = nil
+		m.remaining = 0
 		m.gameState = nil
 		m.disconnected = false
+		m.connStatus = client.ConnDisconnected
+		m.rtt = 0
 		m.err = nil
 		return m, tickCmd()
 	}
 	return m, nil
 }
 
+// handleStatsKeys returns from the statistics screen to the game over menu.
+func (m Model) handleStatsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "enter", "backspace":
+		m.screen = ScreenGameOver
+	}
+	return m, nil
+}
+
 // --- Tick handlers ---
 
 func (m Model) handleTick() (tea.Model, tea.Cmd) {
 	// During gameplay/countdown/gameover, don't reschedule the general tick.
 	// Game ticks, snapshot ticks, and server messages handle those screens.
-	if m.screen == ScreenPlaying || m.screen == ScreenCountdown || m.screen == ScreenGameOver {
+	if m.screen == ScreenPlaying || m.screen == ScreenCountdown || m.screen == ScreenGameOver || m.screen == ScreenTutorial {
 		return m, nil
 	}
+	m.attractTick(time.Now())
 	return m, tickCmd()
 }
 
@@ -670,19 +1878,63 @@ func (m Model) handleGameTick() (tea.Model, tea.Cmd) {
 	// Check for game over
 	if m.gameState.IsGameOver {
 		if m.mode == ModeSingle {
+			m.saveReplay()
+			m.recordGame()
+			unlockCmd := m.unlockAchievements()
 			m.screen = ScreenGameOver
+			m.gameOverCursor = 0
+			return m, tea.Batch(m.startEndAnim(false), unlockCmd)
 		}
 		// For multiplayer, wait for MsgMatchOver from the server.
 		return m, nil
 	}
 
-	m.gameState.Tick()
+	now := time.Now()
+	m.advance(now)
+	m.sendSnapshot()
+
+	return m, tea.Batch(gameTickCmd(m.engine.Wait(now)), m.lockCue())
+}
+
+// handlePieceDelayDone moves the engine on when an entry or line clear
+// delay started by a hard drop ends, and starts waiting for the next one
+// if there is one.
+func (m Model) handlePieceDelayDone(now time.Time) (tea.Model, tea.Cmd) {
+	if m.screen != ScreenPlaying || m.gameState == nil {
+		return m, nil
+	}
+	m.advance(now)
+	m.sendSnapshot()
+	return m, pieceDelayCmd(m.gameState)
+}
 
-	// After tick, check if lines were cleared (attack)
-	m.sendAttackIfNeeded()
-	m.checkLocalGameOver()
+// startEndAnim begins the end-screen animation unless reduced motion is on.
+func (m *Model) startEndAnim(victory bool) tea.Cmd {
+	if m.prefs != nil && m.prefs.ReducedMotion {
+		m.endAnim = nil
+		return nil
+	}
+	var board *game.Board
+	if m.gameState != nil {
+		board = m.gameState.Board
+	}
+	m.endAnim = newEndAnim(victory, board)
+	return animTickCmd()
+}
 
-	return m, gameTickCmd(m.gameState.GetDropSpeed())
+func (m Model) handleAnimTick() (tea.Model, tea.Cmd) {
+	if m.endAnim == nil || m.screen != ScreenGameOver {
+		m.endAnim = nil
+		return m, nil
+	}
+	anim := *m.endAnim
+	anim.frame++
+	if anim.done() {
+		m.endAnim = nil
+		return m, nil
+	}
+	m.endAnim = &anim
+	return m, animTickCmd()
 }
 
 func (m Model) handleCountdown() (tea.Model, tea.Cmd) {
@@ -696,63 +1948,104 @@ func (m Model) handleSnapshotTick() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	// Send board snapshot to server
-	if m.client != nil {
-		m.client.Send(protocol.Envelope{
-			Type: protocol.MsgBoardSnapshot,
-			Payload: protocol.BoardSnapshotPayload{
-				Score: m.gameState.Score,
-				Level: m.gameState.Level,
-				Lines: m.gameState.Lines,
-				Alive: !m.gameState.IsGameOver,
-				Board: m.gameState.Board.ToFlat(),
-			},
-		})
-	}
-
+	m.sendSnapshot()
 	return m, snapshotTickCmd()
 }
 
-// sendAttackIfNeeded checks if the game state has accumulated attack power and sends it.
-func (m *Model) sendAttackIfNeeded() {
+// sendSnapshot sends the board to the server if it changed since the last
+// snapshot, or if snapshotKeepalive has passed.
+func (m *Model) sendSnapshot() {
 	if m.mode != ModeMulti || m.gameState == nil || m.client == nil {
 		return
 	}
-	if m.gameState.AttackPower > 0 {
-		m.client.Send(protocol.Envelope{
-			Type: protocol.MsgLinesCleared,
-			Payload: protocol.LinesClearedPayload{
-				Count:       m.gameState.AttackPower, // simplified: count = attack
-				AttackPower: m.gameState.AttackPower,
-			},
-		})
-		m.gameState.AttackPower = 0
+	snap := protocol.BoardSnapshotPayload{
+		Score: m.gameState.Score,
+		Level: m.gameState.Level,
+		Lines: m.gameState.Lines,
+		Alive: !m.gameState.IsGameOver,
+		Board: m.gameState.Board.ToFlat(),
+	}
+	if last := m.lastSnap; last != nil && time.Since(m.lastSnapAt) < snapshotKeepalive &&
+		last.Score == snap.Score && last.Level == snap.Level && last.Lines == snap.Lines &&
+		last.Alive == snap.Alive && slices.Equal(last.Board, snap.Board) {
+		return
+	}
+	m.client.SendBoard(snap)
+	m.lastSnap = &snap
+	m.lastSnapAt = time.Now()
+}
+
+// sendClear tells the server about a clear, and the garbage it sends.
+func (m *Model) sendClear(ev game.Event) {
+	if m.mode != ModeMulti || m.client == nil {
+		return
 	}
+	a := ev.Clear
+	m.client.SendClear(protocol.LinesClearedPayload{
+		Count:       a.Lines,
+		AttackPower: ev.Attack,
+		ClearType:   protocol.ClearTypeFor(a.Lines, a.TSpin),
+		Combo:       a.Combo,
+		B2B:         a.B2B,
+		Spin:        a.Spin,
+	})
+}
+
+// input applies one of the player's inputs to the game straight away.
+func (m *Model) input(in game.Input) {
+	m.engine.Queue(in)
+	m.advance(time.Now())
 }
 
-// checkLocalGameOver notifies the server when this player dies.
-func (m *Model) checkLocalGameOver() {
+// advance runs the game up to now, sending the server each clear (with
+// its garbage, and for a chance at an item) and word if the player tops
+// out.
+func (m *Model) advance(now time.Time) {
+	for _, ev := range m.engine.Advance(now) {
+		switch {
+		case ev.Kind == game.EventLock && ev.Clear.Lines > 0:
+			m.sendClear(ev)
+		case ev.Kind == game.EventTopOut && m.mode == ModeMulti && m.client != nil:
+			m.client.SendDead()
+		}
+	}
+}
+
+// useItem fires the item the player is holding: ItemClear on their own
+// board, the rest at their target through the server.
+func (m *Model) useItem() {
 	if m.mode != ModeMulti || m.gameState == nil || m.client == nil {
 		return
 	}
-	if m.gameState.IsGameOver {
-		m.client.Send(protocol.Envelope{
-			Type:    protocol.MsgPlayerDead,
-			Payload: protocol.PlayerDeadPayload{},
-		})
+	item := m.engine.TakeItem()
+	if item == "" {
+		return
+	}
+	if !game.IsTargeted(item) {
+		m.engine.ApplyItem(item, time.Now())
+		m.sendSnapshot()
 	}
+	m.client.UseItem(item)
 }
 
 // --- View ---
 
 func (m Model) View() string {
+	v := m.view()
+	if m.showDebug && m.debugLog != nil {
+		v = m.overlayDebug(v)
+	}
+	return v
+}
+
+func (m Model) view() string {
 	if m.disconnected {
-		return m.renderCentered("Disconnected from server.\nPress Ctrl+C to exit.")
+		return m.renderCentered(i18n.T("status.disconnected"))
 	}
 
 	switch m.screen {
 	case ScreenConnecting:
-		connMsg := "Connecting..."
+		connMsg := i18n.T("status.connecting")
 		if m.roomError != "" {
 			connMsg = m.roomError
 		}
@@ -763,6 +2056,8 @@ func (m Model) View() string {
 		return m.renderEditName()
 	case ScreenJoinRoom:
 		return m.renderJoinRoom()
+	case ScreenCreateRoom:
+		return m.renderCentered(RenderCreateRoom(m.titleInput, m.roomError))
 	case ScreenListRooms:
 		return m.renderListRooms()
 	case ScreenLobby:
@@ -773,10 +2068,39 @@ func (m Model) View() string {
 		return m.renderPlaying()
 	case ScreenGameOver:
 		return m.renderGameOver()
+	case ScreenSettings:
+		return m.renderSettings()
+	case ScreenServer:
+		return m.renderCentered(RenderServer(m.serverInput, m.recentServers(), m.serverCursor, m.serverChecking, m.roomError))
+	case ScreenTutorial:
+		return m.renderTutorial()
+	case ScreenStats:
+		if m.gameState == nil {
+			return ""
+		}
+		return m.renderCentered(RenderStats(&m.gameState.Stats))
+	case ScreenHistory:
+		return m.renderHistory()
+	case ScreenQuickPlay:
+		return m.renderCentered(RenderQuickPlay(m.quickPlayCursor))
+	case ScreenAchievements:
+		return m.renderCentered(RenderAchievements(m.prefs.Achievements))
 	}
 	return ""
 }
 
+func (m Model) renderTutorial() string {
+	if m.tutorial == nil || m.gameState == nil {
+		return ""
+	}
+	return m.renderCentered(lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		lipgloss.NewStyle().Width(24).Render(RenderInfo(m.gameState, "", false, "")),
+		lipgloss.NewStyle().Padding(1, 2).Render(RenderBoard(m.gameState, game.BoardWidth, game.BoardHeight)),
+		lipgloss.NewStyle().Padding(1, 2).Render(m.tutorial.render(m.keymap())),
+	))
+}
+
 func (m Model) renderCentered(content string) string {
 	return lipgloss.NewStyle().
 		Width(m.width).
@@ -785,12 +2109,39 @@ func (m Model) renderCentered(content string) string {
 		Render(content)
 }
 
+// menuStatus is the main menu's server totals and invite, one under the
+// other.
+func (m Model) menuStatus() string {
+	var lines []string
+	for _, s := range []string{renderServerStats(m.serverStats), renderInvite(m.invites)} {
+		if s != "" {
+			lines = append(lines, s)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (m Model) renderMainMenu() string {
+	menu := RenderMainMenu(m.playerName, m.server(), m.lastRoom(), m.menuStatus(), m.menuCursor)
+	if m.attract != nil {
+		menu = renderAttract(m.attract, menu, m.width)
+	}
 	return lipgloss.NewStyle().
 		Width(m.width).
 		Height(m.height).
 		Align(lipgloss.Center, lipgloss.Center).
-		Render(RenderMainMenu(m.playerName))
+		Render(menu)
+}
+
+func (m Model) renderSettings() string {
+	var labels []string
+	var values []bool
+	for _, item := range m.settings() {
+		labels = append(labels, item.label)
+		values = append(values, *item.value)
+	}
+	km := m.keymap()
+	return m.renderCentered(RenderSettings(labels, values, i18n.T("keymap."+km.name), renderControls(km), m.settingsCursor))
 }
 
 func (m Model) renderEditName() string {
@@ -814,11 +2165,35 @@ func (m Model) renderListRooms() string {
 		Width(m.width).
 		Height(m.height).
 		Align(lipgloss.Center, lipgloss.Center).
-		Render(RenderListRooms(m.availableRooms, m.roomError, m.roomListCursor, m.roomListPage))
+		Render(RenderListRooms(m.roomBrowser()))
 }
 
 func (m Model) renderLobby() string {
-	lobbyContent := RenderLobby(m.lobbyPlayers, m.playerID, m.roomCode)
+	if m.showQR {
+		content := RenderJoinQR(m.roomCode, m.joinLink())
+		if m.lobbyNote != "" {
+			content += "\n" + readyStyle.Render(m.lobbyNote) + "\n"
+		}
+		return m.renderCentered(content)
+	}
+	lobbyContent := RenderLobby(m.lobbyPlayers, m.playerID, m.roomCode, m.roomType, m.hostID, m.roomSettings, m.autoStart, m.roomError)
+	if m.seedEditing {
+		lobbyContent += "\n" + cursorStyle.Render(i18n.T("room.seed_prompt", m.seedInput+"_")) + "\n"
+		lobbyContent += infoStyle.Render(i18n.T("room.seed_hint")) + "\n"
+	}
+	if m.inviting {
+		lobbyContent += "\n" + renderInviting(m.inviteInput, m.friendCode)
+	}
+	if addr := m.lanAddress(); addr != "" {
+		lobbyContent += "\n" + targetStyle.Render(i18n.T("lan.address", addr)) + "\n"
+	}
+	if m.lobbyNote != "" {
+		lobbyContent += "\n" + readyStyle.Render(m.lobbyNote) + "\n"
+	}
+	if m.motd != "" {
+		lobbyContent += "\n" + targetStyle.Render(m.motd) + "\n"
+	}
+	lobbyContent += "\n" + RenderConnStatus(m.connStatus, m.rtt, m.reconnects)
 
 	return lipgloss.NewStyle().
 		Width(m.width).
@@ -837,16 +2212,19 @@ func (m Model) renderCountdown() string {
 
 func (m Model) renderPlaying() string {
 	if m.gameState == nil {
-		return "Loading..."
+		return i18n.T("status.loading")
 	}
 
 	board := RenderBoard(m.gameState, game.BoardWidth, game.BoardHeight)
+	if m.goFlash {
+		board = RenderGoFlash()
+	}
 
 	// Build target name for info panel
 	targetName := ""
 	if m.mode == ModeMulti {
 		if m.targetID == "" {
-			targetName = "Random"
+			targetName = i18n.T("target.random")
 		} else {
 			for _, opp := range m.opponents {
 				if opp.PlayerID == m.targetID {
@@ -855,12 +2233,26 @@ func (m Model) renderPlaying() string {
 				}
 			}
 			if targetName == "" {
-				targetName = "Random" // target left, reset display
+				targetName = i18n.T("target.random") // target left, reset display
 			}
 		}
 	}
 
-	info := RenderInfo(m.gameState, targetName)
+	info := RenderInfo(m.gameState, targetName, m.targetingLocked(), m.attackNote())
+	if m.mode == ModeMulti {
+		info += "\n\n" + RenderConnStatus(m.connStatus, m.rtt, m.reconnects)
+		if b, ok := m.emotes[m.playerID]; ok {
+			info += "\n" + renderEmote(emoteText(b.emote), 22)
+		} else if m.client != nil && m.client.Supports(protocol.CapChat) {
+			info += "\n" + infoStyle.Render(i18n.T("emote.hint"))
+		}
+	}
+	if m.mode == ModeSingle {
+		info += "\n\n" + RenderPracticeControls(m.practiceInterval)
+	}
+	if m.showInputs() {
+		info += "\n\n" + renderInputDisplay(m.inputLit, m.inputHistory)
+	}
 
 	leftPanel := lipgloss.NewStyle().
 		Width(24).
@@ -877,7 +2269,15 @@ func (m Model) renderPlaying() string {
 	)
 
 	if m.mode == ModeMulti && len(m.opponents) > 0 {
-		opponentView := RenderNetOpponents(m.opponents, 8, m.targetID)
+		cols, _ := m.opponentGrid()
+		visible, page, pages := m.pageOpponents()
+		opponentView := RenderNetOpponents(visible, cols, page, pages, m.targetID, m.attackerID, m.emoteTexts())
+		if m.remaining > 0 {
+			opponentView = infoStyle.Render(i18n.T("opponent.remaining", m.remaining)) + "\n\n" + opponentView
+		}
+		if len(m.events) > 0 {
+			opponentView += "\n\n" + RenderEventFeed(m.events)
+		}
 		if opponentView != "" {
 			rightPanel := lipgloss.NewStyle().
 				Padding(1, 2).
@@ -899,8 +2299,11 @@ func (m Model) renderPlaying() string {
 }
 
 func (m Model) renderGameOver() string {
+	if m.endAnim != nil {
+		return m.renderCentered(m.endAnim.render())
+	}
 	if m.gameState == nil {
-		return m.renderCentered("Game Over")
+		return m.renderCentered(i18n.T("result.game_over"))
 	}
 
 	score := m.gameState.Score
@@ -910,13 +2313,32 @@ func (m Model) renderGameOver() string {
 		content = RenderSingleGameOver(score)
 	} else if m.matchResult != nil {
 		isWinner := m.matchResult.WinnerID == m.playerID
-		content = RenderGameOver(isWinner, score, m.matchResult.YourRank)
+		content = RenderGameOver(isWinner, score, m.matchResult.YourRank, m.matchResult.Standings, m.playerID)
 	} else {
 		isWinner := m.gameState.IsWinner
 		rank := 0
-		content = RenderGameOver(isWinner, score, rank)
+		content = RenderGameOver(isWinner, score, rank, nil, m.playerID)
 	}
-	content += "\n\nPress ENTER to continue"
+	if m.mode == ModeMulti && m.matchResult != nil && m.matchResult.PointsTarget > 0 {
+		if m.matchResult.ChampionID != "" {
+			content += "\n" + winnerStyle.Render(i18n.T("result.champion", m.matchResult.ChampionName))
+		} else {
+			content += "\n" + infoStyle.Render(i18n.T("result.points_target", m.matchResult.PointsTarget))
+		}
+	}
+	if m.mode == ModeMulti && m.matchResult != nil && len(m.matchResult.Awards) > 0 {
+		content += "\n" + RenderAwards(m.matchResult.Awards, m.playerID)
+	}
+	if m.mode == ModeMulti {
+		content += "\n" + infoStyle.Render(i18n.T("result.seed", m.seedText()))
+	}
+	if m.replayPath != "" {
+		content += "\n" + infoStyle.Render(i18n.T("result.replay", m.replayPath))
+	}
+	if len(m.unlocked) > 0 {
+		content += "\n" + RenderUnlocked(m.unlocked)
+	}
+	content += "\n\n" + RenderMenuItems(m.gameOverItems(), m.gameOverCursor)
 
 	return lipgloss.NewStyle().
 		Width(m.width).
@@ -925,9 +2347,18 @@ func (m Model) renderGameOver() string {
 		Render(content)
 }
 
+// seedText is the last match's seed as players can share it: the room's
+// seed as the host typed it, or the number to type to get the same pieces.
+func (m Model) seedText() string {
+	if m.roomSettings.Seed != "" {
+		return m.roomSettings.Seed
+	}
+	return strconv.FormatInt(m.seed, 10)
+}
+
 // cycleTarget cycles the attack target: random → opponent 0 → opponent 1 → ... → random.
 func (m *Model) cycleTarget() {
-	if m.mode != ModeMulti || len(m.opponents) == 0 {
+	if m.mode != ModeMulti || len(m.opponents) == 0 || m.targetingLocked() {
 		return
 	}
 
@@ -968,16 +2399,156 @@ func (m *Model) cycleTarget() {
 				break
 			}
 		}
+		m.showTarget()
+	}
+
+	m.sendTarget()
+}
+
+// opponentGrid returns how many columns and rows of opponent previews fit
+// in the terminal.
+func (m Model) opponentGrid() (cols, rows int) {
+	if m.width == 0 || m.height == 0 {
+		return opponentCols, opponentRows
+	}
+	cols = min(max((m.width-playfieldW)/opponentCellW, 1), opponentCols)
+	rows = min(max((m.height-opponentReservedH)/opponentCellH, 1), opponentRows)
+	return cols, rows
+}
+
+// opponentPageSize is the number of previews shown per page.
+func (m Model) opponentPageSize() int {
+	cols, rows := m.opponentGrid()
+	return cols * rows
+}
+
+// orderedOpponents lists opponents in display order: players still alive
+// first, then those knocked out, each in the server's order.
+func (m Model) orderedOpponents() []protocol.OpponentState {
+	ordered := make([]protocol.OpponentState, 0, len(m.opponents))
+	for _, opp := range m.opponents {
+		if opp.Alive {
+			ordered = append(ordered, opp)
+		}
+	}
+	for _, opp := range m.opponents {
+		if !opp.Alive {
+			ordered = append(ordered, opp)
+		}
+	}
+	return ordered
+}
+
+// keepBoards fills in the boards a large room's update left out of next
+// with the ones last seen in prev, so opponents outside this update's
+// sample keep showing their board rather than going blank.
+func keepBoards(prev, next []protocol.OpponentState) {
+	for i := range next {
+		if next[i].Board != nil {
+			continue
+		}
+		for _, old := range prev {
+			if old.PlayerID == next[i].PlayerID {
+				next[i].Board = old.Board
+				break
+			}
+		}
 	}
+}
+
+// pageOpponents returns the opponents on the current page, along with the
+// (clamped) page number and the page count.
+func (m Model) pageOpponents() (visible []protocol.OpponentState, page, pages int) {
+	ordered := m.orderedOpponents()
+	size := m.opponentPageSize()
+	pages = max((len(ordered)+size-1)/size, 1)
+	page = min(max(m.opponentPage, 0), pages-1)
+
+	start := page * size
+	end := min(start+size, len(ordered))
+	return ordered[start:end], page, pages
+}
 
-	// Notify the server
+// flipOpponentPage moves the opponent previews by delta pages.
+func (m *Model) flipOpponentPage(delta int) {
+	_, page, pages := m.pageOpponents()
+	m.opponentPage = (page + delta + pages) % pages
+}
+
+// showTarget turns to the page holding the current target.
+func (m *Model) showTarget() {
+	for i, opp := range m.orderedOpponents() {
+		if opp.PlayerID == m.targetID {
+			m.opponentPage = i / m.opponentPageSize()
+			return
+		}
+	}
+}
+
+// restartPracticeGarbage (re)starts the practice garbage timer at the
+// current interval, cancelling any timer already running.
+func (m *Model) restartPracticeGarbage() tea.Cmd {
+	m.practiceSeq++
+	if m.practiceInterval == 0 {
+		return nil
+	}
+	return practiceGarbageCmd(m.practiceInterval, m.practiceSeq)
+}
+
+func (m Model) handlePracticeGarbage(seq int) (tea.Model, tea.Cmd) {
+	if seq != m.practiceSeq || m.mode != ModeSingle || m.screen != ScreenPlaying ||
+		m.gameState == nil || m.gameState.IsGameOver {
+		return m, nil
+	}
+	m.engine.ReceiveGarbage(1)
+	return m, practiceGarbageCmd(m.practiceInterval, seq)
+}
+
+// attackNote describes the attack currently being flashed, e.g.
+// "+3 from Alice", or "" when there is nothing to show.
+func (m Model) attackNote() string {
+	if m.attackLines == 0 {
+		return ""
+	}
+	for _, opp := range m.opponents {
+		if opp.PlayerID == m.attackerID {
+			return i18n.T("info.attack_from", m.attackLines, opp.PlayerName)
+		}
+	}
+	return i18n.T("info.attack", m.attackLines)
+}
+
+// selectTarget targets the opponent shown in panel slot idx (0-based) on
+// the current page. A negative idx returns to random targeting. Empty slots
+// and knocked-out opponents are ignored.
+func (m *Model) selectTarget(idx int) {
+	if m.mode != ModeMulti || m.targetingLocked() {
+		return
+	}
+
+	if idx < 0 {
+		m.targetID = ""
+		m.targetIndex = -1
+	} else {
+		visible, _, _ := m.pageOpponents()
+		if idx >= len(visible) || !visible[idx].Alive {
+			return
+		}
+		m.targetID = visible[idx].PlayerID
+		for i, opp := range m.opponents {
+			if opp.PlayerID == m.targetID {
+				m.targetIndex = i
+			}
+		}
+	}
+
+	m.sendTarget()
+}
+
+// sendTarget notifies the server of the current attack target.
+func (m *Model) sendTarget() {
 	if m.client != nil {
-		m.client.Send(protocol.Envelope{
-			Type: protocol.MsgSetTarget,
-			Payload: protocol.SetTargetPayload{
-				TargetID: m.targetID,
-			},
-		})
+		m.client.SetTarget(m.targetID)
 	}
 }
 
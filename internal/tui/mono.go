@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// --- Monochrome ---
+//
+// In monochrome mode nothing is drawn in color. Each piece's blocks get a
+// fill pattern of their own instead, so the pieces, the ghost and garbage
+// stay apart on terminals without 256 colors and for players who'd rather
+// not rely on color. Menus already mark their cursor with ">" and the
+// target with "▶", so nothing else depends on color. It's on with --mono,
+// and by default when NO_COLOR is set or TERM names a terminal with
+// fewer than 256 colors.
+
+// monoPatterns are the blocks' fill patterns in monochrome, indexed like
+// colors. O and L share a color, so they share a pattern too.
+var monoPatterns = []string{"  ", "▚▚", "▞▞", "██", "▓▓", "▒▒", "░░", "::", "##"}
+
+var (
+	// monochrome is whether the mode is on.
+	monochrome bool
+	// colorProfile is the terminal's own color profile, restored when
+	// the mode is turned off.
+	colorProfile termenv.Profile
+)
+
+// MonochromeByDefault reports whether the terminal calls for
+// monochrome: NO_COLOR is set, or it has fewer than 256 colors.
+func MonochromeByDefault() bool {
+	return lipgloss.ColorProfile() > termenv.ANSI256
+}
+
+// SetMonochrome turns monochrome mode on or off.
+func SetMonochrome(on bool) {
+	if on == monochrome {
+		return
+	}
+	monochrome = on
+	if on {
+		colorProfile = lipgloss.ColorProfile()
+		lipgloss.SetColorProfile(termenv.Ascii)
+	} else {
+		lipgloss.SetColorProfile(colorProfile)
+	}
+}
+
+// block is the two characters a block of the given color is drawn with.
+func block(color int) string {
+	if monochrome && color >= 0 && color < len(monoPatterns) {
+		return monoPatterns[color]
+	}
+	return "██"
+}
+
+// halfBlock is block at half width, for the opponents' small boards.
+func halfBlock(color int) string {
+	return string([]rune(block(color))[0])
+}
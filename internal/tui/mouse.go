@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+)
+
+// --- Mouse handlers ---
+//
+// Menu screens are plain text centered in the window, so clicks are mapped
+// back to the line of rendered content under the cursor and then translated
+// into the equivalent key press. That keeps all of the actual behaviour in
+// the key handlers.
+
+func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Action != tea.MouseActionPress {
+		return m, nil
+	}
+
+	switch m.screen {
+	case ScreenMainMenu:
+		return m.handleMainMenuMouse(msg)
+	case ScreenListRooms:
+		return m.handleListRoomsMouse(msg)
+	case ScreenLobby:
+		return m.handleLobbyMouse(msg)
+	}
+	return m, nil
+}
+
+func (m Model) handleMainMenuMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Button != tea.MouseButtonLeft {
+		return m, nil
+	}
+	line, ok := m.contentLineAt(RenderMainMenu(m.playerName, m.server(), m.lastRoom(), m.menuStatus(), m.menuCursor), msg.Y)
+	if !ok {
+		return m, nil
+	}
+	for _, item := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"} {
+		if strings.Contains(line, "["+item+"]") {
+			return m.handleMainMenuKeys(runeKey(item))
+		}
+	}
+	return m, nil
+}
+
+func (m Model) handleListRoomsMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		return m.handleListRoomsKeys(tea.KeyMsg{Type: tea.KeyUp})
+	case tea.MouseButtonWheelDown:
+		return m.handleListRoomsKeys(tea.KeyMsg{Type: tea.KeyDown})
+	case tea.MouseButtonLeft:
+	default:
+		return m, nil
+	}
+
+	content := RenderListRooms(m.roomBrowser())
+	line, ok := m.contentLineAt(content, msg.Y)
+	if !ok {
+		return m, nil
+	}
+
+	// Pagination: left half of the "◀ Page x / y ▶" line goes back, right half forward.
+	if strings.Contains(line, "◀") {
+		if msg.X < m.width/2 {
+			return m.handleListRoomsKeys(tea.KeyMsg{Type: tea.KeyLeft})
+		}
+		return m.handleListRoomsKeys(tea.KeyMsg{Type: tea.KeyRight})
+	}
+
+	// Room rows: first click selects, clicking the selected row joins.
+	for i, room := range m.availableRooms {
+		if strings.Contains(line, room.RoomID) {
+			if i == m.roomListCursor {
+				return m.handleListRoomsKeys(tea.KeyMsg{Type: tea.KeyEnter})
+			}
+			m.roomListCursor = i
+			return m, nil
+		}
+	}
+
+	if strings.Contains(line, i18n.T("rooms.refresh")) {
+		return m.handleListRoomsKeys(runeKey("r"))
+	}
+	if strings.Contains(line, i18n.T("hint.back")) {
+		return m.handleListRoomsKeys(tea.KeyMsg{Type: tea.KeyEsc})
+	}
+	return m, nil
+}
+
+func (m Model) handleLobbyMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Button != tea.MouseButtonLeft {
+		return m, nil
+	}
+	line, ok := m.contentLineAt(RenderLobby(m.lobbyPlayers, m.playerID, m.roomCode, m.roomType, m.hostID, m.roomSettings, m.autoStart, m.roomError), msg.Y)
+	if !ok {
+		return m, nil
+	}
+
+	// Clicking your own row or the ready hint toggles ready.
+	if strings.HasSuffix(line, " <") || strings.Contains(line, i18n.T("lobby.ready_hint")) {
+		return m.handleLobbyKeys(tea.KeyMsg{Type: tea.KeySpace})
+	}
+	if strings.Contains(line, i18n.T("lobby.leave_hint")) {
+		return m.handleLobbyKeys(tea.KeyMsg{Type: tea.KeyEsc})
+	}
+	return m, nil
+}
+
+// contentLineAt returns the line of content drawn at screen row y when the
+// content is centered vertically in the window (as the render* helpers do).
+func (m Model) contentLineAt(content string, y int) (string, bool) {
+	lines := strings.Split(content, "\n")
+	top := (m.height - len(lines)) / 2
+	if top < 0 {
+		top = 0
+	}
+	idx := y - top
+	if idx < 0 || idx >= len(lines) {
+		return "", false
+	}
+	return lines[idx], true
+}
+
+// runeKey builds the KeyMsg for a single printable key.
+func runeKey(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
@@ -0,0 +1,34 @@
+package tui
+
+import (
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/notify"
+)
+
+// --- Notifications ---
+//
+// With notifications on in settings, a match counting down while the
+// terminal is in the background sends a desktop notification and rings
+// the bell, so players can wait for a match in another window. Whether
+// the terminal has focus comes from its focus reports; a terminal that
+// doesn't send them counts as always focused. Like the bell, anything
+// for the terminal goes to stderr, out of the renderer's way.
+
+func notifyCmd(title, body string) tea.Cmd {
+	return func() tea.Msg {
+		notify.Send(os.Stderr, title, body)
+		return nil
+	}
+}
+
+// matchStartCue notifies that a match is starting, if notifications are
+// on and the terminal isn't focused.
+func (m Model) matchStartCue() tea.Cmd {
+	if !m.blurred || m.prefs == nil || !m.prefs.Notify {
+		return nil
+	}
+	return tea.Batch(bellCmd(), notifyCmd("gotris", i18n.T("notify.match_starting", m.roomCode)))
+}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"math"
+
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/internal/overlay"
+)
+
+// --- Stream overlay ---
+//
+// When an overlay is attached, the model publishes a small JSON view of
+// itself after every update. overlay.Publish drops unchanged states, so
+// the 50ms menu tick doesn't cause any work on its own.
+
+// WithOverlay returns the model with o attached, so the live game state is
+// published to it.
+func (m Model) WithOverlay(o *overlay.Overlay) Model {
+	m.overlay = o
+	return m
+}
+
+var overlayScreens = map[Screen]string{
+	ScreenLobby:     "lobby",
+	ScreenCountdown: "countdown",
+	ScreenPlaying:   "playing",
+	ScreenGameOver:  "game_over",
+	ScreenStats:     "game_over",
+}
+
+var overlayModes = map[GameMode]string{
+	ModeSingle: "single",
+	ModeMulti:  "multi",
+}
+
+// overlayState builds the state published for stream overlays.
+func (m Model) overlayState() overlay.State {
+	screen, ok := overlayScreens[m.screen]
+	if !ok {
+		screen = "menu"
+	}
+	s := overlay.State{
+		Screen: screen,
+		Mode:   overlayModes[m.mode],
+		Player: m.playerName,
+		Room:   m.roomCode,
+	}
+	if m.mode == ModeMulti {
+		for _, opp := range m.opponents {
+			s.Opponents = append(s.Opponents, overlay.Opponent{
+				Name:      opp.PlayerName,
+				Score:     opp.Score,
+				Lines:     opp.Lines,
+				Alive:     opp.Alive,
+				Target:    opp.PlayerID == m.targetID,
+				SentToYou: opp.SentToYou,
+				YouSent:   opp.YouSent,
+			})
+		}
+	}
+
+	// The tutorial borrows gameState but isn't a game.
+	gs := m.gameState
+	if gs == nil || screen == "menu" {
+		return s
+	}
+	s.Score = gs.Score
+	s.Level = gs.Level
+	s.Lines = gs.Lines
+	s.Alive = !gs.IsGameOver
+	s.Winner = gs.IsWinner
+	s.Current = pieceName(gs.CurrentPiece)
+	s.Next = pieceName(gs.NextPiece)
+	s.Hold = pieceName(gs.HoldPiece)
+	s.Garbage = gs.GarbageQueue
+	s.Pieces = gs.Stats.Pieces
+	s.Sent = gs.Stats.Sent
+	s.Received = gs.Stats.Received
+	s.PPS = math.Round(gs.Stats.PPS()*100) / 100
+	s.APM = math.Round(gs.Stats.APM()*10) / 10
+	return s
+}
+
+func pieceName(p *game.Piece) string {
+	if p == nil {
+		return ""
+	}
+	return p.Type.String()
+}
@@ -0,0 +1,91 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/prefs"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Profile sync ---
+//
+// The name and settings (language, keymap, SDF and the Settings toggles)
+// are also kept on the server, in a profile under the player's profile
+// key, so they follow the player to any machine given the same key. The
+// client syncs on startup, on switching servers and after each change:
+// settings changed here since the last sync are sent, otherwise the
+// server's profile, if it has one, replaces them. Any error is ignored,
+// so offline the saved prefs are all there is.
+
+// ProfileSyncedMsg reports a profile sync with server: the profile sent
+// or, if Pulled, the one fetched to replace the settings.
+type ProfileSyncedMsg struct {
+	Server  string
+	Profile protocol.Profile
+	Pulled  bool
+	Err     error
+}
+
+// profileSyncCmd syncs the profile in p with the client's server.
+func profileSyncCmd(c *client.Client, p *prefs.Prefs) tea.Cmd {
+	if c == nil || p == nil {
+		return nil
+	}
+	if p.ProfileKey == "" {
+		p.EnsureProfileKey()
+		p.Save()
+	}
+	key, changed, local, server := p.ProfileKey, p.ProfileChanged, p.Profile(), c.Server()
+	return func() tea.Msg {
+		if !changed {
+			remote, err := c.Profile(key)
+			if err == nil {
+				return ProfileSyncedMsg{Server: server, Profile: remote, Pulled: true}
+			}
+			if client.ErrorCode(err) != protocol.ErrCodeNotFound {
+				return ProfileSyncedMsg{Server: server, Err: err}
+			}
+		}
+		_, err := c.SaveProfile(key, local)
+		return ProfileSyncedMsg{Server: server, Profile: local, Err: err}
+	}
+}
+
+// profileChanged notes a change to a setting in the profile, saving it
+// and sending it to the server.
+func (m *Model) profileChanged() tea.Cmd {
+	if m.prefs == nil {
+		return nil
+	}
+	m.prefs.ProfileChanged = true
+	m.prefs.Save()
+	return profileSyncCmd(m.client, m.prefs)
+}
+
+func (m Model) handleProfileSynced(msg ProfileSyncedMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil || m.prefs == nil || m.client == nil || msg.Server != m.client.Server() {
+		return m, nil
+	}
+	if !msg.Pulled {
+		// Only what was sent is synced; a change since then goes next.
+		if m.prefs.Profile() == msg.Profile {
+			m.prefs.ProfileChanged = false
+			m.prefs.Save()
+		}
+		return m, nil
+	}
+	if m.prefs.ProfileChanged {
+		// Changed while fetching: the change wins.
+		return m, profileSyncCmd(m.client, m.prefs)
+	}
+	m.prefs.ApplyProfile(msg.Profile)
+	if m.prefs.PlayerName != "" {
+		m.playerName = m.prefs.PlayerName
+	}
+	if !i18n.SetLang(m.prefs.Lang) {
+		i18n.SetLang(i18n.Detect())
+	}
+	m.prefs.Save()
+	return m, nil
+}
@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/qr"
+)
+
+// --- Join link ---
+//
+// C in the lobby swaps the player list for a QR code of the room's join
+// link, the server's web client with the room filled in, so a friend can
+// join by scanning it with a phone rather than typing the server and code.
+// Y there copies the link itself. Pasting the link on the join screen
+// takes the room code from it.
+
+// qrQuietZone is the light border around the code, in modules: narrower
+// than the standard's four so the code fits a smaller terminal, which
+// phone cameras cope with.
+const qrQuietZone = 2
+
+// qrStyle draws the code light on dark whatever the terminal's colors, as
+// scanners expect.
+var qrStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("231")).
+	Background(lipgloss.Color("16"))
+
+// joinLink is the link to the room on the web client. In a LAN game it
+// uses the address friends reach the server at.
+func (m Model) joinLink() string {
+	if m.client == nil || m.roomCode == "" {
+		return ""
+	}
+	base := m.lanAddress()
+	if base == "" {
+		base = m.client.Server()
+	}
+	return base + "/web/?room=" + url.QueryEscape(m.roomCode)
+}
+
+// RenderJoinQR renders the lobby's QR code screen for link.
+func RenderJoinQR(roomCode, link string) string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(i18n.T("lobby.title")) + "\n\n")
+	sb.WriteString(i18n.T("qr.scan", roomCode) + "\n\n")
+	if code, err := qr.Encode(link); err == nil {
+		sb.WriteString(renderQR(code) + "\n")
+	}
+	sb.WriteString(targetStyle.Render(link) + "\n\n")
+	sb.WriteString(infoStyle.Render(i18n.T("qr.copy_hint")) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("qr.back_hint")) + "\n")
+	return sb.String()
+}
+
+// renderQR draws code two modules to a character, the top one in the
+// foreground of a half block and the bottom one in the background.
+func renderQR(code *qr.Code) string {
+	var lines []string
+	for y := -qrQuietZone; y < code.Size+qrQuietZone; y += 2 {
+		var line strings.Builder
+		for x := -qrQuietZone; x < code.Size+qrQuietZone; x++ {
+			top, bottom := code.Black(x, y), code.Black(x, y+1)
+			if y+1 >= code.Size+qrQuietZone {
+				bottom = true // past the quiet zone, the terminal's dark
+			}
+			switch {
+			case !top && !bottom:
+				line.WriteString("█")
+			case !top:
+				line.WriteString("▀")
+			case !bottom:
+				line.WriteString("▄")
+			default:
+				line.WriteString(" ")
+			}
+		}
+		lines = append(lines, qrStyle.Render(line.String()))
+	}
+	return strings.Join(lines, "\n")
+}
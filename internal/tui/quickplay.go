@@ -0,0 +1,73 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Quick play ---
+//
+// From the room browser, P asks the server for a seat in an open room of
+// either type (it makes one if there's none): ranked rooms play with fixed
+// settings, casual ones with whatever their host picks.
+
+// quickPlayTypes are the queues on the quick play screen, in order.
+var quickPlayTypes = []string{protocol.RoomTypeRanked, protocol.RoomTypeCasual}
+
+func quickPlayCmd(c *client.Client, playerName, roomType string) tea.Cmd {
+	return func() tea.Msg {
+		roomID, err := c.QuickPlay(playerName, roomType)
+		if err != nil {
+			return RoomJoinedHTTPMsg{Err: err}
+		}
+		return RoomJoinedHTTPMsg{RoomID: roomID}
+	}
+}
+
+func (m Model) handleQuickPlayKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.screen = ScreenListRooms
+		return m, nil
+	case "up", "k":
+		m.quickPlayCursor = moveCursor(m.quickPlayCursor, -1, len(quickPlayTypes))
+		return m, nil
+	case "down", "j":
+		m.quickPlayCursor = moveCursor(m.quickPlayCursor, 1, len(quickPlayTypes))
+		return m, nil
+	case "1", "2":
+		m.quickPlayCursor = int(msg.String()[0] - '1')
+	case "enter":
+	default:
+		return m, nil
+	}
+	if m.client == nil {
+		return m, nil
+	}
+	m.mode = ModeMulti
+	m.screen = ScreenConnecting
+	m.roomError = ""
+	return m, quickPlayCmd(m.client, m.playerName, quickPlayTypes[m.quickPlayCursor])
+}
+
+// RenderQuickPlay renders the choice of quick play queue.
+func RenderQuickPlay(cursor int) string {
+	items := make([]string, len(quickPlayTypes))
+	for i, t := range quickPlayTypes {
+		items[i] = i18n.T("quick." + t)
+	}
+	return titleStyle.Render(i18n.T("quick.title")) + "\n\n" +
+		RenderMenuItems(items, cursor) + "\n" +
+		hintLine("ESC", i18n.T("hint.back"))
+}
+
+// roomTypeName is the localized name of a room type; servers from before
+// room types only have casual rooms.
+func roomTypeName(roomType string) string {
+	if roomType == protocol.RoomTypeRanked {
+		return i18n.T("rooms.type.ranked")
+	}
+	return i18n.T("rooms.type.casual")
+}
@@ -1,14 +1,24 @@
 package tui
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/hersh/gotris/internal/game"
-	"github.com/hersh/gotris/internal/protocol"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
 )
 
+// roomsPerPage is how many rooms the room browser shows per page.
+const roomsPerPage = 10
+
 var (
 	colors = []string{
 		"0",
@@ -53,6 +63,21 @@ var (
 	targetStyle = lipgloss.NewStyle().
 			Bold(true).
 			Foreground(lipgloss.Color("196"))
+
+	attackerStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("15")).
+			Background(lipgloss.Color("160"))
+
+	cursorStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("0")).
+			Background(lipgloss.Color("51"))
+
+	selfRowStyle = lipgloss.NewStyle().
+			Padding(0, 1).
+			Bold(true).
+			Foreground(lipgloss.Color("51"))
 )
 
 func RenderBoard(gs *game.GameState, width, height int) string {
@@ -62,22 +87,35 @@ func RenderBoard(gs *game.GameState, width, height int) string {
 	displayWidth := min(width, game.BoardWidth)
 
 	ghostY := gs.GetGhostY()
+	// During an entry or line clear delay the piece is already part of
+	// the board, and cleared lines flash white until they collapse.
+	falling := gs.Phase == game.PhaseFalling
 
 	for y := 0; y < displayHeight; y++ {
+		clearing := slices.Contains(gs.ClearingRows, y)
 		for x := 0; x < displayWidth; x++ {
 			cell := gs.Board.Cells[y][x]
 			char := "  "
 			color := "0"
 
 			if cell.Filled {
-				char = "██"
+				char = block(cell.Color)
 				color = colors[cell.Color]
 			}
+			if clearing {
+				color = "15"
+			}
+			if !falling {
+				sb.WriteString(lipgloss.NewStyle().
+					Foreground(lipgloss.Color(color)).
+					Render(char))
+				continue
+			}
 
 			for py, row := range gs.CurrentPiece.Shape {
 				for px, filled := range row {
 					if filled && gs.CurrentPiece.Y+py == y && gs.CurrentPiece.X+px == x {
-						char = "██"
+						char = block(gs.CurrentPiece.Color)
 						color = colors[gs.CurrentPiece.Color]
 					} else if filled && ghostY+py == y && gs.CurrentPiece.X+px == x && !cell.Filled {
 						char = "[]"
@@ -100,7 +138,7 @@ func RenderBoard(gs *game.GameState, width, height int) string {
 
 func RenderPiece(p *game.Piece) string {
 	if p == nil {
-		return "Empty"
+		return i18n.T("info.empty")
 	}
 
 	var sb strings.Builder
@@ -109,7 +147,7 @@ func RenderPiece(p *game.Piece) string {
 	for y, row := range p.Shape {
 		for _, filled := range row {
 			if filled {
-				sb.WriteString(pieceStyle.Render("██"))
+				sb.WriteString(pieceStyle.Render(block(p.Color)))
 			} else {
 				sb.WriteString("  ")
 			}
@@ -122,98 +160,441 @@ func RenderPiece(p *game.Piece) string {
 	return sb.String()
 }
 
-func RenderInfo(gs *game.GameState, targetName string) string {
+// RenderInfo renders the left-hand HUD. targetLocked hides the targeting
+// hints when the room assigns targets at random; attackNote, if set, is
+// flashed under the garbage meter.
+func RenderInfo(gs *game.GameState, targetName string, targetLocked bool, attackNote string) string {
 	var sb strings.Builder
 
 	sb.WriteString(titleStyle.Render("GOTRIS") + "\n\n")
-	sb.WriteString(infoStyle.Render(fmt.Sprintf("Player: %s", gs.PlayerName)) + "\n")
-	sb.WriteString(infoStyle.Render(fmt.Sprintf("Score: %d", gs.Score)) + "\n")
-	sb.WriteString(infoStyle.Render(fmt.Sprintf("Level: %d", gs.Level)) + "\n")
-	sb.WriteString(infoStyle.Render(fmt.Sprintf("Lines: %d", gs.Lines)) + "\n\n")
+	sb.WriteString(infoStyle.Render(i18n.T("info.player", gs.PlayerName)) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("info.score", gs.Score)) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("info.level", gs.Level)) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("info.lines", gs.Lines)) + "\n\n")
 
-	sb.WriteString(titleStyle.Render("NEXT") + "\n")
+	sb.WriteString(titleStyle.Render(i18n.T("info.next")) + "\n")
 	sb.WriteString(RenderPiece(gs.NextPiece) + "\n\n")
 
-	sb.WriteString(titleStyle.Render("HOLD") + "\n")
-	sb.WriteString(RenderPiece(gs.HoldPiece) + "\n")
+	if gs.Rules.Hold != game.HoldOff {
+		sb.WriteString(titleStyle.Render(i18n.T("info.hold")) + "\n")
+		sb.WriteString(RenderPiece(gs.HoldPiece) + "\n")
+	}
+
+	if gs.Item != "" {
+		sb.WriteString("\n" + targetStyle.Render(i18n.T("info.item", i18n.T("item."+gs.Item))))
+	}
+	if left := gs.SpeedUpLeft(time.Now()); left > 0 {
+		sb.WriteString("\n" + attackerStyle.Render(i18n.T("info.speed_up", int(left.Seconds()+1))))
+	}
 
 	if gs.GarbageQueue > 0 {
 		sb.WriteString("\n")
 		sb.WriteString(lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196")).
-			Render(fmt.Sprintf("INCOMING: %d", gs.GarbageQueue)))
+			Render(i18n.T("info.incoming", gs.GarbageQueue)))
+	}
+	if attackNote != "" {
+		sb.WriteString("\n" + attackerStyle.Render(attackNote))
 	}
 
 	if targetName != "" {
 		sb.WriteString("\n\n")
-		sb.WriteString(targetStyle.Render(fmt.Sprintf("TARGET: %s", targetName)) + "\n")
-		sb.WriteString(infoStyle.Render("[Tab] change target"))
+		sb.WriteString(targetStyle.Render(i18n.T("info.target", targetName)))
+		if targetLocked {
+			sb.WriteString("\n" + infoStyle.Render(i18n.T("info.target_locked")))
+		} else {
+			sb.WriteString("\n" + infoStyle.Render(i18n.T("info.target_hint")))
+			sb.WriteString("\n" + infoStyle.Render(i18n.T("info.random_hint")))
+		}
 	}
 
 	return sb.String()
 }
 
-func RenderLobby(players []protocol.LobbyPlayer, currentPlayerID string, roomCode string) string {
+// RenderLobby renders the room lobby: the player list, the room settings
+// chosen by the host, and (for the host) the keys that change them.
+func RenderLobby(players []protocol.LobbyPlayer, currentPlayerID, roomCode, roomType, hostID string, settings protocol.RoomSettings, autoStart protocol.AutoStartPayload, errorMsg string) string {
 	var sb strings.Builder
 
-	sb.WriteString(titleStyle.Render("=== LOBBY ===") + "\n\n")
+	sb.WriteString(titleStyle.Render(i18n.T("lobby.title")) + "\n\n")
 	if roomCode != "" {
 		sb.WriteString(lipgloss.NewStyle().
 			Bold(true).
 			Foreground(lipgloss.Color("226")).
-			Render(fmt.Sprintf("Room Code: %s", roomCode)) + "\n")
-		sb.WriteString(infoStyle.Render("Share this code with friends!") + "\n\n")
+			Render(i18n.T("lobby.code", roomCode)) + "\n")
+		sb.WriteString(infoStyle.Render(i18n.T("lobby.share")) + "\n")
+		sb.WriteString(infoStyle.Render(i18n.T("lobby.copy_hint")) + "\n")
+		sb.WriteString(infoStyle.Render(i18n.T("lobby.qr_hint")) + "\n")
+		sb.WriteString(infoStyle.Render(i18n.T("lobby.invite_hint")) + "\n\n")
+	}
+	ranked := roomType == protocol.RoomTypeRanked
+	if ranked {
+		sb.WriteString(targetStyle.Render(i18n.T("lobby.ranked")) + "\n\n")
 	}
-	sb.WriteString(infoStyle.Render("Players in lobby:") + "\n\n")
+	sb.WriteString(infoStyle.Render(i18n.T("lobby.players")) + "\n\n")
 
-	for _, p := range players {
+	isHost := hostID != "" && hostID == currentPlayerID
+	for i, p := range players {
 		status := notReadyStyle.Render("[ ]")
 		if p.Ready {
 			status = readyStyle.Render("[✓]")
 		}
 
+		host := ""
+		if p.PlayerID == hostID {
+			host = " " + targetStyle.Render(i18n.T("lobby.host"))
+		}
+
+		if p.Bot {
+			host = " " + infoStyle.Render(i18n.T("lobby.bot"))
+		}
+
+		muted := ""
+		if p.Muted {
+			muted = " " + notReadyStyle.Render(i18n.T("lobby.muted"))
+		}
+
 		marker := ""
 		if p.PlayerID == currentPlayerID {
 			marker = " <"
 		}
 
-		sb.WriteString(fmt.Sprintf("%s %s%s\n", status, p.Name, marker))
+		// The host mutes players by their number
+		num := ""
+		if isHost && i < 9 {
+			num = fmt.Sprintf("%d. ", i+1)
+		}
+
+		sb.WriteString(fmt.Sprintf("%s%s %s%s%s%s\n", num, status, p.Name, host, muted, marker))
+	}
+
+	if settings.MaxPlayers > 0 {
+		sb.WriteString("\n" + RenderRoomSettings(settings, isHost && !ranked))
+	}
+
+	if autoStart.Seconds > 0 {
+		sb.WriteString("\n")
+		if autoStart.Paused {
+			sb.WriteString(notReadyStyle.Render(i18n.T("lobby.auto_start_paused", autoStart.Seconds)) + "\n")
+		} else {
+			sb.WriteString(targetStyle.Render(i18n.T("lobby.auto_start", autoStart.Seconds)) + "\n")
+		}
+	}
+
+	if errorMsg != "" {
+		sb.WriteString("\n" + gameOverStyle.Render(errorMsg) + "\n")
 	}
 
 	sb.WriteString("\n")
-	sb.WriteString(infoStyle.Render("Press SPACE to toggle ready") + "\n")
-	sb.WriteString(infoStyle.Render("Press ESC to leave room") + "\n")
-	sb.WriteString(infoStyle.Render("Press Q to quit") + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("lobby.ready_hint")) + "\n")
+	if isHost && len(players) > 1 {
+		sb.WriteString(infoStyle.Render(i18n.T("lobby.mute_hint")) + "\n")
+	}
+	sb.WriteString(infoStyle.Render(i18n.T("lobby.leave_hint")) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("hint.quit")) + "\n")
 
 	return sb.String()
 }
 
+// RenderRoomSettings renders the room's match settings. When isHost is set,
+// each line carries the key that changes it.
+func RenderRoomSettings(s protocol.RoomSettings, isHost bool) string {
+	rows := []struct{ key, label, value string }{
+		{"M", i18n.T("room.max_players"), fmt.Sprintf("%d", s.MaxPlayers)},
+		{"T", i18n.T("room.targeting"), i18n.T("room.targeting." + s.Targeting)},
+		{"A", i18n.T("room.attack_table"), i18n.T("room.attack." + s.AttackTable)},
+		{"R", i18n.T("room.randomizer"), i18n.T("room.randomizer." + s.Randomizer)},
+		{"J", i18n.T("room.on_join"), i18n.T("room.on_join." + s.OnJoin)},
+		{"E", i18n.T("room.entry_delay"), formatDelay(s.EntryDelayMs)},
+		{"L", i18n.T("room.line_clear_delay"), formatDelay(s.LineClearDelayMs)},
+		{"S", i18n.T("room.spins"), i18n.T(spinRule(s.AllSpin))},
+		{"G", i18n.T("room.garbage"), i18n.T("room.garbage." + cmp.Or(s.GarbageStyle, game.GarbageClean))},
+		{"I", i18n.T("room.items"), onOff(s.Items)},
+		{"H", i18n.T("room.hold"), i18n.T("room.hold." + cmp.Or(s.HoldMode, game.HoldNormal))},
+		{"D", i18n.T("room.seed"), cmp.Or(s.Seed, i18n.T("room.seed.random"))},
+		{"B", i18n.T("room.bots"), fmt.Sprintf("%d", s.Bots)},
+		{"P", i18n.T("room.points"), pointsRule(s)},
+		{"V", i18n.T("room.speed_race"), speedRace(s.SpeedRaceSecs)},
+	}
+
+	var sb strings.Builder
+	sb.WriteString(infoStyle.Render(i18n.T("room.settings")) + "\n")
+	for _, r := range rows {
+		line := fmt.Sprintf("  %-17s %s", r.label+":", r.value)
+		if isHost {
+			line = fmt.Sprintf("%-36s %s", line, infoStyle.Render("["+r.key+"]"))
+		}
+		sb.WriteString(line + "\n")
+	}
+	if isHost {
+		sb.WriteString(infoStyle.Render(i18n.T("room.host_hint")) + "\n")
+	}
+	return sb.String()
+}
+
+// onOff shows a room on/off setting.
+func onOff(on bool) string {
+	if on {
+		return i18n.T("settings.on")
+	}
+	return i18n.T("settings.off")
+}
+
+// pointsRule shows a room's points play settings, or "off".
+func pointsRule(s protocol.RoomSettings) string {
+	if s.PointsTarget == 0 {
+		return i18n.T("settings.off")
+	}
+	placement := make([]string, len(s.PlacementPoints))
+	for i, n := range s.PlacementPoints {
+		placement[i] = strconv.Itoa(n)
+	}
+	return i18n.T("room.points.rule", s.PointsTarget, strings.Join(placement, "-"), s.KOPoints)
+}
+
+// spinRule is the i18n key for a room's spin bonus rule.
+func spinRule(allSpin bool) string {
+	if allSpin {
+		return "room.spins.all"
+	}
+	return "room.spins.t"
+}
+
+// speedRace shows a room's speed race setting, or "off".
+func speedRace(secs int) string {
+	if secs == 0 {
+		return i18n.T("settings.off")
+	}
+	return i18n.T("room.speed_race.rule", secs)
+}
+
+// formatDelay shows a room delay setting in milliseconds, or "off".
+func formatDelay(ms int) string {
+	if ms == 0 {
+		return i18n.T("settings.off")
+	}
+	return i18n.T("room.delay_ms", ms)
+}
+
+// RenderPracticeControls renders the single-player garbage practice status
+// and the keys that drive it.
+func RenderPracticeControls(interval time.Duration) string {
+	rate := i18n.T("practice.off")
+	if interval > 0 {
+		rate = i18n.T("practice.every", interval.Seconds())
+	}
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(i18n.T("practice.title")) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("practice.auto", rate)) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("practice.add_hint")) + "\n")
+	sb.WriteString(infoStyle.Render(i18n.T("practice.interval_hint")))
+	return sb.String()
+}
+
+// RenderConnStatus renders the small connection widget: a colored dot,
+// the connection state, and the last measured round-trip time (or the
+// attempt number while reconnecting).
+func RenderConnStatus(status client.ConnStatus, rtt time.Duration, attempt int) string {
+	color := "196"
+	label := i18n.T("conn." + status.String())
+	switch status {
+	case client.ConnConnected:
+		color = "46"
+		if rtt > 250*time.Millisecond {
+			color = "196"
+		} else if rtt > 100*time.Millisecond {
+			color = "226"
+		}
+		if rtt > 0 {
+			label = i18n.T("conn.rtt", rtt.Milliseconds())
+		}
+	case client.ConnReconnecting:
+		color = "226"
+		if attempt > 0 {
+			label = i18n.T("conn.attempt", attempt, client.ReconnectAttempts)
+		}
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render("●") +
+		infoStyle.Render(label)
+}
+
+// bigGlyphs is a 5-row block font used for the countdown and GO! signal.
+var bigGlyphs = map[rune][]string{
+	'0': {"█████", "█   █", "█   █", "█   █", "█████"},
+	'1': {"  █  ", " ██  ", "  █  ", "  █  ", " ███ "},
+	'2': {"█████", "    █", "█████", "█    ", "█████"},
+	'3': {"█████", "    █", " ████", "    █", "█████"},
+	'4': {"█   █", "█   █", "█████", "    █", "    █"},
+	'5': {"█████", "█    ", "█████", "    █", "█████"},
+	'6': {"█████", "█    ", "█████", "█   █", "█████"},
+	'7': {"█████", "    █", "   █ ", "  █  ", "  █  "},
+	'8': {"█████", "█   █", "█████", "█   █", "█████"},
+	'9': {"█████", "█   █", "█████", "    █", "█████"},
+	'G': {"█████", "█    ", "█  ██", "█   █", "█████"},
+	'O': {"█████", "█   █", "█   █", "█   █", "█████"},
+	'!': {"█", "█", "█", " ", "█"},
+}
+
+// countdownColors colors each countdown step, from 3 (red) down to 1 (green).
+var countdownColors = map[int]string{
+	3: "196",
+	2: "226",
+	1: "46",
+}
+
+// RenderBigText renders s in the large block font. Characters without
+// a glyph are skipped.
+func RenderBigText(s string) string {
+	rows := make([]string, 5)
+	for i, r := range s {
+		glyph, ok := bigGlyphs[r]
+		if !ok {
+			continue
+		}
+		for y := range rows {
+			if i > 0 {
+				rows[y] += " "
+			}
+			rows[y] += glyph[y]
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
 func RenderCountdown(count int) string {
+	color, ok := countdownColors[count]
+	if !ok {
+		color = "51"
+	}
 	return lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("51")).
+		Foreground(lipgloss.Color(color)).
 		Align(lipgloss.Center).
-		Render(fmt.Sprintf("\n\n\n     %d     \n\n\n", count))
+		Render(RenderBigText(fmt.Sprintf("%d", count)))
+}
+
+// RenderGoFlash renders the GO! signal inside an empty board frame of the
+// same size as the playfield, so the switch to the real board doesn't shift
+// the layout.
+func RenderGoFlash() string {
+	goText := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("46")).
+		Render(RenderBigText("GO!"))
+	return boardStyle.Render(lipgloss.Place(game.BoardWidth*2, game.BoardHeight,
+		lipgloss.Center, lipgloss.Center, goText))
 }
 
-func RenderGameOver(isWinner bool, score int, rank int) string {
+// RenderGameOver renders the multiplayer result screen. When the server
+// sent full standings they are shown as a table with the local player's row
+// highlighted; otherwise it falls back to a short win/lose summary.
+func RenderGameOver(isWinner bool, score int, rank int, standings []protocol.PlayerStanding, playerID string) string {
+	if len(standings) > 0 {
+		header := gameOverStyle.Render(i18n.T("result.game_over"))
+		if isWinner {
+			header = winnerStyle.Render(i18n.T("result.winner"))
+		}
+		return header + "\n\n" + RenderStandings(standings, playerID)
+	}
 	if isWinner {
 		return lipgloss.NewStyle().
 			Bold(true).
 			Foreground(lipgloss.Color("226")).
 			Align(lipgloss.Center).
-			Render(fmt.Sprintf("\n\n\n     WINNER!     \n     Score: %d     \n\n\n", score))
+			Render(fmt.Sprintf("\n\n\n     %s     \n     %s     \n\n\n",
+				i18n.T("result.winner"), i18n.T("result.score", score)))
 	}
 	return lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("196")).
 		Align(lipgloss.Center).
-		Render(fmt.Sprintf("\n\n\n     GAME OVER     \n     Score: %d     \n     Rank: #%d     \n\n\n", score, rank))
+		Render(fmt.Sprintf("\n\n\n     %s     \n     %s     \n     %s     \n\n\n",
+			i18n.T("result.game_over"), i18n.T("result.score", score), i18n.T("result.rank", rank)))
+}
+
+// RenderStandings renders the final match standings as a table.
+func RenderStandings(standings []protocol.PlayerStanding, playerID string) string {
+	var sb strings.Builder
+
+	// Points play adds a column with each player's total and what this
+	// match earned them.
+	points := slices.ContainsFunc(standings, func(st protocol.PlayerStanding) bool { return st.TotalPoints > 0 })
+	pointsHeader, pointsSep := "", ""
+	if points {
+		pointsHeader = fmt.Sprintf("  %9s", i18n.T("standings.points"))
+		pointsSep = "  ---------"
+	}
+
+	sb.WriteString(titleStyle.Render(i18n.T("standings.title")) + "\n\n")
+	sb.WriteString(infoStyle.Render(fmt.Sprintf("  %-4s  %-16s  %8s  %5s  %3s  %5s",
+		i18n.T("standings.rank"), i18n.T("standings.player"), i18n.T("standings.score"),
+		i18n.T("standings.lines"), i18n.T("standings.kos"), i18n.T("standings.time"))+pointsHeader) + "\n")
+	sb.WriteString(infoStyle.Render("  ----  ----------------  --------  -----  ---  -----"+pointsSep) + "\n")
+
+	for _, st := range standings {
+		prefix := "  "
+		rowStyle := infoStyle
+		if st.PlayerID == playerID {
+			prefix = "> "
+			rowStyle = selfRowStyle
+		} else if st.Rank == 1 {
+			rowStyle = winnerStyle.Padding(0, 1)
+		}
+		row := fmt.Sprintf("%s#%-3d  %-16s  %8d  %5d  %3d  %5s",
+			prefix, st.Rank, truncateRunes(st.PlayerName, 16), st.Score, st.Lines, st.KOs, formatDuration(st.SurvivalMs))
+		if points {
+			row += fmt.Sprintf("  %9s", fmt.Sprintf("%d (+%d)", st.TotalPoints, st.Points))
+		}
+		sb.WriteString(rowStyle.Render(row) + "\n")
+	}
+
+	return sb.String()
+}
+
+// RenderAwards renders the match's awards, one a line, highlighting the
+// local player's.
+func RenderAwards(awards []protocol.MatchAward, playerID string) string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(i18n.T("awards.title")) + "\n")
+	for _, a := range awards {
+		var line string
+		switch a.Kind {
+		case protocol.AwardMostSent:
+			line = i18n.T("award.most_sent", a.PlayerName, a.Value)
+		case protocol.AwardMostKOs:
+			line = i18n.T("award.most_kos", a.PlayerName, a.Value)
+		case protocol.AwardFastestTetris:
+			line = i18n.T("award.fastest_tetris", a.PlayerName, formatDuration(a.Value))
+		case protocol.AwardLongestSurvival:
+			line = i18n.T("award.longest_survival", a.PlayerName, formatDuration(a.Value))
+		default:
+			continue // from a newer server
+		}
+		style := winnerStyle
+		if a.PlayerID == playerID {
+			style = selfRowStyle
+		}
+		sb.WriteString(style.Render("★ "+line) + "\n")
+	}
+	return sb.String()
+}
+
+// formatDuration formats milliseconds as m:ss.
+func formatDuration(ms int64) string {
+	if ms < 0 {
+		ms = 0
+	}
+	secs := ms / 1000
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
 }
 
 // RenderNetOpponentPreview renders a mini-board from a network OpponentState.
 // Shows the full board width (10 cols) and the bottom portion where pieces stack.
-func RenderNetOpponentPreview(opp protocol.OpponentState, isTarget bool) string {
+// slot is the 1-based number key that targets this opponent (0 = unnumbered).
+// isAttacker highlights the name of whoever just sent garbage our way.
+// emote, if set, is shown as a bubble over the top row of the board.
+func RenderNetOpponentPreview(opp protocol.OpponentState, isTarget, isAttacker bool, slot int, emote string) string {
 	previewWidth := game.BoardWidth // full 10 columns
 	previewHeight := 10             // bottom 10 rows of the 20-row board
 	startY := game.BoardHeight - previewHeight
@@ -224,24 +605,42 @@ func RenderNetOpponentPreview(opp protocol.OpponentState, isTarget bool) string
 		MaxWidth(previewWidth).
 		Foreground(lipgloss.Color("15"))
 
+	label := opp.PlayerName
+	if slot > 0 {
+		label = fmt.Sprintf("%d %s", slot, opp.PlayerName)
+	}
 	if isTarget {
-		sb.WriteString(targetStyle.Render("\u25b6 "+opp.PlayerName) + "\n")
+		label = "\u25b6 " + label
+	}
+	if isAttacker {
+		sb.WriteString(attackerStyle.Render(label) + "\n")
+	} else if isTarget {
+		sb.WriteString(targetStyle.Render(label) + "\n")
 	} else {
-		sb.WriteString(nameStyle.Render(opp.PlayerName) + "\n")
+		sb.WriteString(nameStyle.Render(label) + "\n")
 	}
 
 	if !opp.Alive {
 		for y := 0; y < previewHeight; y++ {
+			if y == 0 && emote != "" {
+				sb.WriteString(renderEmote(emote, previewWidth) + "\n")
+				continue
+			}
 			for x := 0; x < previewWidth; x++ {
 				sb.WriteString("·")
 			}
 			sb.WriteString("\n")
 		}
-		sb.WriteString(gameOverStyle.Render("OUT"))
+		sb.WriteString(gameOverStyle.Render(i18n.T("opponent.out")))
+		sb.WriteString("\n" + infoStyle.Render(i18n.T("opponent.attacks", opp.SentToYou, opp.YouSent)))
 		return sb.String()
 	}
 
 	for y := startY; y < game.BoardHeight; y++ {
+		if y == startY && emote != "" {
+			sb.WriteString(renderEmote(emote, previewWidth) + "\n")
+			continue
+		}
 		for x := 0; x < previewWidth; x++ {
 			idx := y*game.BoardWidth + x
 			colorIdx := 0
@@ -255,7 +654,7 @@ func RenderNetOpponentPreview(opp protocol.OpponentState, isTarget bool) string
 				}
 				sb.WriteString(lipgloss.NewStyle().
 					Foreground(lipgloss.Color(c)).
-					Render("█"))
+					Render(halfBlock(colorIdx)))
 			} else {
 				sb.WriteString("·")
 			}
@@ -263,50 +662,148 @@ func RenderNetOpponentPreview(opp protocol.OpponentState, isTarget bool) string
 		sb.WriteString("\n")
 	}
 
-	sb.WriteString(infoStyle.Render(fmt.Sprintf("S:%d L:%d", opp.Score, opp.Lines)))
+	sb.WriteString(infoStyle.Render(i18n.T("opponent.stats", opp.Score, opp.Lines)))
+	sb.WriteString("\n" + infoStyle.Render(i18n.T("opponent.attacks", opp.SentToYou, opp.YouSent)))
 
 	return sb.String()
 }
 
-// RenderNetOpponents renders a grid of opponent previews from network state.
-func RenderNetOpponents(opponents []protocol.OpponentState, maxDisplay int, targetID string) string {
+// RenderNetOpponents renders one page of opponent previews from network
+// state in a grid cols wide, marking the current target and highlighting
+// attackerID's board, with any emotes (by player ID) over their senders'
+// boards. A page indicator is added when there is more than one page.
+func RenderNetOpponents(opponents []protocol.OpponentState, cols, page, pages int, targetID, attackerID string, emotes map[string]string) string {
 	if len(opponents) == 0 {
 		return ""
 	}
 
-	display := opponents
-	if len(display) > maxDisplay {
-		display = display[:maxDisplay]
+	var rows, row []string
+	for i, opp := range opponents {
+		isTarget := (targetID != "" && opp.PlayerID == targetID)
+		isAttacker := attackerID != "" && opp.PlayerID == attackerID
+		preview := RenderNetOpponentPreview(opp, isTarget, isAttacker, i+1, emotes[opp.PlayerID])
+		row = append(row, lipgloss.NewStyle().
+			Padding(0, 1).
+			Render(preview))
+
+		if len(row) >= cols {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
 	}
 
-	var sb strings.Builder
-	row := ""
-	col := 0
-	cols := 4
+	if pages > 1 {
+		rows = append(rows, infoStyle.Render(i18n.T("opponent.page", page+1, pages)))
+	}
 
-	for _, opp := range display {
-		isTarget := (targetID != "" && opp.PlayerID == targetID)
-		preview := RenderNetOpponentPreview(opp, isTarget)
-		row += lipgloss.NewStyle().
-			Padding(0, 1).
-			Render(preview)
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
 
-		col++
-		if col >= cols {
-			sb.WriteString(row + "\n")
-			row = ""
-			col = 0
+// RenderEventFeed renders the kill feed, newest event last.
+func RenderEventFeed(events []protocol.MatchEventPayload) string {
+	lines := make([]string, 0, len(events))
+	for i, ev := range events {
+		var text string
+		switch ev.Kind {
+		case protocol.EventTetris:
+			text = i18n.T("feed.tetris", ev.PlayerName) + describeAttack(ev)
+		case protocol.EventAttack:
+			text = i18n.T("feed.attack", ev.PlayerName, i18n.T("clear."+ev.ClearType)) + describeAttack(ev)
+		case protocol.EventKO:
+			text = i18n.T("feed.ko", ev.PlayerName, ev.ByName)
+		case protocol.EventOut:
+			text = i18n.T("feed.out", ev.PlayerName)
+		case protocol.EventSuspect:
+			text = i18n.T("feed.suspect", ev.PlayerName)
+		case protocol.EventItem:
+			if ev.ByName == "" {
+				text = i18n.T("feed.item_self", ev.PlayerName, i18n.T("item."+ev.Item))
+			} else {
+				text = i18n.T("feed.item", ev.PlayerName, i18n.T("item."+ev.Item), ev.ByName)
+			}
+		default:
+			continue
+		}
+		// Older events fade so the newest stands out.
+		style := infoStyle
+		if i < len(events)-1 {
+			style = style.Foreground(lipgloss.Color("245"))
 		}
+		lines = append(lines, style.Render(text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeAttack lists what made an attack event special beyond its clear
+// type: back-to-back and combo.
+func describeAttack(ev protocol.MatchEventPayload) string {
+	var extras []string
+	if ev.B2B {
+		extras = append(extras, i18n.T("feed.b2b"))
 	}
+	if ev.Combo > 0 {
+		extras = append(extras, i18n.T("feed.combo", ev.Combo))
+	}
+	if len(extras) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(extras, ", ") + ")"
+}
 
-	if row != "" {
-		sb.WriteString(row)
+// RenderMenuItems renders numbered menu entries with the one under the
+// cursor highlighted.
+func RenderMenuItems(items []string, cursor int) string {
+	width := 0
+	for _, item := range items {
+		width = max(width, lipgloss.Width(item))
 	}
 
+	// Pad every entry to the same width so the list stays left-aligned
+	// when the surrounding block is centered.
+	var sb strings.Builder
+	for i, item := range items {
+		// The tenth entry is picked with 0
+		line := fmt.Sprintf("[%d] %s", (i+1)%10, item) + strings.Repeat(" ", width-lipgloss.Width(item))
+		if i == cursor {
+			sb.WriteString(" > " + cursorStyle.Render(line) + "\n")
+		} else {
+			sb.WriteString("   " + line + "\n")
+		}
+	}
 	return sb.String()
 }
 
-func RenderMainMenu(playerName string) string {
+// RenderMainMenu renders the main menu. server and status (server totals
+// and invites), if set, are shown under the player name; lastRoom, if
+// set, adds a "rejoin last room" entry; cursor is the highlighted entry.
+func RenderMainMenu(playerName, server, lastRoom, status string, cursor int) string {
+	items := []string{
+		i18n.T("menu.single"),
+		i18n.T("menu.create"),
+		i18n.T("menu.join"),
+		i18n.T("menu.browse"),
+		i18n.T("menu.name"),
+		i18n.T("menu.settings"),
+		i18n.T("menu.server"),
+		i18n.T("menu.tutorial"),
+		i18n.T("menu.history"),
+	}
+	if lastRoom != "" {
+		items = append(items, i18n.T("menu.rejoin", lastRoom))
+	}
+
+	player := i18n.T("menu.player", playerName)
+	if server != "" {
+		player += "\n" + i18n.T("menu.server_addr", server)
+	}
+	if status != "" {
+		player += "\n" + status
+	}
+
+	subtitle := lipgloss.PlaceHorizontal(30, lipgloss.Center, i18n.T("menu.subtitle"))
 	return lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("51")).
@@ -314,19 +811,53 @@ func RenderMainMenu(playerName string) string {
 		Render(fmt.Sprintf(`
 ╔══════════════════════════════╗
 ║          G O T R I S         ║
-║    Multiplayer Tetris TUI    ║
+║%s║
 ╚══════════════════════════════╝
 
-   Player: %s
+   %s
 
-   [1] Single Player (Practice)
-   [2] Create Room
-   [3] Join Room (by code)
-   [4] Browse Rooms
-   [5] Edit Name
+%s
+   %s
+`, subtitle, player, RenderMenuItems(items, cursor), i18n.T("hint.quit")))
+}
 
-   Press Q to quit
-`, playerName))
+// hintLine renders one "KEY  description" line of a key-hint footer.
+func hintLine(key, desc string) string {
+	return infoStyle.Render(fmt.Sprintf("  %-6s %s", key, desc)) + "\n"
+}
+
+// RenderSettings renders the settings screen: a list of on/off toggles,
+// then the gameplay keys' preset, keys, with its controls below.
+func RenderSettings(labels []string, values []bool, keys, controls string, cursor int) string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render(i18n.T("settings.title")) + "\n\n")
+	for i, label := range labels {
+		state := notReadyStyle.Render(i18n.T("settings.off"))
+		if values[i] {
+			state = readyStyle.Render(i18n.T("settings.on"))
+		}
+		row := fmt.Sprintf("[%d] %-26s", i+1, label)
+		if i == cursor {
+			sb.WriteString(" > " + cursorStyle.Render(row) + " " + state + "\n")
+		} else {
+			sb.WriteString("   " + row + " " + state + "\n")
+		}
+	}
+	row := fmt.Sprintf("[%d] %-26s", len(labels)+1, i18n.T("settings.keymap"))
+	if cursor == len(labels) {
+		sb.WriteString(" > " + cursorStyle.Render(row) + " " + targetStyle.Render(keys) + "\n")
+	} else {
+		sb.WriteString("   " + row + " " + targetStyle.Render(keys) + "\n")
+	}
+	sb.WriteString(controls)
+
+	sb.WriteString("\n")
+	sb.WriteString(hintLine("↑/↓", i18n.T("hint.select")))
+	sb.WriteString(hintLine("ENTER", i18n.T("hint.toggle")))
+	sb.WriteString(hintLine("ESC", i18n.T("hint.back")))
+
+	return sb.String()
 }
 
 func RenderEditName(currentInput string) string {
@@ -335,13 +866,54 @@ func RenderEditName(currentInput string) string {
 		Foreground(lipgloss.Color("51")).
 		Align(lipgloss.Center).
 		Render(fmt.Sprintf(`
-=== Edit Name ===
+%s
 
-Type your name: %s_
+%s
 
-Press ENTER to confirm
-Press ESC to cancel
-`, currentInput))
+%s
+%s
+`, i18n.T("name.title"), i18n.T("name.prompt", currentInput), i18n.T("hint.confirm"), i18n.T("hint.cancel")))
+}
+
+// RenderServer renders the server screen: the address being edited, the
+// recently used servers (cursor marks the one picked), and the result of
+// the last /health check.
+func RenderServer(input string, recent []string, cursor int, checking bool, errorMsg string) string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render(i18n.T("server.title")) + "\n\n")
+	sb.WriteString(i18n.T("server.prompt", input) + "\n")
+
+	if len(recent) > 0 {
+		sb.WriteString("\n" + infoStyle.Render(i18n.T("server.recent")) + "\n")
+		for i, addr := range recent {
+			if i == cursor {
+				sb.WriteString(" > " + cursorStyle.Render(addr) + "\n")
+			} else {
+				sb.WriteString("   " + addr + "\n")
+			}
+		}
+	}
+	// Hosting a LAN game comes after the recent servers.
+	if cursor == len(recent) {
+		sb.WriteString("\n > " + cursorStyle.Render(i18n.T("server.host_lan")) + "\n")
+	} else {
+		sb.WriteString("\n   " + i18n.T("server.host_lan") + "\n")
+	}
+
+	sb.WriteString("\n")
+	switch {
+	case checking:
+		sb.WriteString(targetStyle.Render(i18n.T("server.checking")) + "\n\n")
+	case errorMsg != "":
+		sb.WriteString(notReadyStyle.Render(errorMsg) + "\n\n")
+	}
+
+	sb.WriteString(hintLine("↑/↓", i18n.T("server.pick")))
+	sb.WriteString(hintLine("ENTER", i18n.T("server.connect")))
+	sb.WriteString(hintLine("ESC", i18n.T("hint.back")))
+
+	return sb.String()
 }
 
 func RenderJoinRoom(currentInput string, errorMsg string) string {
@@ -356,88 +928,143 @@ func RenderJoinRoom(currentInput string, errorMsg string) string {
 		Foreground(lipgloss.Color("51")).
 		Align(lipgloss.Center).
 		Render(fmt.Sprintf(`
-=== Join Room ===
+%s
 
-Enter room code: %s_
+%s
 
-Press ENTER to join
-Press ESC to cancel
-%s`, currentInput, errLine))
+%s
+%s
+%s
+%s`, i18n.T("join.title"), i18n.T("join.prompt", currentInput), i18n.T("join.confirm"), i18n.T("join.paste_hint"), i18n.T("hint.cancel"), errLine))
 }
 
-func RenderListRooms(rooms []protocol.RoomInfo, errorMsg string, cursor, page int) string {
-	const roomsPerPage = 10
+// RenderCreateRoom renders the prompt for a new room's optional title.
+func RenderCreateRoom(currentInput string, errorMsg string) string {
+	errLine := ""
+	if errorMsg != "" {
+		errLine = "\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render(errorMsg)
+	}
+	return lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("51")).
+		Align(lipgloss.Center).
+		Render(fmt.Sprintf(`
+%s
+
+%s
+
+%s
+%s
+%s`, i18n.T("create.title"), i18n.T("create.prompt", currentInput), i18n.T("create.confirm"), i18n.T("hint.cancel"), errLine))
+}
+
+// RoomBrowser is what the room browser shows: one page of rooms fetched
+// from the server, and the search and filters they were fetched with.
+type RoomBrowser struct {
+	Rooms        []protocol.RoomInfo // the current page
+	Total        int                 // rooms matching in all
+	Page, Cursor int
+	Query        string
+	Searching    bool // the search is being typed
+	OpenOnly     bool
+	Sort         string
+	Error        string
+}
+
+// Pages is how many pages the matching rooms fill.
+func (b RoomBrowser) Pages() int {
+	return max(1, (b.Total+roomsPerPage-1)/roomsPerPage)
+}
+
+// RenderListRooms renders the room browser.
+func RenderListRooms(b RoomBrowser) string {
 	var sb strings.Builder
 
-	sb.WriteString(titleStyle.Render("=== Browse Rooms ===") + "\n\n")
+	sb.WriteString(titleStyle.Render(i18n.T("rooms.title")) + "\n\n")
+	if b.Searching {
+		sb.WriteString(cursorStyle.Render(i18n.T("rooms.search", b.Query+"_")) + "\n")
+	} else if b.Query != "" {
+		sb.WriteString(infoStyle.Render(i18n.T("rooms.search", b.Query)) + "\n")
+	}
+	sb.WriteString(infoStyle.Render(i18n.T("rooms.filters", onOff(b.OpenOnly), i18n.T("rooms.sort."+cmp.Or(b.Sort, protocol.RoomSortCode)))) + "\n\n")
 
-	if errorMsg != "" {
+	if b.Error != "" {
 		sb.WriteString(lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196")).
-			Render(errorMsg) + "\n\n")
+			Render(b.Error) + "\n\n")
 	}
 
-	totalRooms := len(rooms)
-	totalPages := (totalRooms + roomsPerPage - 1) / roomsPerPage
-	if totalPages < 1 {
-		totalPages = 1
-	}
+	totalPages := b.Pages()
+	filtered := b.Query != "" || b.OpenOnly
 
-	if totalRooms == 0 {
-		sb.WriteString(infoStyle.Render("No rooms available. Create one!") + "\n")
+	if len(b.Rooms) == 0 && filtered {
+		sb.WriteString(infoStyle.Render(i18n.T("rooms.no_match")) + "\n")
+	} else if len(b.Rooms) == 0 {
+		sb.WriteString(infoStyle.Render(i18n.T("rooms.empty")) + "\n")
 	} else {
-		pageStart := page * roomsPerPage
-		pageEnd := pageStart + roomsPerPage
-		if pageEnd > totalRooms {
-			pageEnd = totalRooms
-		}
-
-		sb.WriteString(infoStyle.Render(fmt.Sprintf("     %-8s   %-7s   %s", "Room", "Players", "Status")) + "\n")
-		sb.WriteString(infoStyle.Render("     --------   -------   ---------") + "\n")
+		sb.WriteString(infoStyle.Render(fmt.Sprintf("     %-8s   %-20s   %-11s   %-7s   %s",
+			i18n.T("rooms.col_room"), i18n.T("rooms.col_title"), i18n.T("rooms.col_type"), i18n.T("rooms.col_players"), i18n.T("rooms.col_status"))) + "\n")
+		sb.WriteString(infoStyle.Render("     --------   --------------------   -----------   -------   ---------") + "\n")
 
-		for i := pageStart; i < pageEnd; i++ {
-			room := rooms[i]
+		for i, room := range b.Rooms {
 			phaseDisplay := room.Phase
 			switch room.Phase {
-			case "lobby":
-				phaseDisplay = readyStyle.Render("Lobby")
-			case "playing":
-				phaseDisplay = notReadyStyle.Render("Playing")
-			case "countdown":
-				phaseDisplay = lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Render("Starting")
-			case "game_over":
-				phaseDisplay = infoStyle.Render("Finished")
+			case protocol.RoomPhaseLobby:
+				phaseDisplay = readyStyle.Render(i18n.T("rooms.lobby"))
+			case protocol.RoomPhasePlaying:
+				phaseDisplay = notReadyStyle.Render(i18n.T("rooms.playing"))
+			case protocol.RoomPhaseCountdown:
+				phaseDisplay = lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Render(i18n.T("rooms.starting"))
+			case protocol.RoomPhaseGameOver:
+				phaseDisplay = infoStyle.Render(i18n.T("rooms.finished"))
 			}
 
 			prefix := "  "
 			rowStyle := infoStyle
-			if i-pageStart == cursor {
+			if i == b.Cursor {
 				prefix = "> "
 				rowStyle = lipgloss.NewStyle().
 					Foreground(lipgloss.Color("51")).
 					Bold(true)
 			}
-			sb.WriteString(rowStyle.Render(fmt.Sprintf("%s   %-8s   %d/%-5d   ",
-				prefix, room.RoomID, room.PlayerCount, room.MaxPlayers)))
+			title := room.Title
+			if utf8.RuneCountInString(title) > 20 {
+				title = string([]rune(title)[:19]) + "…"
+			}
+			sb.WriteString(rowStyle.Render(fmt.Sprintf("%s   %-8s   %-20s   %-11s   %d/%-5d   ",
+				prefix, room.RoomID, title, roomTypeName(room.Type), room.PlayerCount, room.MaxPlayers)))
 			sb.WriteString(phaseDisplay + "\n")
+			if len(room.Players) > 0 {
+				names := strings.Join(room.Players, ", ")
+				if utf8.RuneCountInString(names) > 60 {
+					names = string([]rune(names)[:59]) + "…"
+				}
+				sb.WriteString(infoStyle.Render("       "+i18n.T("rooms.live_players", names)) + "\n")
+			}
 		}
 
 		if totalPages > 1 {
 			sb.WriteString("\n")
-			sb.WriteString(infoStyle.Render(fmt.Sprintf("  Page %d / %d", page+1, totalPages)) + "\n")
+			sb.WriteString(infoStyle.Render("  ◀ "+i18n.T("rooms.page", b.Page+1, totalPages)+" ▶") + "\n")
 		}
 	}
 
 	sb.WriteString("\n")
-	if totalRooms > 0 {
-		sb.WriteString(infoStyle.Render("  ↑/↓  Select room") + "\n")
+	if len(b.Rooms) > 0 {
+		sb.WriteString(hintLine("↑/↓", i18n.T("rooms.select")))
 		if totalPages > 1 {
-			sb.WriteString(infoStyle.Render("  ←/→  Change page") + "\n")
+			sb.WriteString(hintLine("←/→", i18n.T("rooms.change_page")))
 		}
-		sb.WriteString(infoStyle.Render("  ENTER  Join selected room") + "\n")
+		sb.WriteString(hintLine("ENTER", i18n.T("rooms.join")))
 	}
-	sb.WriteString(infoStyle.Render("  R      Refresh") + "\n")
-	sb.WriteString(infoStyle.Render("  ESC    Go back") + "\n")
+	sb.WriteString(hintLine("P", i18n.T("rooms.quick_hint")))
+	sb.WriteString(hintLine("/", i18n.T("rooms.search_hint")))
+	sb.WriteString(hintLine("O", i18n.T("rooms.open_hint")))
+	sb.WriteString(hintLine("S", i18n.T("rooms.sort_hint")))
+	sb.WriteString(hintLine("R", i18n.T("rooms.refresh")))
+	sb.WriteString(hintLine("ESC", i18n.T("hint.back")))
 
 	return sb.String()
 }
@@ -447,19 +1074,93 @@ func RenderSingleGameOver(score int) string {
 		Bold(true).
 		Foreground(lipgloss.Color("196")).
 		Align(lipgloss.Center).
-		Render(fmt.Sprintf("\n\n\n     GAME OVER     \n     Score: %d     \n\n\n", score))
-}
-
-func RenderControls() string {
-	return infoStyle.Render(`
-Controls:
-  ← →    Move left/right
-  ↓      Soft drop
-  Space  Hard drop
-  ↑/X    Rotate
-  Z      Hold piece
-  Q      Quit
-`)
+		Render(fmt.Sprintf("\n\n\n     %s     \n     %s     \n\n\n",
+			i18n.T("result.game_over"), i18n.T("result.score", score)))
+}
+
+// statsGraphWidth caps the sparklines on the statistics screen; longer
+// games are averaged down to fit.
+const statsGraphWidth = 40
+
+// RenderStats renders the post-game breakdown for one player's game.
+func RenderStats(st *game.Stats) string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render(i18n.T("stats.title")) + "\n\n")
+
+	d := st.Duration().Round(time.Second)
+	sb.WriteString(i18n.T("stats.time", int(d.Minutes()), int(d.Seconds())%60) + "\n")
+	sb.WriteString(i18n.T("stats.pieces", st.Pieces, st.PPS()) + "\n")
+	sb.WriteString(i18n.T("stats.attack", st.Sent, st.Received, st.APM()) + "\n\n")
+
+	sb.WriteString(i18n.T("stats.clears", st.Clears[1], st.Clears[2], st.Clears[3], st.Clears[4]) + "\n")
+	sb.WriteString(i18n.T("stats.tspins", st.TSpins) + "   " + i18n.T("stats.combo", st.MaxCombo) + "\n\n")
+
+	pps, apm := st.Rates()
+	if len(pps) == 0 {
+		sb.WriteString(infoStyle.Render(i18n.T("stats.no_samples")) + "\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("%-4s %s\n", "PPS", readyStyle.Render(sparkline(pps, statsGraphWidth))))
+		sb.WriteString(fmt.Sprintf("%-4s %s\n", "APM", notReadyStyle.Render(sparkline(apm, statsGraphWidth))))
+		sb.WriteString(infoStyle.Render(i18n.T("stats.interval", int(game.StatsInterval.Seconds()))) + "\n")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(hintLine("ESC", i18n.T("hint.back")))
+
+	return sb.String()
+}
+
+// sparkline draws values as a row of block characters scaled to the
+// largest value, averaging neighbours so it is at most width wide.
+func sparkline(values []float64, width int) string {
+	if len(values) > width {
+		buckets := make([]float64, width)
+		for i := range buckets {
+			from, to := i*len(values)/width, (i+1)*len(values)/width
+			for _, v := range values[from:to] {
+				buckets[i] += v
+			}
+			buckets[i] /= float64(to - from)
+		}
+		values = buckets
+	}
+
+	top := 0.0
+	for _, v := range values {
+		top = max(top, v)
+	}
+
+	bars := []rune("▁▂▃▄▅▆▇█")
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if top > 0 {
+			i = int(v / top * float64(len(bars)-1))
+		}
+		sb.WriteRune(bars[i])
+	}
+	return sb.String()
+}
+
+// renderControls lists the gameplay keys in km.
+func renderControls(km keymap) string {
+	rows := []struct {
+		keys, action string
+	}{
+		{km.label(inputLeft) + " " + km.label(inputRight), i18n.T("controls.move")},
+		{km.label(inputDown), i18n.T("controls.soft_drop")},
+		{km.label(inputDrop), i18n.T("controls.hard_drop")},
+		{km.label(inputRotate), i18n.T("controls.rotate")},
+		{km.label(inputRotateBack), i18n.T("controls.rotate_back")},
+		{km.label(inputHold), i18n.T("controls.hold")},
+	}
+	var sb strings.Builder
+	sb.WriteString("\n" + i18n.T("controls.title") + "\n")
+	for _, r := range rows {
+		sb.WriteString(fmt.Sprintf("  %-6s %s\n", r.keys, r.action))
+	}
+	return infoStyle.Render(sb.String())
 }
 
 func min(a, b int) int {
@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"os"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/internal/prefs"
+)
+
+// --- Replays ---
+//
+// Every game played is recorded by its engine and saved when it ends, in
+// the replay directory under the config directory, for cmd/replay to
+// render. Only the newest are kept; see prefs.NewReplayPath.
+
+// newEngine starts the engine on gs, recording from now.
+func (m *Model) newEngine(gs *game.GameState) {
+	m.gameState = gs
+	m.engine = game.NewLoop(gs)
+	m.engine.Record(time.Now())
+	m.replayPath = ""
+}
+
+// saveReplay saves the game just finished, once, and notes where for the
+// game over screen. A replay that can't be saved is let go: it's no
+// reason to get in the player's way.
+func (m *Model) saveReplay() {
+	if m.engine == nil || m.replayPath != "" {
+		return
+	}
+	r := m.engine.Recording()
+	if r == nil || len(r.Steps) == 0 {
+		return
+	}
+	path, err := prefs.NewReplayPath(r.Started)
+	if err != nil {
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	err = r.Write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return
+	}
+	m.replayPath = path
+}
+
+// RenderReplayFrame renders a game being played back as the game screen
+// draws it: the HUD beside the board.
+func RenderReplayFrame(gs *game.GameState) string {
+	return lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		lipgloss.NewStyle().Width(24).Render(RenderInfo(gs, "", false, "")),
+		lipgloss.NewStyle().Padding(1, 2).Render(RenderBoard(gs, game.BoardWidth, game.BoardHeight)),
+	)
+}
+
+// CellColors are the xterm-256 color codes the board draws blocks in,
+// indexed by the Color a Cell or Piece holds; 0 is the empty board.
+func CellColors() []string {
+	return slices.Clone(colors)
+}
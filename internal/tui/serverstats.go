@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Server statistics ---
+//
+// The main menu shows a line of the server's totals from GET /stats,
+// refreshed every serverStatsInterval while the menu is up. Servers
+// without /stats just don't get the line.
+
+// serverStatsInterval is how often the main menu's server stats refresh.
+const serverStatsInterval = 30 * time.Second
+
+// ServerStatsMsg is the result of a GET /stats.
+type ServerStatsMsg struct {
+	Server string // the server asked, in case it changed meanwhile
+	Stats  protocol.ServerStatsResponse
+	Err    error
+}
+
+// serverStatsTickMsg is time to refresh the server stats.
+type serverStatsTickMsg struct{}
+
+func serverStatsCmd(c *client.Client) tea.Cmd {
+	if c == nil {
+		return nil
+	}
+	server := c.Server()
+	return func() tea.Msg {
+		stats, err := c.ServerStats()
+		return ServerStatsMsg{Server: server, Stats: stats, Err: err}
+	}
+}
+
+func serverStatsTickCmd() tea.Cmd {
+	return tea.Tick(serverStatsInterval, func(time.Time) tea.Msg {
+		return serverStatsTickMsg{}
+	})
+}
+
+func (m Model) handleServerStats(msg ServerStatsMsg) (tea.Model, tea.Cmd) {
+	if m.client == nil || msg.Server != m.client.Server() {
+		return m, nil
+	}
+	if msg.Err != nil {
+		m.serverStats = nil
+	} else {
+		m.serverStats = &msg.Stats
+	}
+	return m, nil
+}
+
+// handleServerStatsTick refreshes the stats if the main menu is showing
+// them, and waits for the next refresh either way.
+func (m Model) handleServerStatsTick() (tea.Model, tea.Cmd) {
+	if m.screen != ScreenMainMenu {
+		return m, serverStatsTickCmd()
+	}
+	return m, tea.Batch(serverStatsCmd(m.client), serverStatsTickCmd())
+}
+
+// renderServerStats is the main menu's line of server totals, or "".
+func renderServerStats(stats *protocol.ServerStatsResponse) string {
+	if stats == nil {
+		return ""
+	}
+	return i18n.T("menu.server_stats", stats.OnlinePlayers, stats.PeakToday, stats.MatchesPlayed, stats.LinesCleared)
+}
@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/game"
+)
+
+// --- Held soft drop ---
+//
+// Terminals only report key presses, so holding ↓ arrives as a press
+// followed, after the keyboard's repeat delay, by a stream of repeats. A
+// lone press moves the piece down one row. A second press soon after
+// means the key is held: from then on the piece falls at the soft drop
+// factor (SDF) times gravity from the tick loop, until the repeats stop.
+
+const (
+	// DefaultSoftDropFactor is the SDF used unless the player sets one.
+	DefaultSoftDropFactor = 20
+	// MaxSoftDropFactor is the highest SDF accepted.
+	MaxSoftDropFactor = 40
+
+	// softDropRepeatWindow is how soon a second press must follow the
+	// first to count as holding the key. It covers typical keyboard
+	// repeat delays.
+	softDropRepeatWindow = 600 * time.Millisecond
+	// softDropRelease is how long after the last repeat the key counts
+	// as released.
+	softDropRelease = 150 * time.Millisecond
+	// softDropMinInterval caps the soft drop rate at high levels.
+	softDropMinInterval = 10 * time.Millisecond
+)
+
+// SoftDropTickMsg moves the piece down while soft drop is held, for the
+// hold with the same sequence number.
+type SoftDropTickMsg int
+
+func softDropTickCmd(interval time.Duration, seq int) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return SoftDropTickMsg(seq)
+	})
+}
+
+// softDropFactor returns the player's SDF, or the default.
+func (m Model) softDropFactor() int {
+	if m.prefs == nil || m.prefs.SoftDropFactor <= 0 {
+		return DefaultSoftDropFactor
+	}
+	return min(m.prefs.SoftDropFactor, MaxSoftDropFactor)
+}
+
+// softDropInterval is the time per row while soft dropping.
+func (m Model) softDropInterval() time.Duration {
+	return max(m.gameState.GetDropSpeed()/time.Duration(m.softDropFactor()), softDropMinInterval)
+}
+
+// pressSoftDrop handles a press of the soft drop key.
+func (m *Model) pressSoftDrop() tea.Cmd {
+	now := time.Now()
+	held := now.Sub(m.softDropAt) < softDropRepeatWindow
+	m.softDropAt = now
+	if !held {
+		m.input(game.InputSoftDrop)
+		return nil
+	}
+	if m.softDropping {
+		return nil // the tick loop is already dropping
+	}
+	m.softDropping = true
+	m.softDropSeq++
+	return softDropTickCmd(m.softDropInterval(), m.softDropSeq)
+}
+
+func (m Model) handleSoftDropTick(seq int) (tea.Model, tea.Cmd) {
+	if seq != m.softDropSeq || !m.softDropping {
+		return m, nil
+	}
+	if m.screen != ScreenPlaying || m.gameState == nil || m.gameState.IsGameOver ||
+		time.Since(m.softDropAt) > softDropRelease {
+		m.softDropping = false
+		return m, nil
+	}
+	m.input(game.InputSoftDrop)
+	return m, softDropTickCmd(m.softDropInterval(), seq)
+}
@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// --- Sound cues ---
+//
+// Audio feedback is the terminal bell, written to stderr so it doesn't
+// interfere with the renderer's output on stdout. It rings on Tetris
+// clears, incoming garbage, countdown ticks and KOs when enabled in settings.
+
+func bellCmd() tea.Cmd {
+	return func() tea.Msg {
+		os.Stderr.WriteString("\a")
+		return nil
+	}
+}
+
+// bell returns a command that rings the bell, or nil if sound is off.
+func (m Model) bell() tea.Cmd {
+	if m.prefs == nil || !m.prefs.Sound {
+		return nil
+	}
+	return bellCmd()
+}
+
+// lockCue consumes the last line clear and rings for a Tetris, or when
+// the lock topped the player out.
+func (m *Model) lockCue() tea.Cmd {
+	if m.gameState == nil {
+		return nil
+	}
+	cleared := m.gameState.LastClear
+	m.gameState.LastClear = 0
+	if cleared == 4 || m.gameState.IsGameOver {
+		return m.bell()
+	}
+	return nil
+}
+
+// koCue rings when an opponent that was alive in the previous update
+// has been knocked out.
+func (m Model) koCue(prev, next []protocol.OpponentState) tea.Cmd {
+	wasAlive := make(map[string]bool, len(prev))
+	for _, opp := range prev {
+		wasAlive[opp.PlayerID] = opp.Alive
+	}
+	for _, opp := range next {
+		if wasAlive[opp.PlayerID] && !opp.Alive {
+			return m.bell()
+		}
+	}
+	return nil
+}
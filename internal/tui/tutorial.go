@@ -0,0 +1,245 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hersh/gotris/internal/game"
+	"github.com/hersh/gotris/internal/i18n"
+)
+
+// --- Tutorial ---
+//
+// The tutorial is a fixed list of lessons. Each lesson sets up a scripted
+// board, allows only the keys it is teaching, and waits until its goal is
+// met before offering to move on. There is no gravity, so players can take
+// their time.
+
+// tutorialAction is a set of key groups a lesson accepts.
+type tutorialAction int
+
+const (
+	actMove tutorialAction = 1 << iota // left/right/soft drop
+	actRotate
+	actDrop
+	actHold
+)
+
+// lesson is one step of the tutorial. Text comes from the catalog under
+// tutorial.<key>.title, .prompt and .done; the prompt names the keys for
+// prompts in the player's keymap.
+type lesson struct {
+	key     string
+	actions tutorialAction
+	prompts []inputAction
+	setup   func() *game.GameState
+	goal    func(t *tutorial, gs *game.GameState) bool
+}
+
+var lessons = []lesson{
+	{
+		key:     "move",
+		actions: actMove,
+		prompts: []inputAction{inputLeft, inputRight, inputDown},
+		setup:   func() *game.GameState { return tutorialBoard(game.PieceT) },
+		goal: func(t *tutorial, gs *game.GameState) bool {
+			return t.movedLeft && t.movedRight
+		},
+	},
+	{
+		key:     "rotate",
+		actions: actMove | actRotate,
+		prompts: []inputAction{inputRotate},
+		setup:   func() *game.GameState { return tutorialBoard(game.PieceT) },
+		goal: func(t *tutorial, gs *game.GameState) bool {
+			return t.rotations >= 4
+		},
+	},
+	{
+		// Two rows with a two-wide gap for an O piece at columns 7-8.
+		key:     "ghost",
+		actions: actMove | actRotate,
+		setup: func() *game.GameState {
+			return tutorialBoard(game.PieceO, "#######..#", "#######..#")
+		},
+		goal: func(t *tutorial, gs *game.GameState) bool {
+			return gs.CurrentPiece.X == 7
+		},
+	},
+	{
+		key:     "drop",
+		actions: actMove | actRotate | actDrop,
+		prompts: []inputAction{inputDrop},
+		setup: func() *game.GameState {
+			return tutorialBoard(game.PieceO, "#######..#", "#######..#")
+		},
+		goal: func(t *tutorial, gs *game.GameState) bool {
+			return t.dropped && gs.LastClear >= 2
+		},
+	},
+	{
+		key:     "hold",
+		actions: actMove | actRotate | actHold,
+		prompts: []inputAction{inputHold},
+		setup:   func() *game.GameState { return tutorialBoard(game.PieceS) },
+		goal: func(t *tutorial, gs *game.GameState) bool {
+			return gs.HoldPiece != nil
+		},
+	},
+	{
+		// A four-deep well in the last column for a vertical I piece.
+		key:     "attack",
+		actions: actMove | actRotate | actDrop | actHold,
+		setup: func() *game.GameState {
+			return tutorialBoard(game.PieceI, "#########.", "#########.", "#########.", "#########.")
+		},
+		goal: func(t *tutorial, gs *game.GameState) bool {
+			return t.dropped && gs.AttackPower > 0
+		},
+	},
+}
+
+// tutorial is the progress through the lessons.
+type tutorial struct {
+	lesson int
+	done   bool // goal reached, waiting for ENTER
+	retry  bool // the last drop missed and the lesson was reset
+
+	movedLeft  bool
+	movedRight bool
+	rotations  int
+	dropped    bool
+}
+
+// tutorialBoard builds a lesson's game state: the given piece falling into
+// a board whose bottom rows are drawn from rows ('#' = filled), top first.
+func tutorialBoard(piece game.PieceType, rows ...string) *game.GameState {
+	gs := game.NewSeededGameState("tutorial", "", 1)
+	gs.CurrentPiece = game.NewPiece(piece)
+	top := game.BoardHeight - len(rows)
+	for i, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				gs.Board.Cells[top+i][x] = game.Cell{Filled: true, Color: 8}
+			}
+		}
+	}
+	return gs
+}
+
+// startTutorial resets t to the start of lesson n and returns its board.
+func (t *tutorial) start(n int) *game.GameState {
+	*t = tutorial{lesson: n}
+	return lessons[n].setup()
+}
+
+func (m Model) handleTutorialKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := *m.tutorial
+	l := lessons[t.lesson]
+
+	switch msg.String() {
+	case "esc":
+		m.tutorial = nil
+		m.gameState = nil
+		m.screen = ScreenMainMenu
+		return m, tickCmd()
+	case "enter":
+		if !t.done {
+			return m, nil
+		}
+		if t.lesson+1 >= len(lessons) {
+			m.tutorial = nil
+			m.gameState = nil
+			m.screen = ScreenMainMenu
+			return m, tickCmd()
+		}
+		m.gameState = t.start(t.lesson + 1)
+		m.tutorial = &t
+		return m, nil
+	}
+
+	if t.done {
+		return m, nil
+	}
+
+	gs := m.gameState
+	action, ok := m.keymap().action(msg.String())
+	if !ok {
+		return m, nil
+	}
+	switch action {
+	case inputLeft:
+		if l.actions&actMove != 0 && gs.MoveLeft() {
+			t.movedLeft = true
+		}
+	case inputRight:
+		if l.actions&actMove != 0 && gs.MoveRight() {
+			t.movedRight = true
+		}
+	case inputDown:
+		if l.actions&actMove != 0 {
+			gs.MoveDown()
+		}
+	case inputRotate:
+		if l.actions&actRotate != 0 && gs.Rotate() {
+			t.rotations++
+		}
+	case inputRotateBack:
+		if l.actions&actRotate != 0 && gs.RotateBack() {
+			t.rotations++
+		}
+	case inputHold:
+		if l.actions&actHold != 0 {
+			gs.Hold()
+		}
+	case inputDrop:
+		if l.actions&actDrop != 0 {
+			gs.HardDrop()
+			t.dropped = true
+		}
+	}
+
+	t.retry = false
+	switch {
+	case l.goal(&t, gs):
+		t.done = true
+	case t.dropped:
+		// Missed: put the board back and let them try again.
+		m.gameState = t.start(t.lesson)
+		t.retry = true
+	}
+	m.tutorial = &t
+	return m, m.lockCue()
+}
+
+// render draws the lesson text shown beside the board, naming keys from
+// km.
+func (t *tutorial) render(km keymap) string {
+	l := lessons[t.lesson]
+	prefix := "tutorial." + l.key
+	var keys []any
+	for _, a := range l.prompts {
+		keys = append(keys, km.label(a))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(i18n.T("tutorial.step", t.lesson+1, len(lessons))) + "\n")
+	sb.WriteString(titleStyle.Render(i18n.T(prefix+".title")) + "\n\n")
+	sb.WriteString(lipgloss.NewStyle().Width(32).Render(i18n.T(prefix+".prompt", keys...)) + "\n\n")
+
+	switch {
+	case t.done && t.lesson+1 >= len(lessons):
+		sb.WriteString(readyStyle.Render(i18n.T(prefix+".done")) + "\n")
+		sb.WriteString(winnerStyle.Render(i18n.T("tutorial.finished")) + "\n")
+	case t.done:
+		sb.WriteString(readyStyle.Render(i18n.T(prefix+".done")) + "\n")
+		sb.WriteString(infoStyle.Render(i18n.T("tutorial.next")) + "\n")
+	case t.retry:
+		sb.WriteString(notReadyStyle.Render(i18n.T("tutorial.retry")) + "\n")
+	}
+
+	sb.WriteString("\n" + infoStyle.Render(fmt.Sprintf("ESC  %s", i18n.T("tutorial.quit"))))
+	return sb.String()
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hersh/gotris/internal/i18n"
+	"github.com/hersh/gotris/internal/prefs"
 	"github.com/hersh/gotris/internal/tui"
 )
 
@@ -14,13 +16,20 @@ import (
 //   Client: go run ./cmd/client --server ws://localhost:8080/ws --name YourName
 
 func main() {
+	settings := prefs.Load()
+	if !i18n.SetLang(settings.Lang) {
+		i18n.SetLang(i18n.Detect())
+	}
+
 	name := "Player"
 	if len(os.Args) > 1 {
 		name = os.Args[1]
+	} else if settings.PlayerName != "" {
+		name = settings.PlayerName
 	}
 
 	// nil client = single-player only mode (no network)
-	model := tui.NewModel(name, nil)
+	model := tui.NewModel(name, nil, settings)
 
 	p := tea.NewProgram(
 		model,
@@ -0,0 +1,1333 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	maxMessageSize = 65536 // opponent updates from a full large room run past 16KB
+
+	// heartbeatInterval is how often we ping the server. Pings double as the
+	// keepalive and as the RTT probe for the HUD connection widget.
+	heartbeatInterval = 2 * time.Second
+
+	// Reconnect backoff: the delay doubles from reconnectBaseDelay up to
+	// reconnectMaxDelay, with jitter. The attempts fit inside the server's
+	// 60s window for using a reconnect token.
+	reconnectBaseDelay = 500 * time.Millisecond
+	reconnectMaxDelay  = 10 * time.Second
+	ReconnectAttempts  = 8
+
+	// maxPlayRedirects is how many times the /play handshake follows a
+	// server sending us to another instance.
+	maxPlayRedirects = 2
+)
+
+// Outbound buffering while reconnecting: critical messages wait for the
+// connection to come back, the latest board snapshot is kept only while
+// it's fresh, and everything else is dropped.
+const (
+	maxPending     = 64
+	snapshotMaxAge = time.Second
+)
+
+// Capabilities are the optional protocol features this client supports,
+// offered to the server when connecting.
+var Capabilities = []protocol.Capability{protocol.CapReconnect, protocol.CapChat, protocol.CapBatch}
+
+// bufferedTypes are the messages worth holding on to across a reconnect.
+var bufferedTypes = map[protocol.MessageType]bool{
+	protocol.MsgLinesCleared: true,
+	protocol.MsgPlayerDead:   true,
+	protocol.MsgSetTarget:    true,
+	protocol.MsgUseItem:      true,
+}
+
+// outMsg is a marshalled outbound message.
+type outMsg struct {
+	typ  protocol.MessageType
+	data []byte
+	at   time.Time
+}
+
+// ErrTokenExpired means the server didn't accept a join or reconnect
+// token: it was used already, has expired, or (for a reconnect token) the
+// server hasn't yet noticed the old connection is gone.
+var ErrTokenExpired = errors.New("invalid or expired token")
+
+// errRejected means the server turned a /play dial away for good (room
+// gone, full, or token for another room), so retrying won't help.
+var errRejected = errors.New("rejected by server")
+
+// Errors from the HTTP methods, for errors.Is. When the server turns a
+// request down (room not found, room full, ...) the error matches none of
+// them and its message is the server's.
+var (
+	ErrUnreachable = errors.New("server unreachable")
+	ErrServer      = errors.New("server error")
+	ErrBadResponse = errors.New("bad response from server")
+)
+
+// requestError is a failed HTTP call: what kind of failure it was, and a
+// message for people.
+type requestError struct {
+	kind       error              // one of the Err* above, nil if the server said no
+	code       protocol.ErrorCode // the server's code, if it sent one
+	msg        string
+	err        error
+	retryAfter time.Duration // when the server said to try again, if it did
+}
+
+func (e *requestError) Error() string { return e.msg }
+
+func (e *requestError) Is(target error) bool { return e.kind != nil && target == e.kind }
+
+func (e *requestError) Unwrap() error { return e.err }
+
+// ErrorCode returns the server's error code for a failed call (for
+// example protocol.ErrCodeRoomFull), or "" if the server didn't send one
+// or never answered.
+func ErrorCode(err error) protocol.ErrorCode {
+	var re *requestError
+	if errors.As(err, &re) {
+		return re.code
+	}
+	return ""
+}
+
+// RetryAfter returns how long the server asked the client to wait before
+// trying a failed call again, as it does when it's full or the client
+// has created too many rooms, or 0.
+func RetryAfter(err error) time.Duration {
+	var re *requestError
+	if errors.As(err, &re) {
+		return re.retryAfter
+	}
+	return 0
+}
+
+// ConnStatus describes the state of the game-room WebSocket.
+type ConnStatus int
+
+const (
+	ConnDisconnected ConnStatus = iota
+	ConnConnected
+	ConnReconnecting
+)
+
+func (s ConnStatus) String() string {
+	switch s {
+	case ConnConnected:
+		return "connected"
+	case ConnReconnecting:
+		return "reconnecting"
+	}
+	return "disconnected"
+}
+
+// --- Events ---
+
+// Event is something the client reports asynchronously, delivered on the
+// Events channel. It is one of the *Msg types below.
+type Event interface {
+	event()
+}
+
+func (ServerMsg) event()       {}
+func (ConnectedMsg) event()    {}
+func (DisconnectedMsg) event() {}
+func (ReconnectingMsg) event() {}
+func (ReconnectedMsg) event()  {}
+func (ConnStatusMsg) event()   {}
+
+// ServerMsg wraps an incoming WebSocket server message. Seq and Sent are
+// zero if the server doesn't stamp its messages. Sent is by the server's
+// clock, so time.Since(Sent) is the one-way delay give or take clock skew.
+type ServerMsg struct {
+	Type protocol.MessageType
+	Raw  json.RawMessage
+	Seq  uint64
+	Sent time.Time
+}
+
+// ConnectedMsg is sent when the WS connects and receives its PlayerID.
+// Resumed is set when a reconnect got back the seat in a match in
+// progress, and Missed when the server could no longer send everything
+// missed meanwhile. MOTD is the server's message of the day, if any.
+type ConnectedMsg struct {
+	PlayerID string
+	Resumed  bool
+	Missed   bool
+	MOTD     string
+}
+
+// DisconnectedMsg is sent when the WebSocket connection drops unexpectedly.
+type DisconnectedMsg struct {
+	Err error
+}
+
+// ReconnectingMsg is sent before each attempt to re-establish a dropped
+// connection. Err is why the previous attempt failed, if there was one.
+type ReconnectingMsg struct {
+	Attempt int
+	Delay   time.Duration
+	Err     error
+}
+
+// ReconnectedMsg is sent when a dropped connection has been re-established.
+// A ConnectedMsg follows, saying whether the server kept our seat.
+type ReconnectedMsg struct {
+	Attempts int
+}
+
+// ConnStatusMsg reports the connection state and the latest round-trip time.
+// It is sent on connect and after every heartbeat pong.
+type ConnStatusMsg struct {
+	Status ConnStatus
+	RTT    time.Duration
+}
+
+// --- Client ---
+
+// Client manages HTTP and WebSocket connections to the game server.
+// HTTP is used for room creation/listing (Front Desk).
+// WebSocket is used for real-time gameplay (Game Room).
+type Client struct {
+	mu         sync.Mutex
+	httpBase   string // e.g. "http://localhost:8080"
+	wsBase     string // e.g. "ws://localhost:8080"
+	httpClient *http.Client
+	dialer     *websocket.Dialer
+	header     http.Header // extra headers for every request and handshake
+	retry      retryConfig
+
+	// WebSocket (created on demand when joining a room)
+	conn     *websocket.Conn
+	sendCh   chan outMsg
+	done     chan struct{}
+	wsActive bool
+	rtt      time.Duration // latest heartbeat round-trip time
+
+	// Reconnection
+	roomID         string
+	reconnectToken string         // from the server's AssignID, "" = can't reconnect
+	onSession      func(*Session) // WithSessionHook
+	log            *slog.Logger   // WithLogger, nil = slog.Default()
+	simSend        *netSim        // WithNetSim, nil = off
+	simRecv        *netSim
+	pending        []outMsg              // held while reconnecting, oldest first
+	resuming       bool                  // reconnected, waiting to hear if the seat was kept
+	sendSeq        uint64                // last Seq stamped on an outgoing message
+	caps           []protocol.Capability // features both we and the server support
+	opponentSeq    uint64                // Seq of the newest opponent update delivered
+	lastSeq        uint64                // highest Seq received on this seat, for backfill
+
+	events    chan Event
+	closed    chan struct{} // closed by Close; unblocks pending events
+	closeOnce sync.Once
+}
+
+// New creates a Client that talks to the given HTTP base URL.
+// No connections are opened; the client starts immediately.
+func New(httpBaseURL string, opts ...Option) *Client {
+	cfg := config{
+		dialTimeout: defaultDialTimeout,
+		httpTimeout: defaultHTTPTimeout,
+		header:      http.Header{},
+		proxy:       http.ProxyFromEnvironment,
+		retry:       retryConfig{attempts: defaultRetryAttempts, delay: defaultRetryDelay},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	httpClient, dialer := cfg.transports()
+
+	c := &Client{
+		httpClient: httpClient,
+		dialer:     dialer,
+		header:     cfg.header,
+		retry:      cfg.retry,
+		onSession:  cfg.onSession,
+		log:        cfg.logger,
+		sendCh:     make(chan outMsg, 256),
+		events:     make(chan Event, 64),
+		closed:     make(chan struct{}),
+	}
+	if cfg.sim != (NetSim{}) {
+		c.simSend = newNetSim(cfg.sim)
+		c.simRecv = newNetSim(cfg.sim)
+	}
+	c.SetServer(httpBaseURL)
+	return c
+}
+
+// NormalizeServer turns a user-typed server address into an HTTP base URL:
+// a missing scheme defaults to http://, ws:// and wss:// map to their HTTP
+// equivalents, and any trailing slash or /ws path is dropped.
+func NormalizeServer(addr string) string {
+	addr = strings.TrimSpace(addr)
+	switch {
+	case strings.HasPrefix(addr, "ws://"):
+		addr = "http://" + strings.TrimPrefix(addr, "ws://")
+	case strings.HasPrefix(addr, "wss://"):
+		addr = "https://" + strings.TrimPrefix(addr, "wss://")
+	case !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://"):
+		addr = "http://" + addr
+	}
+	addr = strings.TrimSuffix(addr, "/")
+	return strings.TrimSuffix(addr, "/ws")
+}
+
+// SetServer points the client at a different server. It should only be
+// called while not connected to a room.
+func (c *Client) SetServer(httpBaseURL string) {
+	httpBaseURL = NormalizeServer(httpBaseURL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.httpBase = httpBaseURL
+	c.wsBase = toWebSocket(httpBaseURL)
+}
+
+// toWebSocket turns an http:// or https:// URL into its ws:// or wss://
+// equivalent.
+func toWebSocket(url string) string {
+	url = strings.Replace(url, "https://", "wss://", 1)
+	return strings.Replace(url, "http://", "ws://", 1)
+}
+
+// Server returns the HTTP base URL the client talks to.
+func (c *Client) Server() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.httpBase
+}
+
+// CheckServer calls GET /health on addr (which need not be the current
+// server) and reports whether a gotris server answered.
+func (c *Client) CheckServer(addr string) error {
+	return c.call(http.MethodGet, NormalizeServer(addr)+"/health", nil, true, nil)
+}
+
+// Events returns the channel the client reports connection changes and
+// server messages on. It must be drained while connected to a room: the
+// network goroutines wait for room in the buffer. It is never closed.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// --- HTTP methods (Front Desk) ---
+
+// CreateRoom calls POST /create-room and returns the room ID and join token.
+func (c *Client) CreateRoom(playerName string) (roomID, token string, err error) {
+	return c.CreateTitledRoom(playerName, "")
+}
+
+// CreateTitledRoom is CreateRoom for a room with a title, shown in the
+// room browser and matched by SearchRooms.
+func (c *Client) CreateTitledRoom(playerName, title string) (roomID, token string, err error) {
+	data, _ := json.Marshal(protocol.CreateRoomRequest{PlayerName: playerName, Title: title})
+
+	var result protocol.CreateRoomResponse
+	if err := c.call(http.MethodPost, c.Server()+"/create-room", data, false, &result); err != nil {
+		return "", "", err
+	}
+	return result.RoomID, result.JoinToken, nil
+}
+
+// JoinRoom calls POST /join-room and returns the join token.
+func (c *Client) JoinRoom(roomID, playerName string) (token string, err error) {
+	data, _ := json.Marshal(protocol.JoinRoomHTTPRequest{RoomID: roomID, PlayerName: playerName})
+
+	var result protocol.JoinRoomHTTPResponse
+	if err := c.call(http.MethodPost, c.Server()+"/join-room", data, false, &result); err != nil {
+		return "", err
+	}
+	return result.JoinToken, nil
+}
+
+// JoinInviteRoom calls POST /join-room with an invite's token, using it
+// up, and returns the invite's room with a join token.
+func (c *Client) JoinInviteRoom(invite, playerName string) (roomID, token string, err error) {
+	data, _ := json.Marshal(protocol.JoinRoomHTTPRequest{Invite: invite, PlayerName: playerName})
+
+	var result protocol.JoinRoomHTTPResponse
+	if err := c.call(http.MethodPost, c.Server()+"/join-room", data, false, &result); err != nil {
+		return "", "", err
+	}
+	return result.RoomID, result.JoinToken, nil
+}
+
+// QuickPlayRoom calls POST /quick-play, which finds (or makes) an open
+// room of roomType, protocol.RoomTypeCasual or RoomTypeRanked, and
+// returns it with a join token.
+func (c *Client) QuickPlayRoom(playerName, roomType string) (roomID, token string, err error) {
+	data, _ := json.Marshal(protocol.QuickPlayRequest{PlayerName: playerName, Type: roomType})
+
+	var result protocol.JoinRoomHTTPResponse
+	if err := c.call(http.MethodPost, c.Server()+"/quick-play", data, false, &result); err != nil {
+		return "", "", err
+	}
+	return result.RoomID, result.JoinToken, nil
+}
+
+// ListRooms calls GET /list-rooms and returns the active rooms.
+func (c *Client) ListRooms() ([]protocol.RoomInfo, error) {
+	return c.SearchRooms("")
+}
+
+// SearchRooms is ListRooms for just the rooms whose code or title
+// contains query, ignoring case.
+func (c *Client) SearchRooms(query string) ([]protocol.RoomInfo, error) {
+	resp, err := c.QueryRooms(RoomQuery{Search: query})
+	return resp.Rooms, err
+}
+
+// RoomQuery narrows and pages a QueryRooms call. The zero value lists
+// every room.
+type RoomQuery struct {
+	Search   string // code or title contains this, ignoring case
+	Phase    string // protocol.RoomPhaseLobby etc., "" = any
+	OpenOnly bool   // in the lobby with a free seat
+	Type     string // protocol.RoomTypeCasual or RoomTypeRanked, "" = any
+	Sort     string // protocol.RoomSortCode etc., "" = by code
+	Limit    int    // 0 = as many as the server gives
+	Offset   int
+}
+
+// QueryRooms calls GET /list-rooms with q's filters, returning one page of
+// rooms and the number that matched in all. Against a server from before
+// paging, which returns every room, the page is cut out here.
+func (c *Client) QueryRooms(q RoomQuery) (protocol.ListRoomsResponse, error) {
+	v := url.Values{}
+	if q.Search != "" {
+		v.Set("q", q.Search)
+	}
+	if q.Phase != "" {
+		v.Set("phase", q.Phase)
+	}
+	if q.OpenOnly {
+		v.Set("open", "1")
+	}
+	if q.Type != "" {
+		v.Set("type", q.Type)
+	}
+	if q.Sort != "" {
+		v.Set("sort", q.Sort)
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		v.Set("offset", strconv.Itoa(q.Offset))
+	}
+	u := c.Server() + "/list-rooms"
+	if len(v) > 0 {
+		u += "?" + v.Encode()
+	}
+
+	var result protocol.ListRoomsResponse
+	if err := c.call(http.MethodGet, u, nil, true, &result); err != nil {
+		return protocol.ListRoomsResponse{}, err
+	}
+	if result.Total == 0 && len(result.Rooms) > 0 {
+		result.Total = len(result.Rooms)
+		rooms := result.Rooms[min(q.Offset, len(result.Rooms)):]
+		if q.Limit > 0 && len(rooms) > q.Limit {
+			rooms = rooms[:q.Limit]
+		}
+		result.Rooms = rooms
+	}
+	return result, nil
+}
+
+// ServerStats calls GET /stats for the server's totals.
+func (c *Client) ServerStats() (protocol.ServerStatsResponse, error) {
+	var result protocol.ServerStatsResponse
+	err := c.call(http.MethodGet, c.Server()+"/stats", nil, true, &result)
+	return result, err
+}
+
+// MatchHistory calls GET /players/{id}/matches, returning up to limit of
+// the player's recent matches, most recent first (limit 0 = the server's
+// default).
+func (c *Client) MatchHistory(playerID string, limit int) ([]protocol.MatchRecord, error) {
+	u := c.Server() + "/players/" + url.PathEscape(playerID) + "/matches"
+	if limit > 0 {
+		u += "?limit=" + strconv.Itoa(limit)
+	}
+	var result protocol.MatchHistoryResponse
+	if err := c.call(http.MethodGet, u, nil, true, &result); err != nil {
+		return nil, err
+	}
+	return result.Matches, nil
+}
+
+// Profile calls GET /profile for the profile stored under key. With no
+// profile, ErrorCode reports protocol.ErrCodeNotFound.
+func (c *Client) Profile(key string) (protocol.Profile, error) {
+	var result protocol.Profile
+	err := c.callWithKey(http.MethodGet, c.Server()+"/profile", key, nil, true, &result)
+	return result, err
+}
+
+// SaveProfile calls PUT /profile to store p under key, returning it as
+// stored.
+func (c *Client) SaveProfile(key string, p protocol.Profile) (protocol.Profile, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return protocol.Profile{}, err
+	}
+	var result protocol.Profile
+	err = c.callWithKey(http.MethodPut, c.Server()+"/profile", key, body, true, &result)
+	return result, err
+}
+
+// AddAchievements calls POST /achievements to add the achievements in
+// unlocked to those stored under key, returning them all.
+func (c *Client) AddAchievements(key string, unlocked map[string]int64) (protocol.Achievements, error) {
+	body, err := json.Marshal(protocol.Achievements{Unlocked: unlocked})
+	if err != nil {
+		return protocol.Achievements{}, err
+	}
+	var result protocol.Achievements
+	err = c.callWithKey(http.MethodPost, c.Server()+"/achievements", key, body, true, &result)
+	return result, err
+}
+
+// Invites calls GET /invites for the friend code of the profile key and
+// the invites to it.
+func (c *Client) Invites(key string) (protocol.InvitesResponse, error) {
+	var result protocol.InvitesResponse
+	err := c.callWithKey(http.MethodGet, c.Server()+"/invites", key, nil, true, &result)
+	return result, err
+}
+
+// DeclineInvite calls DELETE /invites/{token} to decline an invite to the
+// profile key's friend code.
+func (c *Client) DeclineInvite(key, token string) error {
+	return c.callWithKey(http.MethodDelete, c.Server()+"/invites/"+url.PathEscape(token), key, nil, true, nil)
+}
+
+// call makes an HTTP request to the server and decodes the JSON reply into
+// out, if not nil. Idempotent calls are retried after any network error or
+// 5xx reply; others only when the request never got out (the dial
+// failed), so a retry can't create a second room.
+func (c *Client) call(method, url string, body []byte, idempotent bool, out any) error {
+	return c.callWithKey(method, url, "", body, idempotent, out)
+}
+
+// callWithKey is call sending key, unless it's "", as a bearer token.
+func (c *Client) callWithKey(method, url, key string, body []byte, idempotent bool, out any) error {
+	delay := c.retry.delay
+	for attempt := 1; ; attempt++ {
+		err := c.callOnce(method, url, key, body, out)
+		if err == nil || attempt >= c.retry.attempts || !retryable(err, idempotent) {
+			return err
+		}
+
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		c.logger().Debug("retrying request", "url", url, "attempt", attempt, "wait", wait, "err", err)
+		select {
+		case <-time.After(wait):
+		case <-c.closed:
+			return err
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+func (c *Client) callOnce(method, url, key string, body []byte, out any) error {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &requestError{kind: ErrUnreachable, msg: "server unreachable: " + err.Error(), err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &requestError{kind: ErrUnreachable, msg: "server unreachable: " + err.Error(), err: err}
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		e := errorResponse(resp, data)
+		if resp.StatusCode >= 500 && e.Code != protocol.ErrCodeServerFull {
+			return &requestError{kind: ErrServer, code: e.Code, msg: "server error: " + e.Error}
+		}
+		return &requestError{code: e.Code, msg: e.Error, retryAfter: time.Duration(e.RetryAfter) * time.Second}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return &requestError{kind: ErrBadResponse, msg: "bad response from server: " + err.Error(), err: err}
+	}
+	return nil
+}
+
+// errorResponse reads a failed reply's body, falling back to the HTTP
+// status for the message when it isn't an ErrorResponse.
+func errorResponse(resp *http.Response, body []byte) protocol.ErrorResponse {
+	var errResp protocol.ErrorResponse
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+		return errResp
+	}
+	return protocol.ErrorResponse{Error: resp.Status}
+}
+
+// retryable reports whether a failed call may be tried again.
+func retryable(err error, idempotent bool) bool {
+	switch {
+	case errors.Is(err, ErrServer):
+		return idempotent
+	case errors.Is(err, ErrUnreachable):
+		var opErr *net.OpError
+		return idempotent || (errors.As(err, &opErr) && opErr.Op == "dial")
+	}
+	return false
+}
+
+// --- WebSocket methods (Game Room) ---
+
+// Create creates a room and connects to it as its host. The room ID is
+// returned even if connecting fails, since the room exists by then.
+func (c *Client) Create(playerName string) (roomID string, err error) {
+	return c.CreateTitled(playerName, "")
+}
+
+// CreateTitled is Create for a room with a title.
+func (c *Client) CreateTitled(playerName, title string) (roomID string, err error) {
+	roomID, token, err := c.CreateTitledRoom(playerName, title)
+	if err != nil {
+		return "", err
+	}
+	return roomID, c.ConnectToRoom(roomID, token)
+}
+
+// QuickPlay joins an open room of roomType (or a new one) via HTTP, then
+// connects via WebSocket, and returns the room's code.
+func (c *Client) QuickPlay(playerName, roomType string) (roomID string, err error) {
+	roomID, token, err := c.QuickPlayRoom(playerName, roomType)
+	if err != nil {
+		return "", err
+	}
+	return roomID, c.ConnectToRoom(roomID, token)
+}
+
+// JoinInvite joins the room an invite is to, like Join, and returns its
+// code.
+func (c *Client) JoinInvite(invite, playerName string) (roomID string, err error) {
+	roomID, token, err := c.JoinInviteRoom(invite, playerName)
+	if err != nil {
+		return "", err
+	}
+	return roomID, c.ConnectToRoom(roomID, token)
+}
+
+// Resume reconnects to a room with the token from an earlier session, e.g.
+// one saved by a WithSessionHook before the program crashed. The server
+// only accepts the token once it has noticed the old connection drop, and
+// for about a minute after. If the player's seat in a match in progress
+// was still held, the ConnectedMsg that follows has Resumed set; otherwise
+// they are back in the lobby. It fails with ErrTokenExpired if the token
+// is no good, and the caller can join afresh instead.
+func (c *Client) Resume(roomID, reconnectToken string) error {
+	return c.ConnectToRoom(roomID, reconnectToken)
+}
+
+// Session returns what's needed to Resume the current room session, or
+// nil if there is none (not in a room, or the server hasn't sent a token).
+func (c *Client) Session() *Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionLocked()
+}
+
+func (c *Client) sessionLocked() *Session {
+	if !c.wsActive || c.reconnectToken == "" {
+		return nil
+	}
+	return &Session{Server: c.httpBase, RoomID: c.roomID, Token: c.reconnectToken}
+}
+
+// logger returns the logger set with WithLogger, or slog's default one.
+func (c *Client) logger() *slog.Logger {
+	if c.log != nil {
+		return c.log
+	}
+	return slog.Default()
+}
+
+// sessionChanged calls the session hook, if there is one.
+func (c *Client) sessionChanged(s *Session) {
+	if c.onSession != nil {
+		c.onSession(s)
+	}
+}
+
+// Join joins room roomID and connects to it.
+func (c *Client) Join(roomID, playerName string) error {
+	token, err := c.JoinRoom(roomID, playerName)
+	if err != nil {
+		return err
+	}
+	return c.ConnectToRoom(roomID, token)
+}
+
+// ConnectToRoom opens a WebSocket to /play?room=...&token=... and starts pumps.
+func (c *Client) ConnectToRoom(roomID, token string) error {
+	c.mu.Lock()
+	if c.wsActive {
+		c.mu.Unlock()
+		c.DisconnectFromRoom()
+		c.mu.Lock()
+	}
+	c.mu.Unlock()
+
+	conn, err := c.dialPlay(roomID, token, 0)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.sendCh = make(chan outMsg, 256)
+	c.done = make(chan struct{})
+	c.wsActive = true
+	c.rtt = 0
+	c.roomID = roomID
+	c.reconnectToken = ""
+	c.sendSeq = 0
+	c.opponentSeq = 0
+	c.lastSeq = 0
+	c.caps = nil
+	c.mu.Unlock()
+
+	c.logger().Info("connected to room", "room", roomID)
+	c.startPumps(conn)
+
+	return nil
+}
+
+// dialPlay opens the /play WebSocket for roomID with a join or reconnect
+// token. A reconnect passes the Seq of the last message it got as since,
+// so the server can send again whatever critical messages came after it.
+func (c *Client) dialPlay(roomID, token string, since uint64) (*websocket.Conn, error) {
+	c.mu.Lock()
+	wsBase := c.wsBase
+	c.mu.Unlock()
+
+	caps := make([]string, len(Capabilities))
+	for i, cap := range Capabilities {
+		caps[i] = string(cap)
+	}
+	wsURL := fmt.Sprintf("%s/play?room=%s&token=%s&caps=%s", wsBase, roomID, token, strings.Join(caps, ","))
+	if since > 0 {
+		wsURL += fmt.Sprintf("&since=%d", since)
+	}
+	conn, resp, err := c.dialer.Dial(wsURL, c.header)
+	// A server running several instances sends us to the one hosting the
+	// room.
+	for redirects := 0; err != nil && resp != nil && redirects < maxPlayRedirects; redirects++ {
+		if resp.StatusCode != http.StatusTemporaryRedirect && resp.StatusCode != http.StatusPermanentRedirect {
+			break
+		}
+		loc, lerr := resp.Location()
+		if lerr != nil {
+			break
+		}
+		conn, resp, err = c.dialer.Dial(toWebSocket(loc.String()), c.header)
+	}
+	if err != nil {
+		if resp == nil || resp.StatusCode < 400 || (resp.StatusCode >= 500 && resp.StatusCode != http.StatusServiceUnavailable) {
+			return nil, fmt.Errorf("WebSocket connection failed: %w", err)
+		}
+		// The handshake reply's body is there to read, and needn't be closed.
+		body, _ := io.ReadAll(resp.Body)
+		e := errorResponse(resp, body)
+		switch {
+		case e.Code == protocol.ErrCodeServerFull:
+			// Not final either: a seat may free up before we give up.
+			return nil, &requestError{code: e.Code, msg: e.Error, retryAfter: time.Duration(e.RetryAfter) * time.Second}
+		case resp.StatusCode >= 500:
+			return nil, fmt.Errorf("WebSocket connection failed: %w", err)
+		case resp.StatusCode == http.StatusUnauthorized:
+			// 401 isn't final: the server only accepts a reconnect token
+			// once it has noticed the old connection is gone.
+			return nil, &requestError{kind: ErrTokenExpired, code: e.Code, msg: ErrTokenExpired.Error() + ": " + e.Error}
+		}
+		return nil, &requestError{kind: errRejected, code: e.Code, msg: e.Error}
+	}
+	return conn, nil
+}
+
+// startPumps runs the read and write pumps for one connection.
+func (c *Client) startPumps(conn *websocket.Conn) {
+	connDone := make(chan struct{})
+	go c.writePump(conn, connDone)
+	go c.readPump(conn, connDone)
+}
+
+// reconnect re-dials /play with the reconnect token after the connection
+// drops, backing off exponentially with jitter between attempts. It gives
+// up after ReconnectAttempts tries, or straight away if the server rejects
+// the token for good. done is the session's channel; closing it (by
+// leaving the room) stops the loop.
+func (c *Client) reconnect(roomID, token string, done chan struct{}) {
+	delay := reconnectBaseDelay
+	var err error
+	for attempt := 1; attempt <= ReconnectAttempts; attempt++ {
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		c.logger().Info("reconnecting", "room", roomID, "attempt", attempt, "wait", wait, "err", err)
+		c.notify(ReconnectingMsg{Attempt: attempt, Delay: wait, Err: err})
+
+		select {
+		case <-time.After(wait):
+		case <-done:
+			return
+		}
+
+		c.mu.Lock()
+		since := c.lastSeq
+		c.mu.Unlock()
+
+		var conn *websocket.Conn
+		conn, err = c.dialPlay(roomID, token, since)
+		if err == nil {
+			c.mu.Lock()
+			if !c.wsActive || c.done != done {
+				c.mu.Unlock()
+				conn.Close()
+				return
+			}
+			c.conn = conn
+			c.rtt = 0
+			c.reconnectToken = "" // used up; the server sends a new one
+			c.resuming = true
+			c.opponentSeq = 0 // a fresh seat on the server counts from 1 again
+			c.mu.Unlock()
+
+			c.logger().Info("reconnected", "room", roomID, "attempts", attempt)
+			c.notify(ReconnectedMsg{Attempts: attempt})
+			c.startPumps(conn)
+			return
+		}
+		if errors.Is(err, errRejected) {
+			break
+		}
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+	c.dropSession(done, err)
+}
+
+// dropSession ends the room session started with done and reports the
+// disconnect, unless the session was already closed.
+func (c *Client) dropSession(done chan struct{}, err error) {
+	c.mu.Lock()
+	if !c.wsActive || c.done != done {
+		c.mu.Unlock()
+		return
+	}
+	c.wsActive = false
+	c.reconnectToken = ""
+	c.pending = nil
+	c.resuming = false
+	close(done)
+	c.mu.Unlock()
+
+	c.logger().Warn("disconnected from room", "err", err)
+	c.sessionChanged(nil)
+	c.notify(DisconnectedMsg{Err: err})
+}
+
+// notify delivers ev on the events channel, giving up once the client is
+// closed.
+func (c *Client) notify(ev Event) {
+	select {
+	case c.events <- ev:
+	case <-c.closed:
+	}
+}
+
+// DisconnectFromRoom gracefully closes the WebSocket without destroying the client.
+func (c *Client) DisconnectFromRoom() {
+	c.mu.Lock()
+	if !c.wsActive {
+		c.mu.Unlock()
+		return
+	}
+	c.wsActive = false
+	c.reconnectToken = ""
+	c.pending = nil
+	c.resuming = false
+
+	// Signal goroutines to stop
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+
+	if c.conn != nil {
+		// WriteControl, unlike WriteMessage, is safe alongside the
+		// writePump that may still be sending.
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeWait))
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.mu.Unlock()
+
+	c.sessionChanged(nil)
+}
+
+// Send stamps an envelope with the next sequence number and the time,
+// and sends it over the active WebSocket. While reconnecting, critical
+// messages are held and sent once the session is resumed.
+func (c *Client) Send(env protocol.Envelope) {
+	c.mu.Lock()
+	if !c.wsActive {
+		c.mu.Unlock()
+		return
+	}
+	c.sendSeq++
+	env.Seq = c.sendSeq
+	now := time.Now()
+	env.TS = now.UnixMilli()
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		c.mu.Unlock()
+		c.logger().Error("marshal failed", "type", env.Type, "err", err)
+		return
+	}
+	msg := outMsg{typ: env.Type, data: data, at: now}
+
+	if c.conn == nil || c.resuming {
+		c.bufferLocked(msg)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	select {
+	case c.sendCh <- msg:
+	default:
+		c.logger().Warn("send queue full, dropping message", "type", env.Type)
+	}
+}
+
+// --- Game messages ---
+
+// SetReady marks the player ready (or not) for the next match.
+func (c *Client) SetReady(ready bool) {
+	c.Send(protocol.Envelope{
+		Type:    protocol.MsgReady,
+		Payload: protocol.ReadyPayload{Ready: ready},
+	})
+}
+
+// MutePlayer mutes or unmutes a player's emotes for the rest of the
+// room. Only the host may; otherwise the server replies with a room error.
+func (c *Client) MutePlayer(playerID string, muted bool) {
+	c.Send(protocol.Envelope{
+		Type:    protocol.MsgMutePlayer,
+		Payload: protocol.MutePlayerPayload{PlayerID: playerID, Muted: muted},
+	})
+}
+
+// UpdateSettings changes the room's match settings. Only the host may,
+// and only in the lobby; otherwise the server replies with a room error.
+func (c *Client) UpdateSettings(s protocol.RoomSettings) {
+	c.Send(protocol.Envelope{Type: protocol.MsgRoomSettings, Payload: s})
+}
+
+// SendBoard reports the player's board, for opponents' views. Send it
+// regularly during a match; the TUI does every 100ms.
+func (c *Client) SendBoard(b protocol.BoardSnapshotPayload) {
+	c.Send(protocol.Envelope{Type: protocol.MsgBoardSnapshot, Payload: b})
+}
+
+// SendAttack sends lines of garbage to the current target, reported as a
+// clear of that many lines without saying what kind: the server sends no
+// more than such a clear could, and nothing in ranked rooms. SendClear
+// says what was cleared.
+func (c *Client) SendAttack(lines int) {
+	c.SendClear(protocol.LinesClearedPayload{
+		Count:       lines, // simplified: count = attack
+		AttackPower: lines,
+	})
+}
+
+// SendClear reports a line clear and the garbage it sends, with what
+// kind of clear it was for the server and the kill feed.
+func (c *Client) SendClear(p protocol.LinesClearedPayload) {
+	c.Send(protocol.Envelope{Type: protocol.MsgLinesCleared, Payload: p})
+}
+
+// SendDead reports that the player topped out.
+func (c *Client) SendDead() {
+	c.Send(protocol.Envelope{
+		Type:    protocol.MsgPlayerDead,
+		Payload: protocol.PlayerDeadPayload{},
+	})
+}
+
+// SetTarget picks who the player's attacks go to. An empty ID means a
+// random opponent.
+func (c *Client) SetTarget(playerID string) {
+	c.Send(protocol.Envelope{
+		Type:    protocol.MsgSetTarget,
+		Payload: protocol.SetTargetPayload{TargetID: playerID},
+	})
+}
+
+// Supports reports whether the server of the current room session
+// supports cap too. It is false until the session's ConnectedMsg.
+func (c *Client) Supports(cap protocol.Capability) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Contains(c.caps, cap)
+}
+
+// SendEmote fires one of protocol.Emotes at the room. Servers without
+// protocol.CapChat would ignore it, so it isn't sent to them.
+func (c *Client) SendEmote(emote string) {
+	if !c.Supports(protocol.CapChat) {
+		return
+	}
+	c.Send(protocol.Envelope{
+		Type:    protocol.MsgEmote,
+		Payload: protocol.EmotePayload{Emote: emote},
+	})
+}
+
+// CreateInvite asks for an invite to the player's room, for the player
+// with the friend code to, or for a link if that's "". The server answers
+// with MsgInviteCreated, or MsgRoomError.
+func (c *Client) CreateInvite(to string) {
+	c.Send(protocol.Envelope{
+		Type:    protocol.MsgCreateInvite,
+		Payload: protocol.CreateInvitePayload{To: to},
+	})
+}
+
+// UseItem reports that the player used an item in item mode. The server
+// fires targeted items at the player's current target.
+func (c *Client) UseItem(item string) {
+	c.Send(protocol.Envelope{
+		Type:    protocol.MsgUseItem,
+		Payload: protocol.UseItemPayload{Item: item},
+	})
+}
+
+// bufferLocked holds msg until the connection is back, if it is worth
+// keeping. Must be called with c.mu held.
+func (c *Client) bufferLocked(msg outMsg) {
+	switch {
+	case msg.typ == protocol.MsgBoardSnapshot:
+		// Only the latest board matters.
+		kept := c.pending[:0]
+		for _, m := range c.pending {
+			if m.typ != protocol.MsgBoardSnapshot {
+				kept = append(kept, m)
+			}
+		}
+		c.pending = append(kept, msg)
+	case bufferedTypes[msg.typ]:
+		if len(c.pending) >= maxPending {
+			c.logger().Warn("reconnect buffer full, dropping message", "type", msg.typ)
+			return
+		}
+		c.pending = append(c.pending, msg)
+	}
+}
+
+// flushLocked sends the held messages if the server kept our seat, and
+// drops them otherwise (a fresh join has no match for them to apply to).
+// Must be called with c.mu held.
+func (c *Client) flushLocked(resumed bool) {
+	pending := c.pending
+	c.pending = nil
+	c.resuming = false
+	if !resumed {
+		return
+	}
+	for _, m := range pending {
+		if m.typ == protocol.MsgBoardSnapshot && time.Since(m.at) > snapshotMaxAge {
+			continue
+		}
+		select {
+		case c.sendCh <- m:
+		default:
+			c.logger().Warn("send queue full, dropping message", "type", m.typ)
+		}
+	}
+}
+
+// Close shuts down the client entirely.
+func (c *Client) Close() {
+	c.DisconnectFromRoom()
+	c.closeOnce.Do(func() { close(c.closed) })
+}
+
+// RTT returns the round-trip time measured by the most recent heartbeat.
+func (c *Client) RTT() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rtt
+}
+
+// IsWSActive returns whether a WebSocket connection is active.
+func (c *Client) IsWSActive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.wsActive
+}
+
+// --- Pumps ---
+
+// readPump reads messages from conn and reports them as events. When the connection drops unexpectedly it starts reconnecting,
+// or reports the disconnect if there is no reconnect token.
+func (c *Client) readPump(conn *websocket.Conn, connDone chan struct{}) {
+	defer func() {
+		close(connDone)
+
+		c.mu.Lock()
+		active := c.wsActive && c.conn == conn // false = intentional disconnect, don't notify
+		if active {
+			c.conn = nil
+			// Anything the write pump hadn't got to waits for the reconnect.
+			for len(c.sendCh) > 0 {
+				c.bufferLocked(<-c.sendCh)
+			}
+		}
+		roomID, token, done := c.roomID, c.reconnectToken, c.done
+		c.mu.Unlock()
+
+		if !active {
+			return
+		}
+		if token != "" {
+			go c.reconnect(roomID, token, done)
+			return
+		}
+		c.dropSession(done, nil)
+	}()
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(appData string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		// Our pings carry their send time; the server echoes it back.
+		sentAt, err := strconv.ParseInt(appData, 10, 64)
+		if err != nil {
+			return nil
+		}
+		rtt := time.Since(time.Unix(0, sentAt))
+
+		c.mu.Lock()
+		c.rtt = rtt
+		c.mu.Unlock()
+		c.notify(ConnStatusMsg{Status: ConnConnected, RTT: rtt})
+		return nil
+	})
+
+	c.notify(ConnStatusMsg{Status: ConnConnected, RTT: c.RTT()})
+
+	// With a network simulation, messages go through a delay line first.
+	handle := c.handleMessage
+	if c.simRecv != nil {
+		in := make(chan simMsg, 256)
+		defer close(in)
+		go c.simRecv.deliver(in, c.handleMessage)
+		handle = func(message []byte) {
+			if at, ok := c.simRecv.schedule(time.Now()); ok {
+				in <- simMsg{data: message, at: at}
+			}
+		}
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				c.logger().Warn("connection lost", "err", err)
+			}
+			return
+		}
+		handle(message)
+	}
+}
+
+// handleMessage decodes one server message and reports it. Opponent
+// updates older than one already reported are dropped, since they would
+// only show boards going back in time.
+func (c *Client) handleMessage(message []byte) {
+	// With CapBatch, a frame can hold several envelopes in an array.
+	if len(message) > 0 && message[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(message, &batch); err != nil {
+			c.logger().Warn("unreadable message from server", "err", err)
+			return
+		}
+		for _, m := range batch {
+			c.handleMessage(m)
+		}
+		return
+	}
+
+	var env protocol.RawEnvelope
+	if err := json.Unmarshal(message, &env); err != nil {
+		c.logger().Warn("unreadable message from server", "err", err)
+		return
+	}
+	c.mu.Lock()
+	c.lastSeq = max(c.lastSeq, env.Seq)
+	c.mu.Unlock()
+
+	var sent time.Time
+	if env.TS != 0 {
+		sent = time.UnixMilli(env.TS)
+		c.logger().Debug("received", "type", env.Type, "bytes", len(message), "seq", env.Seq, "delay", time.Since(sent))
+	} else {
+		c.logger().Debug("received", "type", env.Type, "bytes", len(message))
+	}
+
+	switch env.Type {
+	case protocol.MsgAssignID:
+		if payload, err := protocol.DecodePayload[protocol.AssignIDPayload](env.Type, env.Payload); err == nil {
+			serverCaps := payload.Capabilities
+			if serverCaps == nil {
+				serverCaps = protocol.LegacyCapabilities
+			}
+			c.mu.Lock()
+			c.caps = nil
+			for _, cap := range serverCaps {
+				if slices.Contains(Capabilities, cap) {
+					c.caps = append(c.caps, cap)
+				}
+			}
+			c.reconnectToken = payload.ReconnectToken
+			if !payload.Resumed {
+				c.lastSeq = env.Seq // a fresh seat counts from here
+			}
+			c.flushLocked(payload.Resumed)
+			session := c.sessionLocked()
+			c.mu.Unlock()
+			c.sessionChanged(session)
+			c.notify(ConnectedMsg{PlayerID: payload.PlayerID, Resumed: payload.Resumed, Missed: payload.Missed, MOTD: payload.MOTD})
+		}
+	case protocol.MsgOpponentUpdate:
+		c.mu.Lock()
+		stale := env.Seq != 0 && env.Seq <= c.opponentSeq
+		if !stale {
+			c.opponentSeq = env.Seq
+		}
+		c.mu.Unlock()
+		if stale {
+			c.logger().Debug("dropping stale opponent update", "seq", env.Seq)
+			return
+		}
+		c.notify(ServerMsg{Type: env.Type, Raw: env.Payload, Seq: env.Seq, Sent: sent})
+	default:
+		c.notify(ServerMsg{Type: env.Type, Raw: env.Payload, Seq: env.Seq, Sent: sent})
+	}
+}
+
+// writePump writes messages from sendCh to conn until the session ends or
+// the connection's read side gives up (connDone).
+func (c *Client) writePump(conn *websocket.Conn, connDone chan struct{}) {
+	c.mu.Lock()
+	sendCh := c.sendCh
+	done := c.done
+	c.mu.Unlock()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-sendCh:
+			if ok && c.simSend != nil {
+				at, keep := c.simSend.schedule(msg.at)
+				if !keep {
+					continue
+				}
+				select {
+				case <-time.After(time.Until(at)):
+				case <-done:
+					return
+				case <-connDone:
+					return
+				}
+			}
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, msg.data); err != nil {
+				// Lost with the connection; hold it for the reconnect.
+				c.mu.Lock()
+				if c.wsActive {
+					c.bufferLocked(msg)
+				}
+				c.mu.Unlock()
+				return
+			}
+			c.logger().Debug("sent", "type", msg.typ, "bytes", len(msg.data))
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			stamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+			if err := conn.WriteMessage(websocket.PingMessage, []byte(stamp)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		case <-connDone:
+			return
+		}
+	}
+}
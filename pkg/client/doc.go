@@ -0,0 +1,37 @@
+// Package client talks to a gotris server, for bots, alternative frontends
+// and integration tests. It is the same client the gotris TUI uses.
+//
+// Rooms are created, listed and joined over HTTP; play happens over a
+// WebSocket that the client keeps alive, reconnecting on its own if it
+// drops. Everything the server says arrives on the Events channel, which
+// must be drained while in a room:
+//
+//	c := client.New("localhost:8080")
+//	defer c.Close()
+//
+//	roomID, err := c.Create("bot")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	log.Printf("playing in room %s", roomID)
+//	c.SetReady(true)
+//
+//	for ev := range c.Events() {
+//		switch ev := ev.(type) {
+//		case client.ServerMsg:
+//			switch ev.Type {
+//			case protocol.MsgGameStart:
+//				// start playing: SendBoard, SendAttack, SendDead, ...
+//			case protocol.MsgReceiveGarbage:
+//				p, _ := protocol.DecodePayload[protocol.ReceiveGarbagePayload](ev.Type, ev.Raw)
+//				log.Printf("%d lines of garbage incoming", p.Lines)
+//			}
+//		case client.DisconnectedMsg:
+//			return
+//		}
+//	}
+//
+// The message payloads are defined in package protocol, and
+// protocol.DecodePayload decodes them, checking the type is the right one
+// for the message.
+package client
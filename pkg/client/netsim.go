@@ -0,0 +1,66 @@
+package client
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// NetSim describes a bad network for the client to imitate, so the game's
+// handling of lag and loss can be tried out without external tools. Every
+// message sent or received is held back by Latency plus up to Jitter, and
+// dropped with probability Drop. Messages keep their order, as over TCP.
+// Heartbeat pings queue behind delayed sends, so the RTT shown grows too.
+type NetSim struct {
+	Latency time.Duration
+	Jitter  time.Duration
+	Drop    float64 // 0 to 1
+}
+
+// netSim applies a NetSim to one direction of traffic.
+type netSim struct {
+	NetSim
+	mu   sync.Mutex
+	rng  *rand.Rand
+	last time.Time // latest delivery time handed out
+}
+
+func newNetSim(cfg NetSim) *netSim {
+	return &netSim{NetSim: cfg, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// simMsg is a received message waiting in the delay line.
+type simMsg struct {
+	data []byte
+	at   time.Time
+}
+
+// schedule returns when a message that came along at t should go through,
+// or false if it is dropped.
+func (s *netSim) schedule(t time.Time) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Drop > 0 && s.rng.Float64() < s.Drop {
+		return time.Time{}, false
+	}
+	at := t.Add(s.Latency)
+	if s.Jitter > 0 {
+		at = at.Add(time.Duration(s.rng.Int63n(int64(s.Jitter) + 1)))
+	}
+	// No overtaking: a short delay waits for the message ahead of it.
+	if at.Before(s.last) {
+		at = s.last
+	}
+	s.last = at
+	return at, true
+}
+
+// deliver passes each message from in to handle at its delivery time,
+// until in is closed.
+func (s *netSim) deliver(in <-chan simMsg, handle func([]byte)) {
+	for m := range in {
+		time.Sleep(time.Until(m.at))
+		handle(m.data)
+	}
+}
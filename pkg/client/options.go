@@ -0,0 +1,140 @@
+package client
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultDialTimeout = 10 * time.Second
+	defaultHTTPTimeout = 10 * time.Second
+
+	// HTTP calls are retried with the same jittered backoff as reconnects,
+	// just shorter.
+	defaultRetryAttempts = 3
+	defaultRetryDelay    = 250 * time.Millisecond
+	retryMaxDelay        = 2 * time.Second
+)
+
+// Option configures a Client; pass them to New.
+type Option func(*config)
+
+type config struct {
+	tlsConfig   *tls.Config
+	dialTimeout time.Duration
+	httpTimeout time.Duration
+	header      http.Header
+	proxy       func(*http.Request) (*url.URL, error)
+	retry       retryConfig
+	onSession   func(*Session)
+	sim         NetSim
+	logger      *slog.Logger
+}
+
+type retryConfig struct {
+	attempts int           // tries per call, including the first
+	delay    time.Duration // wait before the first retry, doubling after
+}
+
+// WithTLSConfig sets the TLS configuration for https:// and wss://
+// servers, e.g. to trust a private server's self-signed CA.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *config) { c.tlsConfig = cfg }
+}
+
+// WithDialTimeout limits how long connecting (TCP, TLS and the WebSocket
+// handshake) may take. The default is 10s.
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *config) { c.dialTimeout = d }
+}
+
+// WithHTTPTimeout limits how long each HTTP request (create, join, list)
+// may take in total. The default is 10s.
+func WithHTTPTimeout(d time.Duration) Option {
+	return func(c *config) { c.httpTimeout = d }
+}
+
+// WithHeader adds a header to every HTTP request and WebSocket handshake,
+// e.g. for an authenticating reverse proxy in front of the server.
+func WithHeader(key, value string) Option {
+	return func(c *config) { c.header.Add(key, value) }
+}
+
+// WithRetry sets how many times an HTTP call is tried before giving up
+// (1 means no retries) and the delay before the first retry, which
+// doubles for each one after. Only failures that are safe to repeat are
+// retried; a negative delay counts as none. The default is 3 tries
+// starting at 250ms.
+func WithRetry(attempts int, delay time.Duration) Option {
+	return func(c *config) { c.retry = retryConfig{attempts: max(attempts, 1), delay: max(delay, 0)} }
+}
+
+// Session identifies a room session that can be picked up again with
+// Client.Resume.
+type Session struct {
+	Server string // HTTP base URL
+	RoomID string
+	Token  string // reconnect token, good for one use
+}
+
+// WithSessionHook calls fn each time the session changes, so it can be
+// saved and resumed after a crash: with the new session whenever the
+// server hands out a reconnect token, and with nil when the session ends
+// on purpose or for good. fn runs on the client's network goroutines and
+// should not block for long.
+func WithSessionHook(fn func(*Session)) Option {
+	return func(c *config) { c.onSession = fn }
+}
+
+// WithLogger sets where the client logs to. Without it, the client uses
+// slog.Default() at the time of each message. Connection changes are
+// logged at Info, dropped messages at Warn, and every message sent
+// and received and each HTTP retry at Debug.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) { c.logger = l }
+}
+
+// WithNetSim makes the client imitate a bad network; see NetSim.
+func WithNetSim(sim NetSim) Option {
+	return func(c *config) { c.sim = sim }
+}
+
+// WithProxy sends all HTTP requests and the game WebSocket through the
+// proxy at proxyURL, which may be http:// (using CONNECT) or socks5://,
+// with credentials in the URL if the proxy needs them. Without this
+// option the client uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the
+// environment; a nil URL turns proxying off.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(c *config) {
+		if proxyURL == nil {
+			c.proxy = nil
+			return
+		}
+		c.proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// transports builds the HTTP client and WebSocket dialer for cfg.
+func (cfg *config) transports() (*http.Client, *websocket.Dialer) {
+	netDialer := &net.Dialer{Timeout: cfg.dialTimeout, KeepAlive: 30 * time.Second}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = cfg.proxy
+	transport.DialContext = netDialer.DialContext
+	transport.TLSClientConfig = cfg.tlsConfig
+	transport.TLSHandshakeTimeout = cfg.dialTimeout
+
+	dialer := &websocket.Dialer{
+		Proxy:            cfg.proxy,
+		NetDialContext:   netDialer.DialContext,
+		TLSClientConfig:  cfg.tlsConfig,
+		HandshakeTimeout: cfg.dialTimeout,
+	}
+	return &http.Client{Timeout: cfg.httpTimeout, Transport: transport}, dialer
+}
@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// RawEnvelope is an Envelope as received, with the payload left as JSON
+// until the message type says what it holds.
+type RawEnvelope struct {
+	Type    MessageType     `json:"type"`
+	Seq     uint64          `json:"seq,omitempty"`
+	TS      int64           `json:"ts,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Errors from Decode and DecodePayload, for errors.Is.
+var (
+	ErrUnknownType  = errors.New("unknown message type")
+	ErrWrongPayload = errors.New("wrong payload type for message")
+)
+
+// payloadTypes maps each message type to the type of its payload. Every
+// MessageType must be listed here: decoding a message whose type isn't
+// fails, as does decoding into any other type than the one listed.
+var payloadTypes = map[MessageType]reflect.Type{
+	// Server -> Client
+	MsgAssignID:       reflect.TypeFor[AssignIDPayload](),
+	MsgGameStart:      reflect.TypeFor[GameStartPayload](),
+	MsgCountdown:      reflect.TypeFor[CountdownPayload](),
+	MsgOpponentUpdate: reflect.TypeFor[OpponentUpdatePayload](),
+	MsgReceiveGarbage: reflect.TypeFor[ReceiveGarbagePayload](),
+	MsgGameOver:       reflect.TypeFor[GameOverPayload](),
+	MsgLobbyUpdate:    reflect.TypeFor[LobbyUpdatePayload](),
+	MsgMatchOver:      reflect.TypeFor[MatchOverPayload](),
+	MsgRoomCreated:    reflect.TypeFor[RoomCreatedPayload](),
+	MsgRoomJoined:     reflect.TypeFor[RoomJoinedPayload](),
+	MsgRoomError:      reflect.TypeFor[RoomErrorPayload](),
+	MsgAutoStart:      reflect.TypeFor[AutoStartPayload](),
+	MsgMatchEvent:     reflect.TypeFor[MatchEventPayload](),
+	MsgItemEffect:     reflect.TypeFor[ItemEffectPayload](),
+	MsgItemGranted:    reflect.TypeFor[ItemGrantedPayload](),
+	MsgInviteCreated:  reflect.TypeFor[InviteCreatedPayload](),
+	MsgLevelUp:        reflect.TypeFor[LevelUpPayload](),
+
+	// Both ways
+	MsgEmote: reflect.TypeFor[EmotePayload](),
+
+	// Room event stream
+	MsgAttack: reflect.TypeFor[AttackPayload](),
+
+	// Client -> Server
+	MsgJoin:          reflect.TypeFor[JoinPayload](),
+	MsgReady:         reflect.TypeFor[ReadyPayload](),
+	MsgBoardSnapshot: reflect.TypeFor[BoardSnapshotPayload](),
+	MsgLinesCleared:  reflect.TypeFor[LinesClearedPayload](),
+	MsgPlayerDead:    reflect.TypeFor[PlayerDeadPayload](),
+	MsgCreateRoom:    reflect.TypeFor[CreateRoomPayload](),
+	MsgJoinRoom:      reflect.TypeFor[JoinRoomPayload](),
+	MsgLeaveRoom:     reflect.TypeFor[LeaveRoomPayload](),
+	MsgSetName:       reflect.TypeFor[SetNamePayload](),
+	MsgSetTarget:     reflect.TypeFor[SetTargetPayload](),
+	MsgRoomSettings:  reflect.TypeFor[RoomSettings](),
+	MsgUseItem:       reflect.TypeFor[UseItemPayload](),
+	MsgMutePlayer:    reflect.TypeFor[MutePlayerPayload](),
+	MsgCreateInvite:  reflect.TypeFor[CreateInvitePayload](),
+}
+
+// PayloadType returns the payload type registered for t.
+func PayloadType(t MessageType) (reflect.Type, bool) {
+	rt, ok := payloadTypes[t]
+	return rt, ok
+}
+
+// Decode decodes the payload of raw, a whole message as read off the
+// wire, as a T. T must be the payload type registered for the message's
+// type.
+func Decode[T any](raw []byte) (T, error) {
+	var env RawEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		var zero T
+		return zero, err
+	}
+	return DecodePayload[T](env.Type, env.Payload)
+}
+
+// DecodePayload decodes the payload of a message of type t as a T, which
+// must be the payload type registered for t. A missing or null payload
+// decodes as T's zero value.
+func DecodePayload[T any](t MessageType, payload json.RawMessage) (T, error) {
+	var v T
+	want, ok := payloadTypes[t]
+	if !ok {
+		return v, fmt.Errorf("%w %q", ErrUnknownType, t)
+	}
+	if got := reflect.TypeFor[T](); got != want {
+		return v, fmt.Errorf("%w %q: want %v, got %v", ErrWrongPayload, t, want, got)
+	}
+	if len(payload) == 0 {
+		return v, nil
+	}
+	err := json.Unmarshal(payload, &v)
+	return v, err
+}
@@ -0,0 +1,803 @@
+// Package protocol defines the messages exchanged between gotris clients
+// and the server: the WebSocket envelopes and payloads, and the HTTP
+// request and response bodies.
+package protocol
+
+// MessageType identifies the kind of message sent over the wire.
+type MessageType string
+
+const (
+	// Server -> Client messages
+	MsgAssignID       MessageType = "assign_id"
+	MsgGameStart      MessageType = "game_start"
+	MsgCountdown      MessageType = "countdown"
+	MsgOpponentUpdate MessageType = "opponent_update"
+	MsgReceiveGarbage MessageType = "receive_garbage"
+	MsgGameOver       MessageType = "game_over"
+	MsgLobbyUpdate    MessageType = "lobby_update"
+	MsgMatchOver      MessageType = "match_over"
+	MsgRoomCreated    MessageType = "room_created"
+	MsgRoomJoined     MessageType = "room_joined"
+	MsgRoomError      MessageType = "room_error"
+	MsgAutoStart      MessageType = "auto_start"
+	MsgMatchEvent     MessageType = "match_event"
+	MsgItemEffect     MessageType = "item_effect"    // item mode: an opponent used an item on you
+	MsgItemGranted    MessageType = "item_granted"   // item mode: a clear earned you an item
+	MsgInviteCreated  MessageType = "invite_created" // answers MsgCreateInvite
+	MsgLevelUp        MessageType = "level_up"       // speed race: everyone's level goes up
+
+	// Both ways: a player fires an emote, the server passes it to the room
+	MsgEmote MessageType = "emote"
+
+	// Server -> room event stream (GET /rooms/{code}/events) only
+	MsgAttack MessageType = "attack"
+
+	// Client -> Server messages
+	MsgJoin          MessageType = "join"
+	MsgReady         MessageType = "ready"
+	MsgBoardSnapshot MessageType = "board_snapshot"
+	MsgLinesCleared  MessageType = "lines_cleared"
+	MsgPlayerDead    MessageType = "player_dead"
+	MsgCreateRoom    MessageType = "create_room"
+	MsgJoinRoom      MessageType = "join_room"
+	MsgLeaveRoom     MessageType = "leave_room"
+	MsgSetName       MessageType = "set_name"
+	MsgSetTarget     MessageType = "set_target"
+	MsgRoomSettings  MessageType = "room_settings" // host only, lobby only
+	MsgUseItem       MessageType = "use_item"      // item mode, during a match
+	MsgMutePlayer    MessageType = "mute_player"   // host only
+	MsgCreateInvite  MessageType = "create_invite" // from a player in a room
+)
+
+// ErrorCode says what went wrong in an ErrorResponse or RoomErrorPayload,
+// so clients can react to (and word) an error without parsing its
+// message. The message is English and meant for logs. Older servers send
+// no code.
+type ErrorCode string
+
+const (
+	ErrCodeBadRequest      ErrorCode = "bad_request"      // malformed request body or query
+	ErrCodeRoomNotFound    ErrorCode = "room_not_found"   // no room with that code
+	ErrCodeRoomFull        ErrorCode = "room_full"        // room is at max players
+	ErrCodeInProgress      ErrorCode = "game_in_progress" // room is mid-match, can't join
+	ErrCodeInvalidToken    ErrorCode = "invalid_token"    // join/reconnect token or invite unknown, used or expired
+	ErrCodeTokenMismatch   ErrorCode = "token_mismatch"   // token is for a different room
+	ErrCodeNotHost         ErrorCode = "not_host"         // only the host may do that
+	ErrCodeNotInLobby      ErrorCode = "not_in_lobby"     // only allowed between matches
+	ErrCodeInvalidSettings ErrorCode = "invalid_settings" // a room setting is out of range or unknown
+	ErrCodeTooManyPlayers  ErrorCode = "too_many_players" // max players below the players already in
+	ErrCodeServerFull      ErrorCode = "server_full"      // at the server's room or connection cap; try again later
+	ErrCodeBanned          ErrorCode = "banned"           // the player's address is banned from the server
+	ErrCodeUnauthorized    ErrorCode = "unauthorized"     // admin API: missing or wrong admin token
+	ErrCodeNotFound        ErrorCode = "not_found"        // admin API: no such ban or player; GET /profile: no profile for the key; DELETE /invites: no such invite
+	ErrCodeRankedRoom      ErrorCode = "ranked_room"      // ranked rooms play with fixed settings
+	ErrCodeRateLimited     ErrorCode = "rate_limited"     // too many rooms or profile keys created from the address lately; try again later
+)
+
+// Capability is an optional protocol feature. A client lists the ones it
+// supports in the caps query parameter of /play (comma-separated), and
+// the server lists its own in AssignIDPayload; each side then only uses
+// features both have, so newer and older peers degrade gracefully rather
+// than sending messages the other ignores.
+type Capability string
+
+const (
+	CapReconnect Capability = "reconnect" // reconnect tokens and held seats
+	CapChat      Capability = "chat"      // MsgEmote quick chat
+	// CapBatch lets the server send several envelopes in one WebSocket
+	// frame, as a JSON array, instead of one frame each.
+	CapBatch Capability = "batch"
+)
+
+// LegacyCapabilities are what a peer from before capability flags
+// supports: a client that sends no caps, or a server whose AssignID has
+// none.
+var LegacyCapabilities = []Capability{CapReconnect}
+
+// Targeting modes for RoomSettings.Targeting.
+const (
+	TargetingFree   = "free"   // players pick targets (tab / number keys)
+	TargetingRandom = "random" // all garbage goes to a random opponent
+)
+
+// What a new player joining does to a running lobby auto-start timer,
+// for RoomSettings.OnJoin.
+const (
+	OnJoinReset = "reset" // restart the timer from the top
+	OnJoinPause = "pause" // hold the timer until the newcomer is ready
+)
+
+// RoomSettings are the match options the room host configures in the lobby.
+type RoomSettings struct {
+	MaxPlayers  int    `json:"max_players"`
+	Targeting   string `json:"targeting"`
+	AttackTable string `json:"attack_table"` // garbage preset, see game.AttackTables
+	Randomizer  string `json:"randomizer"`   // "7bag" or "random"
+	OnJoin      string `json:"on_join"`      // OnJoinReset or OnJoinPause
+	// Classic-style pacing: entry delay (ARE) before each new piece, and
+	// how long cleared lines stay up before collapsing. 0 = none.
+	EntryDelayMs     int `json:"entry_delay_ms,omitempty"`
+	LineClearDelayMs int `json:"line_clear_delay_ms,omitempty"`
+	// AllSpin gives spins by any piece bonus attack, not just T-spins.
+	AllSpin bool `json:"all_spin,omitempty"`
+	// GarbageStyle is where received garbage gets its holes, one of
+	// game.GarbageStyles. Empty means clean.
+	GarbageStyle string `json:"garbage_style,omitempty"`
+	// Items turns on item mode: clears sometimes earn items (game.Items)
+	// to use on yourself or your target.
+	Items bool `json:"items,omitempty"`
+	// HoldMode is one of game.HoldModes. Empty means normal.
+	HoldMode string `json:"hold_mode,omitempty"`
+	// Seed, if set, fixes the match seed, so every match in the room deals
+	// the same pieces (see game.SeedFromText). Empty means a new random
+	// seed each match.
+	Seed string `json:"seed,omitempty"`
+	// Bots is how many seats the server fills with CPU opponents.
+	Bots int `json:"bots,omitempty"`
+	// PointsTarget turns on points play: each match awards points by
+	// placement (PlacementPoints, first place first; empty means the
+	// server's default) plus KOPoints per knockout, the totals carry over
+	// from match to match, and the first to reach PointsTarget wins the
+	// room's series. 0 = off.
+	PointsTarget    int   `json:"points_target,omitempty"`
+	PlacementPoints []int `json:"placement_points,omitempty"`
+	KOPoints        int   `json:"ko_points,omitempty"`
+	// SpeedRaceSecs turns on a speed race: levels don't come from lines
+	// cleared, and instead the server raises everyone's level together,
+	// one every SpeedRaceSecs seconds (MsgLevelUp), so all players face
+	// the same gravity. 0 = off.
+	SpeedRaceSecs int `json:"speed_race_secs,omitempty"`
+}
+
+// Envelope is the top-level wire format for all messages.
+//
+// Seq and TS are stamped by the sender and may be missing (zero) from
+// older peers. Seq counts the messages one side has sent the other, from
+// 1, so a receiver can spot reordered or stale messages; the server keeps
+// counting across a player's reconnects. TS is the send time in Unix
+// milliseconds by the sender's clock, for measuring (skewed) one-way
+// delay and for timing logs.
+type Envelope struct {
+	Type    MessageType `json:"type"`
+	Seq     uint64      `json:"seq,omitempty"`
+	TS      int64       `json:"ts,omitempty"`
+	Payload interface{} `json:"payload"`
+}
+
+// --- Server -> Client payloads ---
+
+// AssignIDPayload is sent when a client first connects.
+type AssignIDPayload struct {
+	PlayerID string `json:"player_id"`
+	// ReconnectToken lets the client rejoin the room as the same player
+	// (via /play) if its connection drops.
+	ReconnectToken string `json:"reconnect_token,omitempty"`
+	// Resumed is set when the player reconnected into the seat they held,
+	// rather than joining afresh.
+	Resumed bool `json:"resumed,omitempty"`
+	// Missed is set on a resume when some of the critical messages sent
+	// while the player was away are no longer kept, so what the client
+	// was sent again (since= on /play) leaves its game out of step.
+	Missed bool `json:"missed,omitempty"`
+	// Capabilities lists the optional features the server supports.
+	Capabilities []Capability `json:"capabilities,omitempty"`
+	// MOTD is the server's message of the day, if it has one.
+	MOTD string `json:"motd,omitempty"`
+}
+
+// GameStartPayload tells all clients to begin the game.
+type GameStartPayload struct {
+	Seed     int64        `json:"seed"`
+	Players  []string     `json:"players"` // list of player IDs in the match
+	Settings RoomSettings `json:"settings"`
+}
+
+// CountdownPayload carries the countdown tick value.
+type CountdownPayload struct {
+	Value int `json:"value"`
+}
+
+// AutoStartPayload reports the lobby auto-start timer, which runs once
+// enough players are ready and starts the match when it reaches zero.
+type AutoStartPayload struct {
+	Seconds int  `json:"seconds"` // seconds left, 0 = timer not running
+	Paused  bool `json:"paused"`  // held for a player who just joined
+}
+
+// OpponentState is a compressed snapshot of one opponent's board.
+type OpponentState struct {
+	PlayerID   string `json:"player_id"`
+	PlayerName string `json:"player_name"`
+	Score      int    `json:"score"`
+	Level      int    `json:"level"`
+	Lines      int    `json:"lines"`
+	Alive      bool   `json:"alive"`
+	IsWinner   bool   `json:"is_winner"`
+	// Garbage lines traded this match between this opponent and the recipient.
+	SentToYou int `json:"sent_to_you"`
+	YouSent   int `json:"you_sent"`
+	// Board is a flat array: BoardHeight * BoardWidth cells.
+	// Each value is a color index (0 = empty). It is left out when the
+	// update only carries this opponent's stats.
+	Board []int `json:"board,omitempty"`
+}
+
+// OpponentUpdatePayload carries snapshots of all opponents. In large
+// rooms, only some opponents come with their board: the recipient's
+// target, whoever last attacked them, and a sample that changes from one
+// update to the next. The rest come with their stats alone.
+type OpponentUpdatePayload struct {
+	Opponents []OpponentState `json:"opponents"`
+	// Remaining is how many players are still alive, the recipient
+	// included.
+	Remaining int `json:"remaining"`
+}
+
+// AttackPayload is garbage one player sent another, as seen by room
+// event stream watchers.
+type AttackPayload struct {
+	AttackerID   string `json:"attacker_id"`
+	AttackerName string `json:"attacker_name"`
+	TargetID     string `json:"target_id"`
+	TargetName   string `json:"target_name"`
+	Lines        int    `json:"lines"`
+}
+
+// ReceiveGarbagePayload tells a client to buffer incoming garbage.
+type ReceiveGarbagePayload struct {
+	Lines      int    `json:"lines"`
+	AttackerID string `json:"attacker_id"`
+}
+
+// Kinds of MatchEventPayload.
+const (
+	EventTetris = "tetris" // Player cleared four lines at once
+	EventAttack = "attack" // Player made a T-spin, back-to-back or long combo
+	EventItem   = "item"   // Player used Item on By
+	EventKO     = "ko"     // Player was knocked out by By
+	EventOut    = "out"    // Player topped out with no one to credit
+	// Player's board changed in a way no real game could
+	EventSuspect = "suspect"
+)
+
+// MatchEventPayload is a notable in-match event for the kill feed.
+type MatchEventPayload struct {
+	Kind       string `json:"kind"`
+	PlayerID   string `json:"player_id"`
+	PlayerName string `json:"player_name"`
+	ByID       string `json:"by_id,omitempty"`
+	ByName     string `json:"by_name,omitempty"`
+
+	// What a Tetris or attack event's clear was, as in LinesClearedPayload.
+	ClearType string `json:"clear_type,omitempty"`
+	Combo     int    `json:"combo,omitempty"`
+	B2B       bool   `json:"b2b,omitempty"`
+
+	Item string `json:"item,omitempty"` // for EventItem
+}
+
+// Emotes for EmotePayload.Emote: quick predefined messages players can
+// fire mid-match without typing.
+const (
+	EmoteGLHF    = "glhf"
+	EmoteGG      = "gg"
+	EmoteNice    = "nice"
+	EmoteOops    = "oops"
+	EmoteBringIt = "bring_it"
+)
+
+// Emotes lists the valid emotes in hotkey order.
+var Emotes = []string{EmoteGLHF, EmoteGG, EmoteNice, EmoteOops, EmoteBringIt}
+
+// EmotePayload carries an emote. Clients send just the Emote; the server
+// fills in who sent it when passing it on to everyone in the room.
+type EmotePayload struct {
+	Emote    string `json:"emote"`
+	PlayerID string `json:"player_id,omitempty"`
+}
+
+// UseItemPayload fires an item at the player's current target.
+type UseItemPayload struct {
+	Item string `json:"item"`
+}
+
+// MutePlayerPayload is the host muting or unmuting a player's emotes for
+// the rest of the room.
+type MutePlayerPayload struct {
+	PlayerID string `json:"player_id"`
+	Muted    bool   `json:"muted"`
+}
+
+// ItemEffectPayload tells a player an item hit them, for their engine to
+// apply.
+type ItemEffectPayload struct {
+	Item   string `json:"item"`
+	FromID string `json:"from_id"`
+}
+
+// ItemGrantedPayload tells a player a clear earned them an item, which
+// they hold until they fire it with MsgUseItem. The server only fires
+// items it has given.
+type ItemGrantedPayload struct {
+	Item string `json:"item"`
+}
+
+// LevelUpPayload tells the players of a speed race the level everyone is
+// now on.
+type LevelUpPayload struct {
+	Level int `json:"level"`
+}
+
+// GameOverPayload informs a client that the match ended.
+type GameOverPayload struct {
+	WinnerID   string `json:"winner_id"`
+	WinnerName string `json:"winner_name"`
+}
+
+// LobbyPlayer is one player entry in a lobby update.
+type LobbyPlayer struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	Ready    bool   `json:"ready"`
+	Muted    bool   `json:"muted,omitempty"` // the host has muted their emotes
+	Bot      bool   `json:"bot,omitempty"`   // a CPU opponent run by the server
+}
+
+// LobbyUpdatePayload is sent whenever the lobby state changes.
+type LobbyUpdatePayload struct {
+	Players  []LobbyPlayer `json:"players"`
+	HostID   string        `json:"host_id"`
+	Settings RoomSettings  `json:"settings"`
+	RoomType string        `json:"room_type,omitempty"` // RoomTypeCasual or RoomTypeRanked
+}
+
+// PlayerStanding is one row of the final match standings.
+type PlayerStanding struct {
+	PlayerID   string `json:"player_id"`
+	PlayerName string `json:"player_name"`
+	Rank       int    `json:"rank"`
+	Score      int    `json:"score"`
+	Lines      int    `json:"lines"`
+	KOs        int    `json:"kos"`
+	SurvivalMs int64  `json:"survival_ms"`    // time from game start until knocked out (or match end)
+	Sent       int    `json:"sent,omitempty"` // garbage lines sent to opponents
+	// How far into the match the player made their first Tetris, 0 if
+	// they made none.
+	FirstTetrisMs int64 `json:"first_tetris_ms,omitempty"`
+	// In points play, the points earned this match and the player's total
+	// so far.
+	Points      int `json:"points,omitempty"`
+	TotalPoints int `json:"total_points,omitempty"`
+}
+
+// MatchOverPayload is sent when the match concludes (last player standing).
+type MatchOverPayload struct {
+	WinnerID   string           `json:"winner_id"`
+	WinnerName string           `json:"winner_name"`
+	YourRank   int              `json:"your_rank"`
+	Standings  []PlayerStanding `json:"standings,omitempty"` // sorted by rank
+	// In points play, the room's target, and who won the series if this
+	// match took them to it. The totals start over after a series is won.
+	PointsTarget int    `json:"points_target,omitempty"`
+	ChampionID   string `json:"champion_id,omitempty"`
+	ChampionName string `json:"champion_name,omitempty"`
+	// The match's awards, in the order of the Award* kinds. A match with
+	// one player has none.
+	Awards []MatchAward `json:"awards,omitempty"`
+}
+
+// Award kinds, for MatchAward.Kind.
+const (
+	AwardMostSent        = "most_sent"        // Value: garbage lines sent
+	AwardMostKOs         = "most_kos"         // Value: KOs
+	AwardFastestTetris   = "fastest_tetris"   // Value: ms into the match
+	AwardLongestSurvival = "longest_survival" // Value: ms survived
+)
+
+// MatchAward is a standout performance in a match and the player who
+// made it.
+type MatchAward struct {
+	Kind       string `json:"kind"`
+	PlayerID   string `json:"player_id"`
+	PlayerName string `json:"player_name"`
+	Value      int64  `json:"value"`
+}
+
+// --- Client -> Server payloads ---
+
+// JoinPayload is sent when a client wants to join the match.
+type JoinPayload struct {
+	PlayerName string `json:"player_name"`
+}
+
+// ReadyPayload toggles ready status.
+type ReadyPayload struct {
+	Ready bool `json:"ready"`
+}
+
+// BoardSnapshotPayload is the client's current board state.
+type BoardSnapshotPayload struct {
+	Score int   `json:"score"`
+	Level int   `json:"level"`
+	Lines int   `json:"lines"`
+	Alive bool  `json:"alive"`
+	Board []int `json:"board"` // flat array, BoardHeight * BoardWidth
+}
+
+// Clear types for LinesClearedPayload.ClearType.
+const (
+	ClearSingle      = "single"
+	ClearDouble      = "double"
+	ClearTriple      = "triple"
+	ClearTetris      = "tetris"
+	ClearTSpinSingle = "tspin_single"
+	ClearTSpinDouble = "tspin_double"
+	ClearTSpinTriple = "tspin_triple"
+)
+
+// ClearTypeFor names a clear of lines rows, made by a T-spin or not. It
+// returns "" for counts no piece can clear.
+func ClearTypeFor(lines int, tspin bool) string {
+	names := []string{ClearSingle, ClearDouble, ClearTriple, ClearTetris}
+	if tspin {
+		names = []string{ClearTSpinSingle, ClearTSpinDouble, ClearTSpinTriple}
+	}
+	if lines < 1 || lines > len(names) {
+		return ""
+	}
+	return names[lines-1]
+}
+
+// ClearTypeLines is how many rows a clear type clears, or 0 if the type is
+// unknown.
+func ClearTypeLines(clearType string) int {
+	for lines := 1; lines <= 4; lines++ {
+		if ClearTypeFor(lines, false) == clearType || ClearTypeFor(lines, true) == clearType {
+			return lines
+		}
+	}
+	return 0
+}
+
+// LinesClearedPayload informs the server that lines were cleared. The
+// clear type, combo and back-to-back fields describe the attack; older
+// clients leave them empty.
+type LinesClearedPayload struct {
+	Count       int    `json:"count"`
+	AttackPower int    `json:"attack_power"`
+	TargetID    string `json:"target_id,omitempty"` // explicit target, empty = use server-stored target
+	ClearType   string `json:"clear_type,omitempty"`
+	Combo       int    `json:"combo,omitempty"` // clears in a row before this one
+	B2B         bool   `json:"b2b,omitempty"`   // back-to-back Tetris or T-spin
+	Spin        bool   `json:"spin,omitempty"`  // spin by any piece, in all-spin rooms
+}
+
+// SetTargetPayload tells the server who this player wants to attack.
+// Empty TargetID means "random".
+type SetTargetPayload struct {
+	TargetID string `json:"target_id"`
+}
+
+// PlayerDeadPayload informs the server this player has died.
+type PlayerDeadPayload struct{}
+
+// --- Room-based payloads ---
+
+// RoomCreatedPayload is sent to the player who created a room.
+type RoomCreatedPayload struct {
+	RoomCode string `json:"room_code"`
+}
+
+// RoomJoinedPayload is sent when a player successfully joins a room.
+type RoomJoinedPayload struct {
+	RoomCode string `json:"room_code"`
+}
+
+// RoomErrorPayload is sent when a room operation fails.
+type RoomErrorPayload struct {
+	Code    ErrorCode `json:"code,omitempty"`
+	Message string    `json:"message"`
+}
+
+// CreateRoomPayload is sent by a client to create a new room.
+type CreateRoomPayload struct {
+	PlayerName string `json:"player_name"`
+}
+
+// JoinRoomPayload is sent by a client to join an existing room.
+type JoinRoomPayload struct {
+	RoomCode   string `json:"room_code"`
+	PlayerName string `json:"player_name"`
+}
+
+// LeaveRoomPayload is sent by a client to leave the current room.
+type LeaveRoomPayload struct{}
+
+// SetNamePayload is sent by a client to update their display name.
+type SetNamePayload struct {
+	PlayerName string `json:"player_name"`
+}
+
+// --- HTTP Request/Response types ---
+
+// CreateRoomRequest is the JSON body for POST /create-room. Title is an
+// optional name for the room shown in the room browser.
+type CreateRoomRequest struct {
+	PlayerName string `json:"player_name"`
+	Title      string `json:"title,omitempty"`
+}
+
+// CreateRoomResponse is returned by POST /create-room.
+type CreateRoomResponse struct {
+	RoomID    string `json:"room_id"`
+	JoinToken string `json:"join_token"`
+}
+
+// JoinRoomHTTPRequest is the JSON body for POST /join-room. With Invite,
+// an invite's token, it joins the invite's room, whatever RoomID says,
+// and uses the invite up.
+type JoinRoomHTTPRequest struct {
+	RoomID     string `json:"room_id"`
+	PlayerName string `json:"player_name"`
+	Invite     string `json:"invite,omitempty"`
+}
+
+// Room types. Casual rooms are any the players set up, with whatever
+// settings the host likes; ranked rooms come from the ranked quick-play
+// queue, always play with the default settings and have the attacks
+// players report checked more strictly. Servers from before room types
+// send no type: their rooms are all casual.
+const (
+	RoomTypeCasual = "casual"
+	RoomTypeRanked = "ranked"
+)
+
+// QuickPlayRequest is the JSON body for POST /quick-play, which puts the
+// player in an open lobby of the given type (RoomTypeCasual or
+// RoomTypeRanked), or a new room if there is none. It returns a
+// JoinRoomHTTPResponse.
+type QuickPlayRequest struct {
+	PlayerName string `json:"player_name"`
+	Type       string `json:"type"`
+}
+
+// JoinRoomHTTPResponse is returned by POST /join-room and POST
+// /quick-play.
+type JoinRoomHTTPResponse struct {
+	RoomID    string `json:"room_id"`
+	JoinToken string `json:"join_token"`
+}
+
+// Room phases in RoomInfo.Phase, and for the phase query parameter of
+// /list-rooms.
+const (
+	RoomPhaseLobby     = "lobby"
+	RoomPhaseCountdown = "countdown"
+	RoomPhasePlaying   = "playing"
+	RoomPhaseGameOver  = "game_over"
+)
+
+// Orders for the sort query parameter of /list-rooms.
+const (
+	RoomSortCode    = "code"    // by room code (default)
+	RoomSortPlayers = "players" // most players first
+	RoomSortNewest  = "newest"  // most recently created first
+)
+
+// RoomInfo describes a room in the list-rooms response.
+type RoomInfo struct {
+	RoomID      string `json:"room_id"`
+	Title       string `json:"title,omitempty"`
+	Type        string `json:"type,omitempty"` // RoomTypeCasual or RoomTypeRanked
+	PlayerCount int    `json:"player_count"`
+	MaxPlayers  int    `json:"max_players"`
+	Phase       string `json:"phase"`
+	// Players names who is playing, for rooms in countdown or mid-match.
+	Players []string `json:"players,omitempty"`
+	// LastActivity is when a player last did something in the room or
+	// its phase last changed, in Unix milliseconds. Servers from before
+	// it leave it 0.
+	LastActivity int64 `json:"last_activity,omitempty"`
+}
+
+// ListRoomsResponse is returned by GET /list-rooms. Query parameters, all
+// optional, narrow and page the list:
+//
+//	q       rooms whose code or title contains it, ignoring case
+//	phase   rooms in that phase (RoomPhaseLobby etc.)
+//	open    if "1", rooms in the lobby with a free seat
+//	type    rooms of that type (RoomTypeCasual or RoomTypeRanked)
+//	sort    RoomSortCode, RoomSortPlayers or RoomSortNewest
+//	limit   at most this many rooms (capped by the server)
+//	offset  skip this many matching rooms first
+//
+// Total is how many rooms matched before limit and offset. Servers from
+// before paging ignore the parameters, return every room and no Total.
+type ListRoomsResponse struct {
+	Rooms []RoomInfo `json:"rooms"`
+	Total int        `json:"total,omitempty"`
+}
+
+// MatchRecord is one finished match in a player's history.
+type MatchRecord struct {
+	MatchID    string           `json:"match_id"`
+	RoomID     string           `json:"room_id"`
+	RoomTitle  string           `json:"room_title,omitempty"`
+	RoomType   string           `json:"room_type,omitempty"` // RoomTypeCasual or RoomTypeRanked
+	StartedAt  int64            `json:"started_at"`          // Unix milliseconds
+	DurationMs int64            `json:"duration_ms"`
+	WinnerID   string           `json:"winner_id,omitempty"`
+	Settings   RoomSettings     `json:"settings"`
+	Standings  []PlayerStanding `json:"standings"` // sorted by rank
+}
+
+// MatchHistoryResponse is returned by GET /players/{id}/matches: the
+// player's recent matches, most recent first. The optional limit query
+// parameter caps how many (the server caps it too). The server only
+// remembers matches since it started, and player IDs last one session
+// in a room.
+type MatchHistoryResponse struct {
+	Matches []MatchRecord `json:"matches"`
+}
+
+// ServerStatsResponse is returned by GET /stats. The totals count the
+// matches finished since the server started. PeakToday is the most
+// players connected at once since midnight, server time.
+type ServerStatsResponse struct {
+	MatchesPlayed int64 `json:"matches_played"`
+	LinesCleared  int64 `json:"lines_cleared"`
+	GarbageSent   int64 `json:"garbage_sent"`
+	OnlinePlayers int   `json:"online_players"`
+	PeakToday     int   `json:"peak_today"`
+	Rooms         int   `json:"rooms"`
+}
+
+// Profile is a player's client settings, kept by the server so they
+// follow the player to another machine. GET /profile returns it and PUT
+// /profile replaces it; both take the player's profile key, a secret the
+// client makes up, as a bearer token: "Authorization: Bearer <key>".
+// UpdatedAt, in Unix milliseconds, is set by the server when it's stored.
+type Profile struct {
+	Name           string `json:"name,omitempty"`
+	Lang           string `json:"lang,omitempty"`
+	Keymap         string `json:"keymap,omitempty"`
+	SoftDropFactor int    `json:"soft_drop_factor,omitempty"`
+	Sound          bool   `json:"sound,omitempty"`
+	ReducedMotion  bool   `json:"reduced_motion,omitempty"`
+	InputDisplay   bool   `json:"input_display,omitempty"`
+	Notify         bool   `json:"notify,omitempty"`
+	UpdatedAt      int64  `json:"updated_at,omitempty"`
+}
+
+// The achievements, by the ID they're kept under.
+const (
+	AchievementFirstTetris = "first_tetris" // clear a Tetris
+	AchievementFirstTSpin  = "first_tspin"  // clear lines with a T-spin
+	AchievementSprint      = "sprint"       // clear 40 lines in a game's first 2 minutes
+	AchievementFirstWin    = "first_win"    // win a match against others
+	AchievementTenWins     = "ten_wins"     // win 10 matches against others
+	AchievementCloseCall   = "close_call"   // win with 3 or more lines of garbage waiting
+)
+
+// AchievementIDs lists the achievements in the order they're shown.
+var AchievementIDs = []string{
+	AchievementFirstTetris,
+	AchievementFirstTSpin,
+	AchievementSprint,
+	AchievementFirstWin,
+	AchievementTenWins,
+	AchievementCloseCall,
+}
+
+// Achievements is the body of GET /achievements, and of POST
+// /achievements both ways: when each of the player's achievements was
+// unlocked, in Unix milliseconds, by ID.
+type Achievements struct {
+	Unlocked  map[string]int64 `json:"unlocked"`
+	UpdatedAt int64            `json:"updated_at,omitempty"`
+}
+
+// MergeAchievements adds the achievements unlocked in src to dst, keeping
+// the earlier time of any in both and leaving out IDs that aren't in
+// AchievementIDs. It returns dst, made if it was nil, and whether it
+// changed.
+func MergeAchievements(dst, src map[string]int64) (map[string]int64, bool) {
+	if dst == nil {
+		dst = make(map[string]int64)
+	}
+	changed := false
+	for _, id := range AchievementIDs {
+		at, ok := src[id]
+		if !ok || at <= 0 {
+			continue
+		}
+		if old, ok := dst[id]; !ok || at < old {
+			dst[id] = at
+			changed = true
+		}
+	}
+	return dst, changed
+}
+
+// CreateInvitePayload asks for an invite to the player's room, for the
+// player with the friend code To, or if To is empty, for whoever is given
+// the link. Only a player in the room can make one; the server answers
+// with MsgInviteCreated, or MsgRoomError.
+type CreateInvitePayload struct {
+	To string `json:"to,omitempty"`
+}
+
+// InviteCreatedPayload is the invite made for a MsgCreateInvite, and the
+// friend code it was for.
+type InviteCreatedPayload struct {
+	Invite Invite `json:"invite"`
+	To     string `json:"to,omitempty"`
+}
+
+// Invite is an invite to a room, made with MsgCreateInvite. Its Token
+// joins the room once (see JoinRoomHTTPRequest) until ExpiresAt, in Unix
+// milliseconds.
+type Invite struct {
+	Token     string `json:"token"`
+	RoomID    string `json:"room_id"`
+	FromName  string `json:"from_name"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// InvitesResponse is returned by GET /invites, which takes a profile key
+// like GET /profile: the friend code friends invite the key's player by,
+// and the invites to them, oldest first. DELETE /invites/{token}
+// declines one.
+type InvitesResponse struct {
+	FriendCode string   `json:"friend_code"`
+	Invites    []Invite `json:"invites"`
+}
+
+// ErrorResponse is a generic JSON error response. RetryAfter, in
+// seconds, comes with ErrCodeServerFull (as does a Retry-After header).
+type ErrorResponse struct {
+	Code       ErrorCode `json:"code,omitempty"`
+	Error      string    `json:"error"`
+	RetryAfter int       `json:"retry_after,omitempty"`
+}
+
+// --- Admin API ---
+//
+// The admin endpoints take the server's admin token as a bearer token:
+// "Authorization: Bearer <token>".
+
+// Ban is a banned address in the server's ban list. Since is when it was
+// banned, in Unix seconds.
+type Ban struct {
+	IP     string `json:"ip"`
+	Reason string `json:"reason,omitempty"`
+	Since  int64  `json:"since"`
+}
+
+// BansResponse is returned by GET /admin/bans.
+type BansResponse struct {
+	Bans []Ban `json:"bans"`
+}
+
+// BanRequest is the JSON body for POST /admin/bans. It bans IP, or if
+// that's empty, the address the connected player PlayerID plays from.
+// Players on a banned address are disconnected. POST /admin/quarantine
+// takes it too, to quarantine the address instead.
+type BanRequest struct {
+	IP       string `json:"ip,omitempty"`
+	PlayerID string `json:"player_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// QuarantineResponse is returned by GET /admin/quarantine: the
+// quarantined addresses, oldest first, each with why it was quarantined.
+type QuarantineResponse struct {
+	Quarantined []Ban `json:"quarantined"`
+}
+
+// MuteRequest is the JSON body for POST /admin/mutes: it mutes or unmutes
+// a player's emotes in their room, as the room's host can.
+type MuteRequest struct {
+	PlayerID string `json:"player_id"`
+	Muted    bool   `json:"muted"`
+}
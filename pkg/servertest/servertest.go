@@ -0,0 +1,242 @@
+// Package servertest runs a gotris server in-process, for integration
+// tests of clients, bots and the server itself. The server listens on an
+// ephemeral port on the loopback interface, and its timers can be sped up
+// so that countdowns and the pause after a match don't slow tests down.
+// Players are pkg/client clients with helpers for waiting on what the
+// server sends, so a scenario can be scripted step by step:
+//
+//	srv := servertest.Start(servertest.WithSpeed(20))
+//	defer srv.Close()
+//
+//	alice, room, err := srv.Create("alice")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	bob, err := srv.Join(room, "bob")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	alice.SetReady(true)
+//	bob.SetReady(true)
+//	if _, err := alice.Await(protocol.MsgGameStart); err != nil {
+//		log.Fatal(err)
+//	}
+//	alice.SendClear(protocol.LinesClearedPayload{Count: 4, AttackPower: 4, ClearType: protocol.ClearTetris})
+//	garbage, err := servertest.AwaitPayload[protocol.ReceiveGarbagePayload](bob, protocol.MsgReceiveGarbage)
+//
+// A room's setting Seed fixes the pieces every match deals, for games
+// that play out the same way each run.
+package servertest
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"time"
+
+	"github.com/hersh/gotris/internal/server"
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+)
+
+// defaultTimeout is how long Await waits by default.
+const defaultTimeout = 5 * time.Second
+
+// ErrTimeout is returned by Await when nothing it was waiting for came.
+var ErrTimeout = errors.New("servertest: timed out")
+
+// Option configures a Server; pass them to Start.
+type Option func(*config)
+
+type config struct {
+	opts    server.Options
+	timeout time.Duration
+	client  []client.Option
+}
+
+// WithSpeed runs the server's timers n times faster than normal: the
+// countdown, the auto-start timer, the pause after a match, the reconnect
+// grace period and the room watchdog.
+func WithSpeed(n int) Option {
+	return func(c *config) { c.opts.Speed = n }
+}
+
+// WithCapacity caps the rooms and WebSocket connections the server takes
+// at once; 0 is no limit, the default.
+func WithCapacity(rooms, conns int) Option {
+	return func(c *config) { c.opts.MaxRooms, c.opts.MaxConns = rooms, conns }
+}
+
+// WithAdminToken turns on the admin API, behind token.
+func WithAdminToken(token string) Option {
+	return func(c *config) { c.opts.AdminToken = token }
+}
+
+// WithTimeout sets how long Await waits before giving up. The default is
+// 5s.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) { c.timeout = d }
+}
+
+// WithClientOptions passes opts to client.New for every player, e.g.
+// client.WithNetSim for a player on a bad network.
+func WithClientOptions(opts ...client.Option) Option {
+	return func(c *config) { c.client = append(c.client, opts...) }
+}
+
+// Server is a gotris server running in-process.
+type Server struct {
+	// URL is the server's base URL, http://127.0.0.1:port, for
+	// client.New and HTTP requests of your own.
+	URL string
+
+	srv     *server.Server
+	ts      *httptest.Server
+	timeout time.Duration
+	client  []client.Option
+	players []*Player
+}
+
+// Start starts a server with opts. Close it when done.
+func Start(opts ...Option) *Server {
+	cfg := config{timeout: defaultTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	srv := server.New(cfg.opts)
+	ts := httptest.NewServer(srv)
+	return &Server{
+		URL:     ts.URL,
+		srv:     srv,
+		ts:      ts,
+		timeout: cfg.timeout,
+		client:  cfg.client,
+	}
+}
+
+// Close closes every player made by the server's helpers, then stops
+// the server.
+func (s *Server) Close() {
+	for _, p := range s.players {
+		p.Close()
+	}
+	s.srv.Close()
+	s.ts.Close()
+}
+
+// Drop cuts p's connection from the server's end, as a network failure
+// would, without a close frame. p's client goes on to reconnect, and
+// resumes their seat if a match is in progress. It reports false if p
+// wasn't connected.
+func (s *Server) Drop(p *Player) bool {
+	return s.srv.Drop(p.ID)
+}
+
+// Client returns a new client for the server, not yet in a room. Unlike
+// the Players made by Create and Join, it isn't closed by Close.
+func (s *Server) Client(opts ...client.Option) *client.Client {
+	return client.New(s.URL, append(s.client, opts...)...)
+}
+
+// Create makes a room with a player called name in it as host, and
+// returns the player once they're connected, with the room's code.
+func (s *Server) Create(name string) (*Player, string, error) {
+	p := s.newPlayer()
+	roomID, err := p.Client.Create(name)
+	if err != nil {
+		p.Close()
+		return nil, "", fmt.Errorf("creating room: %w", err)
+	}
+	if err := p.connected(); err != nil {
+		p.Close()
+		return nil, "", err
+	}
+	s.players = append(s.players, p)
+	return p, roomID, nil
+}
+
+// Join adds a player called name to room, and returns them once they're
+// connected.
+func (s *Server) Join(room, name string) (*Player, error) {
+	p := s.newPlayer()
+	if err := p.Client.Join(room, name); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("joining room %s: %w", room, err)
+	}
+	if err := p.connected(); err != nil {
+		p.Close()
+		return nil, err
+	}
+	s.players = append(s.players, p)
+	return p, nil
+}
+
+// newPlayer makes a player, for Create and Join to add to s.players once
+// they're connected.
+func (s *Server) newPlayer() *Player {
+	return &Player{Client: s.Client(), timeout: s.timeout}
+}
+
+// Player is a client in a room on the server.
+type Player struct {
+	*client.Client
+	// ID is the player's ID, as the server gave it.
+	ID string
+
+	timeout time.Duration
+}
+
+// connected waits for the server to give p their ID.
+func (p *Player) connected() error {
+	ev, err := p.AwaitEvent(func(ev client.Event) bool {
+		_, ok := ev.(client.ConnectedMsg)
+		return ok
+	})
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	p.ID = ev.(client.ConnectedMsg).PlayerID
+	return nil
+}
+
+// Await waits for the next message of type typ from the server and
+// returns it, passing over everything that comes before it.
+func (p *Player) Await(typ protocol.MessageType) (client.ServerMsg, error) {
+	ev, err := p.AwaitEvent(func(ev client.Event) bool {
+		msg, ok := ev.(client.ServerMsg)
+		return ok && msg.Type == typ
+	})
+	if err != nil {
+		return client.ServerMsg{}, fmt.Errorf("waiting for %s: %w", typ, err)
+	}
+	return ev.(client.ServerMsg), nil
+}
+
+// AwaitPayload waits for the next message of type typ to p and decodes
+// its payload.
+func AwaitPayload[T any](p *Player, typ protocol.MessageType) (T, error) {
+	msg, err := p.Await(typ)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return protocol.DecodePayload[T](msg.Type, msg.Raw)
+}
+
+// AwaitEvent waits for the next event from the client that match
+// accepts and returns it, passing over the ones before it. It gives up
+// with ErrTimeout once the WithTimeout timeout has passed.
+func (p *Player) AwaitEvent(match func(client.Event) bool) (client.Event, error) {
+	timeout := time.NewTimer(p.timeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case ev := <-p.Events():
+			if match(ev) {
+				return ev, nil
+			}
+		case <-timeout.C:
+			return nil, ErrTimeout
+		}
+	}
+}
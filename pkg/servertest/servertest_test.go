@@ -0,0 +1,174 @@
+package servertest_test
+
+import (
+	"testing"
+
+	"github.com/hersh/gotris/pkg/client"
+	"github.com/hersh/gotris/pkg/protocol"
+	"github.com/hersh/gotris/pkg/servertest"
+)
+
+var tetris = protocol.LinesClearedPayload{Count: 4, AttackPower: 4, ClearType: protocol.ClearTetris}
+
+// startMatch puts players with names in a new room, the first as host,
+// and readies them all up, returning them once the match has started.
+func startMatch(t *testing.T, srv *servertest.Server, names ...string) []*servertest.Player {
+	t.Helper()
+	host, room, err := srv.Create(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	players := []*servertest.Player{host}
+	for _, name := range names[1:] {
+		p, err := srv.Join(room, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+	for _, p := range players {
+		p.SetReady(true)
+	}
+	for _, p := range players {
+		if _, err := p.Await(protocol.MsgGameStart); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return players
+}
+
+// TestJoinReadyAttackDisconnect plays the scenario in the package doc:
+// players join a room and ready up, one sends another a Tetris's worth of
+// garbage, and that one leaves mid-match. Once the last other player tops
+// out, the first wins.
+func TestJoinReadyAttackDisconnect(t *testing.T) {
+	srv := servertest.Start(servertest.WithSpeed(20))
+	defer srv.Close()
+	players := startMatch(t, srv, "alice", "bob", "carol")
+	alice, bob, carol := players[0], players[1], players[2]
+
+	alice.SetTarget(bob.ID)
+	alice.SendClear(tetris)
+	garbage, err := servertest.AwaitPayload[protocol.ReceiveGarbagePayload](bob, protocol.MsgReceiveGarbage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if garbage.Lines != 4 || garbage.AttackerID != alice.ID {
+		t.Errorf("bob got %d lines from %q, want 4 from %q", garbage.Lines, garbage.AttackerID, alice.ID)
+	}
+
+	bob.DisconnectFromRoom()
+	carol.SendDead()
+	over, err := servertest.AwaitPayload[protocol.MatchOverPayload](alice, protocol.MsgMatchOver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if over.WinnerID != alice.ID || over.YourRank != 1 {
+		t.Errorf("match over: winner %q, alice ranked %d; want alice first", over.WinnerID, over.YourRank)
+	}
+}
+
+// TestReconnectBackfill drops a player's connection mid-match and attacks
+// them before their client is back. The client resumes their seat, and
+// the garbage sent while they were away is backfilled ahead of the
+// assign_id that confirms it, and comes only once.
+func TestReconnectBackfill(t *testing.T) {
+	srv := servertest.Start(servertest.WithSpeed(20))
+	defer srv.Close()
+	players := startMatch(t, srv, "alice", "bob")
+	alice, bob := players[0], players[1]
+
+	if !srv.Drop(bob) {
+		t.Fatal("bob wasn't connected")
+	}
+	alice.SetTarget(bob.ID)
+	alice.SendClear(tetris)
+
+	var garbage int
+	ev, err := bob.AwaitEvent(func(ev client.Event) bool {
+		switch ev := ev.(type) {
+		case client.ServerMsg:
+			if ev.Type == protocol.MsgReceiveGarbage {
+				garbage++
+			}
+		case client.ConnectedMsg:
+			return true
+		case client.DisconnectedMsg:
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	connected, ok := ev.(client.ConnectedMsg)
+	if !ok {
+		t.Fatalf("bob didn't reconnect: %v", ev.(client.DisconnectedMsg).Err)
+	}
+	if !connected.Resumed || connected.Missed || connected.PlayerID != bob.ID {
+		t.Errorf("bob reconnected as %q, resumed %v, missed %v; want resumed as %q with nothing missed",
+			connected.PlayerID, connected.Resumed, connected.Missed, bob.ID)
+	}
+	if garbage != 1 {
+		t.Fatalf("bob got the garbage %d times before reconnecting, want it backfilled once", garbage)
+	}
+
+	// Anything still to come after a marker message isn't backfill.
+	alice.SendEmote(protocol.Emotes[0])
+	if _, err := bob.AwaitEvent(func(ev client.Event) bool {
+		msg, ok := ev.(client.ServerMsg)
+		if ok && msg.Type == protocol.MsgReceiveGarbage {
+			garbage++
+		}
+		return ok && msg.Type == protocol.MsgEmote
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if garbage != 1 {
+		t.Errorf("bob got the garbage %d times in all, want once", garbage)
+	}
+}
+
+// TestRoomFull fills a room to the maximum its host set, and turns the
+// next player away.
+func TestRoomFull(t *testing.T) {
+	srv := servertest.Start(servertest.WithSpeed(20))
+	defer srv.Close()
+	alice, room, err := srv.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lobby, err := servertest.AwaitPayload[protocol.LobbyUpdatePayload](alice, protocol.MsgLobbyUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := lobby.Settings
+	settings.MaxPlayers = 2
+	alice.UpdateSettings(settings)
+	for lobby.Settings.MaxPlayers != 2 {
+		if lobby, err = servertest.AwaitPayload[protocol.LobbyUpdatePayload](alice, protocol.MsgLobbyUpdate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := srv.Join(room, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = srv.Join(room, "carol")
+	if code := client.ErrorCode(err); code != protocol.ErrCodeRoomFull {
+		t.Errorf("joining a full room: got %v (code %q), want %q", err, code, protocol.ErrCodeRoomFull)
+	}
+}
+
+// TestServerFull caps the server at one room and turns the second away.
+func TestServerFull(t *testing.T) {
+	srv := servertest.Start(servertest.WithSpeed(20), servertest.WithCapacity(1, 0))
+	defer srv.Close()
+	if _, _, err := srv.Create("alice"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := srv.Create("bob")
+	if code := client.ErrorCode(err); code != protocol.ErrCodeServerFull {
+		t.Errorf("creating past the room cap: got %v (code %q), want %q", err, code, protocol.ErrCodeServerFull)
+	}
+}